@@ -1,10 +1,19 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
 	domain "task_manager_api/Domain"
+	infrastructure "task_manager_api/Infrastructure"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 )
 
 type TaskController struct {
@@ -15,6 +24,10 @@ type UserController struct {
 	UserUsecase domain.UserUsecaseInterface
 }
 
+type RoleController struct {
+	RoleUsecase domain.RoleUsecaseInterface
+}
+
 /*
 Get the HTTP status code of an error based on the incoming error type.
 This function checks for the mongoDB errors in particular and returns the
@@ -30,75 +43,268 @@ func GetHTTPErrorCode(err domain.CodedError) int {
 		return http.StatusNotFound
 	case domain.ERR_UNAUTHORIZED:
 		return http.StatusUnauthorized
+	case domain.ERR_FORBIDDEN:
+		return http.StatusForbidden
+	case domain.ERR_CONFLICT:
+		return http.StatusConflict
+	case domain.ERR_PRECONDITION_FAILED:
+		return http.StatusPreconditionFailed
+	case domain.ERR_INVALID_TRANSITION:
+		return http.StatusUnprocessableEntity
+	case domain.ERR_TOO_MANY_REQUESTS:
+		return http.StatusTooManyRequests
+	case domain.ERR_UNSUPPORTED_MEDIA_TYPE:
+		return http.StatusUnsupportedMediaType
 	default:
 		return http.StatusInternalServerError
 	}
 }
 
+/*
+Sends the provided error to the client as an `application/problem+json`
+document with the status code of its type. The details of internal errors
+are logged instead of being sent, see `infrastructure.NewProblem`.
+*/
+func respondWithError(c *gin.Context, err domain.CodedError) {
+	infrastructure.WriteProblem(c, GetHTTPErrorCode(err), err)
+}
+
+/*
+Returns the fields of the request body that caused the binding error, named
+as they appear in the request. The body must be a pointer to the struct the
+request was bound to.
+*/
+func bindingFields(body any, err error) []domain.FieldError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		bodyType := reflect.TypeOf(body).Elem()
+		fields := make([]domain.FieldError, 0, len(validationErrs))
+		for _, validationErr := range validationErrs {
+			name := validationErr.Field()
+			if structField, ok := bodyType.FieldByName(validationErr.StructField()); ok {
+				name, _, _ = strings.Cut(structField.Tag.Get("json"), ",")
+			}
+
+			message := name + " is invalid"
+			if validationErr.Tag() == "required" {
+				message = name + " is required"
+			}
+			fields = append(fields, domain.FieldError{Field: name, Message: message})
+		}
+
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []domain.FieldError{{Field: typeErr.Field, Message: fmt.Sprintf("%v can not be a %v", typeErr.Field, typeErr.Value)}}
+	}
+
+	return nil
+}
+
+/*
+Returns the subject stored in the context by the auth middleware. An
+empty subject is returned if the request has not been authenticated.
+*/
+func getSubject(c *gin.Context) domain.Subject {
+	subject, _ := c.Get(domain.ContextSubject)
+	if subject, ok := subject.(domain.Subject); ok {
+		return subject
+	}
+
+	return domain.Subject{}
+}
+
+/* Returns the entity tag of the provided task, derived from its version */
+func taskETag(task domain.Task) string {
+	return fmt.Sprintf(`"%v"`, task.Version)
+}
+
+/*
+Parses the If-Match header of the request into the task version that the
+client expects. Returns zero if the header is absent or is a wildcard and
+false if the header does not contain a single valid task entity tag.
+*/
+func parseIfMatch(c *gin.Context) (int64, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, true
+	}
+
+	if len(header) < 3 || header[0] != '"' || header[len(header)-1] != '"' {
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(header[1:len(header)-1], 10, 64)
+	if err != nil || version < 1 {
+		return 0, false
+	}
+
+	return version, true
+}
+
+/*
+Checks whether the If-None-Match header of the request matches the provided
+entity tag. Weak comparison is used as specified for If-None-Match.
+*/
+func matchesIfNoneMatch(c *gin.Context, etag string) bool {
+	header := strings.TrimSpace(c.GetHeader("If-None-Match"))
+	if header == "*" {
+		return true
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+Returns the URL of the current request with the offset query parameter
+replaced by the provided value. Used to build the pagination links.
+*/
+func pageLink(c *gin.Context, offset int64) string {
+	link := *c.Request.URL
+	query := link.Query()
+	query.Set("offset", strconv.FormatInt(offset, 10))
+	link.RawQuery = query.Encode()
+	return link.RequestURI()
+}
+
 // handler for GET /tasks
 func (tC *TaskController) GetAll(c *gin.Context) {
-	tasks, err := tC.TaskUsecase.GetAllTasks(c)
+	var query domain.TaskQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondWithError(c, domain.TaskError{Message: "Invalid query parameters", Code: domain.ERR_BAD_REQUEST})
+		return
+	}
+
+	page, err := tC.TaskUsecase.GetAllTasks(c, getSubject(c), query)
 	if err != nil {
-		c.JSON(GetHTTPErrorCode(err), domain.Response{"message": "Error: " + err.Error()})
+		respondWithError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, tasks)
+	if page.Offset+int64(len(page.Tasks)) < page.Total {
+		page.Next = pageLink(c, page.Offset+page.Limit)
+	}
+	if page.Offset > 0 {
+		page.Previous = pageLink(c, max(page.Offset-page.Limit, 0))
+	}
+
+	c.JSON(http.StatusOK, newTaskPageResponse(page))
 }
 
 // handler for GET /tasks/:id
 func (tC *TaskController) GetOne(c *gin.Context) {
 	id := c.Param("id")
-	task, err := tC.TaskUsecase.GetTaskByID(c, id)
+	task, err := tC.TaskUsecase.GetTaskByID(c, getSubject(c), id)
 	if err != nil {
-		c.JSON(GetHTTPErrorCode(err), domain.Response{"message": "Error: " + err.Error()})
+		respondWithError(c, err)
+		return
+	}
+
+	etag := taskETag(task)
+	c.Header("ETag", etag)
+	if matchesIfNoneMatch(c, etag) {
+		c.Status(http.StatusNotModified)
 		return
 	}
 
-	c.JSON(http.StatusOK, task)
+	c.JSON(http.StatusOK, newTaskResponse(task))
 }
 
 // handler for POST /tasks
 func (tC *TaskController) Create(c *gin.Context) {
-	var newTask domain.Task
-	if err := c.Bind(&newTask); err != nil {
-		c.JSON(http.StatusBadRequest, domain.Response{"message": "Error during object binding"})
+	var body taskRequest
+	if err := c.ShouldBind(&body); err != nil {
+		respondWithError(c, domain.TaskError{Message: "Invalid request body", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
 		return
 	}
 
-	err := tC.TaskUsecase.AddTask(c, newTask)
+	createdTask, err := tC.TaskUsecase.AddTask(c, getSubject(c), body.toTask())
 	if err != nil {
-		c.JSON(GetHTTPErrorCode(err), domain.Response{"message": "Error; " + err.Error()})
+		respondWithError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, newTask)
+	c.Header("Location", path.Join(c.Request.URL.Path, createdTask.ID))
+	c.Header("ETag", taskETag(createdTask))
+	c.JSON(http.StatusCreated, newTaskResponse(createdTask))
 }
 
 // handler for PUT /tasks/:id
 func (tC *TaskController) Update(c *gin.Context) {
-	var updatedTask domain.Task
+	var body taskRequest
 	id := c.Param("id")
-	if err := c.Bind(&updatedTask); err != nil {
-		c.JSON(http.StatusBadRequest, domain.Response{"message": "Error during object binding"})
+	if err := c.ShouldBind(&body); err != nil {
+		respondWithError(c, domain.TaskError{Message: "Invalid request body", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
+		return
+	}
+
+	expectedVersion, ok := parseIfMatch(c)
+	if !ok {
+		respondWithError(c, domain.TaskError{Message: "If-Match must contain a single task ETag", Code: domain.ERR_BAD_REQUEST})
 		return
 	}
 
-	newTask, err := tC.TaskUsecase.UpdateTask(c, id, updatedTask)
+	newTask, err := tC.TaskUsecase.UpdateTask(c, getSubject(c), id, body.toTask(), expectedVersion)
 	if err != nil {
-		c.JSON(GetHTTPErrorCode(err), domain.Response{"message": "Error: " + err.Error()})
+		respondWithError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, newTask)
+	c.Header("ETag", taskETag(newTask))
+	c.JSON(http.StatusOK, newTaskResponse(newTask))
+}
+
+// handler for PATCH /tasks/:id
+func (tC *TaskController) Patch(c *gin.Context) {
+	id := c.Param("id")
+	contentType := c.ContentType()
+	if contentType != "application/merge-patch+json" && contentType != "application/json" {
+		respondWithError(c, domain.TaskError{Message: "The request body must be an application/merge-patch+json document", Code: domain.ERR_UNSUPPORTED_MEDIA_TYPE})
+		return
+	}
+
+	var patch domain.TaskPatch
+	if err := json.NewDecoder(c.Request.Body).Decode(&patch); err != nil || patch == nil {
+		respondWithError(c, domain.TaskError{Message: "The request body must be a JSON object", Code: domain.ERR_BAD_REQUEST})
+		return
+	}
+
+	expectedVersion, ok := parseIfMatch(c)
+	if !ok {
+		respondWithError(c, domain.TaskError{Message: "If-Match must contain a single task ETag", Code: domain.ERR_BAD_REQUEST})
+		return
+	}
+
+	patchedTask, err := tC.TaskUsecase.PatchTask(c, getSubject(c), id, patch, expectedVersion)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.Header("ETag", taskETag(patchedTask))
+	c.JSON(http.StatusOK, newTaskResponse(patchedTask))
 }
 
 // handler for DELETE /tasks/:id
 func (tC *TaskController) Delete(c *gin.Context) {
 	id := c.Param("id")
-	err := tC.TaskUsecase.DeleteTask(c, id)
+	expectedVersion, ok := parseIfMatch(c)
+	if !ok {
+		respondWithError(c, domain.TaskError{Message: "If-Match must contain a single task ETag", Code: domain.ERR_BAD_REQUEST})
+		return
+	}
+
+	err := tC.TaskUsecase.DeleteTask(c, getSubject(c), id, expectedVersion)
 	if err != nil {
-		c.JSON(GetHTTPErrorCode(err), domain.Response{"message": "Error: " + err.Error()})
+		respondWithError(c, err)
 		return
 	}
 
@@ -107,46 +313,621 @@ func (tC *TaskController) Delete(c *gin.Context) {
 
 // handler for /signup
 func (uC *UserController) Signup(c *gin.Context) {
-	var user domain.User
-	if err := c.Bind(&user); err != nil {
-		c.JSON(http.StatusBadRequest, domain.Response{"message": "Error during object binding"})
+	var body signupRequest
+	if err := c.ShouldBind(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "Invalid request body", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
 		return
 	}
 
-	err := uC.UserUsecase.CreateUser(c, user)
+	verificationSent, err := uC.UserUsecase.CreateUser(c, body.toUser())
 	if err != nil {
-		c.JSON(GetHTTPErrorCode(err), domain.Response{"message": "Error: " + err.Error()})
+		respondWithError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, domain.Response{"message": "Signup successful"})
+	message := "Signup successful"
+	if verificationSent {
+		message += ": a verification token has been sent to the provided email"
+	}
+
+	c.JSON(http.StatusCreated, domain.Response{"message": message})
+}
+
+// handler for POST /users
+func (uC *UserController) CreateAccount(c *gin.Context) {
+	var body createAccountRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "Invalid request body", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
+		return
+	}
+
+	verificationSent, err := uC.UserUsecase.CreateAccount(c, getSubject(c), body.toUser())
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	message := "Account created"
+	if verificationSent {
+		message += ": a verification token has been sent to the provided email"
+	}
+
+	c.JSON(http.StatusCreated, domain.Response{"message": message})
 }
 
 // handler for /login
 func (uC *UserController) Login(c *gin.Context) {
-	var user domain.User
-	if err := c.Bind(&user); err != nil {
-		c.JSON(http.StatusBadRequest, domain.Response{"message": "Error during object binding"})
+	var body loginRequest
+	if err := c.ShouldBind(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "Invalid request body", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
 		return
 	}
 
-	token, err := uC.UserUsecase.ValidateAndGetToken(c, user)
+	tokens, err := uC.UserUsecase.ValidateAndGetToken(c, body.toUser(), c.ClientIP())
 	if err != nil {
-		c.JSON(GetHTTPErrorCode(err), domain.Response{"message": err.Error()})
+		respondWithError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, domain.Response{"message": "User logged in successfully", "token": token})
+	// the challenge token is exchanged for the tokens at /login/mfa
+	if tokens.ChallengeToken != "" {
+		c.JSON(http.StatusOK, domain.Response{"message": "Two-factor authentication required", "mfa_required": true, "mfa_enrollment_required": tokens.MFAEnrollmentRequired, "challenge_token": tokens.ChallengeToken})
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"message": "User logged in successfully", "token": tokens.AccessToken, "refresh_token": tokens.RefreshToken})
+}
+
+// handler for /login/mfa
+func (uC *UserController) VerifyMFALogin(c *gin.Context) {
+	var body mfaLoginBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "A challenge_token is required", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
+		return
+	}
+
+	if body.Code == "" && body.RecoveryCode == "" {
+		respondWithError(c, domain.UserError{Message: "A code or a recovery_code is required", Code: domain.ERR_BAD_REQUEST, Fields: []domain.FieldError{{Field: "code", Message: "A code or a recovery_code is required"}}})
+		return
+	}
+
+	tokens, err := uC.UserUsecase.VerifyMFALogin(c, body.ChallengeToken, body.Code, body.RecoveryCode, c.ClientIP())
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	response := domain.Response{"message": "User logged in successfully", "token": tokens.AccessToken, "refresh_token": tokens.RefreshToken}
+	if tokens.RecoveryCodes != nil {
+		response["recovery_codes"] = tokens.RecoveryCodes
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handler for /login/mfa/enroll
+func (uC *UserController) EnrollMFALogin(c *gin.Context) {
+	var body mfaChallengeBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "A challenge_token is required", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
+		return
+	}
+
+	setup, err := uC.UserUsecase.EnrollMFALogin(c, body.ChallengeToken)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+// handler for /token/refresh
+func (uC *UserController) Refresh(c *gin.Context) {
+	var body refreshTokenBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "A refresh_token is required", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
+		return
+	}
+
+	tokens, err := uC.UserUsecase.RefreshTokens(c, body.RefreshToken)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"message": "Tokens refreshed successfully", "token": tokens.AccessToken, "refresh_token": tokens.RefreshToken})
+}
+
+// handler for /logout
+func (uC *UserController) Logout(c *gin.Context) {
+	var body refreshTokenBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "A refresh_token is required", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
+		return
+	}
+
+	err := uC.UserUsecase.Logout(c, body.RefreshToken)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"message": "Logged out successfully"})
 }
 
 // handler for /promote/:username
 func (uC *UserController) Promote(c *gin.Context) {
 	username := c.Param("username")
-	err := uC.UserUsecase.Promote(c, username)
+	err := uC.UserUsecase.Promote(c, getSubject(c), username)
 	if err != nil {
-		c.JSON(GetHTTPErrorCode(err), domain.Response{"message": err.Error()})
+		respondWithError(c, err)
 		return
 	}
 
 	c.JSON(200, domain.Response{"message": "Used promoted successfully"})
 }
+
+// handler for GET /sessions
+func (uC *UserController) GetSessions(c *gin.Context) {
+	sessions, err := uC.UserUsecase.GetSessions(c, getSubject(c))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"sessions": sessions})
+}
+
+// handler for DELETE /sessions/:id
+func (uC *UserController) RevokeSession(c *gin.Context) {
+	err := uC.UserUsecase.RevokeSession(c, getSubject(c), c.Param("id"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"message": "Session revoked"})
+}
+
+// handler for POST /me/api-keys
+func (uC *UserController) CreateAPIKey(c *gin.Context) {
+	var body createAPIKeyBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "The name and scopes are required", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
+		return
+	}
+
+	key, rawKey, err := uC.UserUsecase.CreateAPIKey(c, getSubject(c), body.Name, body.Scopes, body.expiry())
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	response := newAPIKeyResponse(key)
+	response.Key = rawKey
+	c.JSON(http.StatusCreated, response)
+}
+
+// handler for GET /me/api-keys
+func (uC *UserController) GetAPIKeys(c *gin.Context) {
+	keys, err := uC.UserUsecase.GetAPIKeys(c, getSubject(c))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	response := make([]apiKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, newAPIKeyResponse(key))
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"api_keys": response})
+}
+
+// handler for DELETE /me/api-keys/:id
+func (uC *UserController) RevokeAPIKey(c *gin.Context) {
+	err := uC.UserUsecase.RevokeAPIKey(c, getSubject(c), c.Param("id"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"message": "API key revoked"})
+}
+
+// handler for DELETE /users/:username/sessions
+func (uC *UserController) RevokeUserSessions(c *gin.Context) {
+	err := uC.UserUsecase.RevokeUserSessions(c, c.Param("username"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"message": "All sessions of the user have been revoked"})
+}
+
+// handler for /demote/:username
+func (uC *UserController) Demote(c *gin.Context) {
+	err := uC.UserUsecase.Demote(c, getSubject(c), c.Param("username"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"message": "User demoted successfully"})
+}
+
+// handler for PUT /users/:username/role
+func (uC *UserController) SetRole(c *gin.Context) {
+	var body setRoleBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "A role is required", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
+		return
+	}
+
+	err := uC.UserUsecase.SetRole(c, getSubject(c), c.Param("username"), body.Role)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"message": "Role assigned successfully"})
+}
+
+// handler for PATCH /users/:username/deactivate
+func (uC *UserController) Deactivate(c *gin.Context) {
+	err := uC.UserUsecase.Deactivate(c, getSubject(c), c.Param("username"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"message": "User deactivated successfully"})
+}
+
+// handler for PATCH /users/:username/activate
+func (uC *UserController) Activate(c *gin.Context) {
+	err := uC.UserUsecase.Activate(c, c.Param("username"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"message": "User activated successfully"})
+}
+
+// handler for PATCH /users/:username/unlock
+func (uC *UserController) Unlock(c *gin.Context) {
+	err := uC.UserUsecase.UnlockAccount(c, c.Param("username"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"message": "Account unlocked successfully"})
+}
+
+// handler for DELETE /users/:username/mfa
+func (uC *UserController) ResetMFA(c *gin.Context) {
+	err := uC.UserUsecase.ResetMFA(c, c.Param("username"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"message": "Two-factor authentication reset successfully"})
+}
+
+// handler for DELETE /users/:username
+func (uC *UserController) DeleteUser(c *gin.Context) {
+	deletion, err := uC.UserUsecase.DeleteUser(c, getSubject(c), c.Param("username"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"message": "User deleted successfully", "deleted_tasks": deletion.DeletedTasks, "unassigned_tasks": deletion.UnassignedTasks})
+}
+
+// handler for GET /users
+func (uC *UserController) GetUsers(c *gin.Context) {
+	var query domain.UserQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondWithError(c, domain.UserError{Message: "Invalid query parameters", Code: domain.ERR_BAD_REQUEST})
+		return
+	}
+
+	page, err := uC.UserUsecase.GetUsers(c, query)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	if page.Offset+int64(len(page.Users)) < page.Total {
+		page.Next = pageLink(c, page.Offset+page.Limit)
+	}
+	if page.Offset > 0 {
+		page.Previous = pageLink(c, max(page.Offset-page.Limit, 0))
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// handler for GET /users/:username
+func (uC *UserController) GetUser(c *gin.Context) {
+	profile, err := uC.UserUsecase.GetUser(c, c.Param("username"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// handler for GET /me
+func (uC *UserController) GetProfile(c *gin.Context) {
+	profile, err := uC.UserUsecase.GetProfile(c, getSubject(c))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// handler for PATCH /me
+func (uC *UserController) UpdateProfile(c *gin.Context) {
+	var body updateProfileBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "The current_password is required", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
+		return
+	}
+
+	profile, err := uC.UserUsecase.UpdateProfile(c, getSubject(c), body.CurrentPassword, body.ProfileUpdate)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// handler for PUT /me/password
+func (uC *UserController) ChangePassword(c *gin.Context) {
+	var body changePasswordBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "The current_password and the new_password are required", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
+		return
+	}
+
+	err := uC.UserUsecase.ChangePassword(c, getSubject(c), body.CurrentPassword, body.NewPassword)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"message": "Password changed successfully: every other session has been revoked"})
+}
+
+// handler for POST /me/mfa/totp
+func (uC *UserController) EnrollTOTP(c *gin.Context) {
+	var body currentPasswordBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "The current_password is required", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
+		return
+	}
+
+	setup, err := uC.UserUsecase.EnrollTOTP(c, getSubject(c), body.CurrentPassword)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+// handler for POST /me/mfa/totp/confirm
+func (uC *UserController) ConfirmTOTP(c *gin.Context) {
+	var body totpCodeBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "A code is required", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
+		return
+	}
+
+	recoveryCodes, err := uC.UserUsecase.ConfirmTOTP(c, getSubject(c), body.Code)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"message": "Two-factor authentication enabled successfully", "recovery_codes": recoveryCodes})
+}
+
+// handler for POST /me/mfa/recovery-codes
+func (uC *UserController) RegenerateRecoveryCodes(c *gin.Context) {
+	var body totpCodeBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "A code is required", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
+		return
+	}
+
+	recoveryCodes, err := uC.UserUsecase.RegenerateRecoveryCodes(c, getSubject(c), body.Code)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"message": "Recovery codes regenerated successfully", "recovery_codes": recoveryCodes})
+}
+
+// handler for DELETE /me/mfa
+func (uC *UserController) DisableMFA(c *gin.Context) {
+	var body disableMFABody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "The current_password is required", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
+		return
+	}
+
+	err := uC.UserUsecase.DisableMFA(c, getSubject(c), body.CurrentPassword, body.Code)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"message": "Two-factor authentication disabled successfully"})
+}
+
+// handler for /password/forgot
+func (uC *UserController) ForgotPassword(c *gin.Context) {
+	var body emailBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "An email is required", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
+		return
+	}
+
+	err := uC.UserUsecase.ForgotPassword(c, body.Email)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	// the same response is sent whether or not the email is registered
+	c.JSON(http.StatusOK, domain.Response{"message": "If an account with the provided email exists, a password reset token has been sent"})
+}
+
+// handler for /password/reset
+func (uC *UserController) ResetPassword(c *gin.Context) {
+	var body resetPasswordBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "A token and a new_password are required", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
+		return
+	}
+
+	err := uC.UserUsecase.ResetPassword(c, body.Token, body.NewPassword)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"message": "Password reset successfully"})
+}
+
+// handler for /verify-email
+func (uC *UserController) VerifyEmail(c *gin.Context) {
+	var body verifyEmailBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "A token is required", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
+		return
+	}
+
+	err := uC.UserUsecase.VerifyEmail(c, body.Token)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"message": "Email verified successfully"})
+}
+
+// handler for /verify-email/resend
+func (uC *UserController) ResendVerification(c *gin.Context) {
+	var body emailBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "An email is required", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
+		return
+	}
+
+	err := uC.UserUsecase.ResendVerification(c, body.Email)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	// the same response is sent whether or not the email is registered
+	c.JSON(http.StatusOK, domain.Response{"message": "If an unverified account with the provided email exists, a verification token has been sent"})
+}
+
+// handler for GET /roles
+func (rC *RoleController) GetRoles(c *gin.Context) {
+	roles, err := rC.RoleUsecase.GetRoles(c)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	response := make([]roleResponse, 0, len(roles))
+	for _, role := range roles {
+		response = append(response, newRoleResponse(role))
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"roles": response})
+}
+
+// handler for GET /roles/:name
+func (rC *RoleController) GetRole(c *gin.Context) {
+	role, err := rC.RoleUsecase.GetRole(c, c.Param("name"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newRoleResponse(role))
+}
+
+// handler for PUT /roles/:name
+func (rC *RoleController) SaveRole(c *gin.Context) {
+	var body roleBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "The permissions are required", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
+		return
+	}
+
+	role, err := rC.RoleUsecase.SaveRole(c, getSubject(c), domain.Role{Name: c.Param("name"), Permissions: body.Permissions})
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newRoleResponse(role))
+}
+
+// handler for PUT /roles/:name/mfa
+func (rC *RoleController) SetRoleMFA(c *gin.Context) {
+	var body roleMFABody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondWithError(c, domain.UserError{Message: "Whether a second factor is required must be provided", Code: domain.ERR_BAD_REQUEST, Fields: bindingFields(&body, err)})
+		return
+	}
+
+	role, err := rC.RoleUsecase.SetRoleMFA(c, getSubject(c), c.Param("name"), *body.Required)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newRoleResponse(role))
+}
+
+// handler for DELETE /roles/:name
+func (rC *RoleController) DeleteRole(c *gin.Context) {
+	err := rC.RoleUsecase.DeleteRole(c, getSubject(c), c.Param("name"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Response{"message": "Role deleted successfully"})
+}
+
+/*
+Returns the handler for /.well-known/jwks.json that serves the public keys
+used to verify the tokens. The keys only change on restarts, so responses
+can be cached for a short while.
+*/
+func JWKSHandler(keySet domain.JSONWebKeySet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, keySet)
+	}
+}
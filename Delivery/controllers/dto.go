@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	domain "task_manager_api/Domain"
+	"time"
+)
+
+/*
+The request and response bodies of the endpoints. The domain models are
+never bound from the requests or sent in the responses directly, so fields
+such as the password hash of the users or the owner and version of the
+tasks can neither leak nor be set by the clients.
+*/
+
+/* request body of /signup */
+type signupRequest struct {
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	DisplayName string `json:"display_name"`
+	Password    string `json:"password"`
+}
+
+/* Returns the account described by the signup request */
+func (request signupRequest) toUser() domain.User {
+	return domain.User{
+		Username:    request.Username,
+		Email:       request.Email,
+		DisplayName: request.DisplayName,
+		Password:    request.Password,
+	}
+}
+
+/* request body of POST /users */
+type createAccountRequest struct {
+	signupRequest
+	Role string `json:"role"`
+}
+
+/* Returns the account described by the request, along with its role */
+func (request createAccountRequest) toUser() domain.User {
+	user := request.signupRequest.toUser()
+	user.Role = request.Role
+	return user
+}
+
+/* request body of /login */
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+/* Returns the credentials of the login request as a user */
+func (request loginRequest) toUser() domain.User {
+	return domain.User{Username: request.Username, Password: request.Password}
+}
+
+/* request body of the endpoints that consume a refresh token */
+type refreshTokenBody struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+/* request body of the endpoints that only take the email of an account */
+type emailBody struct {
+	Email string `json:"email" binding:"required"`
+}
+
+/* request body of /verify-email */
+type verifyEmailBody struct {
+	Token string `json:"token" binding:"required"`
+}
+
+/* request body of /password/reset */
+type resetPasswordBody struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+/* request body of PATCH /me */
+type updateProfileBody struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	domain.ProfileUpdate
+}
+
+/* request body of PUT /me/password */
+type changePasswordBody struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+/* request body of /login/mfa, which takes either a TOTP code or a recovery code */
+type mfaLoginBody struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+/* request body of /login/mfa/enroll */
+type mfaChallengeBody struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+/* request body of POST /me/mfa/totp */
+type currentPasswordBody struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+}
+
+/* request body of the endpoints that take a TOTP code of the subject */
+type totpCodeBody struct {
+	Code string `json:"code" binding:"required"`
+}
+
+/* request body of DELETE /me/mfa. The code is only needed when the second factor is enabled */
+type disableMFABody struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	Code            string `json:"code"`
+}
+
+/* request body of POST /me/api-keys. Keys without an expiry date never expire */
+type createAPIKeyBody struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+/* Returns the expiry date of the new key, the zero time if it never expires */
+func (request createAPIKeyBody) expiry() time.Time {
+	if request.ExpiresAt == nil {
+		return time.Time{}
+	}
+
+	return *request.ExpiresAt
+}
+
+/*
+response body of the endpoints that return an API key. The key itself is
+only sent once, when it is created, and the times are null when the key
+never expires or has never been used.
+*/
+type apiKeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Key        string     `json:"key,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+/* Returns the provided time, or nil for the zero time */
+func optionalTime(value time.Time) *time.Time {
+	if value.IsZero() {
+		return nil
+	}
+
+	return &value
+}
+
+/* Returns the response body of the provided API key */
+func newAPIKeyResponse(key domain.APIKey) apiKeyResponse {
+	return apiKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		CreatedAt:  key.CreatedAt,
+		ExpiresAt:  optionalTime(key.ExpiresAt),
+		LastUsedAt: optionalTime(key.LastUsedAt),
+	}
+}
+
+/* request body of PUT /users/:username/role */
+type setRoleBody struct {
+	Role string `json:"role" binding:"required"`
+}
+
+/* request body of PUT /roles/:name, the role is named in the path */
+type roleBody struct {
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+/* request body of PUT /roles/:name/mfa, a pointer so that `false` is not taken as missing */
+type roleMFABody struct {
+	Required *bool `json:"required" binding:"required"`
+}
+
+/* response body of the endpoints that return a role */
+type roleResponse struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+	MFARequired bool     `json:"mfa_required"`
+	BuiltIn     bool     `json:"built_in"`
+}
+
+/* Converts the role into its response body */
+func newRoleResponse(role domain.Role) roleResponse {
+	permissions := role.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	return roleResponse{Name: role.Name, Permissions: permissions, MFARequired: role.MFARequired, BuiltIn: role.BuiltIn}
+}
+
+/*
+request body of POST /tasks and PUT /tasks/:id. The identifier, owner,
+timestamps, version and status history of the tasks are managed by the API.
+*/
+type taskRequest struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	DueDate     time.Time `json:"due_date"`
+	Status      string    `json:"status"`
+	Assignee    string    `json:"assignee"`
+}
+
+/* Returns the task described by the request */
+func (request taskRequest) toTask() domain.Task {
+	return domain.Task{
+		Title:       request.Title,
+		Description: request.Description,
+		DueDate:     request.DueDate,
+		Status:      request.Status,
+		Assignee:    request.Assignee,
+	}
+}
+
+/* response body of the endpoints that return a task */
+type taskResponse struct {
+	ID            string                `json:"id"`
+	Title         string                `json:"title"`
+	Description   string                `json:"description"`
+	DueDate       time.Time             `json:"due_date"`
+	Status        string                `json:"status"`
+	Owner         string                `json:"owner"`
+	Assignee      string                `json:"assignee"`
+	CreatedAt     time.Time             `json:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at"`
+	Version       int64                 `json:"version"`
+	StatusHistory []domain.StatusChange `json:"status_history"`
+}
+
+/* Returns the response body of the provided task */
+func newTaskResponse(task domain.Task) taskResponse {
+	return taskResponse{
+		ID:            task.ID,
+		Title:         task.Title,
+		Description:   task.Description,
+		DueDate:       task.DueDate,
+		Status:        task.Status,
+		Owner:         task.Owner,
+		Assignee:      task.Assignee,
+		CreatedAt:     task.CreatedAt,
+		UpdatedAt:     task.UpdatedAt,
+		Version:       task.Version,
+		StatusHistory: task.StatusHistory,
+	}
+}
+
+/* response body of GET /tasks */
+type taskPageResponse struct {
+	Tasks    []taskResponse `json:"tasks"`
+	Total    int64          `json:"total"`
+	Limit    int64          `json:"limit"`
+	Offset   int64          `json:"offset"`
+	Next     string         `json:"next,omitempty"`
+	Previous string         `json:"previous,omitempty"`
+}
+
+/* Returns the response body of the provided page of tasks */
+func newTaskPageResponse(page domain.TaskPage) taskPageResponse {
+	tasks := make([]taskResponse, 0, len(page.Tasks))
+	for _, task := range page.Tasks {
+		tasks = append(tasks, newTaskResponse(task))
+	}
+
+	return taskPageResponse{
+		Tasks:    tasks,
+		Total:    page.Total,
+		Limit:    page.Limit,
+		Offset:   page.Offset,
+		Next:     page.Next,
+		Previous: page.Previous,
+	}
+}
@@ -1,11 +1,21 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"slices"
+	"strings"
 	"task_manager_api/Delivery/router"
 	domain "task_manager_api/Domain"
+	infrastructure "task_manager_api/Infrastructure"
+	repository "task_manager_api/Repository"
+	usecase "task_manager_api/Usecase"
+	"time"
 
 	"github.com/spf13/viper"
 	"go.mongodb.org/mongo-driver/bson"
@@ -39,7 +49,10 @@ func ConnectDB(connectionString string) (*mongo.Client, error) {
 }
 
 /*
-Adds indicies to the `users` and `tasks` collection of the databse
+Adds indicies to the `users`, `tasks`, `refresh_tokens`, `sessions`,
+`password_reset_tokens`, `email_verification_tokens`, `login_attempts`,
+`mfa_enrollments`, `mfa_challenges`, `api_keys` and `roles` collection of the
+databse
 */
 func CreateDBIndicies(db *mongo.Database) error {
 	_, err := db.Collection(domain.CollectionTasks).Indexes().CreateOne(context.TODO(), mongo.IndexModel{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)})
@@ -47,6 +60,11 @@ func CreateDBIndicies(db *mongo.Database) error {
 		return fmt.Errorf("error " + err.Error())
 	}
 
+	_, err = db.Collection(domain.CollectionTasks).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{{Keys: bson.D{{Key: "owner", Value: 1}}}, {Keys: bson.D{{Key: "assignee", Value: 1}}}})
+	if err != nil {
+		return fmt.Errorf("error " + err.Error())
+	}
+
 	_, err = db.Collection(domain.CollectionUsers).Indexes().CreateOne(context.TODO(), mongo.IndexModel{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)})
 	if err != nil {
 		return fmt.Errorf("\n\n Error " + err.Error())
@@ -57,24 +75,260 @@ func CreateDBIndicies(db *mongo.Database) error {
 		return fmt.Errorf("\n\n Error " + err.Error())
 	}
 
+	// expired refresh tokens are removed by the TTL index on `expires_at`
+	_, err = db.Collection(domain.CollectionRefreshTokens).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "family_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("error " + err.Error())
+	}
+
+	// expired sessions are removed by the TTL index on `expires_at`
+	_, err = db.Collection(domain.CollectionSessions).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "username", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("error " + err.Error())
+	}
+
+	// expired password reset tokens are removed by the TTL index on `expires_at`
+	_, err = db.Collection(domain.CollectionPasswordResetTokens).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "username", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("error " + err.Error())
+	}
+
+	// expired email verification tokens are removed by the TTL index on `expires_at`
+	_, err = db.Collection(domain.CollectionEmailVerificationTokens).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "username", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("error " + err.Error())
+	}
+
+	// the failed logins are forgotten by the TTL index on `expires_at`
+	_, err = db.Collection(domain.CollectionLoginAttempts).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("error " + err.Error())
+	}
+
+	_, err = db.Collection(domain.CollectionMFAEnrollments).Indexes().CreateOne(context.TODO(), mongo.IndexModel{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true)})
+	if err != nil {
+		return fmt.Errorf("error " + err.Error())
+	}
+
+	// the login challenges are removed by the TTL index on `expires_at`
+	_, err = db.Collection(domain.CollectionMFAChallenges).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("error " + err.Error())
+	}
+
+	// the expired API keys are kept so that they can still be listed and revoked
+	_, err = db.Collection(domain.CollectionAPIKeys).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "key_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "username", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("error " + err.Error())
+	}
+
+	_, err = db.Collection(domain.CollectionRoles).Indexes().CreateOne(context.TODO(), mongo.IndexModel{Keys: bson.D{{Key: "name", Value: 1}}, Options: options.Index().SetUnique(true)})
+	if err != nil {
+		return fmt.Errorf("error " + err.Error())
+	}
+
+	return nil
+}
+
+/*
+Marks the users that were created before the email verification was
+introduced as verified, so that they can keep logging in. Users that already
+have the `verified` field are left untouched, which makes this safe to run on
+every start.
+*/
+func BackfillVerifiedUsers(db *mongo.Database) error {
+	_, err := db.Collection(domain.CollectionUsers).UpdateMany(context.TODO(), bson.D{{Key: "verified", Value: bson.D{{Key: "$exists", Value: false}}}}, bson.D{{Key: "$set", Value: bson.D{{Key: "verified", Value: true}}}})
+	if err != nil {
+		return fmt.Errorf("error " + err.Error())
+	}
+
 	return nil
 }
 
+/*
+Gives the tasks that were stored before the tasks had owners to the existing
+account with the provided username, so that they show up for that user
+rather than only for the users allowed to manage every task.
+*/
+func BackfillTaskOwners(db *mongo.Database, taskRepository *repository.TaskRepository, owner string) error {
+	userRepository := &repository.UserRepository{Collection: db.Collection(domain.CollectionUsers)}
+	if _, err := userRepository.GetByUsername(context.TODO(), owner); err != nil {
+		return fmt.Errorf("error while loading LEGACY_TASK_OWNER: %v", err.Error())
+	}
+
+	backfilled, err := taskRepository.BackfillOwners(context.TODO(), owner)
+	if err != nil {
+		return fmt.Errorf("error " + err.Error())
+	}
+
+	if backfilled > 0 {
+		log.Printf("Gave %v tasks stored without an owner to '%v'", backfilled, owner)
+	}
+
+	return nil
+}
+
+/* The storage backends that can be selected with DB_BACKEND */
+var storageBackends = []string{router.BackendMongo, router.BackendMemory, router.BackendSQLite, router.BackendPostgres}
+
+/* The policies for unverified accounts that can be selected with EMAIL_VERIFICATION */
+var verificationPolicies = []string{domain.VerificationRequired, domain.VerificationGrace, domain.VerificationOptional, domain.VerificationOff}
+
+/*
+Creates the repositories of the provided storage backend. For mongoDB, the
+connection to the DB is established and the indicies are created first. For
+the SQL databases, the pending schema migrations are applied first.
+*/
+func CreateRepositories(backend string) (router.Repositories, error) {
+	switch backend {
+	case router.BackendMemory:
+		log.Println("Using the in-memory storage: the data will be lost when the API stops")
+		return router.NewInMemoryRepositories(), nil
+
+	case router.BackendSQLite, router.BackendPostgres:
+		dialect := repository.SQLDialect(backend)
+		db, err := repository.OpenSQLDatabase(dialect, viper.GetString("DB_ADDRESS"))
+		if err != nil {
+			return router.Repositories{}, err
+		}
+
+		applied, err := repository.RunSQLMigrations(context.TODO(), db, dialect)
+		if err != nil {
+			return router.Repositories{}, err
+		}
+
+		log.Printf("Succesfully connected to DB, applied %v migrations", applied)
+		return router.NewSQLRepositories(db, dialect), nil
+	}
+
+	// connect to DB
+	client, err := ConnectDB(viper.GetString("DB_ADDRESS"))
+	if err != nil {
+		return router.Repositories{}, err
+	}
+
+	// create DB indicies
+	db := client.Database(viper.GetString("DB_NAME"))
+	err = CreateDBIndicies(db)
+	if err != nil {
+		return router.Repositories{}, err
+	}
+
+	err = BackfillVerifiedUsers(db)
+	if err != nil {
+		return router.Repositories{}, err
+	}
+
+	// move the due dates stored before the field was named `due_date`
+	taskRepository := &repository.TaskRepository{Collection: db.Collection(domain.CollectionTasks)}
+	renamed, renameErr := taskRepository.RenameLegacyDueDates(context.TODO())
+	if renameErr != nil {
+		return router.Repositories{}, fmt.Errorf("error " + renameErr.Error())
+	}
+
+	if renamed > 0 {
+		log.Printf("Renamed the due date of %v tasks stored as `duedate`", renamed)
+	}
+
+	// give a version to the tasks stored before the versioning was introduced
+	backfilled, backfillErr := taskRepository.BackfillVersions(context.TODO())
+	if backfillErr != nil {
+		return router.Repositories{}, fmt.Errorf("error " + backfillErr.Error())
+	}
+
+	if backfilled > 0 {
+		log.Printf("Set the version of %v tasks stored without one", backfilled)
+	}
+
+	if owner := viper.GetString("LEGACY_TASK_OWNER"); owner != "" {
+		err = BackfillTaskOwners(db, taskRepository, owner)
+		if err != nil {
+			return router.Repositories{}, err
+		}
+	}
+
+	log.Println("Succesfully connected to DB")
+	return router.NewMongoRepositories(db), nil
+}
+
+/*
+Creates the notifier that delivers the email verification and password reset
+tokens. The notifications are sent by email if SMTP_ADDRESS is set. Otherwise
+they are appended to NOTIFICATIONS_FILE if it is set and written to the
+standard error if it is not.
+*/
+func CreateNotifier() (domain.NotifierInterface, error) {
+	if address := viper.GetString("SMTP_ADDRESS"); address != "" {
+		return infrastructure.NewSMTPNotifier(address, viper.GetString("SMTP_FROM"), viper.GetString("SMTP_USERNAME"), viper.GetString("SMTP_PASSWORD")), nil
+	}
+
+	path := viper.GetString("NOTIFICATIONS_FILE")
+	if path == "" {
+		return infrastructure.NewLogNotifier(os.Stderr), nil
+	}
+
+	return infrastructure.NewFileNotifier(path)
+}
+
 /*
 Verifies that all the required environment variables are present in the
 configured `.env` location.
 */
 func CheckEnvironmentVariables() error {
 	switch {
-	case viper.GetString("DB_ADDRESS") == "":
+	case !slices.Contains(storageBackends, viper.GetString("DB_BACKEND")):
+		return fmt.Errorf("error while loading .env: unknown DB_BACKEND '%v'", viper.GetString("DB_BACKEND"))
+
+	case viper.GetString("DB_BACKEND") != router.BackendMemory && viper.GetString("DB_ADDRESS") == "":
 		return fmt.Errorf("error while loading .env: DB_ADDRESS not found")
 
-	case viper.GetString("DB_NAME") == "":
+	case viper.GetString("DB_BACKEND") == router.BackendMongo && viper.GetString("DB_NAME") == "":
 		return fmt.Errorf("error while loading .env: DB_NAME not found")
 
-	case viper.GetString("SECRET_TOKEN") == "":
+	case viper.GetString("SECRET_TOKEN") == "" && viper.GetString("JWT_KEYS_DIR") == "":
 		return fmt.Errorf("error while loading .env: SECRET_TOKEN not found")
 
+	case viper.GetString("JWT_KEYS_DIR") != "" && viper.GetString("JWT_ACTIVE_KEY_ID") == "":
+		return fmt.Errorf("error while loading .env: JWT_ACTIVE_KEY_ID not found")
+
+	case !slices.Contains(verificationPolicies, viper.GetString("EMAIL_VERIFICATION")):
+		return fmt.Errorf("error while loading .env: unknown EMAIL_VERIFICATION '%v'", viper.GetString("EMAIL_VERIFICATION"))
+
+	case viper.GetString("SMTP_ADDRESS") != "" && viper.GetString("SMTP_FROM") == "":
+		return fmt.Errorf("error while loading .env: SMTP_FROM not found")
+
+	case viper.GetString("INITIAL_ADMIN_USERNAME") != "" && viper.GetString("INITIAL_ADMIN_EMAIL") == "":
+		return fmt.Errorf("error while loading .env: INITIAL_ADMIN_EMAIL not found")
+
+	case viper.GetString("INITIAL_ADMIN_USERNAME") != "" && viper.GetString("INITIAL_ADMIN_PASSWORD") == "":
+		return fmt.Errorf("error while loading .env: INITIAL_ADMIN_PASSWORD not found")
+
 	case viper.GetInt("PORT") == 0:
 		return fmt.Errorf("error while loading .env: PORT not found")
 
@@ -88,9 +342,74 @@ func CheckEnvironmentVariables() error {
 	return nil
 }
 
+/*
+Creates the initial admin account, e.g. with
+`echo "$ADMIN_PASSWORD" | ./api create-admin -username admin -email admin@mail.com`.
+The password is read from the first line of the input so that it does not
+end up in the shell history or the process list.
+*/
+func CreateAdmin(args []string, authUsecase *usecase.UserUsecase, input io.Reader) error {
+	flags := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	username := flags.String("username", "", "the username of the admin")
+	email := flags.String("email", "", "the email of the admin")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	password, err := bufio.NewReader(input).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("error while reading the password: %v", err.Error())
+	}
+
+	admin := domain.User{Username: *username, Email: *email, Password: strings.TrimRight(password, "\r\n")}
+	if createErr := authUsecase.BootstrapAdmin(context.TODO(), admin); createErr != nil {
+		return fmt.Errorf("error: %v", createErr.Error())
+	}
+
+	return nil
+}
+
+/*
+Creates the admin account configured with INITIAL_ADMIN_USERNAME,
+INITIAL_ADMIN_EMAIL and INITIAL_ADMIN_PASSWORD when the API starts. This is
+how the first admin is created with the in-memory storage, where the
+`create-admin` command can not be used. Nothing is done if the username is not
+set, and an account that already exists is left untouched so that the
+variables can stay set across restarts.
+*/
+func CreateInitialAdmin(authUsecase *usecase.UserUsecase) error {
+	username := viper.GetString("INITIAL_ADMIN_USERNAME")
+	if username == "" {
+		return nil
+	}
+
+	admin := domain.User{Username: username, Email: viper.GetString("INITIAL_ADMIN_EMAIL"), Password: viper.GetString("INITIAL_ADMIN_PASSWORD")}
+	created, createErr := authUsecase.EnsureAdmin(context.TODO(), admin)
+	if createErr != nil {
+		return fmt.Errorf("error while creating the initial admin: %v", createErr.Error())
+	}
+
+	if created {
+		log.Printf("Succesfully created the initial admin account '%v'", username)
+	} else {
+		log.Printf("The initial admin account '%v' already exists", username)
+	}
+
+	return nil
+}
+
 func main() {
 	// load the environment variables
 	viper.SetConfigFile(".env")
+	viper.SetDefault("DB_BACKEND", router.BackendMongo)
+	viper.SetDefault("REFRESH_TOKEN_LIFESPAN_HOURS", 168)
+	viper.SetDefault("JWT_ISSUER", "task_manager_api")
+	viper.SetDefault("JWT_AUDIENCE", "task_manager_api")
+	viper.SetDefault("PASSWORD_RESET_TOKEN_LIFESPAN_MINUTES", 30)
+	viper.SetDefault("EMAIL_VERIFICATION", domain.VerificationRequired)
+	viper.SetDefault("EMAIL_VERIFICATION_GRACE_HOURS", 24)
+	viper.SetDefault("EMAIL_VERIFICATION_TOKEN_LIFESPAN_HOURS", 24)
+	viper.SetDefault("TOTP_ISSUER", "task_manager_api")
 	viper.ReadInConfig()
 
 	// check for the environment variables
@@ -100,23 +419,62 @@ func main() {
 		return
 	}
 
-	// connect to DB
-	client, err := ConnectDB(viper.GetString("DB_ADDRESS"))
-	db := client.Database(viper.GetString("DB_NAME"))
+	// load the keys used to sign and verify the tokens
+	jwtService, err := infrastructure.NewJWTService(infrastructure.JWTConfig{
+		Issuer:        viper.GetString("JWT_ISSUER"),
+		Audience:      viper.GetString("JWT_AUDIENCE"),
+		Secret:        viper.GetString("SECRET_TOKEN"),
+		KeysDirectory: viper.GetString("JWT_KEYS_DIR"),
+		ActiveKeyID:   viper.GetString("JWT_ACTIVE_KEY_ID"),
+	})
 	if err != nil {
 		log.Fatalf("Error: %v", err.Error())
 		return
 	}
 
-	// create DB indicies
-	err = CreateDBIndicies(db)
+	// connect to the configured storage backend
+	repositories, err := CreateRepositories(viper.GetString("DB_BACKEND"))
 	if err != nil {
 		log.Fatalf("Error: %v", err.Error())
 		return
 	}
 
-	log.Println("Succesfully connected to DB")
+	notifier, err := CreateNotifier()
+	if err != nil {
+		log.Fatalf("Error: %v", err.Error())
+		return
+	}
+
+	// create the initial admin instead of running the API
+	if len(os.Args) > 1 {
+		if os.Args[1] != "create-admin" {
+			log.Fatalf("Error: unknown command '%v'", os.Args[1])
+			return
+		}
+
+		if viper.GetString("DB_BACKEND") == router.BackendMemory {
+			log.Fatal("Error: the admin can not be created with the in-memory storage, set INITIAL_ADMIN_USERNAME, INITIAL_ADMIN_EMAIL and INITIAL_ADMIN_PASSWORD instead")
+			return
+		}
+
+		timeout := time.Duration(viper.GetInt("TIMEOUT")) * time.Second
+		err = CreateAdmin(os.Args[2:], router.NewAuthUsecase(timeout, repositories, jwtService, notifier), os.Stdin)
+		if err != nil {
+			log.Fatalf("Error: %v", err.Error())
+			return
+		}
+
+		log.Println("Succesfully created the admin account")
+		return
+	}
+
+	timeout := time.Duration(viper.GetInt("TIMEOUT")) * time.Second
+	err = CreateInitialAdmin(router.NewAuthUsecase(timeout, repositories, jwtService, notifier))
+	if err != nil {
+		log.Fatalf("Error: %v", err.Error())
+		return
+	}
 
 	// initiate the router and the endpoints
-	router.CreateRouter(viper.GetInt("PORT"), db)
+	router.CreateRouter(viper.GetInt("PORT"), repositories, jwtService, notifier)
 }
@@ -1,7 +1,10 @@
 package router
 
 import (
+	"database/sql"
 	"fmt"
+	"log"
+	"strings"
 	"task_manager_api/Delivery/controllers"
 	domain "task_manager_api/Domain"
 	infrastructure "task_manager_api/Infrastructure"
@@ -14,71 +17,376 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+/* The storage backends the API can run with */
+const (
+	BackendMongo    = "mongo"
+	BackendMemory   = "memory"
+	BackendSQLite   = "sqlite"
+	BackendPostgres = "postgres"
+)
+
+/*
+The repositories of the API, all backed by the same storage backend except
+for the token buckets of the rate limits, which are kept in memory by default
+*/
+type Repositories struct {
+	Tasks         domain.TaskRepositoryInterface
+	Users         domain.UserRepositoryInterface
+	RefreshTokens domain.RefreshTokenRepositoryInterface
+	Sessions      domain.SessionRepositoryInterface
+	ResetTokens   domain.PasswordResetTokenRepositoryInterface
+	Verification  domain.EmailVerificationTokenRepositoryInterface
+	LoginAttempts domain.LoginAttemptRepositoryInterface
+	MFA           domain.MFARepositoryInterface
+	MFAChallenges domain.MFAChallengeRepositoryInterface
+	APIKeys       domain.APIKeyRepositoryInterface
+	Roles         domain.RoleRepositoryInterface
+	RateLimits    domain.RateLimitRepositoryInterface
+}
+
+/* Creates the repositories backed by the collections of the provided mongoDB database */
+func NewMongoRepositories(db *mongo.Database) Repositories {
+	return Repositories{
+		Tasks:         &repository.TaskRepository{Collection: db.Collection(domain.CollectionTasks)},
+		Users:         &repository.UserRepository{Collection: db.Collection(domain.CollectionUsers)},
+		RefreshTokens: &repository.RefreshTokenRepository{Collection: db.Collection(domain.CollectionRefreshTokens)},
+		Sessions:      &repository.SessionRepository{Collection: db.Collection(domain.CollectionSessions)},
+		ResetTokens:   &repository.PasswordResetTokenRepository{Collection: db.Collection(domain.CollectionPasswordResetTokens)},
+		Verification:  &repository.EmailVerificationTokenRepository{Collection: db.Collection(domain.CollectionEmailVerificationTokens)},
+		LoginAttempts: &repository.LoginAttemptRepository{Collection: db.Collection(domain.CollectionLoginAttempts)},
+		MFA:           &repository.MFARepository{Collection: db.Collection(domain.CollectionMFAEnrollments)},
+		MFAChallenges: &repository.MFAChallengeRepository{Collection: db.Collection(domain.CollectionMFAChallenges)},
+		APIKeys:       &repository.APIKeyRepository{Collection: db.Collection(domain.CollectionAPIKeys)},
+		Roles:         &repository.RoleRepository{Collection: db.Collection(domain.CollectionRoles)},
+		RateLimits:    repository.NewInMemoryRateLimitRepository(),
+	}
+}
+
+/*
+Creates the repositories backed by the tables of the provided SQL database.
+The schema is expected to have been migrated with `RunSQLMigrations`.
+*/
+func NewSQLRepositories(db *sql.DB, dialect repository.SQLDialect) Repositories {
+	return Repositories{
+		Tasks:         &repository.SQLTaskRepository{DB: db, Dialect: dialect},
+		Users:         &repository.SQLUserRepository{DB: db, Dialect: dialect},
+		RefreshTokens: &repository.SQLRefreshTokenRepository{DB: db, Dialect: dialect},
+		Sessions:      &repository.SQLSessionRepository{DB: db, Dialect: dialect},
+		ResetTokens:   &repository.SQLPasswordResetTokenRepository{DB: db, Dialect: dialect},
+		Verification:  &repository.SQLEmailVerificationTokenRepository{DB: db, Dialect: dialect},
+		LoginAttempts: &repository.SQLLoginAttemptRepository{DB: db, Dialect: dialect},
+		MFA:           &repository.SQLMFARepository{DB: db, Dialect: dialect},
+		MFAChallenges: &repository.SQLMFAChallengeRepository{DB: db, Dialect: dialect},
+		APIKeys:       &repository.SQLAPIKeyRepository{DB: db, Dialect: dialect},
+		Roles:         &repository.SQLRoleRepository{DB: db, Dialect: dialect},
+		RateLimits:    repository.NewInMemoryRateLimitRepository(),
+	}
+}
+
+/*
+Creates repositories that keep all the data in memory, which allows running
+the API without a database. The data is lost when the API stops.
+*/
+func NewInMemoryRepositories() Repositories {
+	return Repositories{
+		Tasks:         repository.NewInMemoryTaskRepository(),
+		Users:         repository.NewInMemoryUserRepository(),
+		RefreshTokens: repository.NewInMemoryRefreshTokenRepository(),
+		Sessions:      repository.NewInMemorySessionRepository(),
+		ResetTokens:   repository.NewInMemoryPasswordResetTokenRepository(),
+		Verification:  repository.NewInMemoryEmailVerificationTokenRepository(),
+		LoginAttempts: repository.NewInMemoryLoginAttemptRepository(),
+		MFA:           repository.NewInMemoryMFARepository(),
+		MFAChallenges: repository.NewInMemoryMFAChallengeRepository(),
+		APIKeys:       repository.NewInMemoryAPIKeyRepository(),
+		Roles:         repository.NewInMemoryRoleRepository(),
+		RateLimits:    repository.NewInMemoryRateLimitRepository(),
+	}
+}
+
+/*
+Returns the addresses and CIDR ranges of the reverse proxies listed in the
+comma separated TRUSTED_PROXIES. No proxy is trusted when it is not set, and
+the client IPs are then the addresses of the connections.
+*/
+func TrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(viper.GetString("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+
+	return proxies
+}
+
+/* The route groups whose requests are rate limited separately */
+const (
+	RateLimitGroupGlobal = "global"
+	RateLimitGroupAuth   = "auth"
+	RateLimitGroupTasks  = "tasks"
+	RateLimitGroupUsers  = "users"
+)
+
+/*
+The rate limiting middlewares of the route groups: `Global` for every
+request before it is authenticated, `Auth` for the endpoints that take
+credentials or tokens without authentication, `Tasks` for the task endpoints
+and `Users` for the authenticated account, profile and session endpoints.
+*/
+type RateLimiters struct {
+	Global gin.HandlerFunc
+	Auth   gin.HandlerFunc
+	Tasks  gin.HandlerFunc
+	Users  gin.HandlerFunc
+}
+
+/*
+Returns the rate limiting middleware of the route group. The limit is read
+from RATE_LIMIT_<GROUP> (e.g. `100/1m`, or `off` to disable it) and the kind
+of client the requests are counted by from RATE_LIMIT_<GROUP>_KEY, falling
+back to the provided defaults when they are not set.
+*/
+func NewRateLimitMiddleware(group string, defaultLimit string, defaultKey string, store domain.RateLimitRepositoryInterface) (gin.HandlerFunc, error) {
+	variable := "RATE_LIMIT_" + strings.ToUpper(group)
+	value := strings.TrimSpace(viper.GetString(variable))
+	if value == "" {
+		value = defaultLimit
+	}
+
+	if strings.EqualFold(value, "off") {
+		return func(c *gin.Context) { c.Next() }, nil
+	}
+
+	limit, err := infrastructure.ParseRateLimit(value)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", variable, err.Error())
+	}
+
+	kind := strings.TrimSpace(viper.GetString(variable + "_KEY"))
+	if kind == "" {
+		kind = defaultKey
+	}
+
+	key, err := infrastructure.RateLimitKey(kind)
+	if err != nil {
+		return nil, fmt.Errorf("%v_KEY: %v", variable, err.Error())
+	}
+
+	return infrastructure.RateLimitMiddleware(group, limit, key, store), nil
+}
+
+/*
+Creates the rate limiting middlewares of all the route groups. The requests
+without authentication are counted by IP and the others by user unless
+configured otherwise.
+*/
+func NewRateLimiters(store domain.RateLimitRepositoryInterface) (RateLimiters, error) {
+	global, err := NewRateLimitMiddleware(RateLimitGroupGlobal, "600/1m", infrastructure.RateLimitKeyIP, store)
+	if err != nil {
+		return RateLimiters{}, err
+	}
+
+	auth, err := NewRateLimitMiddleware(RateLimitGroupAuth, "20/1m", infrastructure.RateLimitKeyIP, store)
+	if err != nil {
+		return RateLimiters{}, err
+	}
+
+	tasks, err := NewRateLimitMiddleware(RateLimitGroupTasks, "120/1m", infrastructure.RateLimitKeyUser, store)
+	if err != nil {
+		return RateLimiters{}, err
+	}
+
+	users, err := NewRateLimitMiddleware(RateLimitGroupUsers, "60/1m", infrastructure.RateLimitKeyUser, store)
+	if err != nil {
+		return RateLimiters{}, err
+	}
+
+	return RateLimiters{Global: global, Auth: auth, Tasks: tasks, Users: users}, nil
+}
+
 /*
 Creates a router, attaches all the endpoints and finally
 runs the API with the provided port number.
 */
-func CreateRouter(port int, db *mongo.Database) {
+func CreateRouter(port int, repositories Repositories, jwtService *infrastructure.JWTService, notifier domain.NotifierInterface) {
 	router := gin.Default()
+	router.Use(infrastructure.RequestIDMiddleware(infrastructure.GenerateID))
+
+	// the client IPs are only read from the forwarding headers of trusted proxies
+	if err := router.SetTrustedProxies(TrustedProxies()); err != nil {
+		log.Fatalf("Error: %v", err.Error())
+		return
+	}
+
+	// the requests of every client are limited separately for each route group
+	rateLimiters, err := NewRateLimiters(repositories.RateLimits)
+	if err != nil {
+		log.Fatalf("Error: %v", err.Error())
+		return
+	}
+
+	// every request is also counted by IP before it is authenticated, so that
+	// requests with invalid credentials are limited as well
+	router.Use(rateLimiters.Global)
+
+	timeout := time.Duration(viper.GetInt("TIMEOUT")) * time.Second
+
+	// the auth usecase also validates the sessions of the incoming tokens and the API keys
+	// while the role usecase resolves the permissions of their roles
+	authUsecase := NewAuthUsecase(timeout, repositories, jwtService, notifier)
+	roleUsecase := &usecase.RoleUsecase{RoleRepository: repositories.Roles, UserRepository: repositories.Users, Timeout: timeout}
+	authMiddleware := func(permission string) gin.HandlerFunc {
+		return infrastructure.AuthMiddlewareWithPermission(permission, jwtService.ValidateAndParseToken, authUsecase.ValidateSession, roleUsecase.RolePermissions)
+	}
+	apiKeyMiddleware := func(permission string, scope string) gin.HandlerFunc {
+		return infrastructure.AuthMiddlewareWithAPIKeys(permission, scope, authUsecase.ValidateAPIKey, authMiddleware(permission))
+	}
 
-	// task API
+	// task API, also open to the API keys with the matching scope
 	taskRouter := router.Group("/tasks")
-	timeout := viper.GetInt("TIMEOUT")
-	NewTaskController(time.Duration(timeout)*time.Second, db.Collection(domain.CollectionTasks), taskRouter)
+	NewTaskController(timeout, repositories.Tasks, taskRouter, apiKeyMiddleware, rateLimiters.Tasks)
 
-	// user registeration and login
+	// user registeration, login, sessions and password resets
 	authRouter := router.Group("")
-	NewAuthController(time.Duration(timeout)*time.Second, db.Collection(domain.CollectionUsers), authRouter)
+	NewAuthController(authUsecase, authRouter, authMiddleware, rateLimiters)
+
+	// roles and the permissions they grant
+	roleRouter := router.Group("/roles")
+	NewRoleController(roleUsecase, roleRouter, authMiddleware, rateLimiters.Users)
+
+	// public keys used to verify the tokens
+	router.GET("/.well-known/jwks.json", controllers.JWKSHandler(jwtService.JWKS()))
 
 	router.Run(fmt.Sprintf(":%v", port))
 }
 
+/*
+Creates the usecase that handles user registration, email verification,
+login, two-factor authentication, password resets, account management, the
+API keys and the sessions of the users along with all of its repositories
+and services.
+*/
+func NewAuthUsecase(timeout time.Duration, repositories Repositories, jwtService *infrastructure.JWTService, notifier domain.NotifierInterface) *usecase.UserUsecase {
+	return &usecase.UserUsecase{
+		UserRespository:        repositories.Users,
+		TaskRepository:         repositories.Tasks,
+		RefreshTokenRepository: repositories.RefreshTokens,
+		SessionRepository:      repositories.Sessions,
+		ResetTokenRepository:   repositories.ResetTokens,
+		VerificationRepository: repositories.Verification,
+		LoginAttemptRepository: repositories.LoginAttempts,
+		MFARepository:          repositories.MFA,
+		MFAChallengeRepository: repositories.MFAChallenges,
+		APIKeyRepository:       repositories.APIKeys,
+		RoleRepository:         repositories.Roles,
+		Notifier:               notifier,
+		Timeout:                timeout,
+		HashUserPassword:       infrastructure.HashPassword,
+		SignJWTWithPayload:     jwtService.SignJWTWithPayload,
+		ValidatePassword:       infrastructure.ValidatePassword,
+		GenerateToken:          infrastructure.GenerateOpaqueToken,
+		HashToken:              infrastructure.HashToken,
+		GenerateID:             infrastructure.GenerateID,
+		GenerateTOTPSecret:     infrastructure.GenerateTOTPSecret,
+		TOTPURI:                infrastructure.TOTPURI,
+		ValidateTOTP:           infrastructure.ValidateTOTP,
+		GenerateRecoveryCode:   infrastructure.GenerateRecoveryCode,
+	}
+}
+
 /*
 Attaches to the provided router group all the task endpoints with the
 appropriate auth middleware configurations and creates all the task controller
-that provides the handlers for the endpoints
+that provides the handlers for the endpoints. The endpoints require the task
+permissions while the task usecase checks the ownership of the tasks.
+The endpoints can also be reached with API keys, which need the `tasks:read`
+scope for the reads and the `tasks:write` scope for the changes. The requests
+are rate limited once they have been authenticated, so they can be counted
+by user or by API key, on top of the `Global` limiter that counts them by IP
+beforehand.
 */
-func NewTaskController(timeout time.Duration, collection *mongo.Collection, group *gin.RouterGroup) {
+func NewTaskController(timeout time.Duration, taskRepository domain.TaskRepositoryInterface, group *gin.RouterGroup, authMiddleware func(permission string, scope string) gin.HandlerFunc, rateLimit gin.HandlerFunc) {
 	taskUsecase := usecase.TaskUsecase{
-		TaskRepository: &repository.TaskRepository{
-			Collection: collection,
-		},
-		Timeout: timeout,
+		TaskRepository: taskRepository,
+		Timeout:        timeout,
+		GenerateID:     infrastructure.GenerateID,
 	}
 	taskController := controllers.TaskController{
 		TaskUsecase: &taskUsecase,
 	}
 
-	secret := viper.GetString("SECRET_TOKEN")
-	validateToken := infrastructure.ValidateAndParseToken
-	group.GET("", infrastructure.AuthMiddlewareWithRoles([]string{"user", "admin"}, secret, validateToken), taskController.GetAll)
-	group.GET("/:id", infrastructure.AuthMiddlewareWithRoles([]string{"user", "admin"}, secret, validateToken), taskController.GetOne)
-	group.POST("", infrastructure.AuthMiddlewareWithRoles([]string{"admin"}, secret, validateToken), taskController.Create)
-	group.PUT("/:id", infrastructure.AuthMiddlewareWithRoles([]string{"admin"}, secret, validateToken), taskController.Update)
-	group.DELETE("/:id", infrastructure.AuthMiddlewareWithRoles([]string{"admin"}, secret, validateToken), taskController.Delete)
+	group.GET("", authMiddleware(domain.PermissionTaskRead, domain.ScopeTasksRead), rateLimit, taskController.GetAll)
+	group.GET("/:id", authMiddleware(domain.PermissionTaskRead, domain.ScopeTasksRead), rateLimit, taskController.GetOne)
+	group.POST("", authMiddleware(domain.PermissionTaskCreate, domain.ScopeTasksWrite), rateLimit, taskController.Create)
+	group.PUT("/:id", authMiddleware(domain.PermissionTaskUpdate, domain.ScopeTasksWrite), rateLimit, taskController.Update)
+	group.PATCH("/:id", authMiddleware(domain.PermissionTaskUpdate, domain.ScopeTasksWrite), rateLimit, taskController.Patch)
+	group.DELETE("/:id", authMiddleware(domain.PermissionTaskDelete, domain.ScopeTasksWrite), rateLimit, taskController.Delete)
 }
 
 /*
-Attaches the `/login` and `/signup` routes along with the controller
-that provides the handlers for those endpoints
+Attaches the `/login`, `/signup`, `/token/refresh`, `/logout`, the email
+verification, the password reset, the two-factor authentication, the account
+management, the profile, the API key and the session routes along with the
+controller that provides the handlers for those endpoints. The routes without
+authentication are rate limited by the `Auth` limiter and the others by the
+`Users` limiter.
 */
-func NewAuthController(timeout time.Duration, collection *mongo.Collection, group *gin.RouterGroup) {
-	authUsecase := usecase.UserUsecase{
-		UserRespository: &repository.UserRepository{
-			Collection: collection,
-		},
-		Timeout:            timeout,
-		HashUserPassword:   infrastructure.HashPassword,
-		SignJWTWithPayload: infrastructure.SignJWTWithPayload,
-		ValidatePassword:   infrastructure.ValidatePassword,
-	}
+func NewAuthController(authUsecase *usecase.UserUsecase, group *gin.RouterGroup, authMiddleware func(permission string) gin.HandlerFunc, rateLimiters RateLimiters) {
 	authController := controllers.UserController{
-		UserUsecase: &authUsecase,
+		UserUsecase: authUsecase,
+	}
+
+	group.POST("/signup", rateLimiters.Auth, authController.Signup)
+	group.POST("/login", rateLimiters.Auth, authController.Login)
+	group.POST("/login/mfa", rateLimiters.Auth, authController.VerifyMFALogin)
+	group.POST("/login/mfa/enroll", rateLimiters.Auth, authController.EnrollMFALogin)
+	group.POST("/token/refresh", rateLimiters.Auth, authController.Refresh)
+	group.POST("/logout", rateLimiters.Auth, authController.Logout)
+	group.POST("/verify-email", rateLimiters.Auth, authController.VerifyEmail)
+	group.POST("/verify-email/resend", rateLimiters.Auth, authController.ResendVerification)
+	group.POST("/password/forgot", rateLimiters.Auth, authController.ForgotPassword)
+	group.POST("/password/reset", rateLimiters.Auth, authController.ResetPassword)
+	group.POST("/users", authMiddleware(domain.PermissionUserCreate), rateLimiters.Users, authController.CreateAccount)
+	group.GET("/users", authMiddleware(domain.PermissionUserRead), rateLimiters.Users, authController.GetUsers)
+	group.GET("/users/:username", authMiddleware(domain.PermissionUserRead), rateLimiters.Users, authController.GetUser)
+	group.GET("/me", authMiddleware(domain.PermissionProfileManage), rateLimiters.Users, authController.GetProfile)
+	group.PATCH("/me", authMiddleware(domain.PermissionProfileManage), rateLimiters.Users, authController.UpdateProfile)
+	group.PUT("/me/password", authMiddleware(domain.PermissionProfileManage), rateLimiters.Users, authController.ChangePassword)
+	group.POST("/me/mfa/totp", authMiddleware(domain.PermissionProfileManage), rateLimiters.Users, authController.EnrollTOTP)
+	group.POST("/me/mfa/totp/confirm", authMiddleware(domain.PermissionProfileManage), rateLimiters.Users, authController.ConfirmTOTP)
+	group.POST("/me/mfa/recovery-codes", authMiddleware(domain.PermissionProfileManage), rateLimiters.Users, authController.RegenerateRecoveryCodes)
+	group.DELETE("/me/mfa", authMiddleware(domain.PermissionProfileManage), rateLimiters.Users, authController.DisableMFA)
+	group.POST("/me/api-keys", authMiddleware(domain.PermissionProfileManage), rateLimiters.Users, authController.CreateAPIKey)
+	group.GET("/me/api-keys", authMiddleware(domain.PermissionProfileManage), rateLimiters.Users, authController.GetAPIKeys)
+	group.DELETE("/me/api-keys/:id", authMiddleware(domain.PermissionProfileManage), rateLimiters.Users, authController.RevokeAPIKey)
+	group.PATCH("/promote/:username", authMiddleware(domain.PermissionUserPromote), rateLimiters.Users, authController.Promote)
+	group.PATCH("/demote/:username", authMiddleware(domain.PermissionUserDemote), rateLimiters.Users, authController.Demote)
+	group.PUT("/users/:username/role", authMiddleware(domain.PermissionUserAssignRole), rateLimiters.Users, authController.SetRole)
+	group.PATCH("/users/:username/deactivate", authMiddleware(domain.PermissionUserDeactivate), rateLimiters.Users, authController.Deactivate)
+	group.PATCH("/users/:username/activate", authMiddleware(domain.PermissionUserDeactivate), rateLimiters.Users, authController.Activate)
+	group.PATCH("/users/:username/unlock", authMiddleware(domain.PermissionUserUnlock), rateLimiters.Users, authController.Unlock)
+	group.DELETE("/users/:username/mfa", authMiddleware(domain.PermissionUserResetMFA), rateLimiters.Users, authController.ResetMFA)
+	group.DELETE("/users/:username", authMiddleware(domain.PermissionUserDelete), rateLimiters.Users, authController.DeleteUser)
+	group.GET("/sessions", authMiddleware(domain.PermissionProfileManage), rateLimiters.Users, authController.GetSessions)
+	group.DELETE("/sessions/:id", authMiddleware(domain.PermissionProfileManage), rateLimiters.Users, authController.RevokeSession)
+	group.DELETE("/users/:username/sessions", authMiddleware(domain.PermissionUserRevokeSessions), rateLimiters.Users, authController.RevokeUserSessions)
+}
+
+/*
+Attaches to the provided router group the endpoints used to manage the roles
+and the permissions they grant along with the controller that provides the
+handlers for those endpoints. The requests are rate limited by the `Users`
+limiter.
+*/
+func NewRoleController(roleUsecase domain.RoleUsecaseInterface, group *gin.RouterGroup, authMiddleware func(permission string) gin.HandlerFunc, rateLimit gin.HandlerFunc) {
+	roleController := controllers.RoleController{
+		RoleUsecase: roleUsecase,
 	}
 
-	secret := viper.GetString("SECRET_TOKEN")
-	validateToken := infrastructure.ValidateAndParseToken
-	group.POST("/signup", authController.Signup)
-	group.POST("/login", authController.Login)
-	group.PATCH("/promote/:username", infrastructure.AuthMiddlewareWithRoles([]string{"admin"}, secret, validateToken), authController.Promote)
+	group.GET("", authMiddleware(domain.PermissionRoleRead), rateLimit, roleController.GetRoles)
+	group.GET("/:name", authMiddleware(domain.PermissionRoleRead), rateLimit, roleController.GetRole)
+	group.PUT("/:name", authMiddleware(domain.PermissionRoleManage), rateLimit, roleController.SaveRole)
+	group.PUT("/:name/mfa", authMiddleware(domain.PermissionRoleManage), rateLimit, roleController.SetRoleMFA)
+	group.DELETE("/:name", authMiddleware(domain.PermissionRoleManage), rateLimit, roleController.DeleteRole)
 }
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"context"
+	"slices"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -12,21 +13,226 @@ Definitions of the collection names and error codes that are
 independent of the external environment.
 */
 const (
-	CollectionTasks     = "tasks"
-	CollectionUsers     = "users"
-	ERR_NOT_FOUND       = "not_found"
-	ERR_INTERNAL_SERVER = "internal_server_error"
-	ERR_BAD_REQUEST     = "bad_request"
-	ERR_UNAUTHORIZED    = "unauthorized"
+	CollectionTasks                   = "tasks"
+	CollectionUsers                   = "users"
+	CollectionRefreshTokens           = "refresh_tokens"
+	CollectionSessions                = "sessions"
+	CollectionPasswordResetTokens     = "password_reset_tokens"
+	CollectionEmailVerificationTokens = "email_verification_tokens"
+	CollectionLoginAttempts           = "login_attempts"
+	CollectionMFAEnrollments          = "mfa_enrollments"
+	CollectionMFAChallenges           = "mfa_challenges"
+	CollectionAPIKeys                 = "api_keys"
+	CollectionRoles                   = "roles"
+	ERR_NOT_FOUND                     = "not_found"
+	ERR_INTERNAL_SERVER               = "internal_server_error"
+	ERR_BAD_REQUEST                   = "bad_request"
+	ERR_UNAUTHORIZED                  = "unauthorized"
+	ERR_FORBIDDEN                     = "forbidden"
+	ERR_CONFLICT                      = "conflict"
+	ERR_PRECONDITION_FAILED           = "precondition_failed"
+	ERR_INVALID_TRANSITION            = "invalid_status_transition"
+	ERR_TOO_MANY_REQUESTS             = "too_many_requests"
+	ERR_UNSUPPORTED_MEDIA_TYPE        = "unsupported_media_type"
 )
 
+/*
+Definitions of the policies that decide whether accounts with an unverified
+email can log in. With `grace`, unverified accounts can only log in for a
+limited time after their signup. With `off`, the verification is disabled and
+new accounts are created verified without sending a verification token.
+*/
+const (
+	VerificationRequired = "required"
+	VerificationGrace    = "grace"
+	VerificationOptional = "optional"
+	VerificationOff      = "off"
+)
+
+/*
+Definitions of the statuses that a task can be in. New tasks start in
+the `todo` status unless another one is provided.
+*/
+const (
+	StatusTodo       = "todo"
+	StatusInProgress = "in_progress"
+	StatusBlocked    = "blocked"
+	StatusDone       = "done"
+	StatusArchived   = "archived"
+)
+
+/*
+The allowed transitions between the task statuses, keyed by the current
+status of the task. Done tasks can be reopened and archived tasks can only
+be restored to the `todo` status.
+*/
+var TaskStatusTransitions = map[string][]string{
+	StatusTodo:       {StatusInProgress, StatusBlocked, StatusDone, StatusArchived},
+	StatusInProgress: {StatusTodo, StatusBlocked, StatusDone, StatusArchived},
+	StatusBlocked:    {StatusTodo, StatusInProgress, StatusArchived},
+	StatusDone:       {StatusInProgress, StatusArchived},
+	StatusArchived:   {StatusTodo},
+}
+
+/* Checks whether the provided status is one of the defined task statuses */
+func IsValidTaskStatus(status string) bool {
+	_, ok := TaskStatusTransitions[status]
+	return ok
+}
+
+/*
+Checks whether a task can move from the current status to the next one.
+Staying in the same status is always allowed. Tasks whose current status
+is not one of the defined statuses (i.e. created before the statuses were
+defined) can move to any of them.
+*/
+func CanTransitionTaskStatus(current string, next string) bool {
+	if current == next || !IsValidTaskStatus(current) {
+		return IsValidTaskStatus(next)
+	}
+
+	for _, allowed := range TaskStatusTransitions[current] {
+		if allowed == next {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+Definitions of the bounds and sort orders used when paginating the
+results of list queries.
+*/
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+	SortAscending    = "asc"
+	SortDescending   = "desc"
+)
+
+/*
+Definitions of the user roles and the keys under which the middlewares store
+the subject and the correlation ID of the request in the gin context.
+*/
+const (
+	RoleAdmin        = "admin"
+	RoleUser         = "user"
+	ContextSubject   = "subject"
+	ContextRequestID = "request_id"
+)
+
+/*
+Definitions of the scopes that can be granted to API keys. A key can only
+be used on the endpoints that require one of its scopes.
+*/
+const (
+	ScopeTasksRead  = "tasks:read"
+	ScopeTasksWrite = "tasks:write"
+)
+
+/* The list of scopes that can be granted to API keys */
+var APIKeyScopes = []string{ScopeTasksRead, ScopeTasksWrite}
+
+/*
+Definitions of the permissions that can be granted to roles. The `.any`
+variant of a permission extends it to the resources of every user, e.g.
+`task.delete` allows deleting the own tasks while `task.delete.any` allows
+deleting any task. `profile.manage` covers the endpoints under `/me`.
+*/
+const (
+	PermissionProfileManage      = "profile.manage"
+	PermissionTaskRead           = "task.read"
+	PermissionTaskReadAny        = "task.read.any"
+	PermissionTaskCreate         = "task.create"
+	PermissionTaskUpdate         = "task.update"
+	PermissionTaskUpdateAny      = "task.update.any"
+	PermissionTaskDelete         = "task.delete"
+	PermissionTaskDeleteAny      = "task.delete.any"
+	PermissionUserRead           = "user.read"
+	PermissionUserCreate         = "user.create"
+	PermissionUserPromote        = "user.promote"
+	PermissionUserDemote         = "user.demote"
+	PermissionUserAssignRole     = "user.role.assign"
+	PermissionUserDeactivate     = "user.deactivate"
+	PermissionUserUnlock         = "user.unlock"
+	PermissionUserResetMFA       = "user.mfa.reset"
+	PermissionUserRevokeSessions = "user.sessions.revoke"
+	PermissionUserDelete         = "user.delete"
+	PermissionRoleRead           = "role.read"
+	PermissionRoleManage         = "role.manage"
+)
+
+/* The list of permissions that can be granted to roles */
+var Permissions = []string{
+	PermissionProfileManage,
+	PermissionTaskRead, PermissionTaskReadAny,
+	PermissionTaskCreate,
+	PermissionTaskUpdate, PermissionTaskUpdateAny,
+	PermissionTaskDelete, PermissionTaskDeleteAny,
+	PermissionUserRead, PermissionUserCreate,
+	PermissionUserPromote, PermissionUserDemote, PermissionUserAssignRole,
+	PermissionUserDeactivate, PermissionUserUnlock, PermissionUserResetMFA,
+	PermissionUserRevokeSessions, PermissionUserDelete,
+	PermissionRoleRead, PermissionRoleManage,
+}
+
+/*
+The permissions of the built-in `user` role until they are changed through
+the roles API. The built-in `admin` role is always granted every permission.
+*/
+var DefaultUserPermissions = []string{
+	PermissionProfileManage,
+	PermissionTaskRead,
+	PermissionTaskCreate,
+	PermissionTaskUpdate,
+	PermissionTaskDelete,
+}
+
+/*
+The resource an action is performed on, described by the users that hold
+it (e.g. the owner and the assignee of a task). A resource without holders
+stands for the whole collection, e.g. when listing or creating tasks.
+*/
+type Resource struct {
+	Holders []string
+}
+
+/*
+Checks whether the subject is allowed to perform the action on the
+resource. The subject needs either the `.any` variant of the action, or the
+action itself and to be one of the holders of the resource.
+*/
+func Can(subject Subject, action string, resource Resource) bool {
+	if slices.Contains(subject.Permissions, action+".any") {
+		return true
+	}
+
+	if !slices.Contains(subject.Permissions, action) {
+		return false
+	}
+
+	return len(resource.Holders) == 0 || (subject.Username != "" && slices.Contains(resource.Holders, subject.Username))
+}
+
 /*
 Interface used to define structs that compose the standard error interface
-with an function used to obtain an error code.
+with an function used to obtain an error code and the fields of the request
+that caused the error, if any.
 */
 type CodedError interface {
 	error
 	GetCode() string
+	GetFields() []FieldError
+}
+
+/*
+Describes why the value of a single field of a request is invalid. The
+field is named as it appears in the request.
+*/
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 /*
@@ -37,6 +243,7 @@ the task API.
 type TaskError struct {
 	Message string
 	Code    string
+	Fields  []FieldError
 }
 
 func (err TaskError) Error() string {
@@ -47,6 +254,10 @@ func (err TaskError) GetCode() string {
 	return err.Code
 }
 
+func (err TaskError) GetFields() []FieldError {
+	return err.Fields
+}
+
 /*
 A struct that implements the `CodedError` interface. Created to enable the
 exchange of error messages and signals between the different sections of
@@ -55,6 +266,7 @@ the functionalities of user auth.
 type UserError struct {
 	Message string
 	Code    string
+	Fields  []FieldError
 }
 
 func (err UserError) Error() string {
@@ -65,6 +277,26 @@ func (err UserError) GetCode() string {
 	return err.Code
 }
 
+func (err UserError) GetFields() []FieldError {
+	return err.Fields
+}
+
+/*
+The body of the error responses, following the problem details format of
+RFC 7807. The `code` of the error is the machine-readable counterpart of the
+`type`, and the invalid fields of the request are listed in `errors`.
+*/
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail"`
+	Instance  string       `json:"instance"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
 /*
 This is the definition of the task struct that will be used
 throughout the application. Along with the field names, the
@@ -72,25 +304,384 @@ json labels are provided to facilitate the binding process
 between the model itself and the JSON format.
 */
 type Task struct {
-	ID          string    `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	DueDate     time.Time `json:"due_date"`
-	Status      string    `json:"status"`
+	ID            string         `json:"id" bson:"id"`
+	Title         string         `json:"title" bson:"title"`
+	Description   string         `json:"description" bson:"description"`
+	DueDate       time.Time      `json:"due_date" bson:"due_date,omitempty"`
+	Status        string         `json:"status" bson:"status"`
+	Owner         string         `json:"owner" bson:"owner"`
+	Assignee      string         `json:"assignee" bson:"assignee"`
+	CreatedAt     time.Time      `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at" bson:"updated_at"`
+	Version       int64          `json:"version" bson:"version"`
+	StatusHistory []StatusChange `json:"status_history" bson:"status_history"`
+}
+
+/*
+A record of a change to the status of a task. The history of a task starts
+with the status it was created with, which has an empty `from` status.
+*/
+type StatusChange struct {
+	From      string    `json:"from" bson:"from"`
+	To        string    `json:"to" bson:"to"`
+	ChangedBy string    `json:"changed_by" bson:"changed_by"`
+	ChangedAt time.Time `json:"changed_at" bson:"changed_at"`
+}
+
+/*
+A JSON Merge Patch (RFC 7396) document targeting a task. The keys are the
+json labels of the fields to modify and a nil value removes the field from
+the task.
+*/
+type TaskPatch map[string]interface{}
+
+/*
+The query object used to filter, sort and paginate the tasks returned by
+`GetAllTasks`. Filters with zero values are ignored. The form labels are
+provided to bind the object directly from the URL query parameters.
+`VisibleTo` restricts the results to the tasks owned by or assigned to
+the user with the provided username and is never bound from the request.
+*/
+type TaskQuery struct {
+	Status    string    `form:"status"`
+	Title     string    `form:"title"`
+	DueAfter  time.Time `form:"due_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	DueBefore time.Time `form:"due_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	SortBy    string    `form:"sort"`
+	SortOrder string    `form:"order"`
+	Limit     int64     `form:"limit"`
+	Offset    int64     `form:"offset"`
+	VisibleTo string    `form:"-"`
+}
+
+/*
+A single page of tasks along with the total number of tasks that match
+the query. The links to the adjacent pages are filled in by the controller.
+*/
+type TaskPage struct {
+	Tasks    []Task `json:"tasks"`
+	Total    int64  `json:"total"`
+	Limit    int64  `json:"limit"`
+	Offset   int64  `json:"offset"`
+	Next     string `json:"next,omitempty"`
+	Previous string `json:"previous,omitempty"`
 }
 
 /*
 This is the definition of the user struct used for the authentication
 and authorization aspects of the project. The email and user name will
-be unique through entries. Along with the field names, the json labels
-are provided to facilitate the binding process between the model itself
-and the JSON format.
+be unique through entries. The struct is the storage model of the accounts
+and is never bound from or sent in the requests directly, the password hash
+is excluded from its JSON encoding as a safeguard. New accounts start
+unverified until the user proves that they own the email. Deactivated
+accounts are kept but can not be used until an admin activates them again.
 */
 type User struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	Role     string `json:"role"`
+	Username    string    `json:"username"`
+	Email       string    `json:"email"`
+	DisplayName string    `json:"display_name" bson:"display_name"`
+	Password    string    `json:"-"`
+	Role        string    `json:"role"`
+	Verified    bool      `json:"verified"`
+	Deactivated bool      `json:"deactivated"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+}
+
+/*
+The public view of a user account that is returned by the API. Unlike the
+user struct, it never holds the password hash.
+*/
+type UserProfile struct {
+	Username    string    `json:"username"`
+	Email       string    `json:"email"`
+	DisplayName string    `json:"display_name"`
+	Role        string    `json:"role"`
+	Verified    bool      `json:"verified"`
+	Deactivated bool      `json:"deactivated"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+/* Returns the public view of the user account */
+func (user User) Profile() UserProfile {
+	return UserProfile{
+		Username:    user.Username,
+		Email:       user.Email,
+		DisplayName: user.DisplayName,
+		Role:        user.Role,
+		Verified:    user.Verified,
+		Deactivated: user.Deactivated,
+		CreatedAt:   user.CreatedAt,
+	}
+}
+
+/*
+The changes a user makes to their own profile. Fields that are nil are left
+unchanged.
+*/
+type ProfileUpdate struct {
+	Email       *string `json:"email"`
+	DisplayName *string `json:"display_name"`
+}
+
+/*
+The search, filter, sort and pagination parameters of the user listing.
+`Search` matches the username, the email and the display name of the users
+regardless of their case.
+*/
+type UserQuery struct {
+	Search    string `form:"search"`
+	Role      string `form:"role"`
+	SortBy    string `form:"sort"`
+	SortOrder string `form:"order"`
+	Limit     int64  `form:"limit"`
+	Offset    int64  `form:"offset"`
+}
+
+/*
+A single page of users along with the total number of users that match the
+query. The links to the adjacent pages are filled in by the controller.
+*/
+type UserPage struct {
+	Users    []UserProfile `json:"users"`
+	Total    int64         `json:"total"`
+	Limit    int64         `json:"limit"`
+	Offset   int64         `json:"offset"`
+	Next     string        `json:"next,omitempty"`
+	Previous string        `json:"previous,omitempty"`
+}
+
+/*
+A refresh token issued to a user along with an access token. Only the hash
+of the token is stored. Every token obtained by rotating a refresh token
+belongs to the same family as the original one, which allows the whole
+family to be revoked when the reuse of a rotated token is detected. The
+family ID is the ID of the session started by the login.
+*/
+type RefreshToken struct {
+	TokenHash string    `bson:"token_hash"`
+	FamilyID  string    `bson:"family_id"`
+	Username  string    `bson:"username"`
+	CreatedAt time.Time `bson:"created_at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+	Used      bool      `bson:"used"`
+	Revoked   bool      `bson:"revoked"`
+}
+
+/*
+A login session of a user. Every access token carries the ID of its session
+in the `sid` claim, and the auth middleware rejects tokens whose session has
+been revoked. The session expires along with its latest refresh token.
+`Current` marks the session of the querying user and is never stored.
+*/
+type Session struct {
+	ID         string    `json:"id" bson:"id"`
+	Username   string    `json:"username" bson:"username"`
+	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at" bson:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at" bson:"expires_at"`
+	Revoked    bool      `json:"-" bson:"revoked"`
+	Current    bool      `json:"current" bson:"-"`
+}
+
+/*
+A token that allows a user to choose a new password without knowing their
+current one. Only the hash of the token is stored and each token can only be
+used once before it expires.
+*/
+type PasswordResetToken struct {
+	TokenHash string    `bson:"token_hash"`
+	Username  string    `bson:"username"`
+	CreatedAt time.Time `bson:"created_at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+	Used      bool      `bson:"used"`
+}
+
+/*
+The failed logins recorded for an account or a client IP, identified by the
+key. Failures are only counted until the record expires, after which the next
+failed login starts counting from one again.
+*/
+type LoginAttempts struct {
+	Key         string    `bson:"key"`
+	Failures    int64     `bson:"failures"`
+	LastFailure time.Time `bson:"last_failure"`
+	ExpiresAt   time.Time `bson:"expires_at"`
+}
+
+/*
+The TOTP second factor of a user (RFC 6238). An enrollment stays pending
+until the user proves that their authenticator app generates valid codes,
+after which it is enabled. `LastUsedStep` is the time step of the last code
+that was accepted, so that no code can be used twice, and only the hashes of
+the unused recovery codes are stored.
+*/
+type MFAEnrollment struct {
+	Username      string    `bson:"username"`
+	Secret        string    `bson:"secret"`
+	Enabled       bool      `bson:"enabled"`
+	LastUsedStep  int64     `bson:"last_used_step"`
+	RecoveryCodes []string  `bson:"recovery_codes"`
+	CreatedAt     time.Time `bson:"created_at"`
+}
+
+/*
+A personal API key that lets scripts act on behalf of a user without a
+password or a session. Only the hash of the key is stored, `Prefix` is the
+start of the key that is shown to help users recognize it, and zero times
+mean that the key never expires or has never been used.
+*/
+type APIKey struct {
+	ID         string    `bson:"id"`
+	Username   string    `bson:"username"`
+	Name       string    `bson:"name"`
+	Prefix     string    `bson:"prefix"`
+	KeyHash    string    `bson:"key_hash"`
+	Scopes     []string  `bson:"scopes"`
+	CreatedAt  time.Time `bson:"created_at"`
+	ExpiresAt  time.Time `bson:"expires_at"`
+	LastUsedAt time.Time `bson:"last_used_at"`
+}
+
+/*
+A role along with the permissions it grants to its users and whether they
+must log in with a second factor. The built-in roles `admin` and `user`
+always exist; the permissions of `admin` can not be changed while those of
+`user` can be changed like the ones of custom roles.
+*/
+type Role struct {
+	Name        string   `bson:"name"`
+	Permissions []string `bson:"permissions"`
+	MFARequired bool     `bson:"mfa_required"`
+	BuiltIn     bool     `bson:"-"`
+}
+
+/* The secret of a new TOTP enrollment and the otpauth URI that encodes it for authenticator apps */
+type TOTPSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+/*
+A token issued when the password of a user with a second factor is valid,
+which is exchanged for the tokens of a session along with the second factor.
+Only the hash of the token is stored and each challenge can only be used once
+before it expires.
+*/
+type MFAChallenge struct {
+	TokenHash string    `bson:"token_hash"`
+	Username  string    `bson:"username"`
+	CreatedAt time.Time `bson:"created_at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+	Used      bool      `bson:"used"`
+}
+
+/*
+The rate limit of a group of routes: a client can send a burst of `Requests`
+requests, after which its requests are allowed again at a steady rate of
+`Requests` per `Period`.
+*/
+type RateLimit struct {
+	Requests int64
+	Period   time.Duration
+}
+
+/*
+The outcome of taking a request from the token bucket of a client. `Remaining`
+is the number of requests left in the bucket, `ResetAfter` the time until the
+bucket is full again and, for denied requests, `RetryAfter` the time until
+the next request is allowed.
+*/
+type RateLimitStatus struct {
+	Allowed    bool
+	Limit      int64
+	Remaining  int64
+	ResetAfter time.Duration
+	RetryAfter time.Duration
+}
+
+/*
+A token sent to the email of a new account to prove that the user owns the
+email. Only the hash of the token is stored and each token can only be used
+once before it expires.
+*/
+type EmailVerificationToken struct {
+	TokenHash string    `bson:"token_hash"`
+	Username  string    `bson:"username"`
+	CreatedAt time.Time `bson:"created_at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+	Used      bool      `bson:"used"`
+}
+
+/*
+A message that is delivered to a user outside of the API, such as an email
+holding a password reset token.
+*/
+type Notification struct {
+	Recipient string `json:"recipient"`
+	Username  string `json:"username"`
+	Subject   string `json:"subject"`
+	Body      string `json:"body"`
+}
+
+/*
+The outcome of deleting an account. The tasks owned by the account are
+deleted along with it while the tasks of other users that were assigned to
+the account are left unassigned.
+*/
+type UserDeletion struct {
+	DeletedTasks    int64 `json:"deleted_tasks"`
+	UnassignedTasks int64 `json:"unassigned_tasks"`
+}
+
+/*
+The short-lived access token and the long-lived refresh token issued to a
+user when they log in or rotate their refresh token. When the login needs a
+second factor, only the challenge token is set instead, along with whether
+the user has to enroll a second factor first. The recovery codes are set when
+a second factor is enrolled during the login.
+*/
+type TokenPair struct {
+	AccessToken           string   `json:"token"`
+	RefreshToken          string   `json:"refresh_token"`
+	ChallengeToken        string   `json:"challenge_token,omitempty"`
+	MFAEnrollmentRequired bool     `json:"mfa_enrollment_required,omitempty"`
+	RecoveryCodes         []string `json:"recovery_codes,omitempty"`
+}
+
+/*
+A public key used to verify the tokens issued by the API, as defined by
+RFC 7517. `n` and `e` are set for RSA keys while `crv` and `x` are set for
+Ed25519 keys.
+*/
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Modulus   string `json:"n,omitempty"`
+	Exponent  string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+/* The set of public keys published at `/.well-known/jwks.json` */
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+/*
+The identity of the user making a request, as extracted from the claims
+of their JWT by the auth middleware along with the permissions of their
+role. Requests authenticated with an API key carry the ID and the scopes of
+the key instead of a session.
+*/
+type Subject struct {
+	Username    string
+	Role        string
+	Permissions []string
+	SessionID   string
+	APIKeyID    string
+	Scopes      []string
 }
 
 /*
@@ -102,20 +693,26 @@ type TaskControllerInterface interface {
 	GetOne(c *gin.Context)
 	Create(c *gin.Context)
 	Update(c *gin.Context)
+	Patch(c *gin.Context)
 	Delete(c *gin.Context)
 }
 
 /*
 The definition of the Task usecase that handles all the business and
 application logic along with any input validation regarding the task
-resource in the API.
+resource in the API. The permissions of the subject decide which tasks it
+can act on: the `.any` permissions cover every task, while the others only
+cover the tasks the subject owns or has been assigned. A non-zero
+expected version makes updates and deletions conditional on the task
+not having been modified since that version was read.
 */
 type TaskUsecaseInterface interface {
-	GetAllTasks(c context.Context) ([]Task, CodedError)
-	GetTaskByID(c context.Context, taskID string) (Task, CodedError)
-	AddTask(c context.Context, newTask Task) CodedError
-	UpdateTask(c context.Context, taskID string, updatedTask Task) (Task, CodedError)
-	DeleteTask(c context.Context, taskID string) CodedError
+	GetAllTasks(c context.Context, subject Subject, query TaskQuery) (TaskPage, CodedError)
+	GetTaskByID(c context.Context, subject Subject, taskID string) (Task, CodedError)
+	AddTask(c context.Context, subject Subject, newTask Task) (Task, CodedError)
+	UpdateTask(c context.Context, subject Subject, taskID string, updatedTask Task, expectedVersion int64) (Task, CodedError)
+	PatchTask(c context.Context, subject Subject, taskID string, patch TaskPatch, expectedVersion int64) (Task, CodedError)
+	DeleteTask(c context.Context, subject Subject, taskID string, expectedVersion int64) CodedError
 }
 
 /*
@@ -124,11 +721,14 @@ the database and creates an interface between the usecase and any
 underlying data
 */
 type TaskRepositoryInterface interface {
-	GetAllTasks(c context.Context) ([]Task, CodedError)
+	GetAllTasks(c context.Context, query TaskQuery) ([]Task, int64, CodedError)
 	GetTaskByID(c context.Context, taskID string) (Task, CodedError)
 	AddTask(c context.Context, newTask Task) CodedError
-	UpdateTask(c context.Context, taskID string, updatedTask Task) (Task, CodedError)
-	DeleteTask(c context.Context, taskID string) CodedError
+	UpdateTask(c context.Context, taskID string, updatedTask Task, version int64) (Task, CodedError)
+	PatchTask(c context.Context, taskID string, patch TaskPatch, version int64) (Task, CodedError)
+	DeleteTask(c context.Context, taskID string, version int64) CodedError
+	DeleteOwnerTasks(c context.Context, owner string) (int64, CodedError)
+	UnassignTasks(c context.Context, assignee string, updatedAt time.Time) (int64, CodedError)
 }
 
 /*
@@ -137,7 +737,38 @@ for all the user-related endpoints
 */
 type UserControllerInterface interface {
 	Signup(c *gin.Context)
+	CreateAccount(c *gin.Context)
 	Login(c *gin.Context)
+	Refresh(c *gin.Context)
+	Logout(c *gin.Context)
+	GetSessions(c *gin.Context)
+	RevokeSession(c *gin.Context)
+	RevokeUserSessions(c *gin.Context)
+	Demote(c *gin.Context)
+	SetRole(c *gin.Context)
+	Deactivate(c *gin.Context)
+	Activate(c *gin.Context)
+	Unlock(c *gin.Context)
+	VerifyMFALogin(c *gin.Context)
+	EnrollMFALogin(c *gin.Context)
+	EnrollTOTP(c *gin.Context)
+	ConfirmTOTP(c *gin.Context)
+	RegenerateRecoveryCodes(c *gin.Context)
+	DisableMFA(c *gin.Context)
+	ResetMFA(c *gin.Context)
+	CreateAPIKey(c *gin.Context)
+	GetAPIKeys(c *gin.Context)
+	RevokeAPIKey(c *gin.Context)
+	DeleteUser(c *gin.Context)
+	GetUsers(c *gin.Context)
+	GetUser(c *gin.Context)
+	GetProfile(c *gin.Context)
+	UpdateProfile(c *gin.Context)
+	ChangePassword(c *gin.Context)
+	ForgotPassword(c *gin.Context)
+	ResetPassword(c *gin.Context)
+	VerifyEmail(c *gin.Context)
+	ResendVerification(c *gin.Context)
 }
 
 /*
@@ -147,9 +778,42 @@ and any related security protocols that are applied for the authentication
 and authorization system.
 */
 type UserUsecaseInterface interface {
-	CreateUser(c context.Context, user User) CodedError
-	ValidateAndGetToken(c context.Context, user User) (string, CodedError)
-	Promote(c context.Context, username string) CodedError
+	CreateUser(c context.Context, user User) (bool, CodedError)
+	CreateAccount(c context.Context, subject Subject, user User) (bool, CodedError)
+	ValidateAndGetToken(c context.Context, user User, clientIP string) (TokenPair, CodedError)
+	RefreshTokens(c context.Context, refreshToken string) (TokenPair, CodedError)
+	Logout(c context.Context, refreshToken string) CodedError
+	Promote(c context.Context, subject Subject, username string) CodedError
+	Demote(c context.Context, subject Subject, username string) CodedError
+	SetRole(c context.Context, subject Subject, username string, role string) CodedError
+	Deactivate(c context.Context, subject Subject, username string) CodedError
+	Activate(c context.Context, username string) CodedError
+	UnlockAccount(c context.Context, username string) CodedError
+	VerifyMFALogin(c context.Context, challengeToken string, code string, recoveryCode string, clientIP string) (TokenPair, CodedError)
+	EnrollMFALogin(c context.Context, challengeToken string) (TOTPSetup, CodedError)
+	EnrollTOTP(c context.Context, subject Subject, currentPassword string) (TOTPSetup, CodedError)
+	ConfirmTOTP(c context.Context, subject Subject, code string) ([]string, CodedError)
+	RegenerateRecoveryCodes(c context.Context, subject Subject, code string) ([]string, CodedError)
+	DisableMFA(c context.Context, subject Subject, currentPassword string, code string) CodedError
+	ResetMFA(c context.Context, username string) CodedError
+	CreateAPIKey(c context.Context, subject Subject, name string, scopes []string, expiresAt time.Time) (APIKey, string, CodedError)
+	GetAPIKeys(c context.Context, subject Subject) ([]APIKey, CodedError)
+	RevokeAPIKey(c context.Context, subject Subject, keyID string) CodedError
+	ValidateAPIKey(c context.Context, key string) (Subject, CodedError)
+	DeleteUser(c context.Context, subject Subject, username string) (UserDeletion, CodedError)
+	GetUsers(c context.Context, query UserQuery) (UserPage, CodedError)
+	GetUser(c context.Context, username string) (UserProfile, CodedError)
+	GetProfile(c context.Context, subject Subject) (UserProfile, CodedError)
+	UpdateProfile(c context.Context, subject Subject, currentPassword string, update ProfileUpdate) (UserProfile, CodedError)
+	ChangePassword(c context.Context, subject Subject, currentPassword string, newPassword string) CodedError
+	GetSessions(c context.Context, subject Subject) ([]Session, CodedError)
+	RevokeSession(c context.Context, subject Subject, sessionID string) CodedError
+	RevokeUserSessions(c context.Context, username string) CodedError
+	ValidateSession(c context.Context, sessionID string) CodedError
+	ForgotPassword(c context.Context, email string) CodedError
+	ResetPassword(c context.Context, resetToken string, newPassword string) CodedError
+	VerifyEmail(c context.Context, verificationToken string) CodedError
+	ResendVerification(c context.Context, email string) CodedError
 }
 
 /*
@@ -161,7 +825,174 @@ type UserRepositoryInterface interface {
 	CreateUser(c context.Context, user User) CodedError
 	CheckDuplicate(c context.Context, key string, value interface{}, errorMessage string) CodedError
 	GetByUsername(c context.Context, username string) (User, CodedError)
+	GetByEmail(c context.Context, email string) (User, CodedError)
+	GetUsers(c context.Context, query UserQuery) ([]User, int64, CodedError)
+	UpdateProfile(c context.Context, user User) CodedError
 	PromoteUser(c context.Context, username string) CodedError
+	DemoteUser(c context.Context, username string) CodedError
+	SetRole(c context.Context, username string, role string) CodedError
+	SetDeactivated(c context.Context, username string, deactivated bool) CodedError
+	DeleteUser(c context.Context, username string) CodedError
+	UpdatePassword(c context.Context, username string, password string) CodedError
+	VerifyEmail(c context.Context, username string) CodedError
+}
+
+/*
+The definition of the Refresh token repository that stores the hashes of
+the issued refresh tokens. `UseRefreshToken` atomically marks an unused and
+unrevoked token as used so that each token can only be rotated once.
+*/
+type RefreshTokenRepositoryInterface interface {
+	CreateRefreshToken(c context.Context, token RefreshToken) CodedError
+	GetRefreshToken(c context.Context, tokenHash string) (RefreshToken, CodedError)
+	UseRefreshToken(c context.Context, tokenHash string) (RefreshToken, CodedError)
+	RevokeTokenFamily(c context.Context, familyID string) CodedError
+	RevokeUserTokens(c context.Context, username string) CodedError
+}
+
+/*
+The definition of the Session repository that stores the login sessions of
+the users and serves as the revocation store of the issued access tokens.
+*/
+type SessionRepositoryInterface interface {
+	CreateSession(c context.Context, session Session) CodedError
+	GetSession(c context.Context, sessionID string) (Session, CodedError)
+	GetActiveSessions(c context.Context, username string) ([]Session, CodedError)
+	ExtendSession(c context.Context, sessionID string, lastUsedAt time.Time, expiresAt time.Time) CodedError
+	RevokeSession(c context.Context, sessionID string) CodedError
+	RevokeUserSessions(c context.Context, username string) CodedError
+}
+
+/*
+The definition of the Password reset token repository that stores the hashes
+of the issued reset tokens. `UseResetToken` atomically marks an unused token
+as used so that each token can only be used once.
+*/
+type PasswordResetTokenRepositoryInterface interface {
+	CreateResetToken(c context.Context, token PasswordResetToken) CodedError
+	UseResetToken(c context.Context, tokenHash string) (PasswordResetToken, CodedError)
+	InvalidateUserResetTokens(c context.Context, username string) CodedError
+}
+
+/*
+The definition of the Email verification token repository that stores the
+hashes of the issued verification tokens. `UseVerificationToken` atomically
+marks an unused token as used so that each token can only be used once.
+*/
+type EmailVerificationTokenRepositoryInterface interface {
+	CreateVerificationToken(c context.Context, token EmailVerificationToken) CodedError
+	UseVerificationToken(c context.Context, tokenHash string) (EmailVerificationToken, CodedError)
+	InvalidateUserVerificationTokens(c context.Context, username string) CodedError
+}
+
+/*
+The definition of the Login attempt repository that keeps count of the failed
+logins of the accounts and the client IPs. `RecordLoginFailure` atomically
+counts the failure and returns the updated record, restarting the count if
+the previous record has expired.
+*/
+type LoginAttemptRepositoryInterface interface {
+	GetLoginAttempts(c context.Context, key string) (LoginAttempts, CodedError)
+	RecordLoginFailure(c context.Context, key string, failedAt time.Time, expiresAt time.Time) (LoginAttempts, CodedError)
+	ClearLoginAttempts(c context.Context, key string) CodedError
+}
+
+/*
+The definition of the MFA repository that stores the TOTP enrollments of the
+users. `UseTOTPStep` atomically records the time step of an accepted code and
+fails with ERR_CONFLICT if it is not after the last recorded one, while
+`UseRecoveryCode` atomically removes the recovery code with the provided hash
+and fails with ERR_NOT_FOUND if the user does not have it.
+*/
+type MFARepositoryInterface interface {
+	GetMFA(c context.Context, username string) (MFAEnrollment, CodedError)
+	SaveMFA(c context.Context, enrollment MFAEnrollment) CodedError
+	UseTOTPStep(c context.Context, username string, step int64) CodedError
+	UseRecoveryCode(c context.Context, username string, codeHash string) CodedError
+	DeleteMFA(c context.Context, username string) CodedError
+}
+
+/*
+The definition of the MFA challenge repository that stores the hashes of the
+login challenges. `UseChallenge` atomically marks an unused challenge as used
+so that each challenge can only be used once.
+*/
+type MFAChallengeRepositoryInterface interface {
+	CreateChallenge(c context.Context, challenge MFAChallenge) CodedError
+	GetChallenge(c context.Context, tokenHash string) (MFAChallenge, CodedError)
+	UseChallenge(c context.Context, tokenHash string) (MFAChallenge, CodedError)
+}
+
+/*
+The definition of the API key repository that stores the hashes of the
+personal API keys of the users. `GetAPIKeys` returns the keys of a user from
+the newest to the oldest and `DeleteAPIKey` only deletes the key if it
+belongs to the provided user.
+*/
+type APIKeyRepositoryInterface interface {
+	CreateAPIKey(c context.Context, key APIKey) CodedError
+	GetAPIKeys(c context.Context, username string) ([]APIKey, CodedError)
+	GetAPIKeyByHash(c context.Context, keyHash string) (APIKey, CodedError)
+	TouchAPIKey(c context.Context, keyID string, usedAt time.Time) CodedError
+	DeleteAPIKey(c context.Context, username string, keyID string) CodedError
+	DeleteUserAPIKeys(c context.Context, username string) CodedError
+}
+
+/*
+The definition of the Role controller that encompasses the handlers for
+the endpoints used by the admins to manage the roles and their permissions
+*/
+type RoleControllerInterface interface {
+	GetRoles(c *gin.Context)
+	GetRole(c *gin.Context)
+	SaveRole(c *gin.Context)
+	SetRoleMFA(c *gin.Context)
+	DeleteRole(c *gin.Context)
+}
+
+/*
+The definition of the Role usecase that manages the policy mapping the
+roles to their permissions and to whether their users must log in with a
+second factor. `RolePermissions` is used by the auth middleware to resolve
+the permissions of the role of each request.
+*/
+type RoleUsecaseInterface interface {
+	GetRoles(c context.Context) ([]Role, CodedError)
+	GetRole(c context.Context, name string) (Role, CodedError)
+	SaveRole(c context.Context, subject Subject, role Role) (Role, CodedError)
+	SetRoleMFA(c context.Context, subject Subject, name string, required bool) (Role, CodedError)
+	DeleteRole(c context.Context, subject Subject, name string) CodedError
+	RolePermissions(c context.Context, name string) ([]string, CodedError)
+}
+
+/*
+The definition of the Role repository that stores the custom roles and the
+changes made to the built-in roles. `SaveRole` creates the role or replaces
+an existing one.
+*/
+type RoleRepositoryInterface interface {
+	GetRole(c context.Context, name string) (Role, CodedError)
+	GetRoles(c context.Context) ([]Role, CodedError)
+	SaveRole(c context.Context, role Role) CodedError
+	DeleteRole(c context.Context, name string) CodedError
+}
+
+/*
+The definition of the Rate limit repository that keeps the token buckets of
+the clients. `TakeToken` atomically refills the bucket of the key for the
+time elapsed since its last request and takes a token from it if there is
+one. The buckets of keys that have not been seen yet are full.
+*/
+type RateLimitRepositoryInterface interface {
+	TakeToken(c context.Context, key string, limit RateLimit, now time.Time) (RateLimitStatus, CodedError)
+}
+
+/*
+The definition of the Notifier that delivers notifications to the users,
+e.g. by email. The notifiers are provided by the infrastructure layer.
+*/
+type NotifierInterface interface {
+	Notify(c context.Context, notification Notification) CodedError
 }
 
 /*
@@ -1,7 +1,9 @@
 package infrastructure
 
 import (
+	"context"
 	"fmt"
+	"slices"
 	"strings"
 	domain "task_manager_api/Domain"
 	"time"
@@ -10,81 +12,169 @@ import (
 	"github.com/golang-jwt/jwt"
 )
 
-func MiddlewareError(c *gin.Context, statusCode int, message string) {
-	c.JSON(statusCode, domain.Response{"message": message})
+/*
+Sends the error with the provided status code and error code as an
+`application/problem+json` document and stops the handling of the request.
+*/
+func MiddlewareError(c *gin.Context, statusCode int, code string, message string) {
+	WriteProblem(c, statusCode, domain.UserError{Message: message, Code: code})
 	c.Abort()
 }
 
 /*
-This is the authorization middleware used for the endpoints. It accepts a set of
-roles for which the endpoint is open.
+This is the authorization middleware used for the endpoints. It accepts the
+permission required to access the endpoint and resolves the permissions of
+the role of the user with `RolePermissions`.
 
 WORKFLOW:
   - Obtains the JWT from the authorization header
-  - Parses the JWT and verifies the signature
-  - Checks the role of the user associated with the token
+  - Parses the JWT and verifies the signature, issuer and audience
+  - Checks the expiry date of the token
+  - Checks that the session of the token has not been revoked
+  - Checks that the role of the user associated with the token grants the permission
+  - Stores the username, role, permissions and session of the user in the context as a `domain.Subject`
   - Calls `c.Next()` if the querying user has permission to access the endpoint
 */
-func AuthMiddlewareWithRoles(validRoles []string, secret string, ValidateToken func(string, string) (*jwt.Token, error)) gin.HandlerFunc {
+func AuthMiddlewareWithPermission(permission string, ValidateToken func(string) (*jwt.Token, error), ValidateSession func(context.Context, string) domain.CodedError, RolePermissions func(context.Context, string) ([]string, domain.CodedError)) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// obtain token from the request header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			MiddlewareError(c, 401, "Authorization header not found")
+			MiddlewareError(c, 401, domain.ERR_UNAUTHORIZED, "Authorization header not found")
 			return
 		}
 
 		headerSegments := strings.Split(authHeader, " ")
 		if len(headerSegments) != 2 || strings.ToLower(headerSegments[0]) != "bearer" {
-			MiddlewareError(c, 401, "Authorization header is invalid")
+			MiddlewareError(c, 401, domain.ERR_UNAUTHORIZED, "Authorization header is invalid")
 			return
 		}
 
-		// parses token with the correct signing method and checks for errors and token validity
-		token, validErr := ValidateToken(headerSegments[1], secret)
+		// parses token with the correct signing key and checks for errors and token validity
+		// the reason is not sent as it can describe the signing keys of the API
+		token, validErr := ValidateToken(headerSegments[1])
 		if validErr != nil {
-			MiddlewareError(c, 401, validErr.Error())
+			MiddlewareError(c, 401, domain.ERR_UNAUTHORIZED, "Invalid token")
 			return
 		}
 
 		// check the expiry date of the token
-		expiresAt, ok := token.Claims.(jwt.MapClaims)["expiresAt"]
+		claims, ok := token.Claims.(jwt.MapClaims)
 		if !ok {
-			MiddlewareError(c, 401, "Invalid token: Expiry date not found")
+			MiddlewareError(c, 401, domain.ERR_UNAUTHORIZED, "Invalid token: Claims not found")
 			return
 		}
 
-		expiresAtTime, convErr := time.Parse(time.RFC3339Nano, fmt.Sprintf("%v", expiresAt))
-		if convErr != nil {
-			MiddlewareError(c, 401, "Error while parsing expiry date: "+convErr.Error())
+		if _, ok := claims["exp"]; !ok {
+			MiddlewareError(c, 401, domain.ERR_UNAUTHORIZED, "Invalid token: Expiry date not found")
 			return
 		}
 
-		if expiresAtTime.Compare(time.Now()) == -1 {
-			MiddlewareError(c, 401, "Token expired")
+		if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+			MiddlewareError(c, 401, domain.ERR_UNAUTHORIZED, "Token expired")
 			return
 		}
 
 		// get the role from the claims of the JWT
-		userRole, ok := token.Claims.(jwt.MapClaims)["role"]
+		userRole, ok := claims["role"]
+		if !ok {
+			MiddlewareError(c, 401, domain.ERR_UNAUTHORIZED, "Invalid token: Role not found")
+			return
+		}
+
+		// get the username from the subject claim of the JWT
+		username, ok := claims["sub"]
 		if !ok {
-			MiddlewareError(c, 401, "Invalid token: Role not found")
+			MiddlewareError(c, 401, domain.ERR_UNAUTHORIZED, "Invalid token: Username not found")
+			return
+		}
+
+		// check the session of the token against the revoked sessions
+		sessionID, ok := claims["sid"]
+		if !ok {
+			MiddlewareError(c, 401, domain.ERR_UNAUTHORIZED, "Invalid token: Session not found")
+			return
+		}
+
+		sessionErr := ValidateSession(c, fmt.Sprintf("%v", sessionID))
+		if sessionErr != nil && sessionErr.GetCode() == domain.ERR_UNAUTHORIZED {
+			MiddlewareError(c, 401, domain.ERR_UNAUTHORIZED, sessionErr.Error())
+			return
+		}
+
+		if sessionErr != nil {
+			WriteProblem(c, 500, sessionErr)
+			c.Abort()
+			return
+		}
+
+		// resolve the permissions of the role, roles that no longer exist grant none
+		permissions, roleErr := RolePermissions(c, fmt.Sprintf("%v", userRole))
+		if roleErr != nil && roleErr.GetCode() != domain.ERR_NOT_FOUND {
+			WriteProblem(c, 500, roleErr)
+			c.Abort()
+			return
+		}
+
+		subject := domain.Subject{Username: fmt.Sprintf("%v", username), Role: fmt.Sprintf("%v", userRole), Permissions: permissions, SessionID: fmt.Sprintf("%v", sessionID)}
+		if !domain.Can(subject, permission, domain.Resource{}) {
+			MiddlewareError(c, 403, domain.ERR_FORBIDDEN, fmt.Sprintf("The '%v' permission is required to access this endpoint", permission))
 			return
 		}
 
-		valid := false
-		for _, validRole := range validRoles {
-			if userRole == validRole {
-				valid = true
-				break
-			}
+		c.Set(domain.ContextSubject, subject)
+		c.Next()
+	}
+}
+
+/*
+This is the authorization middleware used for the endpoints that can also be
+reached with a personal API key. It accepts the permission required to
+access the endpoint and the scope an API key needs to reach it.
+
+WORKFLOW:
+  - Delegates to the JWT middleware `tokenAuth` if there is no X-API-Key header
+  - Validates the API key and obtains the user it acts for
+  - Checks the current permissions of the user and the scopes of the key
+  - Stores the username, role, permissions, key ID and scopes in the context as a `domain.Subject`
+  - Calls `c.Next()` if the key has permission to access the endpoint
+*/
+func AuthMiddlewareWithAPIKeys(permission string, scope string, ValidateAPIKey func(context.Context, string) (domain.Subject, domain.CodedError), tokenAuth gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := c.GetHeader(APIKeyHeader)
+		if apiKey == "" {
+			tokenAuth(c)
+			return
+		}
+
+		subject, keyErr := ValidateAPIKey(c, apiKey)
+		if keyErr != nil && keyErr.GetCode() == domain.ERR_UNAUTHORIZED {
+			MiddlewareError(c, 401, domain.ERR_UNAUTHORIZED, keyErr.Error())
+			return
+		}
+
+		if keyErr != nil && keyErr.GetCode() == domain.ERR_FORBIDDEN {
+			MiddlewareError(c, 403, domain.ERR_FORBIDDEN, keyErr.Error())
+			return
+		}
+
+		if keyErr != nil {
+			WriteProblem(c, 500, keyErr)
+			c.Abort()
+			return
+		}
+
+		if !domain.Can(subject, permission, domain.Resource{}) {
+			MiddlewareError(c, 403, domain.ERR_FORBIDDEN, fmt.Sprintf("The '%v' permission is required to access this endpoint", permission))
+			return
 		}
 
-		if !valid {
-			MiddlewareError(c, 403, fmt.Sprintf("'%v' roles are not allowed to access this endpoint", userRole))
+		if !slices.Contains(subject.Scopes, scope) {
+			MiddlewareError(c, 403, domain.ERR_FORBIDDEN, fmt.Sprintf("API key does not have the '%v' scope required by this endpoint", scope))
 			return
 		}
 
+		c.Set(domain.ContextSubject, subject)
 		c.Next()
 	}
 }
@@ -0,0 +1,12 @@
+package infrastructure
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+/*
+Generates a new unique identifier for a resource. The identifiers are the
+hex encoding of a mongoDB ObjectID, which makes them sortable by their
+creation time.
+*/
+func GenerateID() string {
+	return primitive.NewObjectID().Hex()
+}
@@ -1,29 +1,160 @@
 package infrastructure
 
 import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
 	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	domain "task_manager_api/Domain"
 	"time"
 
 	"github.com/golang-jwt/jwt"
 )
 
+/* The key ID of the HMAC key used when no key pairs have been configured */
+const HMACKeyID = "hmac"
+
+/*
+The configuration of the JWT service. When `KeysDirectory` is empty, the
+tokens are signed with HS256 using `Secret`. Otherwise every `.pem` file in
+the directory is loaded as an RSA or Ed25519 key named after the file, and
+the tokens are signed with the private key named `ActiveKeyID`.
+*/
+type JWTConfig struct {
+	Issuer        string
+	Audience      string
+	Secret        string
+	KeysDirectory string
+	ActiveKeyID   string
+}
+
+/*
+A key that the tokens are signed or verified with. The private key is nil
+for keys that are only kept to verify the tokens signed before a rotation.
+*/
+type signingKey struct {
+	id         string
+	method     jwt.SigningMethod
+	privateKey interface{}
+	publicKey  interface{}
+}
+
+/*
+Signs and verifies the JWTs of the API. Tokens are signed with the active key
+and carry its ID in the `kid` header, while the tokens signed with any of the
+other configured keys are still accepted. This allows rotating the keys by
+adding a new key, activating it and removing the old one once all the tokens
+signed with it have expired.
+*/
+type JWTService struct {
+	issuer    string
+	audience  string
+	activeKey signingKey
+	keys      map[string]signingKey
+}
+
+/* Creates the JWT service and loads the keys described by the config */
+func NewJWTService(config JWTConfig) (*JWTService, error) {
+	if config.Issuer == "" || config.Audience == "" {
+		return nil, fmt.Errorf("error: the issuer and the audience of the tokens are required")
+	}
+
+	service := JWTService{issuer: config.Issuer, audience: config.Audience, keys: map[string]signingKey{}}
+	if config.KeysDirectory == "" {
+		if config.Secret == "" {
+			return nil, fmt.Errorf("error: a secret is required to sign tokens with HS256")
+		}
+
+		service.activeKey = signingKey{id: HMACKeyID, method: jwt.SigningMethodHS256, privateKey: []byte(config.Secret), publicKey: []byte(config.Secret)}
+		service.keys[HMACKeyID] = service.activeKey
+		return &service, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(config.KeysDirectory, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("error: %v", err.Error())
+	}
+
+	for _, path := range paths {
+		key, err := loadSigningKey(path)
+		if err != nil {
+			return nil, err
+		}
+
+		service.keys[key.id] = key
+	}
+
+	activeKey, ok := service.keys[config.ActiveKeyID]
+	if !ok || activeKey.privateKey == nil {
+		return nil, fmt.Errorf("error: the private key of the active key '%v' was not found in '%v'", config.ActiveKeyID, config.KeysDirectory)
+	}
+
+	service.activeKey = activeKey
+	return &service, nil
+}
+
 /*
-Creates and signs a JWT with the username, role and tokenLifeSpan as the
-payloads. Returns the signed token if there aren't any errors.
+Loads an RSA or Ed25519 key from a PEM file. Both private keys and public
+keys are accepted, the latter being only usable for verification.
 */
-func SignJWTWithPayload(username string, role string, tokenLifeSpan time.Duration, secret string) (string, domain.CodedError) {
-	if secret == "" {
-		return "", domain.UserError{Message: "internal server error", Code: domain.ERR_INTERNAL_SERVER}
+func loadSigningKey(path string) (signingKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return signingKey{}, fmt.Errorf("error: %v", err.Error())
+	}
+
+	key := signingKey{id: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}
+	if privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(content); err == nil {
+		key.method, key.privateKey, key.publicKey = jwt.SigningMethodRS256, privateKey, &privateKey.PublicKey
+		return key, nil
+	}
+
+	if publicKey, err := jwt.ParseRSAPublicKeyFromPEM(content); err == nil {
+		key.method, key.publicKey = jwt.SigningMethodRS256, publicKey
+		return key, nil
 	}
 
-	jwtSecret := []byte(secret)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"username":  username,
-		"role":      role,
-		"expiresAt": time.Now().Add(time.Hour * 2),
+	if privateKey, err := jwt.ParseEdPrivateKeyFromPEM(content); err == nil {
+		key.method, key.privateKey, key.publicKey = jwt.SigningMethodEdDSA, privateKey, privateKey.(ed25519.PrivateKey).Public()
+		return key, nil
+	}
+
+	if publicKey, err := jwt.ParseEdPublicKeyFromPEM(content); err == nil {
+		key.method, key.publicKey = jwt.SigningMethodEdDSA, publicKey
+		return key, nil
+	}
+
+	return signingKey{}, fmt.Errorf("error: '%v' is not an RSA or Ed25519 key", path)
+}
+
+/*
+Creates and signs a JWT for the user with the registered `iss`, `aud`,
+`sub`, `iat`, `exp` and `jti` claims. The `role` claim holds the role of the
+user and the `sid` claim binds the token to the session it was issued for.
+The `jti` is only informational, e.g. to tell tokens apart in logs: it is
+not checked, and tokens are revoked through their session rather than one
+by one. Returns the signed token if there aren't any errors.
+*/
+func (s *JWTService) SignJWTWithPayload(username string, role string, sessionID string, tokenLifeSpan time.Duration) (string, domain.CodedError) {
+	now := time.Now()
+	token := jwt.NewWithClaims(s.activeKey.method, jwt.MapClaims{
+		"iss":  s.issuer,
+		"aud":  s.audience,
+		"sub":  username,
+		"iat":  now.Unix(),
+		"exp":  now.Add(tokenLifeSpan).Unix(),
+		"jti":  GenerateID(),
+		"sid":  sessionID,
+		"role": role,
 	})
-	jwtToken, signingErr := token.SignedString(jwtSecret)
+	token.Header["kid"] = s.activeKey.id
+
+	jwtToken, signingErr := token.SignedString(s.activeKey.privateKey)
 	if signingErr != nil {
 		return "", domain.UserError{Message: "internal server error: " + signingErr.Error(), Code: domain.ERR_INTERNAL_SERVER}
 	}
@@ -32,17 +163,24 @@ func SignJWTWithPayload(username string, role string, tokenLifeSpan time.Duratio
 }
 
 /*
-Parses the JWT token with the HMAC signing method and returns a pointer
-to a jwt.Token struct if the token is valid and not tampered with.
+Parses the JWT with the key named in its `kid` header and returns a pointer
+to a jwt.Token struct if the token is valid and not tampered with. Tokens
+that are expired or were issued by another issuer or for another audience
+are rejected.
 */
-func ValidateAndParseToken(rawToken string, secret string) (*jwt.Token, error) {
+func (s *JWTService) ValidateAndParseToken(rawToken string) (*jwt.Token, error) {
 	token, err := jwt.Parse(rawToken, func(t *jwt.Token) (interface{}, error) {
-		_, ok := t.Method.(*jwt.SigningMethodHMAC)
+		key, ok := s.keys[fmt.Sprintf("%v", t.Header["kid"])]
 		if !ok {
+			return nil, fmt.Errorf("unknown signing key: %v", t.Header["kid"])
+		}
+
+		// the algorithm of the token must match the key to prevent algorithm confusion
+		if t.Method.Alg() != key.method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
 		}
 
-		return []byte(secret), nil
+		return key.publicKey, nil
 	})
 
 	if err != nil {
@@ -53,5 +191,38 @@ func ValidateAndParseToken(rawToken string, secret string) (*jwt.Token, error) {
 		return nil, fmt.Errorf("error: Invalid token,  Potentially malformed")
 	}
 
+	claims := token.Claims.(jwt.MapClaims)
+	if !claims.VerifyIssuer(s.issuer, true) || !claims.VerifyAudience(s.audience, true) {
+		return nil, fmt.Errorf("error: Invalid token, unexpected issuer or audience")
+	}
+
 	return token, nil
 }
+
+/*
+Returns the public keys of the service as a JSON Web Key Set so that other
+services can verify the tokens. HMAC keys are secret and never published.
+*/
+func (s *JWTService) JWKS() domain.JSONWebKeySet {
+	keySet := domain.JSONWebKeySet{Keys: []domain.JSONWebKey{}}
+	for _, key := range s.keys {
+		jwk := domain.JSONWebKey{KeyID: key.id, Use: "sig", Algorithm: key.method.Alg()}
+		switch publicKey := key.publicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.Modulus = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.Exponent = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+
+		keySet.Keys = append(keySet.Keys, jwk)
+	}
+
+	sort.Slice(keySet.Keys, func(i, j int) bool { return keySet.Keys[i].KeyID < keySet.Keys[j].KeyID })
+	return keySet
+}
@@ -0,0 +1,50 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	domain "task_manager_api/Domain"
+)
+
+/*
+Implements the NotifierInterface defined in `domain` by writing every
+notification as a JSON line to a logger. This stands in for a mail service
+until one is integrated: the notifications (including the tokens in them)
+can be read from the log or the file it writes to.
+*/
+type LogNotifier struct {
+	Logger *log.Logger
+}
+
+/* Creates a notifier that writes the notifications to the provided writer */
+func NewLogNotifier(writer io.Writer) *LogNotifier {
+	return &LogNotifier{Logger: log.New(writer, "", log.LstdFlags)}
+}
+
+/*
+Creates a notifier that appends the notifications to the file with the
+provided path. The file is created if it does not exist and is only readable
+by its owner since the notifications contain secret tokens.
+*/
+func NewFileNotifier(path string) (*LogNotifier, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewLogNotifier(file), nil
+}
+
+/* Writes the notification to the log */
+func (n *LogNotifier) Notify(c context.Context, notification domain.Notification) domain.CodedError {
+	encoded, err := json.Marshal(notification)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	n.Logger.Println(string(encoded))
+	return nil
+}
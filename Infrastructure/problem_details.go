@@ -0,0 +1,55 @@
+package infrastructure
+
+import (
+	"log"
+	"net/http"
+	domain "task_manager_api/Domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* The media type of the error responses, as defined by RFC 7807 */
+const ProblemContentType = "application/problem+json"
+
+/*
+The prefix of the `type` of the problems. The type of a problem is this
+prefix followed by the code of the error, e.g. `/problems/not_found`.
+*/
+const ProblemTypePrefix = "/problems/"
+
+/* The detail sent to the clients in place of the details of internal errors */
+const InternalErrorMessage = "Internal server error"
+
+/*
+Returns the problem details of the error for the request. Internal errors can
+hold details about the database or the services of the API, so they are
+logged along with the correlation ID of the request and the problem only holds
+a generic detail and the correlation ID to report.
+*/
+func NewProblem(c *gin.Context, statusCode int, err domain.CodedError) domain.Problem {
+	problem := domain.Problem{
+		Type:      ProblemTypePrefix + err.GetCode(),
+		Title:     http.StatusText(statusCode),
+		Status:    statusCode,
+		Detail:    err.Error(),
+		Instance:  c.Request.URL.Path,
+		Code:      err.GetCode(),
+		RequestID: c.GetString(domain.ContextRequestID),
+		Errors:    err.GetFields(),
+	}
+
+	if statusCode == http.StatusInternalServerError {
+		log.Printf("[request %v] %v %v: %v", problem.RequestID, c.Request.Method, c.Request.URL.Path, err.Error())
+		problem.Detail = InternalErrorMessage
+		problem.Errors = nil
+	}
+
+	return problem
+}
+
+/* Sends the error to the client as an `application/problem+json` document */
+func WriteProblem(c *gin.Context, statusCode int, err domain.CodedError) {
+	// the content type is only set by c.JSON if it is not already present
+	c.Header("Content-Type", ProblemContentType)
+	c.JSON(statusCode, NewProblem(c, statusCode, err))
+}
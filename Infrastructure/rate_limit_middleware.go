@@ -0,0 +1,141 @@
+package infrastructure
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	domain "task_manager_api/Domain"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* The header that carries the API key of a request */
+const APIKeyHeader = "X-API-Key"
+
+/* The clients the requests can be counted by */
+const (
+	RateLimitKeyIP     = "ip"
+	RateLimitKeyUser   = "user"
+	RateLimitKeyAPIKey = "api_key"
+)
+
+/* Returns the key under which the request is counted */
+type RateLimitKeyFunc func(c *gin.Context) string
+
+/* Counts the requests by the IP of the client */
+func RateLimitByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+/*
+Counts the requests by the username of the authenticated user, and by the IP
+of the client for the requests that are not authenticated.
+*/
+func RateLimitByUser(c *gin.Context) string {
+	subject, _ := c.Get(domain.ContextSubject)
+	if subject, ok := subject.(domain.Subject); ok && subject.Username != "" {
+		return "user:" + subject.Username
+	}
+
+	return RateLimitByIP(c)
+}
+
+/*
+Counts the requests by the ID of the API key they were authenticated with,
+and like `RateLimitByUser` for the requests without one. The ID is only set
+by the auth middleware once the key has been validated, so clients can not
+get a new bucket by sending made up keys.
+*/
+func RateLimitByAPIKey(c *gin.Context) string {
+	subject, _ := c.Get(domain.ContextSubject)
+	if subject, ok := subject.(domain.Subject); ok && subject.APIKeyID != "" {
+		return "api_key:" + subject.APIKeyID
+	}
+
+	return RateLimitByUser(c)
+}
+
+/* Returns the function that counts the requests by the provided kind of client */
+func RateLimitKey(kind string) (RateLimitKeyFunc, error) {
+	switch kind {
+	case RateLimitKeyIP:
+		return RateLimitByIP, nil
+	case RateLimitKeyUser:
+		return RateLimitByUser, nil
+	case RateLimitKeyAPIKey:
+		return RateLimitByAPIKey, nil
+	}
+
+	return nil, fmt.Errorf("unknown rate limit key '%v', expected one of %v, %v or %v", kind, RateLimitKeyIP, RateLimitKeyUser, RateLimitKeyAPIKey)
+}
+
+/*
+Parses a rate limit written as `<requests>/<period>`, e.g. `100/1m` or
+`5/s`, where the period is a Go duration whose count can be left out.
+*/
+func ParseRateLimit(value string) (domain.RateLimit, error) {
+	requests, period, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return domain.RateLimit{}, fmt.Errorf("invalid rate limit '%v', expected <requests>/<period>", value)
+	}
+
+	count, err := strconv.ParseInt(strings.TrimSpace(requests), 10, 64)
+	if err != nil || count <= 0 {
+		return domain.RateLimit{}, fmt.Errorf("invalid rate limit '%v': the number of requests must be a positive integer", value)
+	}
+
+	period = strings.TrimSpace(period)
+	if period != "" && (period[0] < '0' || period[0] > '9') {
+		period = "1" + period
+	}
+
+	duration, err := time.ParseDuration(period)
+	if err != nil || duration <= 0 {
+		return domain.RateLimit{}, fmt.Errorf("invalid rate limit '%v': the period must be a positive duration", value)
+	}
+
+	return domain.RateLimit{Requests: count, Period: duration}, nil
+}
+
+/* Rounds the duration up to whole seconds, as sent in the headers */
+func headerSeconds(duration time.Duration) int64 {
+	return int64(math.Ceil(duration.Seconds()))
+}
+
+/*
+Returns the middleware that limits the requests of every client to the
+provided rate with a token bucket kept by the rate limit repository. The
+buckets are kept per route group, so the name of the group is part of their
+keys.
+
+The state of the bucket is sent in the `RateLimit-Limit`, `RateLimit-Remaining`
+and `RateLimit-Reset` headers along with the `RateLimit-Policy` of the group.
+Requests sent while the bucket is empty are rejected with a status code of 429
+and a `Retry-After` header.
+*/
+func RateLimitMiddleware(group string, limit domain.RateLimit, key RateLimitKeyFunc, store domain.RateLimitRepositoryInterface) gin.HandlerFunc {
+	policy := fmt.Sprintf("%v;w=%v", limit.Requests, headerSeconds(limit.Period))
+	return func(c *gin.Context) {
+		status, err := store.TakeToken(c, group+":"+key(c), limit, time.Now())
+		if err != nil {
+			WriteProblem(c, 500, err)
+			c.Abort()
+			return
+		}
+
+		c.Header("RateLimit-Policy", policy)
+		c.Header("RateLimit-Limit", strconv.FormatInt(status.Limit, 10))
+		c.Header("RateLimit-Remaining", strconv.FormatInt(status.Remaining, 10))
+		c.Header("RateLimit-Reset", strconv.FormatInt(headerSeconds(status.ResetAfter), 10))
+		if !status.Allowed {
+			retryAfter := max(headerSeconds(status.RetryAfter), 1)
+			c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+			MiddlewareError(c, 429, domain.ERR_TOO_MANY_REQUESTS, fmt.Sprintf("Too many requests: try again in %v seconds", retryAfter))
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,34 @@
+package infrastructure
+
+import (
+	"regexp"
+	domain "task_manager_api/Domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* The header that carries the correlation ID of a request */
+const RequestIDHeader = "X-Request-ID"
+
+/* Correlation IDs sent by the clients are only reused if they match this pattern */
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+/*
+Returns the middleware that assigns a correlation ID to every request. The ID
+sent by the client in the X-Request-ID header is reused if it is well formed,
+otherwise a new one is generated. The ID is stored in the context and echoed
+in the response headers so that the logs of a request can be found from its
+response.
+*/
+func RequestIDMiddleware(generateID func() string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = generateID()
+		}
+
+		c.Set(domain.ContextRequestID, requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
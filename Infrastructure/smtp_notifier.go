@@ -0,0 +1,134 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	domain "task_manager_api/Domain"
+	"time"
+)
+
+/*
+Implements the NotifierInterface defined in `domain` by sending every
+notification as a plain text email through an SMTP server. STARTTLS is used
+whenever the server supports it and the credentials are only sent over
+encrypted connections (or to a server on localhost).
+*/
+type SMTPNotifier struct {
+	Address string
+	From    string
+	Auth    smtp.Auth
+}
+
+/*
+Creates a notifier that sends the emails through the SMTP server with the
+provided address (host:port). The emails are sent without authentication if
+no username is provided.
+*/
+func NewSMTPNotifier(address string, from string, username string, password string) *SMTPNotifier {
+	notifier := &SMTPNotifier{Address: address, From: from}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(address)
+		notifier.Auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return notifier
+}
+
+/* removes the line breaks from a header value to prevent header injection */
+func sanitizeHeader(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
+
+/* builds the email with the headers and the CRLF line endings required by SMTP */
+func (n *SMTPNotifier) message(notification domain.Notification) []byte {
+	headers := []string{
+		"From: " + sanitizeHeader(n.From),
+		"To: " + sanitizeHeader(notification.Recipient),
+		"Subject: " + sanitizeHeader(notification.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+
+	body := strings.ReplaceAll(strings.ReplaceAll(notification.Body, "\r\n", "\n"), "\n", "\r\n")
+	return []byte(strings.Join(headers, "\r\n") + "\r\n\r\n" + body + "\r\n")
+}
+
+/*
+Sends the notification to its recipient. The connection is bound to the
+deadline of the provided context.
+*/
+func (n *SMTPNotifier) Notify(c context.Context, notification domain.Notification) domain.CodedError {
+	if err := n.send(c, notification); err != nil {
+		return domain.UserError{Message: "Internal server error: error while sending the email: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/* delivers the email with the same steps as `smtp.SendMail` */
+func (n *SMTPNotifier) send(c context.Context, notification domain.Notification) error {
+	host, _, err := net.SplitHostPort(n.Address)
+	if err != nil {
+		return err
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(c, "tcp", n.Address)
+	if err != nil {
+		return err
+	}
+
+	if deadline, ok := c.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+
+	if n.Auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("the server does not support authentication")
+		}
+
+		if err := client.Auth(n.Auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(n.From); err != nil {
+		return err
+	}
+
+	if err := client.Rcpt(notification.Recipient); err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := writer.Write(n.message(notification)); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
@@ -0,0 +1,31 @@
+package infrastructure
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	domain "task_manager_api/Domain"
+)
+
+/*
+Generates a random opaque token (e.g. a refresh token) with 256 bits of
+entropy. The token is encoded with the URL-safe base64 alphabet.
+*/
+func GenerateOpaqueToken() (string, domain.CodedError) {
+	buffer := make([]byte, 32)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buffer), nil
+}
+
+/*
+Hashes an opaque token with SHA-256 so that only the hash needs to be stored.
+A fast hash is sufficient since the tokens are random and high in entropy.
+*/
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,142 @@
+package infrastructure
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	domain "task_manager_api/Domain"
+	"time"
+)
+
+/*
+The parameters of the TOTP codes (RFC 6238). They are the defaults of the
+authenticator apps, some of which ignore the parameters of the otpauth URIs.
+*/
+const (
+	TOTPDigits = 6
+	TOTPPeriod = 30 * time.Second
+)
+
+/* The number of time steps before and after the current one whose codes are accepted, to allow for clock drift */
+const totpSkew = 1
+
+/* The characters of the recovery codes, which leave out the ones that are easily confused */
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+/* The encoding of the TOTP secrets, as expected by the authenticator apps */
+var totpSecretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+/* Generates a random TOTP secret of 160 bits, encoded with base32 */
+func GenerateTOTPSecret() (string, domain.CodedError) {
+	buffer := make([]byte, 20)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return totpSecretEncoding.EncodeToString(buffer), nil
+}
+
+/*
+Returns the otpauth URI of the TOTP secret, which authenticator apps import
+(usually from a QR code) to generate the codes of the account.
+*/
+func TOTPURI(issuer string, account string, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(TOTPDigits))
+	query.Set("period", fmt.Sprint(int64(TOTPPeriod.Seconds())))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+/* Returns the time step of the TOTP codes at the provided time */
+func TOTPStep(now time.Time) int64 {
+	return now.Unix() / int64(TOTPPeriod.Seconds())
+}
+
+/* Returns the TOTP code of the secret for the provided time step (the HOTP code of the step, RFC 4226) */
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpSecretEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", err
+	}
+
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	// dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulo := uint32(1)
+	for i := 0; i < TOTPDigits; i++ {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", TOTPDigits, value%modulo), nil
+}
+
+/*
+Checks the code against the codes of the secret around the provided time.
+Returns the time step of the matching code, which is used to reject the
+code if it is presented again.
+*/
+func ValidateTOTP(secret string, code string, now time.Time) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+
+	current := TOTPStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		expected, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+/*
+Generates a random recovery code of 10 characters (about 50 bits of entropy)
+written as two groups of five, e.g. `k7qzm-4xv2c`.
+*/
+func GenerateRecoveryCode() (string, domain.CodedError) {
+	// the bytes past the largest multiple of the alphabet size are skipped so that every character is as likely
+	limit := byte(256 / len(recoveryCodeAlphabet) * len(recoveryCodeAlphabet))
+	code := make([]byte, 0, 11)
+	buffer := make([]byte, 16)
+	for len(code) < 11 {
+		if _, err := rand.Read(buffer); err != nil {
+			return "", domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+		}
+
+		for _, b := range buffer {
+			if len(code) == 5 {
+				code = append(code, '-')
+			}
+
+			if b < limit && len(code) < 11 {
+				code = append(code, recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+			}
+		}
+	}
+
+	return string(code), nil
+}
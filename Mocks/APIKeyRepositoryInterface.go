@@ -0,0 +1,173 @@
+// Code generated by mockery v2.44.1 DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	domain "task_manager_api/Domain"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// APIKeyRepositoryInterface is an autogenerated mock type for the APIKeyRepositoryInterface type
+type APIKeyRepositoryInterface struct {
+	mock.Mock
+}
+
+// CreateAPIKey provides a mock function with given fields: c, key
+func (_m *APIKeyRepositoryInterface) CreateAPIKey(c context.Context, key domain.APIKey) domain.CodedError {
+	ret := _m.Called(c, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateAPIKey")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.APIKey) domain.CodedError); ok {
+		r0 = rf(c, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// DeleteAPIKey provides a mock function with given fields: c, username, keyID
+func (_m *APIKeyRepositoryInterface) DeleteAPIKey(c context.Context, username string, keyID string) domain.CodedError {
+	ret := _m.Called(c, username, keyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteAPIKey")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) domain.CodedError); ok {
+		r0 = rf(c, username, keyID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// DeleteUserAPIKeys provides a mock function with given fields: c, username
+func (_m *APIKeyRepositoryInterface) DeleteUserAPIKeys(c context.Context, username string) domain.CodedError {
+	ret := _m.Called(c, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteUserAPIKeys")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// GetAPIKeyByHash provides a mock function with given fields: c, keyHash
+func (_m *APIKeyRepositoryInterface) GetAPIKeyByHash(c context.Context, keyHash string) (domain.APIKey, domain.CodedError) {
+	ret := _m.Called(c, keyHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAPIKeyByHash")
+	}
+
+	var r0 domain.APIKey
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.APIKey, domain.CodedError)); ok {
+		return rf(c, keyHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.APIKey); ok {
+		r0 = rf(c, keyHash)
+	} else {
+		r0 = ret.Get(0).(domain.APIKey)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, keyHash)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// GetAPIKeys provides a mock function with given fields: c, username
+func (_m *APIKeyRepositoryInterface) GetAPIKeys(c context.Context, username string) ([]domain.APIKey, domain.CodedError) {
+	ret := _m.Called(c, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAPIKeys")
+	}
+
+	var r0 []domain.APIKey
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]domain.APIKey, domain.CodedError)); ok {
+		return rf(c, username)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []domain.APIKey); ok {
+		r0 = rf(c, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.APIKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, username)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// TouchAPIKey provides a mock function with given fields: c, keyID, usedAt
+func (_m *APIKeyRepositoryInterface) TouchAPIKey(c context.Context, keyID string, usedAt time.Time) domain.CodedError {
+	ret := _m.Called(c, keyID, usedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TouchAPIKey")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) domain.CodedError); ok {
+		r0 = rf(c, keyID, usedAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// NewAPIKeyRepositoryInterface creates a new instance of APIKeyRepositoryInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAPIKeyRepositoryInterface(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *APIKeyRepositoryInterface {
+	mock := &APIKeyRepositoryInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
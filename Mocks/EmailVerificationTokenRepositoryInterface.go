@@ -0,0 +1,99 @@
+// Code generated by mockery v2.44.1 DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	domain "task_manager_api/Domain"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// EmailVerificationTokenRepositoryInterface is an autogenerated mock type for the EmailVerificationTokenRepositoryInterface type
+type EmailVerificationTokenRepositoryInterface struct {
+	mock.Mock
+}
+
+// CreateVerificationToken provides a mock function with given fields: c, token
+func (_m *EmailVerificationTokenRepositoryInterface) CreateVerificationToken(c context.Context, token domain.EmailVerificationToken) domain.CodedError {
+	ret := _m.Called(c, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateVerificationToken")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.EmailVerificationToken) domain.CodedError); ok {
+		r0 = rf(c, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// InvalidateUserVerificationTokens provides a mock function with given fields: c, username
+func (_m *EmailVerificationTokenRepositoryInterface) InvalidateUserVerificationTokens(c context.Context, username string) domain.CodedError {
+	ret := _m.Called(c, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for InvalidateUserVerificationTokens")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// UseVerificationToken provides a mock function with given fields: c, tokenHash
+func (_m *EmailVerificationTokenRepositoryInterface) UseVerificationToken(c context.Context, tokenHash string) (domain.EmailVerificationToken, domain.CodedError) {
+	ret := _m.Called(c, tokenHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UseVerificationToken")
+	}
+
+	var r0 domain.EmailVerificationToken
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.EmailVerificationToken, domain.CodedError)); ok {
+		return rf(c, tokenHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.EmailVerificationToken); ok {
+		r0 = rf(c, tokenHash)
+	} else {
+		r0 = ret.Get(0).(domain.EmailVerificationToken)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, tokenHash)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// NewEmailVerificationTokenRepositoryInterface creates a new instance of EmailVerificationTokenRepositoryInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewEmailVerificationTokenRepositoryInterface(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *EmailVerificationTokenRepositoryInterface {
+	mock := &EmailVerificationTokenRepositoryInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,111 @@
+// Code generated by mockery v2.44.1 DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	domain "task_manager_api/Domain"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// LoginAttemptRepositoryInterface is an autogenerated mock type for the LoginAttemptRepositoryInterface type
+type LoginAttemptRepositoryInterface struct {
+	mock.Mock
+}
+
+// ClearLoginAttempts provides a mock function with given fields: c, key
+func (_m *LoginAttemptRepositoryInterface) ClearLoginAttempts(c context.Context, key string) domain.CodedError {
+	ret := _m.Called(c, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClearLoginAttempts")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// GetLoginAttempts provides a mock function with given fields: c, key
+func (_m *LoginAttemptRepositoryInterface) GetLoginAttempts(c context.Context, key string) (domain.LoginAttempts, domain.CodedError) {
+	ret := _m.Called(c, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLoginAttempts")
+	}
+
+	var r0 domain.LoginAttempts
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.LoginAttempts, domain.CodedError)); ok {
+		return rf(c, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.LoginAttempts); ok {
+		r0 = rf(c, key)
+	} else {
+		r0 = ret.Get(0).(domain.LoginAttempts)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, key)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// RecordLoginFailure provides a mock function with given fields: c, key, failedAt, expiresAt
+func (_m *LoginAttemptRepositoryInterface) RecordLoginFailure(c context.Context, key string, failedAt time.Time, expiresAt time.Time) (domain.LoginAttempts, domain.CodedError) {
+	ret := _m.Called(c, key, failedAt, expiresAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordLoginFailure")
+	}
+
+	var r0 domain.LoginAttempts
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Time) (domain.LoginAttempts, domain.CodedError)); ok {
+		return rf(c, key, failedAt, expiresAt)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Time) domain.LoginAttempts); ok {
+		r0 = rf(c, key, failedAt, expiresAt)
+	} else {
+		r0 = ret.Get(0).(domain.LoginAttempts)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time, time.Time) domain.CodedError); ok {
+		r1 = rf(c, key, failedAt, expiresAt)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// NewLoginAttemptRepositoryInterface creates a new instance of LoginAttemptRepositoryInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewLoginAttemptRepositoryInterface(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *LoginAttemptRepositoryInterface {
+	mock := &LoginAttemptRepositoryInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
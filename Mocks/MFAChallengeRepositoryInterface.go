@@ -0,0 +1,109 @@
+// Code generated by mockery v2.44.1 DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	domain "task_manager_api/Domain"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MFAChallengeRepositoryInterface is an autogenerated mock type for the MFAChallengeRepositoryInterface type
+type MFAChallengeRepositoryInterface struct {
+	mock.Mock
+}
+
+// CreateChallenge provides a mock function with given fields: c, challenge
+func (_m *MFAChallengeRepositoryInterface) CreateChallenge(c context.Context, challenge domain.MFAChallenge) domain.CodedError {
+	ret := _m.Called(c, challenge)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateChallenge")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.MFAChallenge) domain.CodedError); ok {
+		r0 = rf(c, challenge)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// GetChallenge provides a mock function with given fields: c, tokenHash
+func (_m *MFAChallengeRepositoryInterface) GetChallenge(c context.Context, tokenHash string) (domain.MFAChallenge, domain.CodedError) {
+	ret := _m.Called(c, tokenHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetChallenge")
+	}
+
+	var r0 domain.MFAChallenge
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.MFAChallenge, domain.CodedError)); ok {
+		return rf(c, tokenHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.MFAChallenge); ok {
+		r0 = rf(c, tokenHash)
+	} else {
+		r0 = ret.Get(0).(domain.MFAChallenge)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, tokenHash)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// UseChallenge provides a mock function with given fields: c, tokenHash
+func (_m *MFAChallengeRepositoryInterface) UseChallenge(c context.Context, tokenHash string) (domain.MFAChallenge, domain.CodedError) {
+	ret := _m.Called(c, tokenHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UseChallenge")
+	}
+
+	var r0 domain.MFAChallenge
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.MFAChallenge, domain.CodedError)); ok {
+		return rf(c, tokenHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.MFAChallenge); ok {
+		r0 = rf(c, tokenHash)
+	} else {
+		r0 = ret.Get(0).(domain.MFAChallenge)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, tokenHash)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// NewMFAChallengeRepositoryInterface creates a new instance of MFAChallengeRepositoryInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMFAChallengeRepositoryInterface(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MFAChallengeRepositoryInterface {
+	mock := &MFAChallengeRepositoryInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
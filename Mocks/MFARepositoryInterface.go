@@ -0,0 +1,139 @@
+// Code generated by mockery v2.44.1 DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	domain "task_manager_api/Domain"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MFARepositoryInterface is an autogenerated mock type for the MFARepositoryInterface type
+type MFARepositoryInterface struct {
+	mock.Mock
+}
+
+// DeleteMFA provides a mock function with given fields: c, username
+func (_m *MFARepositoryInterface) DeleteMFA(c context.Context, username string) domain.CodedError {
+	ret := _m.Called(c, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteMFA")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// GetMFA provides a mock function with given fields: c, username
+func (_m *MFARepositoryInterface) GetMFA(c context.Context, username string) (domain.MFAEnrollment, domain.CodedError) {
+	ret := _m.Called(c, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMFA")
+	}
+
+	var r0 domain.MFAEnrollment
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.MFAEnrollment, domain.CodedError)); ok {
+		return rf(c, username)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.MFAEnrollment); ok {
+		r0 = rf(c, username)
+	} else {
+		r0 = ret.Get(0).(domain.MFAEnrollment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, username)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// SaveMFA provides a mock function with given fields: c, enrollment
+func (_m *MFARepositoryInterface) SaveMFA(c context.Context, enrollment domain.MFAEnrollment) domain.CodedError {
+	ret := _m.Called(c, enrollment)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveMFA")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.MFAEnrollment) domain.CodedError); ok {
+		r0 = rf(c, enrollment)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// UseRecoveryCode provides a mock function with given fields: c, username, codeHash
+func (_m *MFARepositoryInterface) UseRecoveryCode(c context.Context, username string, codeHash string) domain.CodedError {
+	ret := _m.Called(c, username, codeHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UseRecoveryCode")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) domain.CodedError); ok {
+		r0 = rf(c, username, codeHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// UseTOTPStep provides a mock function with given fields: c, username, step
+func (_m *MFARepositoryInterface) UseTOTPStep(c context.Context, username string, step int64) domain.CodedError {
+	ret := _m.Called(c, username, step)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UseTOTPStep")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) domain.CodedError); ok {
+		r0 = rf(c, username, step)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// NewMFARepositoryInterface creates a new instance of MFARepositoryInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMFARepositoryInterface(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MFARepositoryInterface {
+	mock := &MFARepositoryInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,49 @@
+// Code generated by mockery v2.44.1 DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	domain "task_manager_api/Domain"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// NotifierInterface is an autogenerated mock type for the NotifierInterface type
+type NotifierInterface struct {
+	mock.Mock
+}
+
+// Notify provides a mock function with given fields: c, notification
+func (_m *NotifierInterface) Notify(c context.Context, notification domain.Notification) domain.CodedError {
+	ret := _m.Called(c, notification)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Notify")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Notification) domain.CodedError); ok {
+		r0 = rf(c, notification)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// NewNotifierInterface creates a new instance of NotifierInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewNotifierInterface(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *NotifierInterface {
+	mock := &NotifierInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
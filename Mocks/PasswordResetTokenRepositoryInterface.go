@@ -0,0 +1,99 @@
+// Code generated by mockery v2.44.1 DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	domain "task_manager_api/Domain"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// PasswordResetTokenRepositoryInterface is an autogenerated mock type for the PasswordResetTokenRepositoryInterface type
+type PasswordResetTokenRepositoryInterface struct {
+	mock.Mock
+}
+
+// CreateResetToken provides a mock function with given fields: c, token
+func (_m *PasswordResetTokenRepositoryInterface) CreateResetToken(c context.Context, token domain.PasswordResetToken) domain.CodedError {
+	ret := _m.Called(c, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateResetToken")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.PasswordResetToken) domain.CodedError); ok {
+		r0 = rf(c, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// InvalidateUserResetTokens provides a mock function with given fields: c, username
+func (_m *PasswordResetTokenRepositoryInterface) InvalidateUserResetTokens(c context.Context, username string) domain.CodedError {
+	ret := _m.Called(c, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for InvalidateUserResetTokens")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// UseResetToken provides a mock function with given fields: c, tokenHash
+func (_m *PasswordResetTokenRepositoryInterface) UseResetToken(c context.Context, tokenHash string) (domain.PasswordResetToken, domain.CodedError) {
+	ret := _m.Called(c, tokenHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UseResetToken")
+	}
+
+	var r0 domain.PasswordResetToken
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.PasswordResetToken, domain.CodedError)); ok {
+		return rf(c, tokenHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.PasswordResetToken); ok {
+		r0 = rf(c, tokenHash)
+	} else {
+		r0 = ret.Get(0).(domain.PasswordResetToken)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, tokenHash)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// NewPasswordResetTokenRepositoryInterface creates a new instance of PasswordResetTokenRepositoryInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewPasswordResetTokenRepositoryInterface(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *PasswordResetTokenRepositoryInterface {
+	mock := &PasswordResetTokenRepositoryInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,61 @@
+// Code generated by mockery v2.44.1 DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	domain "task_manager_api/Domain"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// RateLimitRepositoryInterface is an autogenerated mock type for the RateLimitRepositoryInterface type
+type RateLimitRepositoryInterface struct {
+	mock.Mock
+}
+
+// TakeToken provides a mock function with given fields: c, key, limit, now
+func (_m *RateLimitRepositoryInterface) TakeToken(c context.Context, key string, limit domain.RateLimit, now time.Time) (domain.RateLimitStatus, domain.CodedError) {
+	ret := _m.Called(c, key, limit, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TakeToken")
+	}
+
+	var r0 domain.RateLimitStatus
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.RateLimit, time.Time) (domain.RateLimitStatus, domain.CodedError)); ok {
+		return rf(c, key, limit, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.RateLimit, time.Time) domain.RateLimitStatus); ok {
+		r0 = rf(c, key, limit, now)
+	} else {
+		r0 = ret.Get(0).(domain.RateLimitStatus)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, domain.RateLimit, time.Time) domain.CodedError); ok {
+		r1 = rf(c, key, limit, now)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// NewRateLimitRepositoryInterface creates a new instance of RateLimitRepositoryInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewRateLimitRepositoryInterface(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *RateLimitRepositoryInterface {
+	mock := &RateLimitRepositoryInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
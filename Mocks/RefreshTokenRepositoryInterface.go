@@ -0,0 +1,149 @@
+// Code generated by mockery v2.44.1 DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	domain "task_manager_api/Domain"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// RefreshTokenRepositoryInterface is an autogenerated mock type for the RefreshTokenRepositoryInterface type
+type RefreshTokenRepositoryInterface struct {
+	mock.Mock
+}
+
+// CreateRefreshToken provides a mock function with given fields: c, token
+func (_m *RefreshTokenRepositoryInterface) CreateRefreshToken(c context.Context, token domain.RefreshToken) domain.CodedError {
+	ret := _m.Called(c, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateRefreshToken")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.RefreshToken) domain.CodedError); ok {
+		r0 = rf(c, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// GetRefreshToken provides a mock function with given fields: c, tokenHash
+func (_m *RefreshTokenRepositoryInterface) GetRefreshToken(c context.Context, tokenHash string) (domain.RefreshToken, domain.CodedError) {
+	ret := _m.Called(c, tokenHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRefreshToken")
+	}
+
+	var r0 domain.RefreshToken
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.RefreshToken, domain.CodedError)); ok {
+		return rf(c, tokenHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.RefreshToken); ok {
+		r0 = rf(c, tokenHash)
+	} else {
+		r0 = ret.Get(0).(domain.RefreshToken)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, tokenHash)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// RevokeTokenFamily provides a mock function with given fields: c, familyID
+func (_m *RefreshTokenRepositoryInterface) RevokeTokenFamily(c context.Context, familyID string) domain.CodedError {
+	ret := _m.Called(c, familyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeTokenFamily")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, familyID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// RevokeUserTokens provides a mock function with given fields: c, username
+func (_m *RefreshTokenRepositoryInterface) RevokeUserTokens(c context.Context, username string) domain.CodedError {
+	ret := _m.Called(c, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeUserTokens")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// UseRefreshToken provides a mock function with given fields: c, tokenHash
+func (_m *RefreshTokenRepositoryInterface) UseRefreshToken(c context.Context, tokenHash string) (domain.RefreshToken, domain.CodedError) {
+	ret := _m.Called(c, tokenHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UseRefreshToken")
+	}
+
+	var r0 domain.RefreshToken
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.RefreshToken, domain.CodedError)); ok {
+		return rf(c, tokenHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.RefreshToken); ok {
+		r0 = rf(c, tokenHash)
+	} else {
+		r0 = ret.Get(0).(domain.RefreshToken)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, tokenHash)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// NewRefreshTokenRepositoryInterface creates a new instance of RefreshTokenRepositoryInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewRefreshTokenRepositoryInterface(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *RefreshTokenRepositoryInterface {
+	mock := &RefreshTokenRepositoryInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
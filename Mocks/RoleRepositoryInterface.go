@@ -0,0 +1,131 @@
+// Code generated by mockery v2.44.1 DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	domain "task_manager_api/Domain"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// RoleRepositoryInterface is an autogenerated mock type for the RoleRepositoryInterface type
+type RoleRepositoryInterface struct {
+	mock.Mock
+}
+
+// DeleteRole provides a mock function with given fields: c, name
+func (_m *RoleRepositoryInterface) DeleteRole(c context.Context, name string) domain.CodedError {
+	ret := _m.Called(c, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteRole")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// GetRole provides a mock function with given fields: c, name
+func (_m *RoleRepositoryInterface) GetRole(c context.Context, name string) (domain.Role, domain.CodedError) {
+	ret := _m.Called(c, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRole")
+	}
+
+	var r0 domain.Role
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.Role, domain.CodedError)); ok {
+		return rf(c, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.Role); ok {
+		r0 = rf(c, name)
+	} else {
+		r0 = ret.Get(0).(domain.Role)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, name)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// GetRoles provides a mock function with given fields: c
+func (_m *RoleRepositoryInterface) GetRoles(c context.Context) ([]domain.Role, domain.CodedError) {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRoles")
+	}
+
+	var r0 []domain.Role
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context) ([]domain.Role, domain.CodedError)); ok {
+		return rf(c)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []domain.Role); ok {
+		r0 = rf(c)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Role)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) domain.CodedError); ok {
+		r1 = rf(c)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// SaveRole provides a mock function with given fields: c, role
+func (_m *RoleRepositoryInterface) SaveRole(c context.Context, role domain.Role) domain.CodedError {
+	ret := _m.Called(c, role)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveRole")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Role) domain.CodedError); ok {
+		r0 = rf(c, role)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// NewRoleRepositoryInterface creates a new instance of RoleRepositoryInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewRoleRepositoryInterface(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *RoleRepositoryInterface {
+	mock := &RoleRepositoryInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
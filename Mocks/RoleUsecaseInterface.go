@@ -0,0 +1,203 @@
+// Code generated by mockery v2.44.1 DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	domain "task_manager_api/Domain"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// RoleUsecaseInterface is an autogenerated mock type for the RoleUsecaseInterface type
+type RoleUsecaseInterface struct {
+	mock.Mock
+}
+
+// DeleteRole provides a mock function with given fields: c, subject, name
+func (_m *RoleUsecaseInterface) DeleteRole(c context.Context, subject domain.Subject, name string) domain.CodedError {
+	ret := _m.Called(c, subject, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteRole")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string) domain.CodedError); ok {
+		r0 = rf(c, subject, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// GetRole provides a mock function with given fields: c, name
+func (_m *RoleUsecaseInterface) GetRole(c context.Context, name string) (domain.Role, domain.CodedError) {
+	ret := _m.Called(c, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRole")
+	}
+
+	var r0 domain.Role
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.Role, domain.CodedError)); ok {
+		return rf(c, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.Role); ok {
+		r0 = rf(c, name)
+	} else {
+		r0 = ret.Get(0).(domain.Role)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, name)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// GetRoles provides a mock function with given fields: c
+func (_m *RoleUsecaseInterface) GetRoles(c context.Context) ([]domain.Role, domain.CodedError) {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRoles")
+	}
+
+	var r0 []domain.Role
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context) ([]domain.Role, domain.CodedError)); ok {
+		return rf(c)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []domain.Role); ok {
+		r0 = rf(c)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Role)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) domain.CodedError); ok {
+		r1 = rf(c)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// RolePermissions provides a mock function with given fields: c, name
+func (_m *RoleUsecaseInterface) RolePermissions(c context.Context, name string) ([]string, domain.CodedError) {
+	ret := _m.Called(c, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RolePermissions")
+	}
+
+	var r0 []string
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]string, domain.CodedError)); ok {
+		return rf(c, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []string); ok {
+		r0 = rf(c, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, name)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// SaveRole provides a mock function with given fields: c, subject, role
+func (_m *RoleUsecaseInterface) SaveRole(c context.Context, subject domain.Subject, role domain.Role) (domain.Role, domain.CodedError) {
+	ret := _m.Called(c, subject, role)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveRole")
+	}
+
+	var r0 domain.Role
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, domain.Role) (domain.Role, domain.CodedError)); ok {
+		return rf(c, subject, role)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, domain.Role) domain.Role); ok {
+		r0 = rf(c, subject, role)
+	} else {
+		r0 = ret.Get(0).(domain.Role)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Subject, domain.Role) domain.CodedError); ok {
+		r1 = rf(c, subject, role)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// SetRoleMFA provides a mock function with given fields: c, subject, name, required
+func (_m *RoleUsecaseInterface) SetRoleMFA(c context.Context, subject domain.Subject, name string, required bool) (domain.Role, domain.CodedError) {
+	ret := _m.Called(c, subject, name, required)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetRoleMFA")
+	}
+
+	var r0 domain.Role
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string, bool) (domain.Role, domain.CodedError)); ok {
+		return rf(c, subject, name, required)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string, bool) domain.Role); ok {
+		r0 = rf(c, subject, name, required)
+	} else {
+		r0 = ret.Get(0).(domain.Role)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Subject, string, bool) domain.CodedError); ok {
+		r1 = rf(c, subject, name, required)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// NewRoleUsecaseInterface creates a new instance of RoleUsecaseInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewRoleUsecaseInterface(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *RoleUsecaseInterface {
+	mock := &RoleUsecaseInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
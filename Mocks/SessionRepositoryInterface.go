@@ -0,0 +1,173 @@
+// Code generated by mockery v2.44.1 DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	domain "task_manager_api/Domain"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// SessionRepositoryInterface is an autogenerated mock type for the SessionRepositoryInterface type
+type SessionRepositoryInterface struct {
+	mock.Mock
+}
+
+// CreateSession provides a mock function with given fields: c, session
+func (_m *SessionRepositoryInterface) CreateSession(c context.Context, session domain.Session) domain.CodedError {
+	ret := _m.Called(c, session)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateSession")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Session) domain.CodedError); ok {
+		r0 = rf(c, session)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// ExtendSession provides a mock function with given fields: c, sessionID, lastUsedAt, expiresAt
+func (_m *SessionRepositoryInterface) ExtendSession(c context.Context, sessionID string, lastUsedAt time.Time, expiresAt time.Time) domain.CodedError {
+	ret := _m.Called(c, sessionID, lastUsedAt, expiresAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExtendSession")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Time) domain.CodedError); ok {
+		r0 = rf(c, sessionID, lastUsedAt, expiresAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// GetActiveSessions provides a mock function with given fields: c, username
+func (_m *SessionRepositoryInterface) GetActiveSessions(c context.Context, username string) ([]domain.Session, domain.CodedError) {
+	ret := _m.Called(c, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetActiveSessions")
+	}
+
+	var r0 []domain.Session
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]domain.Session, domain.CodedError)); ok {
+		return rf(c, username)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []domain.Session); ok {
+		r0 = rf(c, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Session)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, username)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// GetSession provides a mock function with given fields: c, sessionID
+func (_m *SessionRepositoryInterface) GetSession(c context.Context, sessionID string) (domain.Session, domain.CodedError) {
+	ret := _m.Called(c, sessionID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSession")
+	}
+
+	var r0 domain.Session
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.Session, domain.CodedError)); ok {
+		return rf(c, sessionID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.Session); ok {
+		r0 = rf(c, sessionID)
+	} else {
+		r0 = ret.Get(0).(domain.Session)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, sessionID)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// RevokeSession provides a mock function with given fields: c, sessionID
+func (_m *SessionRepositoryInterface) RevokeSession(c context.Context, sessionID string) domain.CodedError {
+	ret := _m.Called(c, sessionID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeSession")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, sessionID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// RevokeUserSessions provides a mock function with given fields: c, username
+func (_m *SessionRepositoryInterface) RevokeUserSessions(c context.Context, username string) domain.CodedError {
+	ret := _m.Called(c, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeUserSessions")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// NewSessionRepositoryInterface creates a new instance of SessionRepositoryInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewSessionRepositoryInterface(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *SessionRepositoryInterface {
+	mock := &SessionRepositoryInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
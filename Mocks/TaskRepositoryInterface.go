@@ -1,4 +1,4 @@
-// Code generated by mockery v2.44.1. DO NOT EDIT.
+// Code generated by mockery v2.44.1 DO NOT EDIT.
 
 package mocks
 
@@ -7,6 +7,8 @@ import (
 	domain "task_manager_api/Domain"
 
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // TaskRepositoryInterface is an autogenerated mock type for the TaskRepositoryInterface type
@@ -34,17 +36,47 @@ func (_m *TaskRepositoryInterface) AddTask(c context.Context, newTask domain.Tas
 	return r0
 }
 
-// DeleteTask provides a mock function with given fields: c, taskID
-func (_m *TaskRepositoryInterface) DeleteTask(c context.Context, taskID string) domain.CodedError {
-	ret := _m.Called(c, taskID)
+// DeleteOwnerTasks provides a mock function with given fields: c, owner
+func (_m *TaskRepositoryInterface) DeleteOwnerTasks(c context.Context, owner string) (int64, domain.CodedError) {
+	ret := _m.Called(c, owner)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteOwnerTasks")
+	}
+
+	var r0 int64
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) (int64, domain.CodedError)); ok {
+		return rf(c, owner)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) int64); ok {
+		r0 = rf(c, owner)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, owner)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// DeleteTask provides a mock function with given fields: c, taskID, version
+func (_m *TaskRepositoryInterface) DeleteTask(c context.Context, taskID string, version int64) domain.CodedError {
+	ret := _m.Called(c, taskID, version)
 
 	if len(ret) == 0 {
 		panic("no return value specified for DeleteTask")
 	}
 
 	var r0 domain.CodedError
-	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
-		r0 = rf(c, taskID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) domain.CodedError); ok {
+		r0 = rf(c, taskID, version)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(domain.CodedError)
@@ -54,36 +86,43 @@ func (_m *TaskRepositoryInterface) DeleteTask(c context.Context, taskID string)
 	return r0
 }
 
-// GetAllTasks provides a mock function with given fields: c
-func (_m *TaskRepositoryInterface) GetAllTasks(c context.Context) ([]domain.Task, domain.CodedError) {
-	ret := _m.Called(c)
+// GetAllTasks provides a mock function with given fields: c, query
+func (_m *TaskRepositoryInterface) GetAllTasks(c context.Context, query domain.TaskQuery) ([]domain.Task, int64, domain.CodedError) {
+	ret := _m.Called(c, query)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetAllTasks")
 	}
 
 	var r0 []domain.Task
-	var r1 domain.CodedError
-	if rf, ok := ret.Get(0).(func(context.Context) ([]domain.Task, domain.CodedError)); ok {
-		return rf(c)
+	var r1 int64
+	var r2 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.TaskQuery) ([]domain.Task, int64, domain.CodedError)); ok {
+		return rf(c, query)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context) []domain.Task); ok {
-		r0 = rf(c)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.TaskQuery) []domain.Task); ok {
+		r0 = rf(c, query)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]domain.Task)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context) domain.CodedError); ok {
-		r1 = rf(c)
+	if rf, ok := ret.Get(1).(func(context.Context, domain.TaskQuery) int64); ok {
+		r1 = rf(c, query)
 	} else {
-		if ret.Get(1) != nil {
-			r1 = ret.Get(1).(domain.CodedError)
+		r1 = ret.Get(1).(int64)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, domain.TaskQuery) domain.CodedError); ok {
+		r2 = rf(c, query)
+	} else {
+		if ret.Get(2) != nil {
+			r2 = ret.Get(2).(domain.CodedError)
 		}
 	}
 
-	return r0, r1
+	return r0, r1, r2
 }
 
 // GetTaskByID provides a mock function with given fields: c, taskID
@@ -116,9 +155,69 @@ func (_m *TaskRepositoryInterface) GetTaskByID(c context.Context, taskID string)
 	return r0, r1
 }
 
-// UpdateTask provides a mock function with given fields: c, taskID, updatedTask
-func (_m *TaskRepositoryInterface) UpdateTask(c context.Context, taskID string, updatedTask domain.Task) (domain.Task, domain.CodedError) {
-	ret := _m.Called(c, taskID, updatedTask)
+// PatchTask provides a mock function with given fields: c, taskID, patch, version
+func (_m *TaskRepositoryInterface) PatchTask(c context.Context, taskID string, patch domain.TaskPatch, version int64) (domain.Task, domain.CodedError) {
+	ret := _m.Called(c, taskID, patch, version)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PatchTask")
+	}
+
+	var r0 domain.Task
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.TaskPatch, int64) (domain.Task, domain.CodedError)); ok {
+		return rf(c, taskID, patch, version)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.TaskPatch, int64) domain.Task); ok {
+		r0 = rf(c, taskID, patch, version)
+	} else {
+		r0 = ret.Get(0).(domain.Task)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, domain.TaskPatch, int64) domain.CodedError); ok {
+		r1 = rf(c, taskID, patch, version)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// UnassignTasks provides a mock function with given fields: c, assignee, updatedAt
+func (_m *TaskRepositoryInterface) UnassignTasks(c context.Context, assignee string, updatedAt time.Time) (int64, domain.CodedError) {
+	ret := _m.Called(c, assignee, updatedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UnassignTasks")
+	}
+
+	var r0 int64
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) (int64, domain.CodedError)); ok {
+		return rf(c, assignee, updatedAt)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) int64); ok {
+		r0 = rf(c, assignee, updatedAt)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time) domain.CodedError); ok {
+		r1 = rf(c, assignee, updatedAt)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// UpdateTask provides a mock function with given fields: c, taskID, updatedTask, version
+func (_m *TaskRepositoryInterface) UpdateTask(c context.Context, taskID string, updatedTask domain.Task, version int64) (domain.Task, domain.CodedError) {
+	ret := _m.Called(c, taskID, updatedTask, version)
 
 	if len(ret) == 0 {
 		panic("no return value specified for UpdateTask")
@@ -126,17 +225,17 @@ func (_m *TaskRepositoryInterface) UpdateTask(c context.Context, taskID string,
 
 	var r0 domain.Task
 	var r1 domain.CodedError
-	if rf, ok := ret.Get(0).(func(context.Context, string, domain.Task) (domain.Task, domain.CodedError)); ok {
-		return rf(c, taskID, updatedTask)
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.Task, int64) (domain.Task, domain.CodedError)); ok {
+		return rf(c, taskID, updatedTask, version)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, domain.Task) domain.Task); ok {
-		r0 = rf(c, taskID, updatedTask)
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.Task, int64) domain.Task); ok {
+		r0 = rf(c, taskID, updatedTask, version)
 	} else {
 		r0 = ret.Get(0).(domain.Task)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, domain.Task) domain.CodedError); ok {
-		r1 = rf(c, taskID, updatedTask)
+	if rf, ok := ret.Get(1).(func(context.Context, string, domain.Task, int64) domain.CodedError); ok {
+		r1 = rf(c, taskID, updatedTask, version)
 	} else {
 		if ret.Get(1) != nil {
 			r1 = ret.Get(1).(domain.CodedError)
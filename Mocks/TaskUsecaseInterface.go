@@ -1,4 +1,4 @@
-// Code generated by mockery v2.44.1. DO NOT EDIT.
+// Code generated by mockery v2.44.1 DO NOT EDIT.
 
 package mocks
 
@@ -14,37 +14,47 @@ type TaskUsecaseInterface struct {
 	mock.Mock
 }
 
-// AddTask provides a mock function with given fields: c, newTask
-func (_m *TaskUsecaseInterface) AddTask(c context.Context, newTask domain.Task) domain.CodedError {
-	ret := _m.Called(c, newTask)
+// AddTask provides a mock function with given fields: c, subject, newTask
+func (_m *TaskUsecaseInterface) AddTask(c context.Context, subject domain.Subject, newTask domain.Task) (domain.Task, domain.CodedError) {
+	ret := _m.Called(c, subject, newTask)
 
 	if len(ret) == 0 {
 		panic("no return value specified for AddTask")
 	}
 
-	var r0 domain.CodedError
-	if rf, ok := ret.Get(0).(func(context.Context, domain.Task) domain.CodedError); ok {
-		r0 = rf(c, newTask)
+	var r0 domain.Task
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, domain.Task) (domain.Task, domain.CodedError)); ok {
+		return rf(c, subject, newTask)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, domain.Task) domain.Task); ok {
+		r0 = rf(c, subject, newTask)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(domain.CodedError)
+		r0 = ret.Get(0).(domain.Task)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Subject, domain.Task) domain.CodedError); ok {
+		r1 = rf(c, subject, newTask)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
 		}
 	}
 
-	return r0
+	return r0, r1
 }
 
-// DeleteTask provides a mock function with given fields: c, taskID
-func (_m *TaskUsecaseInterface) DeleteTask(c context.Context, taskID string) domain.CodedError {
-	ret := _m.Called(c, taskID)
+// DeleteTask provides a mock function with given fields: c, subject, taskID, expectedVersion
+func (_m *TaskUsecaseInterface) DeleteTask(c context.Context, subject domain.Subject, taskID string, expectedVersion int64) domain.CodedError {
+	ret := _m.Called(c, subject, taskID, expectedVersion)
 
 	if len(ret) == 0 {
 		panic("no return value specified for DeleteTask")
 	}
 
 	var r0 domain.CodedError
-	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
-		r0 = rf(c, taskID)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string, int64) domain.CodedError); ok {
+		r0 = rf(c, subject, taskID, expectedVersion)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(domain.CodedError)
@@ -54,29 +64,27 @@ func (_m *TaskUsecaseInterface) DeleteTask(c context.Context, taskID string) dom
 	return r0
 }
 
-// GetAllTasks provides a mock function with given fields: c
-func (_m *TaskUsecaseInterface) GetAllTasks(c context.Context) ([]domain.Task, domain.CodedError) {
-	ret := _m.Called(c)
+// GetAllTasks provides a mock function with given fields: c, subject, query
+func (_m *TaskUsecaseInterface) GetAllTasks(c context.Context, subject domain.Subject, query domain.TaskQuery) (domain.TaskPage, domain.CodedError) {
+	ret := _m.Called(c, subject, query)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetAllTasks")
 	}
 
-	var r0 []domain.Task
+	var r0 domain.TaskPage
 	var r1 domain.CodedError
-	if rf, ok := ret.Get(0).(func(context.Context) ([]domain.Task, domain.CodedError)); ok {
-		return rf(c)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, domain.TaskQuery) (domain.TaskPage, domain.CodedError)); ok {
+		return rf(c, subject, query)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context) []domain.Task); ok {
-		r0 = rf(c)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, domain.TaskQuery) domain.TaskPage); ok {
+		r0 = rf(c, subject, query)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]domain.Task)
-		}
+		r0 = ret.Get(0).(domain.TaskPage)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context) domain.CodedError); ok {
-		r1 = rf(c)
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Subject, domain.TaskQuery) domain.CodedError); ok {
+		r1 = rf(c, subject, query)
 	} else {
 		if ret.Get(1) != nil {
 			r1 = ret.Get(1).(domain.CodedError)
@@ -86,9 +94,9 @@ func (_m *TaskUsecaseInterface) GetAllTasks(c context.Context) ([]domain.Task, d
 	return r0, r1
 }
 
-// GetTaskByID provides a mock function with given fields: c, taskID
-func (_m *TaskUsecaseInterface) GetTaskByID(c context.Context, taskID string) (domain.Task, domain.CodedError) {
-	ret := _m.Called(c, taskID)
+// GetTaskByID provides a mock function with given fields: c, subject, taskID
+func (_m *TaskUsecaseInterface) GetTaskByID(c context.Context, subject domain.Subject, taskID string) (domain.Task, domain.CodedError) {
+	ret := _m.Called(c, subject, taskID)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetTaskByID")
@@ -96,17 +104,47 @@ func (_m *TaskUsecaseInterface) GetTaskByID(c context.Context, taskID string) (d
 
 	var r0 domain.Task
 	var r1 domain.CodedError
-	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.Task, domain.CodedError)); ok {
-		return rf(c, taskID)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string) (domain.Task, domain.CodedError)); ok {
+		return rf(c, subject, taskID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string) domain.Task); ok {
+		r0 = rf(c, subject, taskID)
+	} else {
+		r0 = ret.Get(0).(domain.Task)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Subject, string) domain.CodedError); ok {
+		r1 = rf(c, subject, taskID)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// PatchTask provides a mock function with given fields: c, subject, taskID, patch, expectedVersion
+func (_m *TaskUsecaseInterface) PatchTask(c context.Context, subject domain.Subject, taskID string, patch domain.TaskPatch, expectedVersion int64) (domain.Task, domain.CodedError) {
+	ret := _m.Called(c, subject, taskID, patch, expectedVersion)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PatchTask")
+	}
+
+	var r0 domain.Task
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string, domain.TaskPatch, int64) (domain.Task, domain.CodedError)); ok {
+		return rf(c, subject, taskID, patch, expectedVersion)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) domain.Task); ok {
-		r0 = rf(c, taskID)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string, domain.TaskPatch, int64) domain.Task); ok {
+		r0 = rf(c, subject, taskID, patch, expectedVersion)
 	} else {
 		r0 = ret.Get(0).(domain.Task)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
-		r1 = rf(c, taskID)
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Subject, string, domain.TaskPatch, int64) domain.CodedError); ok {
+		r1 = rf(c, subject, taskID, patch, expectedVersion)
 	} else {
 		if ret.Get(1) != nil {
 			r1 = ret.Get(1).(domain.CodedError)
@@ -116,9 +154,9 @@ func (_m *TaskUsecaseInterface) GetTaskByID(c context.Context, taskID string) (d
 	return r0, r1
 }
 
-// UpdateTask provides a mock function with given fields: c, taskID, updatedTask
-func (_m *TaskUsecaseInterface) UpdateTask(c context.Context, taskID string, updatedTask domain.Task) (domain.Task, domain.CodedError) {
-	ret := _m.Called(c, taskID, updatedTask)
+// UpdateTask provides a mock function with given fields: c, subject, taskID, updatedTask, expectedVersion
+func (_m *TaskUsecaseInterface) UpdateTask(c context.Context, subject domain.Subject, taskID string, updatedTask domain.Task, expectedVersion int64) (domain.Task, domain.CodedError) {
+	ret := _m.Called(c, subject, taskID, updatedTask, expectedVersion)
 
 	if len(ret) == 0 {
 		panic("no return value specified for UpdateTask")
@@ -126,17 +164,17 @@ func (_m *TaskUsecaseInterface) UpdateTask(c context.Context, taskID string, upd
 
 	var r0 domain.Task
 	var r1 domain.CodedError
-	if rf, ok := ret.Get(0).(func(context.Context, string, domain.Task) (domain.Task, domain.CodedError)); ok {
-		return rf(c, taskID, updatedTask)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string, domain.Task, int64) (domain.Task, domain.CodedError)); ok {
+		return rf(c, subject, taskID, updatedTask, expectedVersion)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, domain.Task) domain.Task); ok {
-		r0 = rf(c, taskID, updatedTask)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string, domain.Task, int64) domain.Task); ok {
+		r0 = rf(c, subject, taskID, updatedTask, expectedVersion)
 	} else {
 		r0 = ret.Get(0).(domain.Task)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, domain.Task) domain.CodedError); ok {
-		r1 = rf(c, taskID, updatedTask)
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Subject, string, domain.Task, int64) domain.CodedError); ok {
+		r1 = rf(c, subject, taskID, updatedTask, expectedVersion)
 	} else {
 		if ret.Get(1) != nil {
 			r1 = ret.Get(1).(domain.CodedError)
@@ -1,4 +1,4 @@
-// Code generated by mockery v2.44.1. DO NOT EDIT.
+// Code generated by mockery v2.44.1 DO NOT EDIT.
 
 package mocks
 
@@ -54,6 +54,76 @@ func (_m *UserRepositoryInterface) CreateUser(c context.Context, user domain.Use
 	return r0
 }
 
+// DeleteUser provides a mock function with given fields: c, username
+func (_m *UserRepositoryInterface) DeleteUser(c context.Context, username string) domain.CodedError {
+	ret := _m.Called(c, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteUser")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// DemoteUser provides a mock function with given fields: c, username
+func (_m *UserRepositoryInterface) DemoteUser(c context.Context, username string) domain.CodedError {
+	ret := _m.Called(c, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DemoteUser")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// GetByEmail provides a mock function with given fields: c, email
+func (_m *UserRepositoryInterface) GetByEmail(c context.Context, email string) (domain.User, domain.CodedError) {
+	ret := _m.Called(c, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByEmail")
+	}
+
+	var r0 domain.User
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.User, domain.CodedError)); ok {
+		return rf(c, email)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.User); ok {
+		r0 = rf(c, email)
+	} else {
+		r0 = ret.Get(0).(domain.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, email)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
 // GetByUsername provides a mock function with given fields: c, username
 func (_m *UserRepositoryInterface) GetByUsername(c context.Context, username string) (domain.User, domain.CodedError) {
 	ret := _m.Called(c, username)
@@ -84,6 +154,45 @@ func (_m *UserRepositoryInterface) GetByUsername(c context.Context, username str
 	return r0, r1
 }
 
+// GetUsers provides a mock function with given fields: c, query
+func (_m *UserRepositoryInterface) GetUsers(c context.Context, query domain.UserQuery) ([]domain.User, int64, domain.CodedError) {
+	ret := _m.Called(c, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUsers")
+	}
+
+	var r0 []domain.User
+	var r1 int64
+	var r2 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.UserQuery) ([]domain.User, int64, domain.CodedError)); ok {
+		return rf(c, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.UserQuery) []domain.User); ok {
+		r0 = rf(c, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.UserQuery) int64); ok {
+		r1 = rf(c, query)
+	} else {
+		r1 = ret.Get(1).(int64)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, domain.UserQuery) domain.CodedError); ok {
+		r2 = rf(c, query)
+	} else {
+		if ret.Get(2) != nil {
+			r2 = ret.Get(2).(domain.CodedError)
+		}
+	}
+
+	return r0, r1, r2
+}
+
 // PromoteUser provides a mock function with given fields: c, username
 func (_m *UserRepositoryInterface) PromoteUser(c context.Context, username string) domain.CodedError {
 	ret := _m.Called(c, username)
@@ -104,6 +213,106 @@ func (_m *UserRepositoryInterface) PromoteUser(c context.Context, username strin
 	return r0
 }
 
+// SetDeactivated provides a mock function with given fields: c, username, deactivated
+func (_m *UserRepositoryInterface) SetDeactivated(c context.Context, username string, deactivated bool) domain.CodedError {
+	ret := _m.Called(c, username, deactivated)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetDeactivated")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) domain.CodedError); ok {
+		r0 = rf(c, username, deactivated)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// SetRole provides a mock function with given fields: c, username, role
+func (_m *UserRepositoryInterface) SetRole(c context.Context, username string, role string) domain.CodedError {
+	ret := _m.Called(c, username, role)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetRole")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) domain.CodedError); ok {
+		r0 = rf(c, username, role)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// UpdatePassword provides a mock function with given fields: c, username, password
+func (_m *UserRepositoryInterface) UpdatePassword(c context.Context, username string, password string) domain.CodedError {
+	ret := _m.Called(c, username, password)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdatePassword")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) domain.CodedError); ok {
+		r0 = rf(c, username, password)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// UpdateProfile provides a mock function with given fields: c, user
+func (_m *UserRepositoryInterface) UpdateProfile(c context.Context, user domain.User) domain.CodedError {
+	ret := _m.Called(c, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateProfile")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.User) domain.CodedError); ok {
+		r0 = rf(c, user)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// VerifyEmail provides a mock function with given fields: c, username
+func (_m *UserRepositoryInterface) VerifyEmail(c context.Context, username string) domain.CodedError {
+	ret := _m.Called(c, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyEmail")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
 // NewUserRepositoryInterface creates a new instance of UserRepositoryInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewUserRepositoryInterface(t interface {
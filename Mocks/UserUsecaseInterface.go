@@ -1,4 +1,4 @@
-// Code generated by mockery v2.44.1. DO NOT EDIT.
+// Code generated by mockery v2.44.1 DO NOT EDIT.
 
 package mocks
 
@@ -7,6 +7,8 @@ import (
 	domain "task_manager_api/Domain"
 
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // UserUsecaseInterface is an autogenerated mock type for the UserUsecaseInterface type
@@ -14,17 +16,17 @@ type UserUsecaseInterface struct {
 	mock.Mock
 }
 
-// CreateUser provides a mock function with given fields: c, user
-func (_m *UserUsecaseInterface) CreateUser(c context.Context, user domain.User) domain.CodedError {
-	ret := _m.Called(c, user)
+// Activate provides a mock function with given fields: c, username
+func (_m *UserUsecaseInterface) Activate(c context.Context, username string) domain.CodedError {
+	ret := _m.Called(c, username)
 
 	if len(ret) == 0 {
-		panic("no return value specified for CreateUser")
+		panic("no return value specified for Activate")
 	}
 
 	var r0 domain.CodedError
-	if rf, ok := ret.Get(0).(func(context.Context, domain.User) domain.CodedError); ok {
-		r0 = rf(c, user)
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, username)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(domain.CodedError)
@@ -34,17 +36,17 @@ func (_m *UserUsecaseInterface) CreateUser(c context.Context, user domain.User)
 	return r0
 }
 
-// Promote provides a mock function with given fields: c, username
-func (_m *UserUsecaseInterface) Promote(c context.Context, username string) domain.CodedError {
-	ret := _m.Called(c, username)
+// ChangePassword provides a mock function with given fields: c, subject, currentPassword, newPassword
+func (_m *UserUsecaseInterface) ChangePassword(c context.Context, subject domain.Subject, currentPassword string, newPassword string) domain.CodedError {
+	ret := _m.Called(c, subject, currentPassword, newPassword)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Promote")
+		panic("no return value specified for ChangePassword")
 	}
 
 	var r0 domain.CodedError
-	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
-		r0 = rf(c, username)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string, string) domain.CodedError); ok {
+		r0 = rf(c, subject, currentPassword, newPassword)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(domain.CodedError)
@@ -54,23 +56,122 @@ func (_m *UserUsecaseInterface) Promote(c context.Context, username string) doma
 	return r0
 }
 
-// ValidateAndGetToken provides a mock function with given fields: c, user
-func (_m *UserUsecaseInterface) ValidateAndGetToken(c context.Context, user domain.User) (string, domain.CodedError) {
+// ConfirmTOTP provides a mock function with given fields: c, subject, code
+func (_m *UserUsecaseInterface) ConfirmTOTP(c context.Context, subject domain.Subject, code string) ([]string, domain.CodedError) {
+	ret := _m.Called(c, subject, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConfirmTOTP")
+	}
+
+	var r0 []string
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string) ([]string, domain.CodedError)); ok {
+		return rf(c, subject, code)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string) []string); ok {
+		r0 = rf(c, subject, code)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Subject, string) domain.CodedError); ok {
+		r1 = rf(c, subject, code)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// CreateAPIKey provides a mock function with given fields: c, subject, name, scopes, expiresAt
+func (_m *UserUsecaseInterface) CreateAPIKey(c context.Context, subject domain.Subject, name string, scopes []string, expiresAt time.Time) (domain.APIKey, string, domain.CodedError) {
+	ret := _m.Called(c, subject, name, scopes, expiresAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateAPIKey")
+	}
+
+	var r0 domain.APIKey
+	var r1 string
+	var r2 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string, []string, time.Time) (domain.APIKey, string, domain.CodedError)); ok {
+		return rf(c, subject, name, scopes, expiresAt)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string, []string, time.Time) domain.APIKey); ok {
+		r0 = rf(c, subject, name, scopes, expiresAt)
+	} else {
+		r0 = ret.Get(0).(domain.APIKey)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Subject, string, []string, time.Time) string); ok {
+		r1 = rf(c, subject, name, scopes, expiresAt)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, domain.Subject, string, []string, time.Time) domain.CodedError); ok {
+		r2 = rf(c, subject, name, scopes, expiresAt)
+	} else {
+		if ret.Get(2) != nil {
+			r2 = ret.Get(2).(domain.CodedError)
+		}
+	}
+
+	return r0, r1, r2
+}
+
+// CreateAccount provides a mock function with given fields: c, subject, user
+func (_m *UserUsecaseInterface) CreateAccount(c context.Context, subject domain.Subject, user domain.User) (bool, domain.CodedError) {
+	ret := _m.Called(c, subject, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateAccount")
+	}
+
+	var r0 bool
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, domain.User) (bool, domain.CodedError)); ok {
+		return rf(c, subject, user)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, domain.User) bool); ok {
+		r0 = rf(c, subject, user)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Subject, domain.User) domain.CodedError); ok {
+		r1 = rf(c, subject, user)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// CreateUser provides a mock function with given fields: c, user
+func (_m *UserUsecaseInterface) CreateUser(c context.Context, user domain.User) (bool, domain.CodedError) {
 	ret := _m.Called(c, user)
 
 	if len(ret) == 0 {
-		panic("no return value specified for ValidateAndGetToken")
+		panic("no return value specified for CreateUser")
 	}
 
-	var r0 string
+	var r0 bool
 	var r1 domain.CodedError
-	if rf, ok := ret.Get(0).(func(context.Context, domain.User) (string, domain.CodedError)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, domain.User) (bool, domain.CodedError)); ok {
 		return rf(c, user)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, domain.User) string); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, domain.User) bool); ok {
 		r0 = rf(c, user)
 	} else {
-		r0 = ret.Get(0).(string)
+		r0 = ret.Get(0).(bool)
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, domain.User) domain.CodedError); ok {
@@ -84,6 +185,752 @@ func (_m *UserUsecaseInterface) ValidateAndGetToken(c context.Context, user doma
 	return r0, r1
 }
 
+// Deactivate provides a mock function with given fields: c, subject, username
+func (_m *UserUsecaseInterface) Deactivate(c context.Context, subject domain.Subject, username string) domain.CodedError {
+	ret := _m.Called(c, subject, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Deactivate")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string) domain.CodedError); ok {
+		r0 = rf(c, subject, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// DeleteUser provides a mock function with given fields: c, subject, username
+func (_m *UserUsecaseInterface) DeleteUser(c context.Context, subject domain.Subject, username string) (domain.UserDeletion, domain.CodedError) {
+	ret := _m.Called(c, subject, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteUser")
+	}
+
+	var r0 domain.UserDeletion
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string) (domain.UserDeletion, domain.CodedError)); ok {
+		return rf(c, subject, username)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string) domain.UserDeletion); ok {
+		r0 = rf(c, subject, username)
+	} else {
+		r0 = ret.Get(0).(domain.UserDeletion)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Subject, string) domain.CodedError); ok {
+		r1 = rf(c, subject, username)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// Demote provides a mock function with given fields: c, subject, username
+func (_m *UserUsecaseInterface) Demote(c context.Context, subject domain.Subject, username string) domain.CodedError {
+	ret := _m.Called(c, subject, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Demote")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string) domain.CodedError); ok {
+		r0 = rf(c, subject, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// DisableMFA provides a mock function with given fields: c, subject, currentPassword, code
+func (_m *UserUsecaseInterface) DisableMFA(c context.Context, subject domain.Subject, currentPassword string, code string) domain.CodedError {
+	ret := _m.Called(c, subject, currentPassword, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DisableMFA")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string, string) domain.CodedError); ok {
+		r0 = rf(c, subject, currentPassword, code)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// EnrollMFALogin provides a mock function with given fields: c, challengeToken
+func (_m *UserUsecaseInterface) EnrollMFALogin(c context.Context, challengeToken string) (domain.TOTPSetup, domain.CodedError) {
+	ret := _m.Called(c, challengeToken)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnrollMFALogin")
+	}
+
+	var r0 domain.TOTPSetup
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.TOTPSetup, domain.CodedError)); ok {
+		return rf(c, challengeToken)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.TOTPSetup); ok {
+		r0 = rf(c, challengeToken)
+	} else {
+		r0 = ret.Get(0).(domain.TOTPSetup)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, challengeToken)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// EnrollTOTP provides a mock function with given fields: c, subject, currentPassword
+func (_m *UserUsecaseInterface) EnrollTOTP(c context.Context, subject domain.Subject, currentPassword string) (domain.TOTPSetup, domain.CodedError) {
+	ret := _m.Called(c, subject, currentPassword)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnrollTOTP")
+	}
+
+	var r0 domain.TOTPSetup
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string) (domain.TOTPSetup, domain.CodedError)); ok {
+		return rf(c, subject, currentPassword)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string) domain.TOTPSetup); ok {
+		r0 = rf(c, subject, currentPassword)
+	} else {
+		r0 = ret.Get(0).(domain.TOTPSetup)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Subject, string) domain.CodedError); ok {
+		r1 = rf(c, subject, currentPassword)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// ForgotPassword provides a mock function with given fields: c, email
+func (_m *UserUsecaseInterface) ForgotPassword(c context.Context, email string) domain.CodedError {
+	ret := _m.Called(c, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ForgotPassword")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, email)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// GetAPIKeys provides a mock function with given fields: c, subject
+func (_m *UserUsecaseInterface) GetAPIKeys(c context.Context, subject domain.Subject) ([]domain.APIKey, domain.CodedError) {
+	ret := _m.Called(c, subject)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAPIKeys")
+	}
+
+	var r0 []domain.APIKey
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject) ([]domain.APIKey, domain.CodedError)); ok {
+		return rf(c, subject)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject) []domain.APIKey); ok {
+		r0 = rf(c, subject)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.APIKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Subject) domain.CodedError); ok {
+		r1 = rf(c, subject)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// GetProfile provides a mock function with given fields: c, subject
+func (_m *UserUsecaseInterface) GetProfile(c context.Context, subject domain.Subject) (domain.UserProfile, domain.CodedError) {
+	ret := _m.Called(c, subject)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetProfile")
+	}
+
+	var r0 domain.UserProfile
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject) (domain.UserProfile, domain.CodedError)); ok {
+		return rf(c, subject)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject) domain.UserProfile); ok {
+		r0 = rf(c, subject)
+	} else {
+		r0 = ret.Get(0).(domain.UserProfile)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Subject) domain.CodedError); ok {
+		r1 = rf(c, subject)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// GetSessions provides a mock function with given fields: c, subject
+func (_m *UserUsecaseInterface) GetSessions(c context.Context, subject domain.Subject) ([]domain.Session, domain.CodedError) {
+	ret := _m.Called(c, subject)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSessions")
+	}
+
+	var r0 []domain.Session
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject) ([]domain.Session, domain.CodedError)); ok {
+		return rf(c, subject)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject) []domain.Session); ok {
+		r0 = rf(c, subject)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Session)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Subject) domain.CodedError); ok {
+		r1 = rf(c, subject)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// GetUser provides a mock function with given fields: c, username
+func (_m *UserUsecaseInterface) GetUser(c context.Context, username string) (domain.UserProfile, domain.CodedError) {
+	ret := _m.Called(c, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUser")
+	}
+
+	var r0 domain.UserProfile
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.UserProfile, domain.CodedError)); ok {
+		return rf(c, username)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.UserProfile); ok {
+		r0 = rf(c, username)
+	} else {
+		r0 = ret.Get(0).(domain.UserProfile)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, username)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// GetUsers provides a mock function with given fields: c, query
+func (_m *UserUsecaseInterface) GetUsers(c context.Context, query domain.UserQuery) (domain.UserPage, domain.CodedError) {
+	ret := _m.Called(c, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUsers")
+	}
+
+	var r0 domain.UserPage
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.UserQuery) (domain.UserPage, domain.CodedError)); ok {
+		return rf(c, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.UserQuery) domain.UserPage); ok {
+		r0 = rf(c, query)
+	} else {
+		r0 = ret.Get(0).(domain.UserPage)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.UserQuery) domain.CodedError); ok {
+		r1 = rf(c, query)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// Logout provides a mock function with given fields: c, refreshToken
+func (_m *UserUsecaseInterface) Logout(c context.Context, refreshToken string) domain.CodedError {
+	ret := _m.Called(c, refreshToken)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Logout")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, refreshToken)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// Promote provides a mock function with given fields: c, subject, username
+func (_m *UserUsecaseInterface) Promote(c context.Context, subject domain.Subject, username string) domain.CodedError {
+	ret := _m.Called(c, subject, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Promote")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string) domain.CodedError); ok {
+		r0 = rf(c, subject, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// RefreshTokens provides a mock function with given fields: c, refreshToken
+func (_m *UserUsecaseInterface) RefreshTokens(c context.Context, refreshToken string) (domain.TokenPair, domain.CodedError) {
+	ret := _m.Called(c, refreshToken)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RefreshTokens")
+	}
+
+	var r0 domain.TokenPair
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.TokenPair, domain.CodedError)); ok {
+		return rf(c, refreshToken)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.TokenPair); ok {
+		r0 = rf(c, refreshToken)
+	} else {
+		r0 = ret.Get(0).(domain.TokenPair)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, refreshToken)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// RegenerateRecoveryCodes provides a mock function with given fields: c, subject, code
+func (_m *UserUsecaseInterface) RegenerateRecoveryCodes(c context.Context, subject domain.Subject, code string) ([]string, domain.CodedError) {
+	ret := _m.Called(c, subject, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RegenerateRecoveryCodes")
+	}
+
+	var r0 []string
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string) ([]string, domain.CodedError)); ok {
+		return rf(c, subject, code)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string) []string); ok {
+		r0 = rf(c, subject, code)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Subject, string) domain.CodedError); ok {
+		r1 = rf(c, subject, code)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// ResendVerification provides a mock function with given fields: c, email
+func (_m *UserUsecaseInterface) ResendVerification(c context.Context, email string) domain.CodedError {
+	ret := _m.Called(c, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResendVerification")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, email)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// ResetMFA provides a mock function with given fields: c, username
+func (_m *UserUsecaseInterface) ResetMFA(c context.Context, username string) domain.CodedError {
+	ret := _m.Called(c, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetMFA")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// ResetPassword provides a mock function with given fields: c, resetToken, newPassword
+func (_m *UserUsecaseInterface) ResetPassword(c context.Context, resetToken string, newPassword string) domain.CodedError {
+	ret := _m.Called(c, resetToken, newPassword)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetPassword")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) domain.CodedError); ok {
+		r0 = rf(c, resetToken, newPassword)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// RevokeAPIKey provides a mock function with given fields: c, subject, keyID
+func (_m *UserUsecaseInterface) RevokeAPIKey(c context.Context, subject domain.Subject, keyID string) domain.CodedError {
+	ret := _m.Called(c, subject, keyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeAPIKey")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string) domain.CodedError); ok {
+		r0 = rf(c, subject, keyID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// RevokeSession provides a mock function with given fields: c, subject, sessionID
+func (_m *UserUsecaseInterface) RevokeSession(c context.Context, subject domain.Subject, sessionID string) domain.CodedError {
+	ret := _m.Called(c, subject, sessionID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeSession")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string) domain.CodedError); ok {
+		r0 = rf(c, subject, sessionID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// RevokeUserSessions provides a mock function with given fields: c, username
+func (_m *UserUsecaseInterface) RevokeUserSessions(c context.Context, username string) domain.CodedError {
+	ret := _m.Called(c, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeUserSessions")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// SetRole provides a mock function with given fields: c, subject, username, role
+func (_m *UserUsecaseInterface) SetRole(c context.Context, subject domain.Subject, username string, role string) domain.CodedError {
+	ret := _m.Called(c, subject, username, role)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetRole")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string, string) domain.CodedError); ok {
+		r0 = rf(c, subject, username, role)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// UnlockAccount provides a mock function with given fields: c, username
+func (_m *UserUsecaseInterface) UnlockAccount(c context.Context, username string) domain.CodedError {
+	ret := _m.Called(c, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UnlockAccount")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// UpdateProfile provides a mock function with given fields: c, subject, currentPassword, update
+func (_m *UserUsecaseInterface) UpdateProfile(c context.Context, subject domain.Subject, currentPassword string, update domain.ProfileUpdate) (domain.UserProfile, domain.CodedError) {
+	ret := _m.Called(c, subject, currentPassword, update)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateProfile")
+	}
+
+	var r0 domain.UserProfile
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string, domain.ProfileUpdate) (domain.UserProfile, domain.CodedError)); ok {
+		return rf(c, subject, currentPassword, update)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Subject, string, domain.ProfileUpdate) domain.UserProfile); ok {
+		r0 = rf(c, subject, currentPassword, update)
+	} else {
+		r0 = ret.Get(0).(domain.UserProfile)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Subject, string, domain.ProfileUpdate) domain.CodedError); ok {
+		r1 = rf(c, subject, currentPassword, update)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// ValidateAPIKey provides a mock function with given fields: c, key
+func (_m *UserUsecaseInterface) ValidateAPIKey(c context.Context, key string) (domain.Subject, domain.CodedError) {
+	ret := _m.Called(c, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ValidateAPIKey")
+	}
+
+	var r0 domain.Subject
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.Subject, domain.CodedError)); ok {
+		return rf(c, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.Subject); ok {
+		r0 = rf(c, key)
+	} else {
+		r0 = ret.Get(0).(domain.Subject)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) domain.CodedError); ok {
+		r1 = rf(c, key)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// ValidateAndGetToken provides a mock function with given fields: c, user, clientIP
+func (_m *UserUsecaseInterface) ValidateAndGetToken(c context.Context, user domain.User, clientIP string) (domain.TokenPair, domain.CodedError) {
+	ret := _m.Called(c, user, clientIP)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ValidateAndGetToken")
+	}
+
+	var r0 domain.TokenPair
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, domain.User, string) (domain.TokenPair, domain.CodedError)); ok {
+		return rf(c, user, clientIP)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.User, string) domain.TokenPair); ok {
+		r0 = rf(c, user, clientIP)
+	} else {
+		r0 = ret.Get(0).(domain.TokenPair)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.User, string) domain.CodedError); ok {
+		r1 = rf(c, user, clientIP)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
+// ValidateSession provides a mock function with given fields: c, sessionID
+func (_m *UserUsecaseInterface) ValidateSession(c context.Context, sessionID string) domain.CodedError {
+	ret := _m.Called(c, sessionID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ValidateSession")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, sessionID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// VerifyEmail provides a mock function with given fields: c, verificationToken
+func (_m *UserUsecaseInterface) VerifyEmail(c context.Context, verificationToken string) domain.CodedError {
+	ret := _m.Called(c, verificationToken)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyEmail")
+	}
+
+	var r0 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CodedError); ok {
+		r0 = rf(c, verificationToken)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(domain.CodedError)
+		}
+	}
+
+	return r0
+}
+
+// VerifyMFALogin provides a mock function with given fields: c, challengeToken, code, recoveryCode, clientIP
+func (_m *UserUsecaseInterface) VerifyMFALogin(c context.Context, challengeToken string, code string, recoveryCode string, clientIP string) (domain.TokenPair, domain.CodedError) {
+	ret := _m.Called(c, challengeToken, code, recoveryCode, clientIP)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyMFALogin")
+	}
+
+	var r0 domain.TokenPair
+	var r1 domain.CodedError
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) (domain.TokenPair, domain.CodedError)); ok {
+		return rf(c, challengeToken, code, recoveryCode, clientIP)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) domain.TokenPair); ok {
+		r0 = rf(c, challengeToken, code, recoveryCode, clientIP)
+	} else {
+		r0 = ret.Get(0).(domain.TokenPair)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, string) domain.CodedError); ok {
+		r1 = rf(c, challengeToken, code, recoveryCode, clientIP)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(domain.CodedError)
+		}
+	}
+
+	return r0, r1
+}
+
 // NewUserUsecaseInterface creates a new instance of UserUsecaseInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewUserUsecaseInterface(t interface {
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	domain "task_manager_api/Domain"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/* Implements the APIKeyRepositoryInterface defined in `domain`*/
+type APIKeyRepository struct {
+	Collection *mongo.Collection
+}
+
+/* Adds the API key to the DB */
+func (aR *APIKeyRepository) CreateAPIKey(c context.Context, key domain.APIKey) domain.CodedError {
+	_, err := aR.Collection.InsertOne(c, key)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/* retrieves the API keys of the user, most recent first */
+func (aR *APIKeyRepository) GetAPIKeys(c context.Context, username string) ([]domain.APIKey, domain.CodedError) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "id", Value: 1}})
+	cursor, err := aR.Collection.Find(c, bson.D{{Key: "username", Value: username}}, findOptions)
+	if err != nil {
+		return []domain.APIKey{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	keys := []domain.APIKey{}
+	if err := cursor.All(c, &keys); err != nil {
+		return []domain.APIKey{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return keys, nil
+}
+
+/* retrieves the API key with the provided hash if it exists */
+func (aR *APIKeyRepository) GetAPIKeyByHash(c context.Context, keyHash string) (domain.APIKey, domain.CodedError) {
+	var key domain.APIKey
+	result := aR.Collection.FindOne(c, bson.D{{Key: "key_hash", Value: keyHash}})
+	if result.Err() != nil && result.Err().Error() == mongo.ErrNoDocuments.Error() {
+		return key, domain.UserError{Message: "API key not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if result.Err() != nil {
+		return key, domain.UserError{Message: "Internal server error: " + result.Err().Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if err := result.Decode(&key); err != nil {
+		return key, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return key, nil
+}
+
+/* records the use of the API key with the provided ID */
+func (aR *APIKeyRepository) TouchAPIKey(c context.Context, keyID string, usedAt time.Time) domain.CodedError {
+	result, err := aR.Collection.UpdateOne(c, bson.D{{Key: "id", Value: keyID}}, bson.D{{Key: "$set", Value: bson.D{{Key: "last_used_at", Value: usedAt}}}})
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.UserError{Message: "API key not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
+
+/* deletes the API key with the provided ID if it belongs to the user */
+func (aR *APIKeyRepository) DeleteAPIKey(c context.Context, username string, keyID string) domain.CodedError {
+	result, err := aR.Collection.DeleteOne(c, bson.D{{Key: "id", Value: keyID}, {Key: "username", Value: username}})
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if result.DeletedCount == 0 {
+		return domain.UserError{Message: "API key not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
+
+/* deletes every API key of the provided user */
+func (aR *APIKeyRepository) DeleteUserAPIKeys(c context.Context, username string) domain.CodedError {
+	_, err := aR.Collection.DeleteMany(c, bson.D{{Key: "username", Value: username}})
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
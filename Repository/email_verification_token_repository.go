@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	domain "task_manager_api/Domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/* Implements the EmailVerificationTokenRepositoryInterface defined in `domain`*/
+type EmailVerificationTokenRepository struct {
+	Collection *mongo.Collection
+}
+
+/* Adds the email verification token to the DB */
+func (vR *EmailVerificationTokenRepository) CreateVerificationToken(c context.Context, token domain.EmailVerificationToken) domain.CodedError {
+	_, err := vR.Collection.InsertOne(c, token)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/*
+marks the email verification token with the provided hash as used and returns
+it. Only unused tokens are matched, which makes sure that concurrent requests
+can not use the same token twice.
+*/
+func (vR *EmailVerificationTokenRepository) UseVerificationToken(c context.Context, tokenHash string) (domain.EmailVerificationToken, domain.CodedError) {
+	var token domain.EmailVerificationToken
+	filter := bson.D{{Key: "token_hash", Value: tokenHash}, {Key: "used", Value: false}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "used", Value: true}}}}
+	result := vR.Collection.FindOneAndUpdate(c, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After))
+	if result.Err() != nil && result.Err().Error() == mongo.ErrNoDocuments.Error() {
+		return token, domain.UserError{Message: "Email verification token not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if result.Err() != nil {
+		return token, domain.UserError{Message: "Internal server error: " + result.Err().Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if err := result.Decode(&token); err != nil {
+		return token, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return token, nil
+}
+
+/* marks every unused email verification token of the provided user as used */
+func (vR *EmailVerificationTokenRepository) InvalidateUserVerificationTokens(c context.Context, username string) domain.CodedError {
+	_, err := vR.Collection.UpdateMany(c, bson.D{{Key: "username", Value: username}, {Key: "used", Value: false}}, bson.D{{Key: "$set", Value: bson.D{{Key: "used", Value: true}}}})
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	domain "task_manager_api/Domain"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/* Implements the LoginAttemptRepositoryInterface defined in `domain`*/
+type LoginAttemptRepository struct {
+	Collection *mongo.Collection
+}
+
+/* retrieves the failed logins recorded for the key if there are any */
+func (aR *LoginAttemptRepository) GetLoginAttempts(c context.Context, key string) (domain.LoginAttempts, domain.CodedError) {
+	var attempts domain.LoginAttempts
+	result := aR.Collection.FindOne(c, bson.D{{Key: "key", Value: key}})
+	if result.Err() != nil && result.Err().Error() == mongo.ErrNoDocuments.Error() {
+		return attempts, domain.UserError{Message: "Login attempts not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if result.Err() != nil {
+		return attempts, domain.UserError{Message: "Internal server error: " + result.Err().Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if err := result.Decode(&attempts); err != nil {
+		return attempts, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return attempts, nil
+}
+
+/*
+counts a failed login for the key and returns the updated record. The count
+is incremented in a single update pipeline, so concurrent failures are all
+counted, and restarts from one if the stored record has expired.
+*/
+func (aR *LoginAttemptRepository) RecordLoginFailure(c context.Context, key string, failedAt time.Time, expiresAt time.Time) (domain.LoginAttempts, domain.CodedError) {
+	var attempts domain.LoginAttempts
+	failures := bson.D{{Key: "$cond", Value: bson.A{
+		bson.D{{Key: "$gt", Value: bson.A{"$expires_at", failedAt}}},
+		bson.D{{Key: "$add", Value: bson.A{"$failures", 1}}},
+		1,
+	}}}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.D{
+		{Key: "failures", Value: failures},
+		{Key: "last_failure", Value: failedAt},
+		{Key: "expires_at", Value: expiresAt},
+	}}}}
+
+	updateOptions := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	result := aR.Collection.FindOneAndUpdate(c, bson.D{{Key: "key", Value: key}}, update, updateOptions)
+	if result.Err() != nil {
+		return attempts, domain.UserError{Message: "Internal server error: " + result.Err().Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if err := result.Decode(&attempts); err != nil {
+		return attempts, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return attempts, nil
+}
+
+/* forgets the failed logins recorded for the key */
+func (aR *LoginAttemptRepository) ClearLoginAttempts(c context.Context, key string) domain.CodedError {
+	_, err := aR.Collection.DeleteOne(c, bson.D{{Key: "key", Value: key}})
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
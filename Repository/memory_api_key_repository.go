@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	domain "task_manager_api/Domain"
+	"time"
+)
+
+/*
+Implements the APIKeyRepositoryInterface defined in `domain` by keeping the
+API keys in memory. The repository is safe for concurrent use.
+*/
+type InMemoryAPIKeyRepository struct {
+	mutex sync.RWMutex
+	keys  map[string]domain.APIKey
+}
+
+/* Creates an empty in-memory API key repository */
+func NewInMemoryAPIKeyRepository() *InMemoryAPIKeyRepository {
+	return &InMemoryAPIKeyRepository{keys: map[string]domain.APIKey{}}
+}
+
+/* Adds the API key to the repository */
+func (aR *InMemoryAPIKeyRepository) CreateAPIKey(c context.Context, key domain.APIKey) domain.CodedError {
+	aR.mutex.Lock()
+	defer aR.mutex.Unlock()
+
+	if _, ok := aR.keys[key.ID]; ok {
+		return domain.UserError{Message: "Internal server error: duplicate API key", Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	key.Scopes = slices.Clone(key.Scopes)
+	aR.keys[key.ID] = key
+	return nil
+}
+
+/* retrieves the API keys of the user, most recent first */
+func (aR *InMemoryAPIKeyRepository) GetAPIKeys(c context.Context, username string) ([]domain.APIKey, domain.CodedError) {
+	aR.mutex.RLock()
+	defer aR.mutex.RUnlock()
+
+	keys := []domain.APIKey{}
+	for _, key := range aR.keys {
+		if key.Username == username {
+			key.Scopes = slices.Clone(key.Scopes)
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID < keys[j].ID
+		}
+
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+
+	return keys, nil
+}
+
+/* retrieves the API key with the provided hash if it exists */
+func (aR *InMemoryAPIKeyRepository) GetAPIKeyByHash(c context.Context, keyHash string) (domain.APIKey, domain.CodedError) {
+	aR.mutex.RLock()
+	defer aR.mutex.RUnlock()
+
+	for _, key := range aR.keys {
+		if key.KeyHash == keyHash {
+			key.Scopes = slices.Clone(key.Scopes)
+			return key, nil
+		}
+	}
+
+	return domain.APIKey{}, domain.UserError{Message: "API key not found", Code: domain.ERR_NOT_FOUND}
+}
+
+/* records the use of the API key with the provided ID */
+func (aR *InMemoryAPIKeyRepository) TouchAPIKey(c context.Context, keyID string, usedAt time.Time) domain.CodedError {
+	aR.mutex.Lock()
+	defer aR.mutex.Unlock()
+
+	key, ok := aR.keys[keyID]
+	if !ok {
+		return domain.UserError{Message: "API key not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	key.LastUsedAt = usedAt
+	aR.keys[keyID] = key
+	return nil
+}
+
+/* deletes the API key with the provided ID if it belongs to the user */
+func (aR *InMemoryAPIKeyRepository) DeleteAPIKey(c context.Context, username string, keyID string) domain.CodedError {
+	aR.mutex.Lock()
+	defer aR.mutex.Unlock()
+
+	key, ok := aR.keys[keyID]
+	if !ok || key.Username != username {
+		return domain.UserError{Message: "API key not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	delete(aR.keys, keyID)
+	return nil
+}
+
+/* deletes every API key of the provided user */
+func (aR *InMemoryAPIKeyRepository) DeleteUserAPIKeys(c context.Context, username string) domain.CodedError {
+	aR.mutex.Lock()
+	defer aR.mutex.Unlock()
+
+	for keyID, key := range aR.keys {
+		if key.Username == username {
+			delete(aR.keys, keyID)
+		}
+	}
+
+	return nil
+}
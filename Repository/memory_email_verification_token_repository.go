@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	domain "task_manager_api/Domain"
+)
+
+/*
+Implements the EmailVerificationTokenRepositoryInterface defined in `domain` by
+keeping the email verification tokens in memory. The repository is safe for
+concurrent use.
+*/
+type InMemoryEmailVerificationTokenRepository struct {
+	mutex  sync.Mutex
+	tokens map[string]domain.EmailVerificationToken
+}
+
+/* Creates an empty in-memory email verification token repository */
+func NewInMemoryEmailVerificationTokenRepository() *InMemoryEmailVerificationTokenRepository {
+	return &InMemoryEmailVerificationTokenRepository{tokens: map[string]domain.EmailVerificationToken{}}
+}
+
+/* Adds the email verification token to the repository */
+func (vR *InMemoryEmailVerificationTokenRepository) CreateVerificationToken(c context.Context, token domain.EmailVerificationToken) domain.CodedError {
+	vR.mutex.Lock()
+	defer vR.mutex.Unlock()
+
+	if _, ok := vR.tokens[token.TokenHash]; ok {
+		return domain.UserError{Message: "Internal server error: duplicate email verification token", Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	vR.tokens[token.TokenHash] = token
+	return nil
+}
+
+/* marks the unused email verification token with the provided hash as used and returns it */
+func (vR *InMemoryEmailVerificationTokenRepository) UseVerificationToken(c context.Context, tokenHash string) (domain.EmailVerificationToken, domain.CodedError) {
+	vR.mutex.Lock()
+	defer vR.mutex.Unlock()
+
+	token, ok := vR.tokens[tokenHash]
+	if !ok || token.Used {
+		return domain.EmailVerificationToken{}, domain.UserError{Message: "Email verification token not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	token.Used = true
+	vR.tokens[tokenHash] = token
+	return token, nil
+}
+
+/* marks every unused email verification token of the provided user as used */
+func (vR *InMemoryEmailVerificationTokenRepository) InvalidateUserVerificationTokens(c context.Context, username string) domain.CodedError {
+	vR.mutex.Lock()
+	defer vR.mutex.Unlock()
+
+	for tokenHash, token := range vR.tokens {
+		if token.Username == username {
+			token.Used = true
+			vR.tokens[tokenHash] = token
+		}
+	}
+
+	return nil
+}
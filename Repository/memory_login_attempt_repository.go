@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	domain "task_manager_api/Domain"
+	"time"
+)
+
+/*
+Implements the LoginAttemptRepositoryInterface defined in `domain` by keeping
+the failed logins in memory. The repository is safe for concurrent use.
+*/
+type InMemoryLoginAttemptRepository struct {
+	mutex    sync.Mutex
+	attempts map[string]domain.LoginAttempts
+}
+
+/* Creates an empty in-memory login attempt repository */
+func NewInMemoryLoginAttemptRepository() *InMemoryLoginAttemptRepository {
+	return &InMemoryLoginAttemptRepository{attempts: map[string]domain.LoginAttempts{}}
+}
+
+/* retrieves the failed logins recorded for the key if there are any */
+func (aR *InMemoryLoginAttemptRepository) GetLoginAttempts(c context.Context, key string) (domain.LoginAttempts, domain.CodedError) {
+	aR.mutex.Lock()
+	defer aR.mutex.Unlock()
+
+	attempts, ok := aR.attempts[key]
+	if !ok {
+		return domain.LoginAttempts{}, domain.UserError{Message: "Login attempts not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return attempts, nil
+}
+
+/* counts a failed login for the key, restarting the count if the record has expired */
+func (aR *InMemoryLoginAttemptRepository) RecordLoginFailure(c context.Context, key string, failedAt time.Time, expiresAt time.Time) (domain.LoginAttempts, domain.CodedError) {
+	aR.mutex.Lock()
+	defer aR.mutex.Unlock()
+
+	attempts, ok := aR.attempts[key]
+	if !ok || !attempts.ExpiresAt.After(failedAt) {
+		attempts = domain.LoginAttempts{Key: key}
+	}
+
+	attempts.Failures++
+	attempts.LastFailure = failedAt.UTC()
+	attempts.ExpiresAt = expiresAt.UTC()
+	aR.attempts[key] = attempts
+	return attempts, nil
+}
+
+/* forgets the failed logins recorded for the key */
+func (aR *InMemoryLoginAttemptRepository) ClearLoginAttempts(c context.Context, key string) domain.CodedError {
+	aR.mutex.Lock()
+	defer aR.mutex.Unlock()
+
+	delete(aR.attempts, key)
+	return nil
+}
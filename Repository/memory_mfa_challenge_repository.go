@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	domain "task_manager_api/Domain"
+)
+
+/*
+Implements the MFAChallengeRepositoryInterface defined in `domain` by keeping
+the login challenges in memory. The repository is safe for concurrent use.
+*/
+type InMemoryMFAChallengeRepository struct {
+	mutex      sync.Mutex
+	challenges map[string]domain.MFAChallenge
+}
+
+/* Creates an empty in-memory login challenge repository */
+func NewInMemoryMFAChallengeRepository() *InMemoryMFAChallengeRepository {
+	return &InMemoryMFAChallengeRepository{challenges: map[string]domain.MFAChallenge{}}
+}
+
+/* Adds the login challenge to the repository */
+func (cR *InMemoryMFAChallengeRepository) CreateChallenge(c context.Context, challenge domain.MFAChallenge) domain.CodedError {
+	cR.mutex.Lock()
+	defer cR.mutex.Unlock()
+
+	if _, ok := cR.challenges[challenge.TokenHash]; ok {
+		return domain.UserError{Message: "Internal server error: duplicate login challenge", Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	cR.challenges[challenge.TokenHash] = challenge
+	return nil
+}
+
+/* retrieves the unused login challenge with the provided hash */
+func (cR *InMemoryMFAChallengeRepository) GetChallenge(c context.Context, tokenHash string) (domain.MFAChallenge, domain.CodedError) {
+	cR.mutex.Lock()
+	defer cR.mutex.Unlock()
+
+	challenge, ok := cR.challenges[tokenHash]
+	if !ok || challenge.Used {
+		return domain.MFAChallenge{}, domain.UserError{Message: "Login challenge not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return challenge, nil
+}
+
+/* marks the unused login challenge with the provided hash as used and returns it */
+func (cR *InMemoryMFAChallengeRepository) UseChallenge(c context.Context, tokenHash string) (domain.MFAChallenge, domain.CodedError) {
+	cR.mutex.Lock()
+	defer cR.mutex.Unlock()
+
+	challenge, ok := cR.challenges[tokenHash]
+	if !ok || challenge.Used {
+		return domain.MFAChallenge{}, domain.UserError{Message: "Login challenge not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	challenge.Used = true
+	cR.challenges[tokenHash] = challenge
+	return challenge, nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sync"
+	domain "task_manager_api/Domain"
+)
+
+/*
+Implements the MFARepositoryInterface defined in `domain` by keeping the TOTP
+enrollments in memory. The recovery codes are copied in and out so that the
+stored enrollments can not be changed by the callers. The repository is safe
+for concurrent use.
+*/
+type InMemoryMFARepository struct {
+	mutex       sync.Mutex
+	enrollments map[string]domain.MFAEnrollment
+}
+
+/* Creates an empty in-memory MFA repository */
+func NewInMemoryMFARepository() *InMemoryMFARepository {
+	return &InMemoryMFARepository{enrollments: map[string]domain.MFAEnrollment{}}
+}
+
+/* retrieves the TOTP enrollment of the user */
+func (mR *InMemoryMFARepository) GetMFA(c context.Context, username string) (domain.MFAEnrollment, domain.CodedError) {
+	mR.mutex.Lock()
+	defer mR.mutex.Unlock()
+
+	enrollment, ok := mR.enrollments[username]
+	if !ok {
+		return domain.MFAEnrollment{}, domain.UserError{Message: "Two-factor authentication is not set up", Code: domain.ERR_NOT_FOUND}
+	}
+
+	enrollment.RecoveryCodes = slices.Clone(enrollment.RecoveryCodes)
+	return enrollment, nil
+}
+
+/* creates the TOTP enrollment of the user or replaces the existing one */
+func (mR *InMemoryMFARepository) SaveMFA(c context.Context, enrollment domain.MFAEnrollment) domain.CodedError {
+	mR.mutex.Lock()
+	defer mR.mutex.Unlock()
+
+	enrollment.RecoveryCodes = append([]string{}, enrollment.RecoveryCodes...)
+	enrollment.CreatedAt = enrollment.CreatedAt.UTC()
+	mR.enrollments[enrollment.Username] = enrollment
+	return nil
+}
+
+/* records the time step of an accepted TOTP code unless it is not after the last used one */
+func (mR *InMemoryMFARepository) UseTOTPStep(c context.Context, username string, step int64) domain.CodedError {
+	mR.mutex.Lock()
+	defer mR.mutex.Unlock()
+
+	enrollment, ok := mR.enrollments[username]
+	if !ok {
+		return domain.UserError{Message: "Two-factor authentication is not set up", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if enrollment.LastUsedStep >= step {
+		return domain.UserError{Message: "Authentication code has already been used", Code: domain.ERR_CONFLICT}
+	}
+
+	enrollment.LastUsedStep = step
+	mR.enrollments[username] = enrollment
+	return nil
+}
+
+/* removes the recovery code with the provided hash from the codes of the user */
+func (mR *InMemoryMFARepository) UseRecoveryCode(c context.Context, username string, codeHash string) domain.CodedError {
+	mR.mutex.Lock()
+	defer mR.mutex.Unlock()
+
+	enrollment, ok := mR.enrollments[username]
+	index := slices.Index(enrollment.RecoveryCodes, codeHash)
+	if !ok || index < 0 {
+		return domain.UserError{Message: "Recovery code not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	enrollment.RecoveryCodes = slices.Delete(enrollment.RecoveryCodes, index, index+1)
+	mR.enrollments[username] = enrollment
+	return nil
+}
+
+/* deletes the TOTP enrollment of the user */
+func (mR *InMemoryMFARepository) DeleteMFA(c context.Context, username string) domain.CodedError {
+	mR.mutex.Lock()
+	defer mR.mutex.Unlock()
+
+	if _, ok := mR.enrollments[username]; !ok {
+		return domain.UserError{Message: "Two-factor authentication is not set up", Code: domain.ERR_NOT_FOUND}
+	}
+
+	delete(mR.enrollments, username)
+	return nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	domain "task_manager_api/Domain"
+)
+
+/*
+Implements the PasswordResetTokenRepositoryInterface defined in `domain` by
+keeping the password reset tokens in memory. The repository is safe for
+concurrent use.
+*/
+type InMemoryPasswordResetTokenRepository struct {
+	mutex  sync.Mutex
+	tokens map[string]domain.PasswordResetToken
+}
+
+/* Creates an empty in-memory password reset token repository */
+func NewInMemoryPasswordResetTokenRepository() *InMemoryPasswordResetTokenRepository {
+	return &InMemoryPasswordResetTokenRepository{tokens: map[string]domain.PasswordResetToken{}}
+}
+
+/* Adds the password reset token to the repository */
+func (pR *InMemoryPasswordResetTokenRepository) CreateResetToken(c context.Context, token domain.PasswordResetToken) domain.CodedError {
+	pR.mutex.Lock()
+	defer pR.mutex.Unlock()
+
+	if _, ok := pR.tokens[token.TokenHash]; ok {
+		return domain.UserError{Message: "Internal server error: duplicate password reset token", Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	pR.tokens[token.TokenHash] = token
+	return nil
+}
+
+/* marks the unused password reset token with the provided hash as used and returns it */
+func (pR *InMemoryPasswordResetTokenRepository) UseResetToken(c context.Context, tokenHash string) (domain.PasswordResetToken, domain.CodedError) {
+	pR.mutex.Lock()
+	defer pR.mutex.Unlock()
+
+	token, ok := pR.tokens[tokenHash]
+	if !ok || token.Used {
+		return domain.PasswordResetToken{}, domain.UserError{Message: "Password reset token not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	token.Used = true
+	pR.tokens[tokenHash] = token
+	return token, nil
+}
+
+/* marks every unused password reset token of the provided user as used */
+func (pR *InMemoryPasswordResetTokenRepository) InvalidateUserResetTokens(c context.Context, username string) domain.CodedError {
+	pR.mutex.Lock()
+	defer pR.mutex.Unlock()
+
+	for tokenHash, token := range pR.tokens {
+		if token.Username == username {
+			token.Used = true
+			pR.tokens[tokenHash] = token
+		}
+	}
+
+	return nil
+}
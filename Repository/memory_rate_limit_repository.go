@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"sync"
+	domain "task_manager_api/Domain"
+	"time"
+)
+
+/* How often the buckets that have filled up again are dropped */
+const rateLimitSweepInterval = time.Minute
+
+/* The tokens left in the bucket of a key when it was last refilled */
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	period    time.Duration
+}
+
+/*
+Implements the RateLimitRepositoryInterface defined in `domain` by keeping
+the token buckets in the memory of the process, so the limits are not shared
+between several instances of the API. The buckets that have filled up again
+are dropped as they hold no more state than a missing bucket. The repository
+is safe for concurrent use.
+*/
+type InMemoryRateLimitRepository struct {
+	mutex   sync.Mutex
+	buckets map[string]tokenBucket
+	sweptAt time.Time
+}
+
+/* Creates an empty in-memory rate limit repository */
+func NewInMemoryRateLimitRepository() *InMemoryRateLimitRepository {
+	return &InMemoryRateLimitRepository{buckets: map[string]tokenBucket{}}
+}
+
+/* refills the bucket of the key and takes a token from it if there is one */
+func (rR *InMemoryRateLimitRepository) TakeToken(c context.Context, key string, limit domain.RateLimit, now time.Time) (domain.RateLimitStatus, domain.CodedError) {
+	rR.mutex.Lock()
+	defer rR.mutex.Unlock()
+
+	rR.sweep(now)
+
+	// the tokens added back per second
+	rate := float64(limit.Requests) / limit.Period.Seconds()
+	capacity := float64(limit.Requests)
+
+	bucket, ok := rR.buckets[key]
+	if !ok {
+		bucket = tokenBucket{tokens: capacity, updatedAt: now}
+	}
+
+	if elapsed := now.Sub(bucket.updatedAt); elapsed > 0 {
+		bucket.tokens = math.Min(capacity, bucket.tokens+elapsed.Seconds()*rate)
+		bucket.updatedAt = now
+	}
+
+	status := domain.RateLimitStatus{Limit: limit.Requests}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		status.Allowed = true
+	} else {
+		status.RetryAfter = secondsDuration((1 - bucket.tokens) / rate)
+	}
+
+	bucket.period = limit.Period
+	rR.buckets[key] = bucket
+	status.Remaining = int64(bucket.tokens)
+	status.ResetAfter = secondsDuration((capacity - bucket.tokens) / rate)
+	return status, nil
+}
+
+/* drops the buckets that have filled up again, at most once per sweep interval */
+func (rR *InMemoryRateLimitRepository) sweep(now time.Time) {
+	if now.Sub(rR.sweptAt) < rateLimitSweepInterval {
+		return
+	}
+
+	// an empty bucket is full again after a whole period
+	for key, bucket := range rR.buckets {
+		if !bucket.updatedAt.Add(bucket.period).After(now) {
+			delete(rR.buckets, key)
+		}
+	}
+
+	rR.sweptAt = now
+}
+
+/* Converts a number of seconds to a duration */
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
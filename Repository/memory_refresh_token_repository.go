@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	domain "task_manager_api/Domain"
+)
+
+/*
+Implements the RefreshTokenRepositoryInterface defined in `domain` by keeping
+the refresh tokens in memory. The repository is safe for concurrent use.
+*/
+type InMemoryRefreshTokenRepository struct {
+	mutex  sync.Mutex
+	tokens map[string]domain.RefreshToken
+}
+
+/* Creates an empty in-memory refresh token repository */
+func NewInMemoryRefreshTokenRepository() *InMemoryRefreshTokenRepository {
+	return &InMemoryRefreshTokenRepository{tokens: map[string]domain.RefreshToken{}}
+}
+
+/* Adds the refresh token to the repository */
+func (rR *InMemoryRefreshTokenRepository) CreateRefreshToken(c context.Context, token domain.RefreshToken) domain.CodedError {
+	rR.mutex.Lock()
+	defer rR.mutex.Unlock()
+
+	if _, ok := rR.tokens[token.TokenHash]; ok {
+		return domain.UserError{Message: "Internal server error: duplicate refresh token", Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	rR.tokens[token.TokenHash] = token
+	return nil
+}
+
+/* retrieves the refresh token with the provided hash if it exists */
+func (rR *InMemoryRefreshTokenRepository) GetRefreshToken(c context.Context, tokenHash string) (domain.RefreshToken, domain.CodedError) {
+	rR.mutex.Lock()
+	defer rR.mutex.Unlock()
+
+	token, ok := rR.tokens[tokenHash]
+	if !ok {
+		return domain.RefreshToken{}, domain.UserError{Message: "Refresh token not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return token, nil
+}
+
+/*
+marks the refresh token with the provided hash as used and returns it. Only
+tokens that have neither been used nor revoked are matched.
+*/
+func (rR *InMemoryRefreshTokenRepository) UseRefreshToken(c context.Context, tokenHash string) (domain.RefreshToken, domain.CodedError) {
+	rR.mutex.Lock()
+	defer rR.mutex.Unlock()
+
+	token, ok := rR.tokens[tokenHash]
+	if !ok || token.Used || token.Revoked {
+		return domain.RefreshToken{}, domain.UserError{Message: "Refresh token not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	token.Used = true
+	rR.tokens[tokenHash] = token
+	return token, nil
+}
+
+/* revokes every refresh token that matches the provided predicate */
+func (rR *InMemoryRefreshTokenRepository) revokeTokens(matches func(token domain.RefreshToken) bool) {
+	rR.mutex.Lock()
+	defer rR.mutex.Unlock()
+
+	for tokenHash, token := range rR.tokens {
+		if matches(token) {
+			token.Revoked = true
+			rR.tokens[tokenHash] = token
+		}
+	}
+}
+
+/* revokes every refresh token that belongs to the provided family */
+func (rR *InMemoryRefreshTokenRepository) RevokeTokenFamily(c context.Context, familyID string) domain.CodedError {
+	rR.revokeTokens(func(token domain.RefreshToken) bool { return token.FamilyID == familyID })
+	return nil
+}
+
+/* revokes every refresh token that has been issued to the provided user */
+func (rR *InMemoryRefreshTokenRepository) RevokeUserTokens(c context.Context, username string) domain.CodedError {
+	rR.revokeTokens(func(token domain.RefreshToken) bool { return token.Username == username })
+	return nil
+}
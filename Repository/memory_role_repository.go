@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	domain "task_manager_api/Domain"
+)
+
+/*
+Implements the RoleRepositoryInterface defined in `domain` by keeping the
+roles in memory. The repository is safe for concurrent use.
+*/
+type InMemoryRoleRepository struct {
+	mutex sync.RWMutex
+	roles map[string]domain.Role
+}
+
+/* Creates an empty in-memory role repository */
+func NewInMemoryRoleRepository() *InMemoryRoleRepository {
+	return &InMemoryRoleRepository{roles: map[string]domain.Role{}}
+}
+
+/* retrieves the role with the provided name if it exists */
+func (rR *InMemoryRoleRepository) GetRole(c context.Context, name string) (domain.Role, domain.CodedError) {
+	rR.mutex.RLock()
+	defer rR.mutex.RUnlock()
+
+	role, ok := rR.roles[name]
+	if !ok {
+		return domain.Role{}, domain.UserError{Message: "Role not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	role.Permissions = slices.Clone(role.Permissions)
+	return role, nil
+}
+
+/* retrieves the stored roles sorted by name */
+func (rR *InMemoryRoleRepository) GetRoles(c context.Context) ([]domain.Role, domain.CodedError) {
+	rR.mutex.RLock()
+	defer rR.mutex.RUnlock()
+
+	roles := []domain.Role{}
+	for _, role := range rR.roles {
+		role.Permissions = slices.Clone(role.Permissions)
+		roles = append(roles, role)
+	}
+
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles, nil
+}
+
+/* creates the role or replaces the existing role with the same name */
+func (rR *InMemoryRoleRepository) SaveRole(c context.Context, role domain.Role) domain.CodedError {
+	rR.mutex.Lock()
+	defer rR.mutex.Unlock()
+
+	role.Permissions = slices.Clone(role.Permissions)
+	rR.roles[role.Name] = role
+	return nil
+}
+
+/* deletes the role with the provided name */
+func (rR *InMemoryRoleRepository) DeleteRole(c context.Context, name string) domain.CodedError {
+	rR.mutex.Lock()
+	defer rR.mutex.Unlock()
+
+	if _, ok := rR.roles[name]; !ok {
+		return domain.UserError{Message: "Role not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	delete(rR.roles, name)
+	return nil
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	domain "task_manager_api/Domain"
+	"time"
+)
+
+/*
+Implements the SessionRepositoryInterface defined in `domain` by keeping the
+sessions in memory. The repository is safe for concurrent use.
+*/
+type InMemorySessionRepository struct {
+	mutex    sync.RWMutex
+	sessions map[string]domain.Session
+}
+
+/* Creates an empty in-memory session repository */
+func NewInMemorySessionRepository() *InMemorySessionRepository {
+	return &InMemorySessionRepository{sessions: map[string]domain.Session{}}
+}
+
+/* Adds the session to the repository */
+func (sR *InMemorySessionRepository) CreateSession(c context.Context, session domain.Session) domain.CodedError {
+	sR.mutex.Lock()
+	defer sR.mutex.Unlock()
+
+	if _, ok := sR.sessions[session.ID]; ok {
+		return domain.UserError{Message: "Internal server error: duplicate session", Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	session.Current = false
+	sR.sessions[session.ID] = session
+	return nil
+}
+
+/* retrieves the session with the provided ID if it exists */
+func (sR *InMemorySessionRepository) GetSession(c context.Context, sessionID string) (domain.Session, domain.CodedError) {
+	sR.mutex.RLock()
+	defer sR.mutex.RUnlock()
+
+	session, ok := sR.sessions[sessionID]
+	if !ok {
+		return domain.Session{}, domain.UserError{Message: "Session not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return session, nil
+}
+
+/* retrieves the sessions of the user that have neither expired nor been revoked, most recent first */
+func (sR *InMemorySessionRepository) GetActiveSessions(c context.Context, username string) ([]domain.Session, domain.CodedError) {
+	sR.mutex.RLock()
+	defer sR.mutex.RUnlock()
+
+	now := time.Now()
+	sessions := []domain.Session{}
+	for _, session := range sR.sessions {
+		if session.Username == username && !session.Revoked && session.ExpiresAt.After(now) {
+			sessions = append(sessions, session)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].CreatedAt.Equal(sessions[j].CreatedAt) {
+			return sessions[i].ID < sessions[j].ID
+		}
+
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+
+	return sessions, nil
+}
+
+/* records the use of the session and moves its expiry date */
+func (sR *InMemorySessionRepository) ExtendSession(c context.Context, sessionID string, lastUsedAt time.Time, expiresAt time.Time) domain.CodedError {
+	sR.mutex.Lock()
+	defer sR.mutex.Unlock()
+
+	session, ok := sR.sessions[sessionID]
+	if !ok {
+		return domain.UserError{Message: "Session not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	session.LastUsedAt = lastUsedAt
+	session.ExpiresAt = expiresAt
+	sR.sessions[sessionID] = session
+	return nil
+}
+
+/* revokes the session with the provided ID */
+func (sR *InMemorySessionRepository) RevokeSession(c context.Context, sessionID string) domain.CodedError {
+	sR.mutex.Lock()
+	defer sR.mutex.Unlock()
+
+	session, ok := sR.sessions[sessionID]
+	if !ok {
+		return domain.UserError{Message: "Session not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	session.Revoked = true
+	sR.sessions[sessionID] = session
+	return nil
+}
+
+/* revokes every session of the provided user */
+func (sR *InMemorySessionRepository) RevokeUserSessions(c context.Context, username string) domain.CodedError {
+	sR.mutex.Lock()
+	defer sR.mutex.Unlock()
+
+	for sessionID, session := range sR.sessions {
+		if session.Username == username {
+			session.Revoked = true
+			sR.sessions[sessionID] = session
+		}
+	}
+
+	return nil
+}
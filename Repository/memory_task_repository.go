@@ -0,0 +1,313 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	domain "task_manager_api/Domain"
+	"time"
+)
+
+/*
+Implements the TaskRespositoryInterface defined in `domain` by keeping the
+tasks in memory. It behaves like the mongoDB implementation and is safe for
+concurrent use. The stored tasks are lost when the process exits.
+*/
+type InMemoryTaskRepository struct {
+	mutex sync.RWMutex
+	tasks map[string]domain.Task
+}
+
+/* Creates an empty in-memory task repository */
+func NewInMemoryTaskRepository() *InMemoryTaskRepository {
+	return &InMemoryTaskRepository{tasks: map[string]domain.Task{}}
+}
+
+/* returns a copy of the task that does not share its status history */
+func copyTask(task domain.Task) domain.Task {
+	if task.StatusHistory != nil {
+		task.StatusHistory = append([]domain.StatusChange{}, task.StatusHistory...)
+	}
+
+	return task
+}
+
+/* checks whether the task matches the filters of the query */
+func matchesTaskQuery(task domain.Task, query domain.TaskQuery) bool {
+	switch {
+	case query.VisibleTo != "" && task.Owner != query.VisibleTo && task.Assignee != query.VisibleTo:
+		return false
+	case query.Status != "" && task.Status != query.Status:
+		return false
+	case query.Title != "" && !strings.Contains(strings.ToLower(task.Title), strings.ToLower(query.Title)):
+		return false
+	}
+
+	// tasks without a due date never match a due date range
+	if !query.DueAfter.IsZero() && (task.DueDate.IsZero() || task.DueDate.Before(query.DueAfter)) {
+		return false
+	}
+
+	if !query.DueBefore.IsZero() && (task.DueDate.IsZero() || task.DueDate.After(query.DueBefore)) {
+		return false
+	}
+
+	return true
+}
+
+/* compares the tasks by the provided field, returning a negative number if the first one comes first */
+func compareTasks(first domain.Task, second domain.Task, field string) int {
+	switch field {
+	case "title":
+		return strings.Compare(first.Title, second.Title)
+	case "status":
+		return strings.Compare(first.Status, second.Status)
+	case "due_date":
+		return first.DueDate.Compare(second.DueDate)
+	case "created_at":
+		return first.CreatedAt.Compare(second.CreatedAt)
+	case "updated_at":
+		return first.UpdatedAt.Compare(second.UpdatedAt)
+	}
+
+	return strings.Compare(first.ID, second.ID)
+}
+
+/*
+retrieves a page of the tasks that match the filters of the provided query
+along with the total number of matching tasks
+*/
+func (tR *InMemoryTaskRepository) GetAllTasks(c context.Context, query domain.TaskQuery) ([]domain.Task, int64, domain.CodedError) {
+	tR.mutex.RLock()
+	defer tR.mutex.RUnlock()
+
+	tasks := []domain.Task{}
+	for _, task := range tR.tasks {
+		if matchesTaskQuery(task, query) {
+			tasks = append(tasks, copyTask(task))
+		}
+	}
+
+	// the id is used as a tie breaker to keep the order stable across pages
+	sort.Slice(tasks, func(i, j int) bool {
+		comparison := compareTasks(tasks[i], tasks[j], query.SortBy)
+		if query.SortOrder == domain.SortDescending {
+			comparison = -comparison
+		}
+
+		if comparison == 0 {
+			return tasks[i].ID < tasks[j].ID
+		}
+
+		return comparison < 0
+	})
+
+	total := int64(len(tasks))
+	start := min(query.Offset, total)
+	end := total
+	if query.Limit > 0 {
+		end = min(start+query.Limit, total)
+	}
+
+	return tasks[start:end], total, nil
+}
+
+/* retrieves the task associated with the provided id if it exists */
+func (tR *InMemoryTaskRepository) GetTaskByID(c context.Context, taskID string) (domain.Task, domain.CodedError) {
+	tR.mutex.RLock()
+	defer tR.mutex.RUnlock()
+
+	task, ok := tR.tasks[taskID]
+	if !ok {
+		return domain.Task{}, domain.TaskError{Message: "Task not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return copyTask(task), nil
+}
+
+/* adds the provided task to the repository, rejecting tasks with duplicate IDs */
+func (tR *InMemoryTaskRepository) AddTask(c context.Context, newTask domain.Task) domain.CodedError {
+	tR.mutex.Lock()
+	defer tR.mutex.Unlock()
+
+	if _, ok := tR.tasks[newTask.ID]; ok {
+		return domain.TaskError{Message: "Task with the provided ID already exists", Code: domain.ERR_CONFLICT}
+	}
+
+	tR.tasks[newTask.ID] = copyTask(newTask)
+	return nil
+}
+
+/*
+applies the update to the task associated with the provided id and increments
+its version while holding the lock. The update is only applied if the stored
+version matches the provided version (unless it is zero). Returns the task as
+it is after the update.
+*/
+func (tR *InMemoryTaskRepository) applyUpdate(taskID string, version int64, update func(task *domain.Task) domain.CodedError) (domain.Task, domain.CodedError) {
+	tR.mutex.Lock()
+	defer tR.mutex.Unlock()
+
+	task, ok := tR.tasks[taskID]
+	if !ok {
+		return domain.Task{}, domain.TaskError{Message: "Task not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if version != 0 && task.Version != version {
+		return domain.Task{}, domain.TaskError{Message: "Task has been modified since it was last fetched", Code: domain.ERR_PRECONDITION_FAILED}
+	}
+
+	if err := update(&task); err != nil {
+		return domain.Task{}, err
+	}
+
+	task.Version++
+	tR.tasks[taskID] = copyTask(task)
+	return copyTask(task), nil
+}
+
+/*
+replaces the mutable fields of the task associated with the provided id with
+the ones in the provided task struct, along with its status history. The id,
+owner and creation time of the task are never modified.
+*/
+func (tR *InMemoryTaskRepository) UpdateTask(c context.Context, taskID string, updatedTask domain.Task, version int64) (domain.Task, domain.CodedError) {
+	return tR.applyUpdate(taskID, version, func(task *domain.Task) domain.CodedError {
+		task.Title = updatedTask.Title
+		task.Description = updatedTask.Description
+		task.Status = updatedTask.Status
+		task.Assignee = updatedTask.Assignee
+		task.DueDate = updatedTask.DueDate
+		task.UpdatedAt = updatedTask.UpdatedAt
+		task.StatusHistory = updatedTask.StatusHistory
+		return nil
+	})
+}
+
+/*
+updates only the fields of the task associated with the provided id that are
+present in the patch. Fields with nil values are removed from the task.
+*/
+func (tR *InMemoryTaskRepository) PatchTask(c context.Context, taskID string, patch domain.TaskPatch, version int64) (domain.Task, domain.CodedError) {
+	return tR.applyUpdate(taskID, version, func(task *domain.Task) domain.CodedError {
+		return applyPatchToTask(task, patch)
+	})
+}
+
+/*
+sets the fields of the task that are present in the patch, clearing the ones
+with nil values. The patch is expected to hold the typed values produced by
+the task usecase.
+*/
+func applyPatchToTask(task *domain.Task, patch domain.TaskPatch) domain.CodedError {
+	patched := *task
+	for key, value := range patch {
+		ok := true
+		switch key {
+		case "title":
+			patched.Title, ok = stringOrEmpty(value)
+		case "description":
+			patched.Description, ok = stringOrEmpty(value)
+		case "status":
+			patched.Status, ok = stringOrEmpty(value)
+		case "assignee":
+			patched.Assignee, ok = stringOrEmpty(value)
+		case "due_date":
+			patched.DueDate, ok = timeOrZero(value)
+		case "updated_at":
+			patched.UpdatedAt, ok = timeOrZero(value)
+		case "status_history":
+			patched.StatusHistory, ok = value.([]domain.StatusChange)
+			ok = ok || value == nil
+		default:
+			ok = false
+		}
+
+		if !ok {
+			return domain.TaskError{Message: "Internal server error: invalid value for " + key, Code: domain.ERR_INTERNAL_SERVER}
+		}
+	}
+
+	*task = patched
+	return nil
+}
+
+/* converts a patch value to a string, treating nil as the empty string */
+func stringOrEmpty(value interface{}) (string, bool) {
+	if value == nil {
+		return "", true
+	}
+
+	text, ok := value.(string)
+	return text, ok
+}
+
+/* converts a patch value to a time, treating nil as the zero time */
+func timeOrZero(value interface{}) (time.Time, bool) {
+	if value == nil {
+		return time.Time{}, true
+	}
+
+	date, ok := value.(time.Time)
+	return date, ok
+}
+
+/*
+deletes the task associated with the provided id if it exists. The task is
+only deleted if the stored version matches the provided version (unless it is zero).
+*/
+func (tR *InMemoryTaskRepository) DeleteTask(c context.Context, taskID string, version int64) domain.CodedError {
+	tR.mutex.Lock()
+	defer tR.mutex.Unlock()
+
+	task, ok := tR.tasks[taskID]
+	if !ok {
+		return domain.TaskError{Message: "Task not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if version != 0 && task.Version != version {
+		return domain.TaskError{Message: "Task has been modified since it was last fetched", Code: domain.ERR_PRECONDITION_FAILED}
+	}
+
+	delete(tR.tasks, taskID)
+	return nil
+}
+
+/* deletes every task owned by the provided user and returns how many were deleted */
+func (tR *InMemoryTaskRepository) DeleteOwnerTasks(c context.Context, owner string) (int64, domain.CodedError) {
+	tR.mutex.Lock()
+	defer tR.mutex.Unlock()
+
+	var deleted int64
+	for id, task := range tR.tasks {
+		if task.Owner == owner {
+			delete(tR.tasks, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+/*
+clears the assignee of every task assigned to the provided user and
+increments their versions. Returns how many tasks were unassigned.
+*/
+func (tR *InMemoryTaskRepository) UnassignTasks(c context.Context, assignee string, updatedAt time.Time) (int64, domain.CodedError) {
+	tR.mutex.Lock()
+	defer tR.mutex.Unlock()
+
+	var unassigned int64
+	for id, task := range tR.tasks {
+		if task.Assignee == assignee {
+			task.Assignee = ""
+			task.UpdatedAt = updatedAt
+			task.Version++
+			tR.tasks[id] = task
+			unassigned++
+		}
+	}
+
+	return unassigned, nil
+}
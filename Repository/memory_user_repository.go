@@ -0,0 +1,282 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	domain "task_manager_api/Domain"
+)
+
+/*
+Implements the UserRespositoryInterface defined in `domain` by keeping the
+users in memory. Usernames and emails are unique, just like with the indices
+of the mongoDB implementation, and the repository is safe for concurrent use.
+*/
+type InMemoryUserRepository struct {
+	mutex sync.RWMutex
+	users map[string]domain.User
+}
+
+/* Creates an empty in-memory user repository */
+func NewInMemoryUserRepository() *InMemoryUserRepository {
+	return &InMemoryUserRepository{users: map[string]domain.User{}}
+}
+
+/* returns the value of the user field with the provided (stored) name */
+func userField(user domain.User, key string) (interface{}, bool) {
+	switch key {
+	case "username":
+		return user.Username, true
+	case "email":
+		return user.Email, true
+	case "password":
+		return user.Password, true
+	case "role":
+		return user.Role, true
+	}
+
+	return nil, false
+}
+
+/*
+checks if a user that matches the provided the key-value pair exists and
+returns a CodedError if there are any matches
+*/
+func (uR *InMemoryUserRepository) CheckDuplicate(c context.Context, key string, value interface{}, errorMessage string) domain.CodedError {
+	uR.mutex.RLock()
+	defer uR.mutex.RUnlock()
+
+	for _, user := range uR.users {
+		if field, ok := userField(user, key); ok && field == value {
+			return domain.UserError{Message: "Bad request: duplicate " + key, Code: domain.ERR_BAD_REQUEST}
+		}
+	}
+
+	return nil
+}
+
+/* Adds the user to the repository, rejecting duplicate usernames and emails */
+func (uR *InMemoryUserRepository) CreateUser(c context.Context, user domain.User) domain.CodedError {
+	uR.mutex.Lock()
+	defer uR.mutex.Unlock()
+
+	for _, storedUser := range uR.users {
+		if storedUser.Username == user.Username || storedUser.Email == user.Email {
+			return domain.UserError{Message: "User with the provided username or email already exists", Code: domain.ERR_CONFLICT}
+		}
+	}
+
+	uR.users[user.Username] = user
+	return nil
+}
+
+/*
+queries for a user with the provided username and returns the resulting
+user object if it exists
+*/
+func (uR *InMemoryUserRepository) GetByUsername(c context.Context, username string) (domain.User, domain.CodedError) {
+	uR.mutex.RLock()
+	defer uR.mutex.RUnlock()
+
+	user, ok := uR.users[username]
+	if !ok {
+		return domain.User{}, domain.UserError{Message: "User not found", Code: domain.ERR_BAD_REQUEST}
+	}
+
+	return user, nil
+}
+
+/*
+queries for a user with the provided email and returns the resulting user
+object if it exists
+*/
+func (uR *InMemoryUserRepository) GetByEmail(c context.Context, email string) (domain.User, domain.CodedError) {
+	uR.mutex.RLock()
+	defer uR.mutex.RUnlock()
+
+	for _, user := range uR.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+
+	return domain.User{}, domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND}
+}
+
+/* checks whether the user matches the filters of the query */
+func matchesUserQuery(user domain.User, query domain.UserQuery) bool {
+	if query.Role != "" && user.Role != query.Role {
+		return false
+	}
+
+	search := strings.ToLower(query.Search)
+	return search == "" || strings.Contains(strings.ToLower(user.Username), search) ||
+		strings.Contains(strings.ToLower(user.Email), search) || strings.Contains(strings.ToLower(user.DisplayName), search)
+}
+
+/* compares the users by the provided field, returning a negative number if the first one comes first */
+func compareUsers(first domain.User, second domain.User, field string) int {
+	switch field {
+	case "email":
+		return strings.Compare(first.Email, second.Email)
+	case "created_at":
+		return first.CreatedAt.Compare(second.CreatedAt)
+	}
+
+	return strings.Compare(first.Username, second.Username)
+}
+
+/*
+retrieves a page of the users that match the filters of the provided query
+along with the total number of matching users
+*/
+func (uR *InMemoryUserRepository) GetUsers(c context.Context, query domain.UserQuery) ([]domain.User, int64, domain.CodedError) {
+	uR.mutex.RLock()
+	defer uR.mutex.RUnlock()
+
+	users := []domain.User{}
+	for _, user := range uR.users {
+		if matchesUserQuery(user, query) {
+			users = append(users, user)
+		}
+	}
+
+	// the username is used as a tie breaker to keep the order stable across pages
+	sort.Slice(users, func(i, j int) bool {
+		comparison := compareUsers(users[i], users[j], query.SortBy)
+		if query.SortOrder == domain.SortDescending {
+			comparison = -comparison
+		}
+
+		if comparison == 0 {
+			return users[i].Username < users[j].Username
+		}
+
+		return comparison < 0
+	})
+
+	total := int64(len(users))
+	start := min(query.Offset, total)
+	end := total
+	if query.Limit > 0 {
+		end = min(start+query.Limit, total)
+	}
+
+	return users[start:end], total, nil
+}
+
+/*
+Replaces the email, the display name and the verification status of the user
+with the provided username with the ones in the provided user struct, rejecting
+emails that belong to other users
+*/
+func (uR *InMemoryUserRepository) UpdateProfile(c context.Context, user domain.User) domain.CodedError {
+	uR.mutex.Lock()
+	defer uR.mutex.Unlock()
+
+	storedUser, ok := uR.users[user.Username]
+	if !ok {
+		return domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	for _, otherUser := range uR.users {
+		if otherUser.Username != user.Username && otherUser.Email == user.Email {
+			return domain.UserError{Message: "An account with the provided email already exists", Code: domain.ERR_CONFLICT}
+		}
+	}
+
+	storedUser.Email = user.Email
+	storedUser.DisplayName = user.DisplayName
+	storedUser.Verified = user.Verified
+	uR.users[user.Username] = storedUser
+	return nil
+}
+
+/* Promotes an account with role `user` to role `admin` */
+func (uR *InMemoryUserRepository) PromoteUser(c context.Context, username string) domain.CodedError {
+	uR.mutex.Lock()
+	defer uR.mutex.Unlock()
+
+	user, ok := uR.users[username]
+	if !ok {
+		return domain.UserError{Message: "error: user not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	user.Role = domain.RoleAdmin
+	uR.users[username] = user
+	return nil
+}
+
+/* Demotes an account with role `admin` to role `user` */
+func (uR *InMemoryUserRepository) DemoteUser(c context.Context, username string) domain.CodedError {
+	return uR.update(username, func(user *domain.User) { user.Role = domain.RoleUser })
+}
+
+/* Assigns the role with the provided name to the account */
+func (uR *InMemoryUserRepository) SetRole(c context.Context, username string, role string) domain.CodedError {
+	return uR.update(username, func(user *domain.User) { user.Role = role })
+}
+
+/* Deactivates or activates the account with the provided username */
+func (uR *InMemoryUserRepository) SetDeactivated(c context.Context, username string, deactivated bool) domain.CodedError {
+	return uR.update(username, func(user *domain.User) { user.Deactivated = deactivated })
+}
+
+/* applies the update to the user with the provided username */
+func (uR *InMemoryUserRepository) update(username string, update func(user *domain.User)) domain.CodedError {
+	uR.mutex.Lock()
+	defer uR.mutex.Unlock()
+
+	user, ok := uR.users[username]
+	if !ok {
+		return domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	update(&user)
+	uR.users[username] = user
+	return nil
+}
+
+/* Deletes the account with the provided username */
+func (uR *InMemoryUserRepository) DeleteUser(c context.Context, username string) domain.CodedError {
+	uR.mutex.Lock()
+	defer uR.mutex.Unlock()
+
+	if _, ok := uR.users[username]; !ok {
+		return domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	delete(uR.users, username)
+	return nil
+}
+
+/* Replaces the (hashed) password of the user with the provided username */
+func (uR *InMemoryUserRepository) UpdatePassword(c context.Context, username string, password string) domain.CodedError {
+	uR.mutex.Lock()
+	defer uR.mutex.Unlock()
+
+	user, ok := uR.users[username]
+	if !ok {
+		return domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	user.Password = password
+	uR.users[username] = user
+	return nil
+}
+
+/* Marks the email of the user with the provided username as verified */
+func (uR *InMemoryUserRepository) VerifyEmail(c context.Context, username string) domain.CodedError {
+	uR.mutex.Lock()
+	defer uR.mutex.Unlock()
+
+	user, ok := uR.users[username]
+	if !ok {
+		return domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	user.Verified = true
+	uR.users[username] = user
+	return nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	domain "task_manager_api/Domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/* Implements the MFAChallengeRepositoryInterface defined in `domain`*/
+type MFAChallengeRepository struct {
+	Collection *mongo.Collection
+}
+
+/* Adds the login challenge to the DB */
+func (cR *MFAChallengeRepository) CreateChallenge(c context.Context, challenge domain.MFAChallenge) domain.CodedError {
+	_, err := cR.Collection.InsertOne(c, challenge)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/* retrieves the unused login challenge with the provided hash */
+func (cR *MFAChallengeRepository) GetChallenge(c context.Context, tokenHash string) (domain.MFAChallenge, domain.CodedError) {
+	result := cR.Collection.FindOne(c, bson.D{{Key: "token_hash", Value: tokenHash}, {Key: "used", Value: false}})
+	return decodeChallenge(result)
+}
+
+/*
+marks the login challenge with the provided hash as used and returns it. Only
+unused challenges are matched, which makes sure that concurrent requests can
+not use the same challenge twice.
+*/
+func (cR *MFAChallengeRepository) UseChallenge(c context.Context, tokenHash string) (domain.MFAChallenge, domain.CodedError) {
+	filter := bson.D{{Key: "token_hash", Value: tokenHash}, {Key: "used", Value: false}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "used", Value: true}}}}
+	result := cR.Collection.FindOneAndUpdate(c, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After))
+	return decodeChallenge(result)
+}
+
+/* decodes the login challenge found by the query */
+func decodeChallenge(result *mongo.SingleResult) (domain.MFAChallenge, domain.CodedError) {
+	var challenge domain.MFAChallenge
+	if result.Err() != nil && result.Err().Error() == mongo.ErrNoDocuments.Error() {
+		return challenge, domain.UserError{Message: "Login challenge not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if result.Err() != nil {
+		return challenge, domain.UserError{Message: "Internal server error: " + result.Err().Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if err := result.Decode(&challenge); err != nil {
+		return challenge, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return challenge, nil
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	domain "task_manager_api/Domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/* Implements the MFARepositoryInterface defined in `domain`*/
+type MFARepository struct {
+	Collection *mongo.Collection
+}
+
+/* retrieves the TOTP enrollment of the user */
+func (mR *MFARepository) GetMFA(c context.Context, username string) (domain.MFAEnrollment, domain.CodedError) {
+	var enrollment domain.MFAEnrollment
+	result := mR.Collection.FindOne(c, bson.D{{Key: "username", Value: username}})
+	if result.Err() != nil && result.Err().Error() == mongo.ErrNoDocuments.Error() {
+		return enrollment, domain.UserError{Message: "Two-factor authentication is not set up", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if result.Err() != nil {
+		return enrollment, domain.UserError{Message: "Internal server error: " + result.Err().Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if err := result.Decode(&enrollment); err != nil {
+		return enrollment, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return enrollment, nil
+}
+
+/* creates the TOTP enrollment of the user or replaces the existing one */
+func (mR *MFARepository) SaveMFA(c context.Context, enrollment domain.MFAEnrollment) domain.CodedError {
+	if enrollment.RecoveryCodes == nil {
+		enrollment.RecoveryCodes = []string{}
+	}
+
+	filter := bson.D{{Key: "username", Value: enrollment.Username}}
+	_, err := mR.Collection.ReplaceOne(c, filter, enrollment, options.Replace().SetUpsert(true))
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/*
+records the time step of an accepted TOTP code. Only enrollments whose last
+used step is before the provided one are matched, which makes sure that
+concurrent requests can not use the same code twice.
+*/
+func (mR *MFARepository) UseTOTPStep(c context.Context, username string, step int64) domain.CodedError {
+	filter := bson.D{{Key: "username", Value: username}, {Key: "last_used_step", Value: bson.D{{Key: "$lt", Value: step}}}}
+	result, err := mR.Collection.UpdateOne(c, filter, bson.D{{Key: "$set", Value: bson.D{{Key: "last_used_step", Value: step}}}})
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if result.MatchedCount == 0 {
+		return mR.missingOrUsed(c, username)
+	}
+
+	return nil
+}
+
+/* tells apart a missing enrollment from a code that has already been used */
+func (mR *MFARepository) missingOrUsed(c context.Context, username string) domain.CodedError {
+	count, err := mR.Collection.CountDocuments(c, bson.D{{Key: "username", Value: username}})
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if count == 0 {
+		return domain.UserError{Message: "Two-factor authentication is not set up", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return domain.UserError{Message: "Authentication code has already been used", Code: domain.ERR_CONFLICT}
+}
+
+/*
+removes the recovery code with the provided hash from the enrollment of the
+user. The code is matched and pulled in a single update, so each recovery
+code can only be used once even with concurrent requests.
+*/
+func (mR *MFARepository) UseRecoveryCode(c context.Context, username string, codeHash string) domain.CodedError {
+	filter := bson.D{{Key: "username", Value: username}, {Key: "recovery_codes", Value: codeHash}}
+	result, err := mR.Collection.UpdateOne(c, filter, bson.D{{Key: "$pull", Value: bson.D{{Key: "recovery_codes", Value: codeHash}}}})
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.UserError{Message: "Recovery code not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
+
+/* deletes the TOTP enrollment of the user along with its recovery codes */
+func (mR *MFARepository) DeleteMFA(c context.Context, username string) domain.CodedError {
+	result, err := mR.Collection.DeleteOne(c, bson.D{{Key: "username", Value: username}})
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if result.DeletedCount == 0 {
+		return domain.UserError{Message: "Two-factor authentication is not set up", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	domain "task_manager_api/Domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/* Implements the PasswordResetTokenRepositoryInterface defined in `domain`*/
+type PasswordResetTokenRepository struct {
+	Collection *mongo.Collection
+}
+
+/* Adds the password reset token to the DB */
+func (pR *PasswordResetTokenRepository) CreateResetToken(c context.Context, token domain.PasswordResetToken) domain.CodedError {
+	_, err := pR.Collection.InsertOne(c, token)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/*
+marks the password reset token with the provided hash as used and returns
+it. Only unused tokens are matched, which makes sure that concurrent requests
+can not use the same token twice.
+*/
+func (pR *PasswordResetTokenRepository) UseResetToken(c context.Context, tokenHash string) (domain.PasswordResetToken, domain.CodedError) {
+	var token domain.PasswordResetToken
+	filter := bson.D{{Key: "token_hash", Value: tokenHash}, {Key: "used", Value: false}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "used", Value: true}}}}
+	result := pR.Collection.FindOneAndUpdate(c, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After))
+	if result.Err() != nil && result.Err().Error() == mongo.ErrNoDocuments.Error() {
+		return token, domain.UserError{Message: "Password reset token not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if result.Err() != nil {
+		return token, domain.UserError{Message: "Internal server error: " + result.Err().Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if err := result.Decode(&token); err != nil {
+		return token, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return token, nil
+}
+
+/* marks every unused password reset token of the provided user as used */
+func (pR *PasswordResetTokenRepository) InvalidateUserResetTokens(c context.Context, username string) domain.CodedError {
+	_, err := pR.Collection.UpdateMany(c, bson.D{{Key: "username", Value: username}, {Key: "used", Value: false}}, bson.D{{Key: "$set", Value: bson.D{{Key: "used", Value: true}}}})
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	domain "task_manager_api/Domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/* Implements the RefreshTokenRepositoryInterface defined in `domain`*/
+type RefreshTokenRepository struct {
+	Collection *mongo.Collection
+}
+
+/* Adds the refresh token to the DB */
+func (rR *RefreshTokenRepository) CreateRefreshToken(c context.Context, token domain.RefreshToken) domain.CodedError {
+	_, err := rR.Collection.InsertOne(c, token)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/* retrieves the refresh token with the provided hash if it exists */
+func (rR *RefreshTokenRepository) GetRefreshToken(c context.Context, tokenHash string) (domain.RefreshToken, domain.CodedError) {
+	var token domain.RefreshToken
+	result := rR.Collection.FindOne(c, bson.D{{Key: "token_hash", Value: tokenHash}})
+	if result.Err() != nil && result.Err().Error() == mongo.ErrNoDocuments.Error() {
+		return token, domain.UserError{Message: "Refresh token not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if result.Err() != nil {
+		return token, domain.UserError{Message: "Internal server error: " + result.Err().Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if err := result.Decode(&token); err != nil {
+		return token, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return token, nil
+}
+
+/*
+marks the refresh token with the provided hash as used and returns it. Only
+tokens that have neither been used nor revoked are matched, which makes sure
+that concurrent requests can not rotate the same token twice.
+*/
+func (rR *RefreshTokenRepository) UseRefreshToken(c context.Context, tokenHash string) (domain.RefreshToken, domain.CodedError) {
+	var token domain.RefreshToken
+	filter := bson.D{{Key: "token_hash", Value: tokenHash}, {Key: "used", Value: false}, {Key: "revoked", Value: false}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "used", Value: true}}}}
+	result := rR.Collection.FindOneAndUpdate(c, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After))
+	if result.Err() != nil && result.Err().Error() == mongo.ErrNoDocuments.Error() {
+		return token, domain.UserError{Message: "Refresh token not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if result.Err() != nil {
+		return token, domain.UserError{Message: "Internal server error: " + result.Err().Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if err := result.Decode(&token); err != nil {
+		return token, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return token, nil
+}
+
+/* revokes every refresh token that belongs to the provided family */
+func (rR *RefreshTokenRepository) RevokeTokenFamily(c context.Context, familyID string) domain.CodedError {
+	_, err := rR.Collection.UpdateMany(c, bson.D{{Key: "family_id", Value: familyID}}, bson.D{{Key: "$set", Value: bson.D{{Key: "revoked", Value: true}}}})
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/* revokes every refresh token that has been issued to the provided user */
+func (rR *RefreshTokenRepository) RevokeUserTokens(c context.Context, username string) domain.CodedError {
+	_, err := rR.Collection.UpdateMany(c, bson.D{{Key: "username", Value: username}}, bson.D{{Key: "$set", Value: bson.D{{Key: "revoked", Value: true}}}})
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	domain "task_manager_api/Domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/* Implements the RoleRepositoryInterface defined in `domain`*/
+type RoleRepository struct {
+	Collection *mongo.Collection
+}
+
+/* retrieves the role with the provided name if it exists */
+func (rR *RoleRepository) GetRole(c context.Context, name string) (domain.Role, domain.CodedError) {
+	var role domain.Role
+	result := rR.Collection.FindOne(c, bson.D{{Key: "name", Value: name}})
+	if result.Err() != nil && result.Err().Error() == mongo.ErrNoDocuments.Error() {
+		return role, domain.UserError{Message: "Role not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if result.Err() != nil {
+		return role, domain.UserError{Message: "Internal server error: " + result.Err().Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if err := result.Decode(&role); err != nil {
+		return role, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return role, nil
+}
+
+/* retrieves the stored roles sorted by name */
+func (rR *RoleRepository) GetRoles(c context.Context) ([]domain.Role, domain.CodedError) {
+	cursor, err := rR.Collection.Find(c, bson.D{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return []domain.Role{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	roles := []domain.Role{}
+	if err := cursor.All(c, &roles); err != nil {
+		return []domain.Role{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return roles, nil
+}
+
+/* creates the role or replaces the existing role with the same name */
+func (rR *RoleRepository) SaveRole(c context.Context, role domain.Role) domain.CodedError {
+	_, err := rR.Collection.UpdateOne(
+		c,
+		bson.D{{Key: "name", Value: role.Name}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "permissions", Value: role.Permissions}, {Key: "mfa_required", Value: role.MFARequired}}}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/* deletes the role with the provided name */
+func (rR *RoleRepository) DeleteRole(c context.Context, name string) domain.CodedError {
+	result, err := rR.Collection.DeleteOne(c, bson.D{{Key: "name", Value: name}})
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if result.DeletedCount == 0 {
+		return domain.UserError{Message: "Role not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
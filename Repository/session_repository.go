@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	domain "task_manager_api/Domain"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/* Implements the SessionRepositoryInterface defined in `domain`*/
+type SessionRepository struct {
+	Collection *mongo.Collection
+}
+
+/* Adds the session to the DB */
+func (sR *SessionRepository) CreateSession(c context.Context, session domain.Session) domain.CodedError {
+	_, err := sR.Collection.InsertOne(c, session)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/* retrieves the session with the provided ID if it exists */
+func (sR *SessionRepository) GetSession(c context.Context, sessionID string) (domain.Session, domain.CodedError) {
+	var session domain.Session
+	result := sR.Collection.FindOne(c, bson.D{{Key: "id", Value: sessionID}})
+	if result.Err() != nil && result.Err().Error() == mongo.ErrNoDocuments.Error() {
+		return session, domain.UserError{Message: "Session not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if result.Err() != nil {
+		return session, domain.UserError{Message: "Internal server error: " + result.Err().Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if err := result.Decode(&session); err != nil {
+		return session, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return session, nil
+}
+
+/* retrieves the sessions of the user that have neither expired nor been revoked, most recent first */
+func (sR *SessionRepository) GetActiveSessions(c context.Context, username string) ([]domain.Session, domain.CodedError) {
+	filter := bson.D{
+		{Key: "username", Value: username},
+		{Key: "revoked", Value: false},
+		{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: time.Now()}}},
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "id", Value: 1}})
+	cursor, err := sR.Collection.Find(c, filter, findOptions)
+	if err != nil {
+		return []domain.Session{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	sessions := []domain.Session{}
+	if err := cursor.All(c, &sessions); err != nil {
+		return []domain.Session{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return sessions, nil
+}
+
+/* records the use of the session and moves its expiry date */
+func (sR *SessionRepository) ExtendSession(c context.Context, sessionID string, lastUsedAt time.Time, expiresAt time.Time) domain.CodedError {
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "last_used_at", Value: lastUsedAt}, {Key: "expires_at", Value: expiresAt}}}}
+	result, err := sR.Collection.UpdateOne(c, bson.D{{Key: "id", Value: sessionID}}, update)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.UserError{Message: "Session not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
+
+/* revokes the session with the provided ID */
+func (sR *SessionRepository) RevokeSession(c context.Context, sessionID string) domain.CodedError {
+	result, err := sR.Collection.UpdateOne(c, bson.D{{Key: "id", Value: sessionID}}, bson.D{{Key: "$set", Value: bson.D{{Key: "revoked", Value: true}}}})
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.UserError{Message: "Session not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
+
+/* revokes every session of the provided user */
+func (sR *SessionRepository) RevokeUserSessions(c context.Context, username string) domain.CodedError {
+	_, err := sR.Collection.UpdateMany(c, bson.D{{Key: "username", Value: username}}, bson.D{{Key: "$set", Value: bson.D{{Key: "revoked", Value: true}}}})
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
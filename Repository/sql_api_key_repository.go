@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	domain "task_manager_api/Domain"
+	"time"
+)
+
+/* The columns of the `api_keys` table in the order they are scanned */
+const apiKeyColumns = "id, username, name, prefix, key_hash, scopes, created_at, expires_at, last_used_at"
+
+/*
+Implements the APIKeyRepositoryInterface defined in `domain` with a SQL
+database. The scopes of a key are stored as a JSON array.
+*/
+type SQLAPIKeyRepository struct {
+	DB      *sql.DB
+	Dialect SQLDialect
+}
+
+/* reads an API key from a row that holds the `apiKeyColumns` */
+func scanAPIKey(row rowScanner) (domain.APIKey, error) {
+	var key domain.APIKey
+	var scopes string
+	var expiresAt, lastUsedAt sql.NullTime
+
+	err := row.Scan(&key.ID, &key.Username, &key.Name, &key.Prefix, &key.KeyHash, &scopes, &key.CreatedAt, &expiresAt, &lastUsedAt)
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+
+	key.ExpiresAt = timeFromSQL(expiresAt)
+	key.LastUsedAt = timeFromSQL(lastUsedAt)
+	if err := json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return domain.APIKey{}, err
+	}
+
+	return key, nil
+}
+
+/* Adds the API key to the DB */
+func (aR *SQLAPIKeyRepository) CreateAPIKey(c context.Context, key domain.APIKey) domain.CodedError {
+	if key.Scopes == nil {
+		key.Scopes = []string{}
+	}
+
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	_, err = aR.DB.ExecContext(c, aR.Dialect.rebind("INSERT INTO api_keys ("+apiKeyColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+		key.ID, key.Username, key.Name, key.Prefix, key.KeyHash, string(scopes), key.CreatedAt.UTC(), sqlTime(key.ExpiresAt), sqlTime(key.LastUsedAt))
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/* retrieves the API keys of the user, most recent first */
+func (aR *SQLAPIKeyRepository) GetAPIKeys(c context.Context, username string) ([]domain.APIKey, domain.CodedError) {
+	statement := "SELECT " + apiKeyColumns + " FROM api_keys WHERE username = ? ORDER BY created_at DESC, id ASC"
+	rows, err := aR.DB.QueryContext(c, aR.Dialect.rebind(statement), username)
+	if err != nil {
+		return []domain.APIKey{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+	defer rows.Close()
+
+	keys := []domain.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return []domain.APIKey{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []domain.APIKey{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return keys, nil
+}
+
+/* retrieves the API key with the provided hash if it exists */
+func (aR *SQLAPIKeyRepository) GetAPIKeyByHash(c context.Context, keyHash string) (domain.APIKey, domain.CodedError) {
+	key, err := scanAPIKey(aR.DB.QueryRowContext(c, aR.Dialect.rebind("SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = ?"), keyHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.APIKey{}, domain.UserError{Message: "API key not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if err != nil {
+		return domain.APIKey{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return key, nil
+}
+
+/* records the use of the API key with the provided ID */
+func (aR *SQLAPIKeyRepository) TouchAPIKey(c context.Context, keyID string, usedAt time.Time) domain.CodedError {
+	result, err := aR.DB.ExecContext(c, aR.Dialect.rebind("UPDATE api_keys SET last_used_at = ? WHERE id = ?"), usedAt.UTC(), keyID)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return domain.UserError{Message: "API key not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
+
+/* deletes the API key with the provided ID if it belongs to the user */
+func (aR *SQLAPIKeyRepository) DeleteAPIKey(c context.Context, username string, keyID string) domain.CodedError {
+	result, err := aR.DB.ExecContext(c, aR.Dialect.rebind("DELETE FROM api_keys WHERE id = ? AND username = ?"), keyID, username)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return domain.UserError{Message: "API key not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
+
+/* deletes every API key of the provided user */
+func (aR *SQLAPIKeyRepository) DeleteUserAPIKeys(c context.Context, username string) domain.CodedError {
+	_, err := aR.DB.ExecContext(c, aR.Dialect.rebind("DELETE FROM api_keys WHERE username = ?"), username)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
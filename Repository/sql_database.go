@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+/* The SQL databases the SQL repositories can be used with */
+type SQLDialect string
+
+const (
+	DialectSQLite   SQLDialect = "sqlite"
+	DialectPostgres SQLDialect = "postgres"
+)
+
+/* returns the name of the `database/sql` driver of the dialect */
+func (d SQLDialect) driverName() string {
+	if d == DialectPostgres {
+		return "pgx"
+	}
+
+	return "sqlite"
+}
+
+/*
+rewrites the `?` placeholders of the query to the numbered `$n` placeholders
+expected by PostgreSQL. Queries are left unchanged for SQLite.
+*/
+func (d SQLDialect) rebind(query string) string {
+	if d != DialectPostgres {
+		return query
+	}
+
+	var builder strings.Builder
+	count := 0
+	for _, char := range query {
+		if char == '?' {
+			count++
+			builder.WriteString(fmt.Sprintf("$%v", count))
+			continue
+		}
+
+		builder.WriteRune(char)
+	}
+
+	return builder.String()
+}
+
+/* replaces the type placeholders of a migration statement with the types of the dialect */
+func (d SQLDialect) expandTypes(statement string) string {
+	timestampType := "TIMESTAMP"
+	if d == DialectPostgres {
+		timestampType = "TIMESTAMPTZ"
+	}
+
+	return strings.ReplaceAll(statement, "{timestamp}", timestampType)
+}
+
+/* returns the LIMIT clause that does not restrict the number of rows */
+func (d SQLDialect) noLimit() string {
+	if d == DialectPostgres {
+		return "LIMIT ALL"
+	}
+
+	return "LIMIT -1"
+}
+
+/*
+Opens a connection pool to the database described by the provided data
+source name and verifies the connection. SQLite databases are limited to a
+single connection since SQLite does not allow concurrent writes, which also
+keeps `:memory:` databases from being split across connections.
+*/
+func OpenSQLDatabase(dialect SQLDialect, dataSourceName string) (*sql.DB, error) {
+	if dialect != DialectSQLite && dialect != DialectPostgres {
+		return nil, fmt.Errorf("error: unknown SQL dialect '%v'", dialect)
+	}
+
+	if dataSourceName == "" {
+		return nil, fmt.Errorf("error: DB connection string not found. Make sure the environment variables are set correctly")
+	}
+
+	db, err := sql.Open(dialect.driverName(), dataSourceName)
+	if err != nil {
+		return nil, fmt.Errorf("error: %v", err.Error())
+	}
+
+	if dialect == DialectSQLite {
+		db.SetMaxOpenConns(1)
+	}
+
+	if err := db.PingContext(context.TODO()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error: %v", err.Error())
+	}
+
+	return db, nil
+}
+
+/* checks whether the error was caused by a unique or primary key constraint */
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+
+	var postgresErr *pgconn.PgError
+	if errors.As(err, &postgresErr) {
+		return postgresErr.Code == "23505"
+	}
+
+	return false
+}
+
+/* a row returned by either `QueryRow` or `Query` */
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+/*
+converts a time to UTC before it is stored so that the stored times can be
+compared as text by SQLite. Zero times are stored as NULL.
+*/
+func sqlTime(value time.Time) any {
+	if value.IsZero() {
+		return nil
+	}
+
+	return value.UTC()
+}
+
+/* converts a nullable stored time back to a time, NULL being the zero time */
+func timeFromSQL(value sql.NullTime) time.Time {
+	if !value.Valid {
+		return time.Time{}
+	}
+
+	return value.Time
+}
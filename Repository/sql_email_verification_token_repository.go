@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	domain "task_manager_api/Domain"
+)
+
+/* Implements the EmailVerificationTokenRepositoryInterface defined in `domain` with a SQL database */
+type SQLEmailVerificationTokenRepository struct {
+	DB      *sql.DB
+	Dialect SQLDialect
+}
+
+/* Adds the email verification token to the DB */
+func (vR *SQLEmailVerificationTokenRepository) CreateVerificationToken(c context.Context, token domain.EmailVerificationToken) domain.CodedError {
+	_, err := vR.DB.ExecContext(c, vR.Dialect.rebind("INSERT INTO email_verification_tokens (token_hash, username, created_at, expires_at, used) VALUES (?, ?, ?, ?, ?)"),
+		token.TokenHash, token.Username, token.CreatedAt.UTC(), token.ExpiresAt.UTC(), token.Used)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/*
+atomically marks the email verification token with the provided hash as used and
+returns it. Only unused tokens are matched, so a token can only be used once
+even with concurrent requests.
+*/
+func (vR *SQLEmailVerificationTokenRepository) UseVerificationToken(c context.Context, tokenHash string) (domain.EmailVerificationToken, domain.CodedError) {
+	var token domain.EmailVerificationToken
+	statement := "UPDATE email_verification_tokens SET used = TRUE WHERE token_hash = ? AND used = FALSE RETURNING token_hash, username, created_at, expires_at, used"
+	err := vR.DB.QueryRowContext(c, vR.Dialect.rebind(statement), tokenHash).Scan(&token.TokenHash, &token.Username, &token.CreatedAt, &token.ExpiresAt, &token.Used)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.EmailVerificationToken{}, domain.UserError{Message: "Email verification token not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if err != nil {
+		return domain.EmailVerificationToken{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return token, nil
+}
+
+/* marks every unused email verification token of the provided user as used */
+func (vR *SQLEmailVerificationTokenRepository) InvalidateUserVerificationTokens(c context.Context, username string) domain.CodedError {
+	_, err := vR.DB.ExecContext(c, vR.Dialect.rebind("UPDATE email_verification_tokens SET used = TRUE WHERE username = ? AND used = FALSE"), username)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
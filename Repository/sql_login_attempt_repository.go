@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	domain "task_manager_api/Domain"
+	"time"
+)
+
+/* Implements the LoginAttemptRepositoryInterface defined in `domain` with a SQL database */
+type SQLLoginAttemptRepository struct {
+	DB      *sql.DB
+	Dialect SQLDialect
+}
+
+/* retrieves the failed logins recorded for the key if there are any */
+func (aR *SQLLoginAttemptRepository) GetLoginAttempts(c context.Context, key string) (domain.LoginAttempts, domain.CodedError) {
+	var attempts domain.LoginAttempts
+	statement := "SELECT attempt_key, failures, last_failure, expires_at FROM login_attempts WHERE attempt_key = ?"
+	err := aR.DB.QueryRowContext(c, aR.Dialect.rebind(statement), key).Scan(&attempts.Key, &attempts.Failures, &attempts.LastFailure, &attempts.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.LoginAttempts{}, domain.UserError{Message: "Login attempts not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if err != nil {
+		return domain.LoginAttempts{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return attempts, nil
+}
+
+/*
+atomically counts a failed login for the key and returns the updated record.
+The count restarts from one if the stored record has expired.
+*/
+func (aR *SQLLoginAttemptRepository) RecordLoginFailure(c context.Context, key string, failedAt time.Time, expiresAt time.Time) (domain.LoginAttempts, domain.CodedError) {
+	var attempts domain.LoginAttempts
+	statement := `INSERT INTO login_attempts (attempt_key, failures, last_failure, expires_at) VALUES (?, 1, ?, ?)
+		ON CONFLICT (attempt_key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.expires_at > excluded.last_failure THEN login_attempts.failures + 1 ELSE 1 END,
+			last_failure = excluded.last_failure,
+			expires_at = excluded.expires_at
+		RETURNING attempt_key, failures, last_failure, expires_at`
+	err := aR.DB.QueryRowContext(c, aR.Dialect.rebind(statement), key, failedAt.UTC(), expiresAt.UTC()).Scan(&attempts.Key, &attempts.Failures, &attempts.LastFailure, &attempts.ExpiresAt)
+	if err != nil {
+		return domain.LoginAttempts{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return attempts, nil
+}
+
+/* forgets the failed logins recorded for the key */
+func (aR *SQLLoginAttemptRepository) ClearLoginAttempts(c context.Context, key string) domain.CodedError {
+	_, err := aR.DB.ExecContext(c, aR.Dialect.rebind("DELETE FROM login_attempts WHERE attempt_key = ?"), key)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
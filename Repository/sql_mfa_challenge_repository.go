@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	domain "task_manager_api/Domain"
+)
+
+/* Implements the MFAChallengeRepositoryInterface defined in `domain` with a SQL database */
+type SQLMFAChallengeRepository struct {
+	DB      *sql.DB
+	Dialect SQLDialect
+}
+
+/* Adds the login challenge to the DB */
+func (cR *SQLMFAChallengeRepository) CreateChallenge(c context.Context, challenge domain.MFAChallenge) domain.CodedError {
+	_, err := cR.DB.ExecContext(c, cR.Dialect.rebind("INSERT INTO mfa_challenges (token_hash, username, created_at, expires_at, used) VALUES (?, ?, ?, ?, ?)"),
+		challenge.TokenHash, challenge.Username, challenge.CreatedAt.UTC(), challenge.ExpiresAt.UTC(), challenge.Used)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/* retrieves the unused login challenge with the provided hash */
+func (cR *SQLMFAChallengeRepository) GetChallenge(c context.Context, tokenHash string) (domain.MFAChallenge, domain.CodedError) {
+	statement := "SELECT token_hash, username, created_at, expires_at, used FROM mfa_challenges WHERE token_hash = ? AND used = FALSE"
+	return scanChallenge(cR.DB.QueryRowContext(c, cR.Dialect.rebind(statement), tokenHash))
+}
+
+/*
+atomically marks the login challenge with the provided hash as used and
+returns it. Only unused challenges are matched, so a challenge can only be
+used once even with concurrent requests.
+*/
+func (cR *SQLMFAChallengeRepository) UseChallenge(c context.Context, tokenHash string) (domain.MFAChallenge, domain.CodedError) {
+	statement := "UPDATE mfa_challenges SET used = TRUE WHERE token_hash = ? AND used = FALSE RETURNING token_hash, username, created_at, expires_at, used"
+	return scanChallenge(cR.DB.QueryRowContext(c, cR.Dialect.rebind(statement), tokenHash))
+}
+
+/* scans the login challenge returned by the query */
+func scanChallenge(row *sql.Row) (domain.MFAChallenge, domain.CodedError) {
+	var challenge domain.MFAChallenge
+	err := row.Scan(&challenge.TokenHash, &challenge.Username, &challenge.CreatedAt, &challenge.ExpiresAt, &challenge.Used)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.MFAChallenge{}, domain.UserError{Message: "Login challenge not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if err != nil {
+		return domain.MFAChallenge{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return challenge, nil
+}
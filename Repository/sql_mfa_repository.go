@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	domain "task_manager_api/Domain"
+)
+
+/*
+Implements the MFARepositoryInterface defined in `domain` with a SQL
+database. The recovery codes are kept in their own table so that a single
+code can be removed atomically.
+*/
+type SQLMFARepository struct {
+	DB      *sql.DB
+	Dialect SQLDialect
+}
+
+/* retrieves the TOTP enrollment of the user along with its unused recovery codes */
+func (mR *SQLMFARepository) GetMFA(c context.Context, username string) (domain.MFAEnrollment, domain.CodedError) {
+	var enrollment domain.MFAEnrollment
+	statement := "SELECT username, secret, enabled, last_used_step, created_at FROM mfa_enrollments WHERE username = ?"
+	err := mR.DB.QueryRowContext(c, mR.Dialect.rebind(statement), username).Scan(&enrollment.Username, &enrollment.Secret, &enrollment.Enabled, &enrollment.LastUsedStep, &enrollment.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.MFAEnrollment{}, domain.UserError{Message: "Two-factor authentication is not set up", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if err != nil {
+		return domain.MFAEnrollment{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	rows, err := mR.DB.QueryContext(c, mR.Dialect.rebind("SELECT code_hash FROM mfa_recovery_codes WHERE username = ? ORDER BY code_hash"), username)
+	if err != nil {
+		return domain.MFAEnrollment{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+	defer rows.Close()
+
+	enrollment.RecoveryCodes = []string{}
+	for rows.Next() {
+		var codeHash string
+		if err := rows.Scan(&codeHash); err != nil {
+			return domain.MFAEnrollment{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+		}
+
+		enrollment.RecoveryCodes = append(enrollment.RecoveryCodes, codeHash)
+	}
+
+	if err := rows.Err(); err != nil {
+		return domain.MFAEnrollment{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return enrollment, nil
+}
+
+/*
+creates the TOTP enrollment of the user or replaces the existing one along
+with all of its recovery codes, in a single transaction
+*/
+func (mR *SQLMFARepository) SaveMFA(c context.Context, enrollment domain.MFAEnrollment) domain.CodedError {
+	tx, err := mR.DB.BeginTx(c, nil)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+	defer tx.Rollback()
+
+	statement := `INSERT INTO mfa_enrollments (username, secret, enabled, last_used_step, created_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (username) DO UPDATE SET
+			secret = excluded.secret,
+			enabled = excluded.enabled,
+			last_used_step = excluded.last_used_step,
+			created_at = excluded.created_at`
+	_, err = tx.ExecContext(c, mR.Dialect.rebind(statement), enrollment.Username, enrollment.Secret, enrollment.Enabled, enrollment.LastUsedStep, enrollment.CreatedAt.UTC())
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if _, err := tx.ExecContext(c, mR.Dialect.rebind("DELETE FROM mfa_recovery_codes WHERE username = ?"), enrollment.Username); err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	for _, codeHash := range enrollment.RecoveryCodes {
+		_, err := tx.ExecContext(c, mR.Dialect.rebind("INSERT INTO mfa_recovery_codes (username, code_hash) VALUES (?, ?)"), enrollment.Username, codeHash)
+		if err != nil {
+			return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/*
+atomically records the time step of an accepted TOTP code. Only enrollments
+whose last used step is before the provided one are updated, so the same
+code can not be used twice even with concurrent requests.
+*/
+func (mR *SQLMFARepository) UseTOTPStep(c context.Context, username string, step int64) domain.CodedError {
+	result, err := mR.DB.ExecContext(c, mR.Dialect.rebind("UPDATE mfa_enrollments SET last_used_step = ? WHERE username = ? AND last_used_step < ?"), step, username, step)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		if _, err := mR.GetMFA(c, username); err != nil {
+			return err
+		}
+
+		return domain.UserError{Message: "Authentication code has already been used", Code: domain.ERR_CONFLICT}
+	}
+
+	return nil
+}
+
+/* atomically removes the recovery code with the provided hash from the codes of the user */
+func (mR *SQLMFARepository) UseRecoveryCode(c context.Context, username string, codeHash string) domain.CodedError {
+	result, err := mR.DB.ExecContext(c, mR.Dialect.rebind("DELETE FROM mfa_recovery_codes WHERE username = ? AND code_hash = ?"), username, codeHash)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return domain.UserError{Message: "Recovery code not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
+
+/* deletes the TOTP enrollment of the user along with its recovery codes */
+func (mR *SQLMFARepository) DeleteMFA(c context.Context, username string) domain.CodedError {
+	tx, err := mR.DB.BeginTx(c, nil)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(c, mR.Dialect.rebind("DELETE FROM mfa_recovery_codes WHERE username = ?"), username); err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	result, err := tx.ExecContext(c, mR.Dialect.rebind("DELETE FROM mfa_enrollments WHERE username = ?"), username)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return domain.UserError{Message: "Two-factor authentication is not set up", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
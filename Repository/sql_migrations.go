@@ -0,0 +1,261 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+/*
+A versioned change to the schema of the SQL database. The `{timestamp}`
+placeholder in the statements is replaced with the timestamp type of the
+dialect. Migrations are append only: once released, a migration must never
+be modified and changes to the schema are made with a new migration.
+*/
+type sqlMigration struct {
+	Version    int64
+	Name       string
+	Statements []string
+}
+
+/*
+The migrations of the SQL database in the order they are applied. The unique
+constraints match the unique indices that are created in mongoDB.
+*/
+var sqlMigrations = []sqlMigration{
+	{
+		Version: 1,
+		Name:    "create tasks and users",
+		Statements: []string{
+			`CREATE TABLE tasks (
+				id TEXT PRIMARY KEY,
+				title TEXT NOT NULL,
+				description TEXT NOT NULL,
+				due_date {timestamp},
+				status TEXT NOT NULL,
+				owner TEXT NOT NULL,
+				assignee TEXT NOT NULL,
+				created_at {timestamp},
+				updated_at {timestamp},
+				version BIGINT NOT NULL,
+				status_history TEXT NOT NULL
+			)`,
+			`CREATE INDEX tasks_owner_idx ON tasks (owner)`,
+			`CREATE INDEX tasks_assignee_idx ON tasks (assignee)`,
+			`CREATE TABLE users (
+				username TEXT PRIMARY KEY,
+				email TEXT NOT NULL UNIQUE,
+				password TEXT NOT NULL,
+				role TEXT NOT NULL
+			)`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "create refresh tokens and sessions",
+		Statements: []string{
+			`CREATE TABLE refresh_tokens (
+				token_hash TEXT PRIMARY KEY,
+				family_id TEXT NOT NULL,
+				username TEXT NOT NULL,
+				created_at {timestamp} NOT NULL,
+				expires_at {timestamp} NOT NULL,
+				used BOOLEAN NOT NULL,
+				revoked BOOLEAN NOT NULL
+			)`,
+			`CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id)`,
+			`CREATE INDEX refresh_tokens_username_idx ON refresh_tokens (username)`,
+			`CREATE TABLE sessions (
+				id TEXT PRIMARY KEY,
+				username TEXT NOT NULL,
+				created_at {timestamp} NOT NULL,
+				last_used_at {timestamp} NOT NULL,
+				expires_at {timestamp} NOT NULL,
+				revoked BOOLEAN NOT NULL
+			)`,
+			`CREATE INDEX sessions_username_idx ON sessions (username)`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create password reset tokens",
+		Statements: []string{
+			`CREATE TABLE password_reset_tokens (
+				token_hash TEXT PRIMARY KEY,
+				username TEXT NOT NULL,
+				created_at {timestamp} NOT NULL,
+				expires_at {timestamp} NOT NULL,
+				used BOOLEAN NOT NULL
+			)`,
+			`CREATE INDEX password_reset_tokens_username_idx ON password_reset_tokens (username)`,
+		},
+	},
+	{
+		// the accounts created before email verification count as verified
+		Version: 4,
+		Name:    "add email verification",
+		Statements: []string{
+			`ALTER TABLE users ADD COLUMN verified BOOLEAN NOT NULL DEFAULT TRUE`,
+			`ALTER TABLE users ADD COLUMN created_at {timestamp}`,
+			`CREATE TABLE email_verification_tokens (
+				token_hash TEXT PRIMARY KEY,
+				username TEXT NOT NULL,
+				created_at {timestamp} NOT NULL,
+				expires_at {timestamp} NOT NULL,
+				used BOOLEAN NOT NULL
+			)`,
+			`CREATE INDEX email_verification_tokens_username_idx ON email_verification_tokens (username)`,
+		},
+	},
+	{
+		// the existing accounts stay active
+		Version: 5,
+		Name:    "add account deactivation",
+		Statements: []string{
+			`ALTER TABLE users ADD COLUMN deactivated BOOLEAN NOT NULL DEFAULT FALSE`,
+		},
+	},
+	{
+		Version: 6,
+		Name:    "add display names",
+		Statements: []string{
+			`ALTER TABLE users ADD COLUMN display_name TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		Version: 7,
+		Name:    "create login attempts",
+		Statements: []string{
+			`CREATE TABLE login_attempts (
+				attempt_key TEXT PRIMARY KEY,
+				failures BIGINT NOT NULL,
+				last_failure {timestamp} NOT NULL,
+				expires_at {timestamp} NOT NULL
+			)`,
+		},
+	},
+	{
+		Version: 8,
+		Name:    "create two-factor authentication",
+		Statements: []string{
+			`CREATE TABLE mfa_enrollments (
+				username TEXT PRIMARY KEY,
+				secret TEXT NOT NULL,
+				enabled BOOLEAN NOT NULL,
+				last_used_step BIGINT NOT NULL,
+				created_at {timestamp} NOT NULL
+			)`,
+			`CREATE TABLE mfa_recovery_codes (
+				username TEXT NOT NULL,
+				code_hash TEXT NOT NULL,
+				PRIMARY KEY (username, code_hash)
+			)`,
+			`CREATE TABLE mfa_challenges (
+				token_hash TEXT PRIMARY KEY,
+				username TEXT NOT NULL,
+				created_at {timestamp} NOT NULL,
+				expires_at {timestamp} NOT NULL,
+				used BOOLEAN NOT NULL
+			)`,
+		},
+	},
+	{
+		Version: 9,
+		Name:    "create api keys",
+		Statements: []string{
+			`CREATE TABLE api_keys (
+				id TEXT PRIMARY KEY,
+				username TEXT NOT NULL,
+				name TEXT NOT NULL,
+				prefix TEXT NOT NULL,
+				key_hash TEXT NOT NULL UNIQUE,
+				scopes TEXT NOT NULL,
+				created_at {timestamp} NOT NULL,
+				expires_at {timestamp},
+				last_used_at {timestamp}
+			)`,
+			`CREATE INDEX api_keys_username_idx ON api_keys (username)`,
+		},
+	},
+	{
+		Version: 10,
+		Name:    "create roles",
+		Statements: []string{
+			`CREATE TABLE roles (
+				name TEXT PRIMARY KEY,
+				permissions TEXT NOT NULL
+			)`,
+		},
+	},
+	{
+		// no role requires a second factor until an admin says so
+		Version: 11,
+		Name:    "add role second factor requirement",
+		Statements: []string{
+			`ALTER TABLE roles ADD COLUMN mfa_required BOOLEAN NOT NULL DEFAULT FALSE`,
+		},
+	},
+}
+
+/*
+Brings the schema of the database up to date by applying the migrations that
+have not been applied yet. The applied versions are recorded in the
+`schema_migrations` table and each migration is applied in its own
+transaction, so a failed migration leaves the schema at the previous version.
+Returns the number of migrations that were applied.
+*/
+func RunSQLMigrations(c context.Context, db *sql.DB, dialect SQLDialect) (int, error) {
+	_, err := db.ExecContext(c, dialect.expandTypes(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at {timestamp} NOT NULL
+	)`))
+	if err != nil {
+		return 0, fmt.Errorf("error: %v", err.Error())
+	}
+
+	var currentVersion int64
+	err = db.QueryRowContext(c, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&currentVersion)
+	if err != nil {
+		return 0, fmt.Errorf("error: %v", err.Error())
+	}
+
+	applied := 0
+	for _, migration := range sqlMigrations {
+		if migration.Version <= currentVersion {
+			continue
+		}
+
+		if err := applySQLMigration(c, db, dialect, migration); err != nil {
+			return applied, fmt.Errorf("error while applying migration %v (%v): %v", migration.Version, migration.Name, err.Error())
+		}
+
+		applied++
+	}
+
+	return applied, nil
+}
+
+/* applies the statements of the migration and records its version in a single transaction */
+func applySQLMigration(c context.Context, db *sql.DB, dialect SQLDialect, migration sqlMigration) error {
+	tx, err := db.BeginTx(c, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range migration.Statements {
+		if _, err := tx.ExecContext(c, dialect.expandTypes(statement)); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(c, dialect.rebind("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"), migration.Version, migration.Name, time.Now().UTC())
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	domain "task_manager_api/Domain"
+)
+
+/* Implements the PasswordResetTokenRepositoryInterface defined in `domain` with a SQL database */
+type SQLPasswordResetTokenRepository struct {
+	DB      *sql.DB
+	Dialect SQLDialect
+}
+
+/* Adds the password reset token to the DB */
+func (pR *SQLPasswordResetTokenRepository) CreateResetToken(c context.Context, token domain.PasswordResetToken) domain.CodedError {
+	_, err := pR.DB.ExecContext(c, pR.Dialect.rebind("INSERT INTO password_reset_tokens (token_hash, username, created_at, expires_at, used) VALUES (?, ?, ?, ?, ?)"),
+		token.TokenHash, token.Username, token.CreatedAt.UTC(), token.ExpiresAt.UTC(), token.Used)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/*
+atomically marks the password reset token with the provided hash as used and
+returns it. Only unused tokens are matched, so a token can only be used once
+even with concurrent requests.
+*/
+func (pR *SQLPasswordResetTokenRepository) UseResetToken(c context.Context, tokenHash string) (domain.PasswordResetToken, domain.CodedError) {
+	var token domain.PasswordResetToken
+	statement := "UPDATE password_reset_tokens SET used = TRUE WHERE token_hash = ? AND used = FALSE RETURNING token_hash, username, created_at, expires_at, used"
+	err := pR.DB.QueryRowContext(c, pR.Dialect.rebind(statement), tokenHash).Scan(&token.TokenHash, &token.Username, &token.CreatedAt, &token.ExpiresAt, &token.Used)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.PasswordResetToken{}, domain.UserError{Message: "Password reset token not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if err != nil {
+		return domain.PasswordResetToken{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return token, nil
+}
+
+/* marks every unused password reset token of the provided user as used */
+func (pR *SQLPasswordResetTokenRepository) InvalidateUserResetTokens(c context.Context, username string) domain.CodedError {
+	_, err := pR.DB.ExecContext(c, pR.Dialect.rebind("UPDATE password_reset_tokens SET used = TRUE WHERE username = ? AND used = FALSE"), username)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	domain "task_manager_api/Domain"
+)
+
+/* The columns of the `refresh_tokens` table in the order they are scanned */
+const refreshTokenColumns = "token_hash, family_id, username, created_at, expires_at, used, revoked"
+
+/* Implements the RefreshTokenRepositoryInterface defined in `domain` with a SQL database */
+type SQLRefreshTokenRepository struct {
+	DB      *sql.DB
+	Dialect SQLDialect
+}
+
+/* reads a refresh token from a row that holds the `refreshTokenColumns` */
+func scanRefreshToken(row rowScanner) (domain.RefreshToken, error) {
+	var token domain.RefreshToken
+	err := row.Scan(&token.TokenHash, &token.FamilyID, &token.Username, &token.CreatedAt, &token.ExpiresAt, &token.Used, &token.Revoked)
+	return token, err
+}
+
+/* Adds the refresh token to the DB */
+func (rR *SQLRefreshTokenRepository) CreateRefreshToken(c context.Context, token domain.RefreshToken) domain.CodedError {
+	_, err := rR.DB.ExecContext(c, rR.Dialect.rebind("INSERT INTO refresh_tokens ("+refreshTokenColumns+") VALUES (?, ?, ?, ?, ?, ?, ?)"),
+		token.TokenHash, token.FamilyID, token.Username, token.CreatedAt.UTC(), token.ExpiresAt.UTC(), token.Used, token.Revoked)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/* retrieves the refresh token with the provided hash if it exists */
+func (rR *SQLRefreshTokenRepository) GetRefreshToken(c context.Context, tokenHash string) (domain.RefreshToken, domain.CodedError) {
+	token, err := scanRefreshToken(rR.DB.QueryRowContext(c, rR.Dialect.rebind("SELECT "+refreshTokenColumns+" FROM refresh_tokens WHERE token_hash = ?"), tokenHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.RefreshToken{}, domain.UserError{Message: "Refresh token not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if err != nil {
+		return domain.RefreshToken{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return token, nil
+}
+
+/*
+atomically marks the refresh token with the provided hash as used and
+returns it. Only tokens that have neither been used nor revoked are matched,
+so a token can only be used once even with concurrent requests.
+*/
+func (rR *SQLRefreshTokenRepository) UseRefreshToken(c context.Context, tokenHash string) (domain.RefreshToken, domain.CodedError) {
+	statement := "UPDATE refresh_tokens SET used = TRUE WHERE token_hash = ? AND used = FALSE AND revoked = FALSE RETURNING " + refreshTokenColumns
+	token, err := scanRefreshToken(rR.DB.QueryRowContext(c, rR.Dialect.rebind(statement), tokenHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.RefreshToken{}, domain.UserError{Message: "Refresh token not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if err != nil {
+		return domain.RefreshToken{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return token, nil
+}
+
+/* revokes every refresh token that belongs to the provided family */
+func (rR *SQLRefreshTokenRepository) RevokeTokenFamily(c context.Context, familyID string) domain.CodedError {
+	_, err := rR.DB.ExecContext(c, rR.Dialect.rebind("UPDATE refresh_tokens SET revoked = TRUE WHERE family_id = ?"), familyID)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/* revokes every refresh token that has been issued to the provided user */
+func (rR *SQLRefreshTokenRepository) RevokeUserTokens(c context.Context, username string) domain.CodedError {
+	_, err := rR.DB.ExecContext(c, rR.Dialect.rebind("UPDATE refresh_tokens SET revoked = TRUE WHERE username = ?"), username)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
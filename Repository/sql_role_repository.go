@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	domain "task_manager_api/Domain"
+)
+
+/*
+Implements the RoleRepositoryInterface defined in `domain` with a SQL
+database. The permissions of a role are stored as a JSON array.
+*/
+type SQLRoleRepository struct {
+	DB      *sql.DB
+	Dialect SQLDialect
+}
+
+/* reads a role from a row that holds its name, permissions and second factor requirement */
+func scanRole(row rowScanner) (domain.Role, error) {
+	var role domain.Role
+	var permissions string
+
+	if err := row.Scan(&role.Name, &permissions, &role.MFARequired); err != nil {
+		return domain.Role{}, err
+	}
+
+	if err := json.Unmarshal([]byte(permissions), &role.Permissions); err != nil {
+		return domain.Role{}, err
+	}
+
+	return role, nil
+}
+
+/* retrieves the role with the provided name if it exists */
+func (rR *SQLRoleRepository) GetRole(c context.Context, name string) (domain.Role, domain.CodedError) {
+	role, err := scanRole(rR.DB.QueryRowContext(c, rR.Dialect.rebind("SELECT name, permissions, mfa_required FROM roles WHERE name = ?"), name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Role{}, domain.UserError{Message: "Role not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if err != nil {
+		return domain.Role{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return role, nil
+}
+
+/* retrieves the stored roles sorted by name */
+func (rR *SQLRoleRepository) GetRoles(c context.Context) ([]domain.Role, domain.CodedError) {
+	rows, err := rR.DB.QueryContext(c, "SELECT name, permissions, mfa_required FROM roles ORDER BY name ASC")
+	if err != nil {
+		return []domain.Role{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+	defer rows.Close()
+
+	roles := []domain.Role{}
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return []domain.Role{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+		}
+
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []domain.Role{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return roles, nil
+}
+
+/* creates the role or replaces the existing role with the same name */
+func (rR *SQLRoleRepository) SaveRole(c context.Context, role domain.Role) domain.CodedError {
+	if role.Permissions == nil {
+		role.Permissions = []string{}
+	}
+
+	permissions, err := json.Marshal(role.Permissions)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	statement := `INSERT INTO roles (name, permissions, mfa_required) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET permissions = excluded.permissions, mfa_required = excluded.mfa_required`
+	if _, err := rR.DB.ExecContext(c, rR.Dialect.rebind(statement), role.Name, string(permissions), role.MFARequired); err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/* deletes the role with the provided name */
+func (rR *SQLRoleRepository) DeleteRole(c context.Context, name string) domain.CodedError {
+	result, err := rR.DB.ExecContext(c, rR.Dialect.rebind("DELETE FROM roles WHERE name = ?"), name)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return domain.UserError{Message: "Role not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	domain "task_manager_api/Domain"
+	"time"
+)
+
+/* The columns of the `sessions` table in the order they are scanned */
+const sessionColumns = "id, username, created_at, last_used_at, expires_at, revoked"
+
+/* Implements the SessionRepositoryInterface defined in `domain` with a SQL database */
+type SQLSessionRepository struct {
+	DB      *sql.DB
+	Dialect SQLDialect
+}
+
+/* reads a session from a row that holds the `sessionColumns` */
+func scanSession(row rowScanner) (domain.Session, error) {
+	var session domain.Session
+	err := row.Scan(&session.ID, &session.Username, &session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt, &session.Revoked)
+	return session, err
+}
+
+/* Adds the session to the DB */
+func (sR *SQLSessionRepository) CreateSession(c context.Context, session domain.Session) domain.CodedError {
+	_, err := sR.DB.ExecContext(c, sR.Dialect.rebind("INSERT INTO sessions ("+sessionColumns+") VALUES (?, ?, ?, ?, ?, ?)"),
+		session.ID, session.Username, session.CreatedAt.UTC(), session.LastUsedAt.UTC(), session.ExpiresAt.UTC(), session.Revoked)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/* retrieves the session with the provided ID if it exists */
+func (sR *SQLSessionRepository) GetSession(c context.Context, sessionID string) (domain.Session, domain.CodedError) {
+	session, err := scanSession(sR.DB.QueryRowContext(c, sR.Dialect.rebind("SELECT "+sessionColumns+" FROM sessions WHERE id = ?"), sessionID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Session{}, domain.UserError{Message: "Session not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if err != nil {
+		return domain.Session{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return session, nil
+}
+
+/* retrieves the sessions of the user that have neither expired nor been revoked, most recent first */
+func (sR *SQLSessionRepository) GetActiveSessions(c context.Context, username string) ([]domain.Session, domain.CodedError) {
+	statement := "SELECT " + sessionColumns + " FROM sessions WHERE username = ? AND revoked = FALSE AND expires_at > ? ORDER BY created_at DESC, id ASC"
+	rows, err := sR.DB.QueryContext(c, sR.Dialect.rebind(statement), username, time.Now().UTC())
+	if err != nil {
+		return []domain.Session{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+	defer rows.Close()
+
+	sessions := []domain.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return []domain.Session{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []domain.Session{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return sessions, nil
+}
+
+/* updates the session with the provided ID, returning a not found error if it does not exist */
+func (sR *SQLSessionRepository) updateSession(c context.Context, sessionID string, statement string, args ...any) domain.CodedError {
+	result, err := sR.DB.ExecContext(c, sR.Dialect.rebind(statement), append(args, sessionID)...)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return domain.UserError{Message: "Session not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
+
+/* records the use of the session and moves its expiry date */
+func (sR *SQLSessionRepository) ExtendSession(c context.Context, sessionID string, lastUsedAt time.Time, expiresAt time.Time) domain.CodedError {
+	return sR.updateSession(c, sessionID, "UPDATE sessions SET last_used_at = ?, expires_at = ? WHERE id = ?", lastUsedAt.UTC(), expiresAt.UTC())
+}
+
+/* revokes the session with the provided ID */
+func (sR *SQLSessionRepository) RevokeSession(c context.Context, sessionID string) domain.CodedError {
+	return sR.updateSession(c, sessionID, "UPDATE sessions SET revoked = TRUE WHERE id = ?")
+}
+
+/* revokes every session of the provided user */
+func (sR *SQLSessionRepository) RevokeUserSessions(c context.Context, username string) domain.CodedError {
+	_, err := sR.DB.ExecContext(c, sR.Dialect.rebind("UPDATE sessions SET revoked = TRUE WHERE username = ?"), username)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
@@ -0,0 +1,317 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	domain "task_manager_api/Domain"
+	"time"
+)
+
+/* The number of times a patch is retried when the task is modified concurrently */
+const patchRetries = 5
+
+/* The columns of the `tasks` table in the order they are scanned */
+const taskColumns = "id, title, description, due_date, status, owner, assignee, created_at, updated_at, version, status_history"
+
+/* The columns the tasks can be sorted by, keyed by the sort field of the query */
+var taskSortColumns = map[string]string{
+	"id":         "id",
+	"title":      "title",
+	"status":     "status",
+	"due_date":   "due_date",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+/* Implements the TaskRespositoryInterface defined in `domain` with a SQL database */
+type SQLTaskRepository struct {
+	DB      *sql.DB
+	Dialect SQLDialect
+}
+
+/* reads a task from a row that holds the `taskColumns` */
+func scanTask(row rowScanner) (domain.Task, error) {
+	var task domain.Task
+	var dueDate, createdAt, updatedAt sql.NullTime
+	var statusHistory string
+
+	err := row.Scan(&task.ID, &task.Title, &task.Description, &dueDate, &task.Status, &task.Owner, &task.Assignee, &createdAt, &updatedAt, &task.Version, &statusHistory)
+	if err != nil {
+		return domain.Task{}, err
+	}
+
+	task.DueDate = timeFromSQL(dueDate)
+	task.CreatedAt = timeFromSQL(createdAt)
+	task.UpdatedAt = timeFromSQL(updatedAt)
+	if err := json.Unmarshal([]byte(statusHistory), &task.StatusHistory); err != nil {
+		return domain.Task{}, err
+	}
+
+	return task, nil
+}
+
+/* encodes the status history of a task as the JSON stored in the `status_history` column */
+func encodeStatusHistory(statusHistory []domain.StatusChange) (string, error) {
+	encoded, err := json.Marshal(statusHistory)
+	return string(encoded), err
+}
+
+/* escapes the wildcards of a LIKE pattern, using `\` as the escape character */
+func escapeLikePattern(pattern string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(pattern)
+}
+
+/*
+retrieves a page of the tasks that match the filters of the provided query
+along with the total number of matching tasks in the db
+*/
+func (tR *SQLTaskRepository) GetAllTasks(c context.Context, query domain.TaskQuery) ([]domain.Task, int64, domain.CodedError) {
+	var conditions []string
+	var args []any
+	if query.VisibleTo != "" {
+		conditions = append(conditions, "(owner = ? OR assignee = ?)")
+		args = append(args, query.VisibleTo, query.VisibleTo)
+	}
+	if query.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, query.Status)
+	}
+	if query.Title != "" {
+		conditions = append(conditions, `LOWER(title) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLikePattern(strings.ToLower(query.Title))+"%")
+	}
+	if !query.DueAfter.IsZero() {
+		conditions = append(conditions, "due_date >= ?")
+		args = append(args, sqlTime(query.DueAfter))
+	}
+	if !query.DueBefore.IsZero() {
+		conditions = append(conditions, "due_date <= ?")
+		args = append(args, sqlTime(query.DueBefore))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	err := tR.DB.QueryRowContext(c, tR.Dialect.rebind("SELECT COUNT(*) FROM tasks"+where), args...).Scan(&total)
+	if err != nil {
+		return []domain.Task{}, 0, domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	// tasks without a due date come first, like with mongoDB, and the id is
+	// used as a tie breaker to keep the order stable across pages
+	sortColumn, ok := taskSortColumns[query.SortBy]
+	if !ok {
+		sortColumn = "id"
+	}
+	orderBy := " ORDER BY " + sortColumn + " ASC NULLS FIRST"
+	if query.SortOrder == domain.SortDescending {
+		orderBy = " ORDER BY " + sortColumn + " DESC NULLS LAST"
+	}
+	if sortColumn != "id" {
+		orderBy += ", id ASC"
+	}
+
+	limit := " " + tR.Dialect.noLimit()
+	if query.Limit > 0 {
+		limit = " LIMIT ?"
+		args = append(args, query.Limit)
+	}
+	args = append(args, query.Offset)
+
+	rows, err := tR.DB.QueryContext(c, tR.Dialect.rebind("SELECT "+taskColumns+" FROM tasks"+where+orderBy+limit+" OFFSET ?"), args...)
+	if err != nil {
+		return []domain.Task{}, 0, domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+	defer rows.Close()
+
+	tasks := []domain.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return []domain.Task{}, 0, domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []domain.Task{}, 0, domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return tasks, total, nil
+}
+
+/* retrieves the task associated with the provided id if it exists */
+func (tR *SQLTaskRepository) GetTaskByID(c context.Context, taskID string) (domain.Task, domain.CodedError) {
+	task, err := scanTask(tR.DB.QueryRowContext(c, tR.Dialect.rebind("SELECT "+taskColumns+" FROM tasks WHERE id = ?"), taskID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Task{}, domain.TaskError{Message: "Task not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if err != nil {
+		return domain.Task{}, domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return task, nil
+}
+
+/*
+adds the provided task to the database. The primary key on the id column
+is relied upon to reject tasks with duplicate IDs.
+*/
+func (tR *SQLTaskRepository) AddTask(c context.Context, newTask domain.Task) domain.CodedError {
+	statusHistory, err := encodeStatusHistory(newTask.StatusHistory)
+	if err != nil {
+		return domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	_, err = tR.DB.ExecContext(c, tR.Dialect.rebind("INSERT INTO tasks ("+taskColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+		newTask.ID, newTask.Title, newTask.Description, sqlTime(newTask.DueDate), newTask.Status, newTask.Owner, newTask.Assignee,
+		sqlTime(newTask.CreatedAt), sqlTime(newTask.UpdatedAt), newTask.Version, statusHistory)
+	if isUniqueViolation(err) {
+		return domain.TaskError{Message: "Task with the provided ID already exists", Code: domain.ERR_CONFLICT}
+	}
+
+	if err != nil {
+		return domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/*
+determines why a versioned write did not match any task. Returns a not found
+error if no task has the provided id and a precondition failed error if the
+task exists but has been modified since the provided version was read.
+*/
+func (tR *SQLTaskRepository) unmatchedTaskError(c context.Context, taskID string) domain.CodedError {
+	var count int64
+	err := tR.DB.QueryRowContext(c, tR.Dialect.rebind("SELECT COUNT(*) FROM tasks WHERE id = ?"), taskID).Scan(&count)
+	if err != nil {
+		return domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if count == 0 {
+		return domain.TaskError{Message: "Task not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return domain.TaskError{Message: "Task has been modified since it was last fetched", Code: domain.ERR_PRECONDITION_FAILED}
+}
+
+/*
+replaces the mutable fields of the task associated with the provided id with
+the ones in the provided task struct, along with its status history, and
+increments its version. The id, owner and creation time of the task are never
+modified. The update is only applied if the stored version matches the
+provided version (unless it is zero). Returns the task as it is after the update.
+*/
+func (tR *SQLTaskRepository) UpdateTask(c context.Context, taskID string, updatedTask domain.Task, version int64) (domain.Task, domain.CodedError) {
+	statusHistory, err := encodeStatusHistory(updatedTask.StatusHistory)
+	if err != nil {
+		return domain.Task{}, domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	statement := "UPDATE tasks SET title = ?, description = ?, status = ?, assignee = ?, due_date = ?, updated_at = ?, status_history = ?, version = version + 1 WHERE id = ?"
+	args := []any{updatedTask.Title, updatedTask.Description, updatedTask.Status, updatedTask.Assignee, sqlTime(updatedTask.DueDate), sqlTime(updatedTask.UpdatedAt), statusHistory, taskID}
+	if version != 0 {
+		statement += " AND version = ?"
+		args = append(args, version)
+	}
+
+	task, err := scanTask(tR.DB.QueryRowContext(c, tR.Dialect.rebind(statement+" RETURNING "+taskColumns), args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Task{}, tR.unmatchedTaskError(c, taskID)
+	}
+
+	if err != nil {
+		return domain.Task{}, domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return task, nil
+}
+
+/*
+updates only the fields of the task associated with the provided id that are
+present in the patch. Fields with nil values are cleared. The patch is applied
+to the stored task, which is then written back only if it has not been
+modified in the meantime. Patches without a version are retried when the
+task is modified concurrently.
+*/
+func (tR *SQLTaskRepository) PatchTask(c context.Context, taskID string, patch domain.TaskPatch, version int64) (domain.Task, domain.CodedError) {
+	for attempt := 0; ; attempt++ {
+		task, err := tR.GetTaskByID(c, taskID)
+		if err != nil {
+			return domain.Task{}, err
+		}
+
+		if version != 0 && task.Version != version {
+			return domain.Task{}, domain.TaskError{Message: "Task has been modified since it was last fetched", Code: domain.ERR_PRECONDITION_FAILED}
+		}
+
+		if err := applyPatchToTask(&task, patch); err != nil {
+			return domain.Task{}, err
+		}
+
+		patchedTask, err := tR.UpdateTask(c, taskID, task, task.Version)
+		if err != nil && err.GetCode() == domain.ERR_PRECONDITION_FAILED && version == 0 && attempt < patchRetries {
+			continue
+		}
+
+		return patchedTask, err
+	}
+}
+
+/*
+deletes the task associated with the provided id if it exists. The task is
+only deleted if the stored version matches the provided version (unless it is zero).
+*/
+func (tR *SQLTaskRepository) DeleteTask(c context.Context, taskID string, version int64) domain.CodedError {
+	statement := "DELETE FROM tasks WHERE id = ?"
+	args := []any{taskID}
+	if version != 0 {
+		statement += " AND version = ?"
+		args = append(args, version)
+	}
+
+	result, err := tR.DB.ExecContext(c, tR.Dialect.rebind(statement), args...)
+	if err != nil {
+		return domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return tR.unmatchedTaskError(c, taskID)
+	}
+
+	return nil
+}
+
+/* deletes every task owned by the provided user and returns how many were deleted */
+func (tR *SQLTaskRepository) DeleteOwnerTasks(c context.Context, owner string) (int64, domain.CodedError) {
+	result, err := tR.DB.ExecContext(c, tR.Dialect.rebind("DELETE FROM tasks WHERE owner = ?"), owner)
+	if err != nil {
+		return 0, domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	deleted, _ := result.RowsAffected()
+	return deleted, nil
+}
+
+/*
+clears the assignee of every task assigned to the provided user and
+increments their versions. Returns how many tasks were unassigned.
+*/
+func (tR *SQLTaskRepository) UnassignTasks(c context.Context, assignee string, updatedAt time.Time) (int64, domain.CodedError) {
+	result, err := tR.DB.ExecContext(c, tR.Dialect.rebind("UPDATE tasks SET assignee = '', updated_at = ?, version = version + 1 WHERE assignee = ?"), sqlTime(updatedAt), assignee)
+	if err != nil {
+		return 0, domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	unassigned, _ := result.RowsAffected()
+	return unassigned, nil
+}
@@ -0,0 +1,287 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	domain "task_manager_api/Domain"
+)
+
+/* The columns of the `users` table in the order they are scanned */
+const sqlUserColumns = "username, email, display_name, password, role, verified, deactivated, created_at"
+
+/* The columns of the `users` table that can be used to sort the users */
+var userSortColumns = map[string]string{
+	"username":   "username",
+	"email":      "email",
+	"created_at": "created_at",
+}
+
+/* The columns of the `users` table that can be checked for duplicates */
+var userColumns = map[string]string{
+	"username": "username",
+	"email":    "email",
+	"password": "password",
+	"role":     "role",
+	"verified": "verified",
+}
+
+/* Implements the UserRespositoryInterface defined in `domain` with a SQL database */
+type SQLUserRepository struct {
+	DB      *sql.DB
+	Dialect SQLDialect
+}
+
+/*
+checks if a user that matches the provided the key-value pair exists and
+returns a CodedError if there are any matches or if there is an error
+during the DB request
+*/
+func (uR *SQLUserRepository) CheckDuplicate(c context.Context, key string, value interface{}, errorMessage string) domain.CodedError {
+	column, ok := userColumns[key]
+	if !ok {
+		return domain.UserError{Message: errorMessage, Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	var count int64
+	err := uR.DB.QueryRowContext(c, uR.Dialect.rebind("SELECT COUNT(*) FROM users WHERE "+column+" = ?"), value).Scan(&count)
+	if err != nil {
+		return domain.UserError{Message: errorMessage, Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if count > 0 {
+		return domain.UserError{Message: "Bad request: duplicate " + key, Code: domain.ERR_BAD_REQUEST}
+	}
+
+	return nil
+}
+
+/*
+Adds the user to the DB. The unique constraints on the username and email
+columns are relied upon to reject duplicate users.
+*/
+func (uR *SQLUserRepository) CreateUser(c context.Context, user domain.User) domain.CodedError {
+	_, err := uR.DB.ExecContext(c, uR.Dialect.rebind("INSERT INTO users ("+sqlUserColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?)"),
+		user.Username, user.Email, user.DisplayName, user.Password, user.Role, user.Verified, user.Deactivated, sqlTime(user.CreatedAt))
+	if isUniqueViolation(err) {
+		return domain.UserError{Message: "User with the provided username or email already exists", Code: domain.ERR_CONFLICT}
+	}
+
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return nil
+}
+
+/* reads a user from a row that holds the `sqlUserColumns` */
+func scanUser(row rowScanner) (domain.User, error) {
+	var user domain.User
+	var createdAt sql.NullTime
+	err := row.Scan(&user.Username, &user.Email, &user.DisplayName, &user.Password, &user.Role, &user.Verified, &user.Deactivated, &createdAt)
+	user.CreatedAt = timeFromSQL(createdAt)
+	return user, err
+}
+
+/*
+queries for the user with the provided value in the provided column. A
+missing user is reported with the provided error code.
+*/
+func (uR *SQLUserRepository) getBy(c context.Context, column string, value string, notFoundCode string) (domain.User, domain.CodedError) {
+	user, err := scanUser(uR.DB.QueryRowContext(c, uR.Dialect.rebind("SELECT "+sqlUserColumns+" FROM users WHERE "+column+" = ?"), value))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.User{}, domain.UserError{Message: "User not found", Code: notFoundCode}
+	}
+
+	if err != nil {
+		return domain.User{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return user, nil
+}
+
+/*
+queries for a user with the provided username and returns the resulting
+user object if it exists
+*/
+func (uR *SQLUserRepository) GetByUsername(c context.Context, username string) (domain.User, domain.CodedError) {
+	return uR.getBy(c, "username", username, domain.ERR_BAD_REQUEST)
+}
+
+/*
+queries for a user with the provided email and returns the resulting user
+object if it exists
+*/
+func (uR *SQLUserRepository) GetByEmail(c context.Context, email string) (domain.User, domain.CodedError) {
+	return uR.getBy(c, "email", email, domain.ERR_NOT_FOUND)
+}
+
+/*
+retrieves a page of the users that match the filters of the provided query
+along with the total number of matching users in the db
+*/
+func (uR *SQLUserRepository) GetUsers(c context.Context, query domain.UserQuery) ([]domain.User, int64, domain.CodedError) {
+	var conditions []string
+	var args []any
+	if query.Role != "" {
+		conditions = append(conditions, "role = ?")
+		args = append(args, query.Role)
+	}
+	if query.Search != "" {
+		conditions = append(conditions, `(LOWER(username) LIKE ? ESCAPE '\' OR LOWER(email) LIKE ? ESCAPE '\' OR LOWER(display_name) LIKE ? ESCAPE '\')`)
+		pattern := "%" + escapeLikePattern(strings.ToLower(query.Search)) + "%"
+		args = append(args, pattern, pattern, pattern)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	err := uR.DB.QueryRowContext(c, uR.Dialect.rebind("SELECT COUNT(*) FROM users"+where), args...).Scan(&total)
+	if err != nil {
+		return []domain.User{}, 0, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	// the username is used as a tie breaker to keep the order stable across pages
+	sortColumn, ok := userSortColumns[query.SortBy]
+	if !ok {
+		sortColumn = "username"
+	}
+	orderBy := " ORDER BY " + sortColumn + " ASC NULLS FIRST"
+	if query.SortOrder == domain.SortDescending {
+		orderBy = " ORDER BY " + sortColumn + " DESC NULLS LAST"
+	}
+	if sortColumn != "username" {
+		orderBy += ", username ASC"
+	}
+
+	limit := " " + uR.Dialect.noLimit()
+	if query.Limit > 0 {
+		limit = " LIMIT ?"
+		args = append(args, query.Limit)
+	}
+	args = append(args, query.Offset)
+
+	rows, err := uR.DB.QueryContext(c, uR.Dialect.rebind("SELECT "+sqlUserColumns+" FROM users"+where+orderBy+limit+" OFFSET ?"), args...)
+	if err != nil {
+		return []domain.User{}, 0, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+	defer rows.Close()
+
+	users := []domain.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return []domain.User{}, 0, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+		}
+
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []domain.User{}, 0, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return users, total, nil
+}
+
+/*
+Replaces the email, the display name and the verification status of the user
+with the provided username with the ones in the provided user struct. The
+unique constraint on the email column is relied upon to reject duplicates.
+*/
+func (uR *SQLUserRepository) UpdateProfile(c context.Context, user domain.User) domain.CodedError {
+	result, err := uR.DB.ExecContext(c, uR.Dialect.rebind("UPDATE users SET email = ?, display_name = ?, verified = ? WHERE username = ?"), user.Email, user.DisplayName, user.Verified, user.Username)
+	if isUniqueViolation(err) {
+		return domain.UserError{Message: "An account with the provided email already exists", Code: domain.ERR_CONFLICT}
+	}
+
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
+
+/* Promotes an account with role `user` to role `admin` */
+func (uR *SQLUserRepository) PromoteUser(c context.Context, username string) domain.CodedError {
+	result, err := uR.DB.ExecContext(c, uR.Dialect.rebind("UPDATE users SET role = ? WHERE username = ?"), domain.RoleAdmin, username)
+	if err != nil {
+		return domain.UserError{Message: "error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return domain.UserError{Message: "error: user not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
+
+/* Demotes an account with role `admin` to role `user` */
+func (uR *SQLUserRepository) DemoteUser(c context.Context, username string) domain.CodedError {
+	return uR.exec(c, "UPDATE users SET role = ? WHERE username = ?", domain.RoleUser, username)
+}
+
+/* Assigns the role with the provided name to the account */
+func (uR *SQLUserRepository) SetRole(c context.Context, username string, role string) domain.CodedError {
+	return uR.exec(c, "UPDATE users SET role = ? WHERE username = ?", role, username)
+}
+
+/* Deactivates or activates the account with the provided username */
+func (uR *SQLUserRepository) SetDeactivated(c context.Context, username string, deactivated bool) domain.CodedError {
+	return uR.exec(c, "UPDATE users SET deactivated = ? WHERE username = ?", deactivated, username)
+}
+
+/* Deletes the account with the provided username */
+func (uR *SQLUserRepository) DeleteUser(c context.Context, username string) domain.CodedError {
+	return uR.exec(c, "DELETE FROM users WHERE username = ?", username)
+}
+
+/* executes the statement and reports a missing user if no row has been affected */
+func (uR *SQLUserRepository) exec(c context.Context, statement string, args ...any) domain.CodedError {
+	result, err := uR.DB.ExecContext(c, uR.Dialect.rebind(statement), args...)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
+
+/* Replaces the (hashed) password of the user with the provided username */
+func (uR *SQLUserRepository) UpdatePassword(c context.Context, username string, password string) domain.CodedError {
+	result, err := uR.DB.ExecContext(c, uR.Dialect.rebind("UPDATE users SET password = ? WHERE username = ?"), password, username)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
+
+/* Marks the email of the user with the provided username as verified */
+func (uR *SQLUserRepository) VerifyEmail(c context.Context, username string) domain.CodedError {
+	result, err := uR.DB.ExecContext(c, uR.Dialect.rebind("UPDATE users SET verified = TRUE WHERE username = ?"), username)
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
@@ -2,10 +2,14 @@ package repository
 
 import (
 	"context"
+	"regexp"
 	domain "task_manager_api/Domain"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 /* Implements the TaskRespositoryInterface defined in `domain`*/
@@ -13,21 +17,66 @@ type TaskRepository struct {
 	Collection *mongo.Collection
 }
 
-/* retrieves all the tasks in the db */
-func (tR *TaskRepository) GetAllTasks(c context.Context) ([]domain.Task, domain.CodedError) {
-	cursor, queryErr := tR.Collection.Find(c, bson.D{{}})
+/*
+retrieves a page of the tasks that match the filters of the provided query
+along with the total number of matching tasks in the db
+*/
+func (tR *TaskRepository) GetAllTasks(c context.Context, query domain.TaskQuery) ([]domain.Task, int64, domain.CodedError) {
+	filter := bson.D{}
+	if query.VisibleTo != "" {
+		filter = append(filter, bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: "owner", Value: query.VisibleTo}},
+			bson.D{{Key: "assignee", Value: query.VisibleTo}},
+		}})
+	}
+	if query.Status != "" {
+		filter = append(filter, bson.E{Key: "status", Value: query.Status})
+	}
+	if query.Title != "" {
+		filter = append(filter, bson.E{Key: "title", Value: primitive.Regex{Pattern: regexp.QuoteMeta(query.Title), Options: "i"}})
+	}
+
+	dueDateRange := bson.D{}
+	if !query.DueAfter.IsZero() {
+		dueDateRange = append(dueDateRange, bson.E{Key: "$gte", Value: query.DueAfter})
+	}
+	if !query.DueBefore.IsZero() {
+		// tasks without a due date have no `due_date`, but older ones may store the zero time
+		dueDateRange = append(dueDateRange, bson.E{Key: "$gt", Value: time.Time{}}, bson.E{Key: "$lte", Value: query.DueBefore})
+	}
+	if len(dueDateRange) > 0 {
+		filter = append(filter, bson.E{Key: "due_date", Value: dueDateRange})
+	}
+
+	total, countErr := tR.Collection.CountDocuments(c, filter)
+	if countErr != nil {
+		return []domain.Task{}, 0, domain.TaskError{Message: "Internal server error: " + countErr.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	// the id is used as a tie breaker to keep the order stable across pages
+	sortOrder := 1
+	if query.SortOrder == domain.SortDescending {
+		sortOrder = -1
+	}
+	sort := bson.D{{Key: query.SortBy, Value: sortOrder}}
+	if query.SortBy != "id" {
+		sort = append(sort, bson.E{Key: "id", Value: 1})
+	}
+
+	findOptions := options.Find().SetSort(sort).SetSkip(query.Offset).SetLimit(query.Limit)
+	cursor, queryErr := tR.Collection.Find(c, filter, findOptions)
 	if queryErr != nil {
-		return []domain.Task{}, domain.TaskError{Message: "Internal server error: " + queryErr.Error(), Code: domain.ERR_INTERNAL_SERVER}
+		return []domain.Task{}, 0, domain.TaskError{Message: "Internal server error: " + queryErr.Error(), Code: domain.ERR_INTERNAL_SERVER}
 	}
 
 	tasks := []domain.Task{}
 	bindErr := cursor.All(c, &tasks)
 	if bindErr != nil {
-		return []domain.Task{}, domain.TaskError{Message: "Internal server error: " + bindErr.Error(), Code: domain.ERR_INTERNAL_SERVER}
+		return []domain.Task{}, 0, domain.TaskError{Message: "Internal server error: " + bindErr.Error(), Code: domain.ERR_INTERNAL_SERVER}
 	}
 
 	cursor.Close(c)
-	return tasks, nil
+	return tasks, total, nil
 }
 
 /* retrieves the task associated with the provided id if it exists */
@@ -46,9 +95,18 @@ func (tR *TaskRepository) GetTaskByID(c context.Context, taskID string) (domain.
 	return task, nil
 }
 
-/* adds the provided task to the database */
+/*
+adds the provided task to the database. The unique index on the id field
+is relied upon to reject tasks with duplicate IDs. Like in the updates, a
+missing due date is left out of the document rather than stored as the zero
+time.
+*/
 func (tR *TaskRepository) AddTask(c context.Context, newTask domain.Task) domain.CodedError {
 	_, err := tR.Collection.InsertOne(c, newTask)
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.TaskError{Message: "Task with the provided ID already exists", Code: domain.ERR_CONFLICT}
+	}
+
 	if err != nil {
 		return domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
 	}
@@ -56,45 +114,123 @@ func (tR *TaskRepository) AddTask(c context.Context, newTask domain.Task) domain
 	return nil
 }
 
-/* updates the task associated with the provided id with the parameters provided in the provided task struct */
-func (tR *TaskRepository) UpdateTask(c context.Context, taskID string, updatedTask domain.Task) (domain.Task, domain.CodedError) {
-	var setAttributes bson.D
+/*
+builds the filter that matches the task with the provided id. The filter
+also matches the version of the task unless the provided version is zero.
+*/
+func taskFilter(taskID string, version int64) bson.D {
+	filter := bson.D{{Key: "id", Value: taskID}}
+	if version != 0 {
+		filter = append(filter, bson.E{Key: "version", Value: version})
+	}
+
+	return filter
+}
+
+/*
+determines why a versioned write did not match any task. Returns a not found
+error if no task has the provided id and a precondition failed error if the
+task exists but has been modified since the provided version was read.
+*/
+func (tR *TaskRepository) unmatchedTaskError(c context.Context, taskID string) domain.CodedError {
+	count, err := tR.Collection.CountDocuments(c, bson.D{{Key: "id", Value: taskID}})
+	if err != nil {
+		return domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if count == 0 {
+		return domain.TaskError{Message: "Task not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return domain.TaskError{Message: "Task has been modified since it was last fetched", Code: domain.ERR_PRECONDITION_FAILED}
+}
+
+/*
+applies the update to the task associated with the provided id and increments
+its version. The update is only applied if the stored version matches the
+provided version (unless it is zero). Returns the task as it is after the update.
+*/
+func (tR *TaskRepository) applyUpdate(c context.Context, taskID string, setAttributes bson.D, unsetAttributes bson.D, version int64) (domain.Task, domain.CodedError) {
 	var task domain.Task
 
-	// check if the fields of the incoming struct are not the default values
-	// and append the results to setAttributes accordingly
-	if updatedTask.Title != "" {
-		setAttributes = append(setAttributes, bson.E{Key: "title", Value: updatedTask.Title})
+	update := bson.D{{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}}}
+	if len(setAttributes) > 0 {
+		update = append(update, bson.E{Key: "$set", Value: setAttributes})
+	}
+	if len(unsetAttributes) > 0 {
+		update = append(update, bson.E{Key: "$unset", Value: unsetAttributes})
+	}
+
+	result := tR.Collection.FindOneAndUpdate(c, taskFilter(taskID, version), update, options.FindOneAndUpdate().SetReturnDocument(options.After))
+	if result.Err() != nil && result.Err().Error() == mongo.ErrNoDocuments.Error() {
+		return task, tR.unmatchedTaskError(c, taskID)
 	}
-	if updatedTask.Description != "" {
-		setAttributes = append(setAttributes, bson.E{Key: "description", Value: updatedTask.Description})
+
+	if result.Err() != nil {
+		return task, domain.TaskError{Message: "Internal server error: " + result.Err().Error(), Code: domain.ERR_INTERNAL_SERVER}
 	}
-	if updatedTask.Status != "" {
-		setAttributes = append(setAttributes, bson.E{Key: "status", Value: updatedTask.Status})
+
+	if err := result.Decode(&task); err != nil {
+		return task, domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
 	}
-	if !updatedTask.DueDate.IsZero() {
+
+	return task, nil
+}
+
+/*
+replaces the mutable fields of the task associated with the provided id with
+the ones in the provided task struct, along with its status history. Fields
+with zero values are cleared. The id, owner and creation time of the task
+are never modified.
+*/
+func (tR *TaskRepository) UpdateTask(c context.Context, taskID string, updatedTask domain.Task, version int64) (domain.Task, domain.CodedError) {
+	setAttributes := bson.D{
+		{Key: "title", Value: updatedTask.Title},
+		{Key: "description", Value: updatedTask.Description},
+		{Key: "status", Value: updatedTask.Status},
+		{Key: "assignee", Value: updatedTask.Assignee},
+		{Key: "updated_at", Value: updatedTask.UpdatedAt},
+		{Key: "status_history", Value: updatedTask.StatusHistory},
+	}
+
+	// the due date is removed rather than set to the zero time
+	var unsetAttributes bson.D
+	if updatedTask.DueDate.IsZero() {
+		unsetAttributes = append(unsetAttributes, bson.E{Key: "due_date", Value: ""})
+	} else {
 		setAttributes = append(setAttributes, bson.E{Key: "due_date", Value: updatedTask.DueDate})
 	}
 
-	result := tR.Collection.FindOneAndUpdate(c, bson.D{{Key: "id", Value: taskID}}, bson.D{
-		{Key: "$set", Value: setAttributes},
-	})
+	return tR.applyUpdate(c, taskID, setAttributes, unsetAttributes, version)
+}
 
-	if result.Err() != nil && result.Err().Error() == mongo.ErrNoDocuments.Error() {
-		return task, domain.TaskError{Message: "Task not found", Code: domain.ERR_NOT_FOUND}
+/*
+updates only the fields of the task associated with the provided id that are
+present in the patch. Fields with nil values are removed from the task.
+*/
+func (tR *TaskRepository) PatchTask(c context.Context, taskID string, patch domain.TaskPatch, version int64) (domain.Task, domain.CodedError) {
+	var setAttributes bson.D
+	var unsetAttributes bson.D
+	for key, value := range patch {
+		if value == nil {
+			unsetAttributes = append(unsetAttributes, bson.E{Key: key, Value: ""})
+		} else {
+			setAttributes = append(setAttributes, bson.E{Key: key, Value: value})
+		}
 	}
 
-	// fetch the updated task to get the latest version of the updated task
-	newTask, _ := tR.GetTaskByID(c, taskID)
-	return newTask, nil
+	return tR.applyUpdate(c, taskID, setAttributes, unsetAttributes, version)
 }
 
-/* deletes the task associated with the provided id if it exists */
-func (tR *TaskRepository) DeleteTask(c context.Context, taskID string) domain.CodedError {
-	result := tR.Collection.FindOneAndDelete(c, bson.D{{Key: "id", Value: taskID}})
+/*
+deletes the task associated with the provided id if it exists. The task is
+only deleted if the stored version matches the provided version (unless it is zero).
+*/
+func (tR *TaskRepository) DeleteTask(c context.Context, taskID string, version int64) domain.CodedError {
+	result := tR.Collection.FindOneAndDelete(c, taskFilter(taskID, version))
 
 	if result.Err() != nil && result.Err().Error() == mongo.ErrNoDocuments.Error() {
-		return domain.TaskError{Message: "Task not found", Code: domain.ERR_NOT_FOUND}
+		return tR.unmatchedTaskError(c, taskID)
 	}
 
 	if result.Err() != nil {
@@ -103,3 +239,92 @@ func (tR *TaskRepository) DeleteTask(c context.Context, taskID string) domain.Co
 
 	return nil
 }
+
+/* deletes every task owned by the provided user and returns how many were deleted */
+func (tR *TaskRepository) DeleteOwnerTasks(c context.Context, owner string) (int64, domain.CodedError) {
+	result, err := tR.Collection.DeleteMany(c, bson.D{{Key: "owner", Value: owner}})
+	if err != nil {
+		return 0, domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return result.DeletedCount, nil
+}
+
+/*
+clears the assignee of every task assigned to the provided user and
+increments their versions. Returns how many tasks were unassigned.
+*/
+func (tR *TaskRepository) UnassignTasks(c context.Context, assignee string, updatedAt time.Time) (int64, domain.CodedError) {
+	update := bson.D{
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+		{Key: "$set", Value: bson.D{{Key: "assignee", Value: ""}, {Key: "updated_at", Value: updatedAt}}},
+	}
+
+	result, err := tR.Collection.UpdateMany(c, bson.D{{Key: "assignee", Value: assignee}}, update)
+	if err != nil {
+		return 0, domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return result.ModifiedCount, nil
+}
+
+/*
+renames the `duedate` field of the tasks that were stored before the field was
+named `due_date`, so that their due dates are decoded, filtered and sorted on
+again. Tasks that already have a `due_date` are left untouched, which makes
+this safe to run on every start. Returns how many tasks were updated.
+*/
+func (tR *TaskRepository) RenameLegacyDueDates(c context.Context) (int64, domain.CodedError) {
+	filter := bson.D{
+		{Key: "duedate", Value: bson.D{{Key: "$exists", Value: true}}},
+		{Key: "due_date", Value: bson.D{{Key: "$exists", Value: false}}},
+	}
+
+	result, err := tR.Collection.UpdateMany(c, filter, bson.D{{Key: "$rename", Value: bson.D{{Key: "duedate", Value: "due_date"}}}})
+	if err != nil {
+		return 0, domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return result.ModifiedCount, nil
+}
+
+/*
+sets the version of the tasks that were stored before the versioning was
+introduced to 1, so that they get a proper ETag and can be updated with
+If-Match. Tasks that already have a version are left untouched, which makes
+this safe to run on every start. Returns how many tasks were updated.
+*/
+func (tR *TaskRepository) BackfillVersions(c context.Context) (int64, domain.CodedError) {
+	filter := bson.D{{Key: "version", Value: bson.D{{Key: "$exists", Value: false}}}}
+	result, err := tR.Collection.UpdateMany(c, filter, bson.D{{Key: "$set", Value: bson.D{{Key: "version", Value: 1}}}})
+	if err != nil {
+		return 0, domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return result.ModifiedCount, nil
+}
+
+/*
+sets the owner of the tasks that were stored before the tasks had owners,
+which can otherwise only be seen and changed by the users allowed to manage
+every task. Tasks that already have an owner are left untouched, which makes
+this safe to run on every start. Returns how many tasks were updated.
+*/
+func (tR *TaskRepository) BackfillOwners(c context.Context, owner string) (int64, domain.CodedError) {
+	filter := bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "owner", Value: bson.D{{Key: "$exists", Value: false}}}},
+		bson.D{{Key: "owner", Value: ""}},
+	}}}
+
+	update := bson.D{
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+		{Key: "$set", Value: bson.D{{Key: "owner", Value: owner}}},
+	}
+
+	result, err := tR.Collection.UpdateMany(c, filter, update)
+	if err != nil {
+		return 0, domain.TaskError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return result.ModifiedCount, nil
+}
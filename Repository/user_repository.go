@@ -2,10 +2,13 @@ package repository
 
 import (
 	"context"
+	"regexp"
 	domain "task_manager_api/Domain"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 /* Implements the UserRespositoryInterface defined in `domain`*/
@@ -31,9 +34,16 @@ func (uR *UserRepository) CheckDuplicate(c context.Context, key string, value in
 	return domain.UserError{Message: errorMessage, Code: domain.ERR_INTERNAL_SERVER}
 }
 
-/* Adds the user to the DB */
+/*
+Adds the user to the DB. The unique indices on the username and email fields
+are relied upon to reject duplicate users.
+*/
 func (uR *UserRepository) CreateUser(c context.Context, user domain.User) domain.CodedError {
 	_, err := uR.Collection.InsertOne(c, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.UserError{Message: "User with the provided username or email already exists", Code: domain.ERR_CONFLICT}
+	}
+
 	if err != nil {
 		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
 	}
@@ -63,6 +73,103 @@ func (uR *UserRepository) GetByUsername(c context.Context, username string) (dom
 	return storedUser, nil
 }
 
+/*
+queries for a user with the provided email and returns the resulting user
+object if it exists
+*/
+func (uR *UserRepository) GetByEmail(c context.Context, email string) (domain.User, domain.CodedError) {
+	var storedUser domain.User
+	result := uR.Collection.FindOne(c, bson.D{{Key: "email", Value: email}})
+	if result.Err() != nil && result.Err().Error() == mongo.ErrNoDocuments.Error() {
+		return domain.User{}, domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if result.Err() != nil {
+		return domain.User{}, domain.UserError{Message: "Internal server error: " + result.Err().Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if err := result.Decode(&storedUser); err != nil {
+		return domain.User{}, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return storedUser, nil
+}
+
+/*
+retrieves a page of the users that match the filters of the provided query
+along with the total number of matching users in the db
+*/
+func (uR *UserRepository) GetUsers(c context.Context, query domain.UserQuery) ([]domain.User, int64, domain.CodedError) {
+	filter := bson.D{}
+	if query.Role != "" {
+		filter = append(filter, bson.E{Key: "role", Value: query.Role})
+	}
+	if query.Search != "" {
+		search := primitive.Regex{Pattern: regexp.QuoteMeta(query.Search), Options: "i"}
+		filter = append(filter, bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: "username", Value: search}},
+			bson.D{{Key: "email", Value: search}},
+			bson.D{{Key: "display_name", Value: search}},
+		}})
+	}
+
+	total, err := uR.Collection.CountDocuments(c, filter)
+	if err != nil {
+		return []domain.User{}, 0, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	// the username is used as a tie breaker to keep the order stable across pages
+	sortOrder := 1
+	if query.SortOrder == domain.SortDescending {
+		sortOrder = -1
+	}
+	sort := bson.D{{Key: query.SortBy, Value: sortOrder}}
+	if query.SortBy != "username" {
+		sort = append(sort, bson.E{Key: "username", Value: 1})
+	}
+
+	cursor, err := uR.Collection.Find(c, filter, options.Find().SetSort(sort).SetSkip(query.Offset).SetLimit(query.Limit))
+	if err != nil {
+		return []domain.User{}, 0, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+	defer cursor.Close(c)
+
+	users := []domain.User{}
+	if err := cursor.All(c, &users); err != nil {
+		return []domain.User{}, 0, domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	return users, total, nil
+}
+
+/*
+Replaces the email, the display name and the verification status of the user
+with the provided username with the ones in the provided user struct. The
+unique index on the email field is relied upon to reject duplicates.
+*/
+func (uR *UserRepository) UpdateProfile(c context.Context, user domain.User) domain.CodedError {
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "email", Value: user.Email},
+		{Key: "display_name", Value: user.DisplayName},
+		{Key: "verified", Value: user.Verified},
+	}}}
+
+	result, err := uR.Collection.UpdateOne(c, bson.D{{Key: "username", Value: user.Username}}, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.UserError{Message: "An account with the provided email already exists", Code: domain.ERR_CONFLICT}
+	}
+
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
+
 /* Promotes an account with role `user` to role `admin` */
 func (uR *UserRepository) PromoteUser(c context.Context, username string) domain.CodedError {
 	result := uR.Collection.FindOneAndUpdate(context.TODO(), bson.D{{Key: "username", Value: username}}, bson.D{{Key: "$set", Value: bson.D{{Key: "role", Value: "admin"}}}})
@@ -76,3 +183,74 @@ func (uR *UserRepository) PromoteUser(c context.Context, username string) domain
 
 	return nil
 }
+
+/* Demotes an account with role `admin` to role `user` */
+func (uR *UserRepository) DemoteUser(c context.Context, username string) domain.CodedError {
+	return uR.setField(c, username, "role", domain.RoleUser)
+}
+
+/* Assigns the role with the provided name to the account */
+func (uR *UserRepository) SetRole(c context.Context, username string, role string) domain.CodedError {
+	return uR.setField(c, username, "role", role)
+}
+
+/* Deactivates or activates the account with the provided username */
+func (uR *UserRepository) SetDeactivated(c context.Context, username string, deactivated bool) domain.CodedError {
+	return uR.setField(c, username, "deactivated", deactivated)
+}
+
+/* sets the field of the user with the provided username to the provided value */
+func (uR *UserRepository) setField(c context.Context, username string, key string, value interface{}) domain.CodedError {
+	result, err := uR.Collection.UpdateOne(c, bson.D{{Key: "username", Value: username}}, bson.D{{Key: "$set", Value: bson.D{{Key: key, Value: value}}}})
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
+
+/* Deletes the account with the provided username */
+func (uR *UserRepository) DeleteUser(c context.Context, username string) domain.CodedError {
+	result, err := uR.Collection.DeleteOne(c, bson.D{{Key: "username", Value: username}})
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if result.DeletedCount == 0 {
+		return domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
+
+/* Replaces the (hashed) password of the user with the provided username */
+func (uR *UserRepository) UpdatePassword(c context.Context, username string, password string) domain.CodedError {
+	result, err := uR.Collection.UpdateOne(c, bson.D{{Key: "username", Value: username}}, bson.D{{Key: "$set", Value: bson.D{{Key: "password", Value: password}}}})
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
+
+/* Marks the email of the user with the provided username as verified */
+func (uR *UserRepository) VerifyEmail(c context.Context, username string) domain.CodedError {
+	result, err := uR.Collection.UpdateOne(c, bson.D{{Key: "username", Value: username}}, bson.D{{Key: "$set", Value: bson.D{{Key: "verified", Value: true}}}})
+	if err != nil {
+		return domain.UserError{Message: "Internal server error: " + err.Error(), Code: domain.ERR_INTERNAL_SERVER}
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return nil
+}
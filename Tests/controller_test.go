@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"task_manager_api/Delivery/controllers"
 	domain "task_manager_api/Domain"
+	infrastructure "task_manager_api/Infrastructure"
 	mocks "task_manager_api/Mocks"
 	"testing"
 	"time"
@@ -20,13 +21,19 @@ type controllerSuite struct {
 	suite.Suite
 	taskUsecase    *mocks.TaskUsecaseInterface
 	userUsecase    *mocks.UserUsecaseInterface
+	roleUsecase    *mocks.RoleUsecaseInterface
 	taskController controllers.TaskController
 	userController controllers.UserController
+	roleController controllers.RoleController
 	testingServer  *httptest.Server
 }
 
+// the subject injected into every request in place of the auth middleware
+var controllerSubject = domain.Subject{Username: "controller_user", Role: domain.RoleAdmin}
+
 type TokenResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 func (suite *controllerSuite) SetupSuite() {
@@ -39,18 +46,63 @@ func (suite *controllerSuite) SetupSuite() {
 	suite.userController = controllers.UserController{
 		UserUsecase: suite.userUsecase,
 	}
+	suite.roleUsecase = new(mocks.RoleUsecaseInterface)
+	suite.roleController = controllers.RoleController{
+		RoleUsecase: suite.roleUsecase,
+	}
 
 	router := gin.Default()
+	router.Use(infrastructure.RequestIDMiddleware(func() string { return "controller_request" }))
+	router.Use(func(c *gin.Context) {
+		c.Set(domain.ContextSubject, controllerSubject)
+	})
 
 	router.GET("/tasks", suite.taskController.GetAll)
 	router.GET("/tasks/:id", suite.taskController.GetOne)
 	router.POST("/tasks", suite.taskController.Create)
 	router.PUT("/tasks/:id", suite.taskController.Update)
+	router.PATCH("/tasks/:id", suite.taskController.Patch)
 	router.DELETE("/tasks/:id", suite.taskController.Delete)
 
 	router.POST("/signup", suite.userController.Signup)
+	router.POST("/users", suite.userController.CreateAccount)
 	router.POST("/login", suite.userController.Login)
+	router.POST("/login/mfa", suite.userController.VerifyMFALogin)
+	router.POST("/login/mfa/enroll", suite.userController.EnrollMFALogin)
+	router.POST("/token/refresh", suite.userController.Refresh)
+	router.POST("/logout", suite.userController.Logout)
+	router.POST("/verify-email", suite.userController.VerifyEmail)
+	router.POST("/verify-email/resend", suite.userController.ResendVerification)
+	router.POST("/password/forgot", suite.userController.ForgotPassword)
+	router.POST("/password/reset", suite.userController.ResetPassword)
 	router.PATCH("/promote/:username", suite.userController.Promote)
+	router.GET("/sessions", suite.userController.GetSessions)
+	router.DELETE("/sessions/:id", suite.userController.RevokeSession)
+	router.DELETE("/users/:username/sessions", suite.userController.RevokeUserSessions)
+	router.PATCH("/demote/:username", suite.userController.Demote)
+	router.PUT("/users/:username/role", suite.userController.SetRole)
+	router.PATCH("/users/:username/deactivate", suite.userController.Deactivate)
+	router.PATCH("/users/:username/activate", suite.userController.Activate)
+	router.PATCH("/users/:username/unlock", suite.userController.Unlock)
+	router.DELETE("/users/:username/mfa", suite.userController.ResetMFA)
+	router.DELETE("/users/:username", suite.userController.DeleteUser)
+	router.GET("/users", suite.userController.GetUsers)
+	router.GET("/users/:username", suite.userController.GetUser)
+	router.GET("/me", suite.userController.GetProfile)
+	router.PATCH("/me", suite.userController.UpdateProfile)
+	router.PUT("/me/password", suite.userController.ChangePassword)
+	router.POST("/me/mfa/totp", suite.userController.EnrollTOTP)
+	router.POST("/me/mfa/totp/confirm", suite.userController.ConfirmTOTP)
+	router.POST("/me/mfa/recovery-codes", suite.userController.RegenerateRecoveryCodes)
+	router.DELETE("/me/mfa", suite.userController.DisableMFA)
+	router.POST("/me/api-keys", suite.userController.CreateAPIKey)
+	router.GET("/me/api-keys", suite.userController.GetAPIKeys)
+	router.DELETE("/me/api-keys/:id", suite.userController.RevokeAPIKey)
+	router.GET("/roles", suite.roleController.GetRoles)
+	router.GET("/roles/:name", suite.roleController.GetRole)
+	router.PUT("/roles/:name", suite.roleController.SaveRole)
+	router.PUT("/roles/:name/mfa", suite.roleController.SetRoleMFA)
+	router.DELETE("/roles/:name", suite.roleController.DeleteRole)
 
 	suite.testingServer = httptest.NewServer(router)
 }
@@ -58,8 +110,10 @@ func (suite *controllerSuite) SetupSuite() {
 func (suite *controllerSuite) SetupTest() {
 	suite.taskUsecase = new(mocks.TaskUsecaseInterface)
 	suite.userUsecase = new(mocks.UserUsecaseInterface)
+	suite.roleUsecase = new(mocks.RoleUsecaseInterface)
 	suite.taskController.TaskUsecase = suite.taskUsecase
 	suite.userController.UserUsecase = suite.userUsecase
+	suite.roleController.RoleUsecase = suite.roleUsecase
 }
 
 func (suite *controllerSuite) TearDownSuite() {
@@ -67,16 +121,120 @@ func (suite *controllerSuite) TearDownSuite() {
 }
 
 func (suite *controllerSuite) TestGetHTTPErrorCodes() {
-	testParams := map[domain.CodedError]int{
-		domain.TaskError{Code: domain.ERR_BAD_REQUEST}:     400,
-		domain.TaskError{Code: domain.ERR_INTERNAL_SERVER}: 500,
-		domain.TaskError{Code: domain.ERR_NOT_FOUND}:       404,
-		domain.TaskError{Code: domain.ERR_UNAUTHORIZED}:    401,
+	testParams := map[string]int{
+		domain.ERR_BAD_REQUEST:            400,
+		domain.ERR_INTERNAL_SERVER:        500,
+		domain.ERR_NOT_FOUND:              404,
+		domain.ERR_UNAUTHORIZED:           401,
+		domain.ERR_FORBIDDEN:              403,
+		domain.ERR_CONFLICT:               409,
+		domain.ERR_PRECONDITION_FAILED:    412,
+		domain.ERR_INVALID_TRANSITION:     422,
+		domain.ERR_TOO_MANY_REQUESTS:      429,
+		domain.ERR_UNSUPPORTED_MEDIA_TYPE: 415,
+	}
+
+	for code, statusCode := range testParams {
+		suite.Equal(statusCode, controllers.GetHTTPErrorCode(domain.TaskError{Code: code}))
 	}
+}
+
+func (suite *controllerSuite) TestErrorResponses() {
+	internalErr := domain.TaskError{Message: "Internal server error: connection refused by db.internal:27017", Code: domain.ERR_INTERNAL_SERVER}
+	suite.taskUsecase.On("GetTaskByID", mock.Anything, controllerSubject, "internal").Return(domain.Task{}, internalErr)
+	suite.taskUsecase.On("GetTaskByID", mock.Anything, controllerSubject, "missing").Return(domain.Task{}, domain.TaskError{Message: "Task not found", Code: domain.ERR_NOT_FOUND})
+
+	// internal errors are replaced with a generic detail and the correlation ID
+	response, err := http.Get(suite.testingServer.URL + "/tasks/internal")
+	suite.NoError(err, "no errors in request")
+	defer response.Body.Close()
+
+	var problem domain.Problem
+	suite.NoError(json.NewDecoder(response.Body).Decode(&problem), "no error during body decoding")
+	suite.Equal(http.StatusInternalServerError, response.StatusCode)
+	suite.Equal(infrastructure.ProblemContentType, response.Header.Get("Content-Type"))
+	suite.Equal(domain.Problem{
+		Type:      "/problems/internal_server_error",
+		Title:     "Internal Server Error",
+		Status:    http.StatusInternalServerError,
+		Detail:    infrastructure.InternalErrorMessage,
+		Instance:  "/tasks/internal",
+		Code:      domain.ERR_INTERNAL_SERVER,
+		RequestID: "controller_request",
+	}, problem)
+	suite.Equal("controller_request", response.Header.Get(infrastructure.RequestIDHeader))
+
+	// the other errors are meant for the client and are sent as they are
+	response, err = http.Get(suite.testingServer.URL + "/tasks/missing")
+	suite.NoError(err, "no errors in request")
+	defer response.Body.Close()
+
+	problem = domain.Problem{}
+	suite.NoError(json.NewDecoder(response.Body).Decode(&problem), "no error during body decoding")
+	suite.Equal(http.StatusNotFound, response.StatusCode)
+	suite.Equal(infrastructure.ProblemContentType, response.Header.Get("Content-Type"))
+	suite.Equal(domain.Problem{
+		Type:      "/problems/not_found",
+		Title:     "Not Found",
+		Status:    http.StatusNotFound,
+		Detail:    "Task not found",
+		Instance:  "/tasks/missing",
+		Code:      domain.ERR_NOT_FOUND,
+		RequestID: "controller_request",
+	}, problem)
+	suite.taskUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestValidationErrorResponses() {
+	fieldErr := domain.TaskError{
+		Message: "Title can not be empty",
+		Code:    domain.ERR_BAD_REQUEST,
+		Fields:  []domain.FieldError{{Field: "title", Message: "Title can not be empty"}},
+	}
+	suite.taskUsecase.On("AddTask", mock.Anything, controllerSubject, domain.Task{}).Return(domain.Task{}, fieldErr)
+
+	testParams := []struct {
+		path   string
+		body   string
+		fields []domain.FieldError
+	}{
+		// errors of the usecase keep their fields
+		{"/tasks", `{}`, fieldErr.Fields},
+		// missing fields are named as they appear in the request
+		{"/password/reset", `{"token": "token"}`, []domain.FieldError{{Field: "new_password", Message: "new_password is required"}}},
+		{"/me", `{"email": "valid@mail.com"}`, []domain.FieldError{{Field: "current_password", Message: "current_password is required"}}},
+		// so are fields of the wrong type
+		{"/tasks", `{"title": 12}`, []domain.FieldError{{Field: "title", Message: "title can not be a number"}}},
+	}
+
+	for _, param := range testParams {
+		method := http.MethodPost
+		if param.path == "/me" {
+			method = http.MethodPatch
+		}
 
-	for domainErr, statusCode := range testParams {
-		suite.Equal(statusCode, controllers.GetHTTPErrorCode(domainErr))
+		request, _ := http.NewRequest(method, suite.testingServer.URL+param.path, bytes.NewBufferString(param.body))
+		request.Header.Add("Content-Type", "application/json")
+		response, err := http.DefaultClient.Do(request)
+		suite.NoError(err, "no errors in request")
+
+		var problem domain.Problem
+		suite.NoError(json.NewDecoder(response.Body).Decode(&problem), "no error during body decoding")
+		response.Body.Close()
+
+		suite.Equal(http.StatusBadRequest, response.StatusCode, param.path)
+		suite.Equal("/problems/bad_request", problem.Type, param.path)
+		suite.Equal(param.fields, problem.Errors, param.path)
 	}
+
+	suite.taskUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestUserJSONExcludesPassword() {
+	encoded, err := json.Marshal(domain.User{Username: "username", Password: "$2a$10$hash"})
+	suite.NoError(err, "no error during encoding")
+	suite.NotContains(string(encoded), "password", "the password field is never encoded")
+	suite.NotContains(string(encoded), "$2a$10$hash", "the password hash is never encoded")
 }
 
 func (suite *controllerSuite) TestGetAllTasks_Positive() {
@@ -88,7 +246,8 @@ func (suite *controllerSuite) TestGetAllTasks_Positive() {
 		Status:      "pending",
 	}
 
-	suite.taskUsecase.On("GetAllTasks", mock.Anything).Return([]domain.Task{task}, nil)
+	page := domain.TaskPage{Tasks: []domain.Task{task}, Total: 1, Limit: domain.DefaultPageLimit}
+	suite.taskUsecase.On("GetAllTasks", mock.Anything, controllerSubject, domain.TaskQuery{}).Return(page, nil)
 	response, err := http.Get(suite.testingServer.URL + "/tasks")
 	if response != nil {
 		defer response.Body.Close()
@@ -98,10 +257,44 @@ func (suite *controllerSuite) TestGetAllTasks_Positive() {
 	suite.Equal(http.StatusOK, response.StatusCode)
 	suite.taskUsecase.AssertExpectations(suite.T())
 
-	var tasks []domain.Task
-	err = json.NewDecoder(response.Body).Decode(&tasks)
+	var fetchedPage domain.TaskPage
+	err = json.NewDecoder(response.Body).Decode(&fetchedPage)
+	suite.NoError(err, "no error during body decoding")
+	suite.Equal(1, len(fetchedPage.Tasks), "sends data correctly")
+	suite.Equal(int64(1), fetchedPage.Total, "sends total correctly")
+	suite.Equal("", fetchedPage.Next, "no next link on the last page")
+	suite.Equal("", fetchedPage.Previous, "no previous link on the first page")
+}
+
+func (suite *controllerSuite) TestGetAllTasks_Pagination() {
+	query := domain.TaskQuery{Status: "pending", Limit: 1, Offset: 1}
+	page := domain.TaskPage{Tasks: []domain.Task{{ID: "2"}}, Total: 3, Limit: 1, Offset: 1}
+	suite.taskUsecase.On("GetAllTasks", mock.Anything, controllerSubject, query).Return(page, nil)
+	response, err := http.Get(suite.testingServer.URL + "/tasks?status=pending&limit=1&offset=1")
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusOK, response.StatusCode)
+	suite.taskUsecase.AssertExpectations(suite.T())
+
+	var fetchedPage domain.TaskPage
+	err = json.NewDecoder(response.Body).Decode(&fetchedPage)
 	suite.NoError(err, "no error during body decoding")
-	suite.Equal(1, len(tasks), "sends data correctly")
+	suite.Equal("/tasks?limit=1&offset=2&status=pending", fetchedPage.Next, "next link points to the following page")
+	suite.Equal("/tasks?limit=1&offset=0&status=pending", fetchedPage.Previous, "previous link points to the preceding page")
+}
+
+func (suite *controllerSuite) TestGetAllTasks_InvalidQuery() {
+	response, err := http.Get(suite.testingServer.URL + "/tasks?due_after=yesterday")
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusBadRequest, response.StatusCode)
+	suite.taskUsecase.AssertNotCalled(suite.T(), "GetAllTasks", mock.Anything, mock.Anything, mock.Anything)
 }
 
 func (suite *controllerSuite) TestGetAllTasks_Negative() {
@@ -114,7 +307,7 @@ func (suite *controllerSuite) TestGetAllTasks_Negative() {
 	}
 
 	sampleErr := domain.TaskError{Message: "msg123", Code: domain.ERR_INTERNAL_SERVER}
-	suite.taskUsecase.On("GetAllTasks", mock.Anything).Return([]domain.Task{task}, sampleErr)
+	suite.taskUsecase.On("GetAllTasks", mock.Anything, controllerSubject, domain.TaskQuery{}).Return(domain.TaskPage{Tasks: []domain.Task{task}}, sampleErr)
 	response, err := http.Get(suite.testingServer.URL + "/tasks")
 	if response != nil {
 		defer response.Body.Close()
@@ -134,7 +327,7 @@ func (suite *controllerSuite) TestGetTaskByID_Positive() {
 		Status:      "pending",
 	}
 
-	suite.taskUsecase.On("GetTaskByID", mock.Anything, task.ID).Return(task, nil)
+	suite.taskUsecase.On("GetTaskByID", mock.Anything, controllerSubject, task.ID).Return(task, nil)
 	response, err := http.Get(suite.testingServer.URL + "/tasks/" + task.ID)
 	if response != nil {
 		defer response.Body.Close()
@@ -157,7 +350,7 @@ func (suite *controllerSuite) TestGetTaskByID_Positive() {
 func (suite *controllerSuite) TestGetTaskByID_Negative() {
 	wrongID := "2"
 	sampleErr := domain.TaskError{Message: "msg123", Code: domain.ERR_BAD_REQUEST}
-	suite.taskUsecase.On("GetTaskByID", mock.Anything, wrongID).Return(domain.Task{}, sampleErr)
+	suite.taskUsecase.On("GetTaskByID", mock.Anything, controllerSubject, wrongID).Return(domain.Task{}, sampleErr)
 	response, err := http.Get(suite.testingServer.URL + "/tasks/" + wrongID)
 	if response != nil {
 		defer response.Body.Close()
@@ -168,10 +361,38 @@ func (suite *controllerSuite) TestGetTaskByID_Negative() {
 	suite.taskUsecase.AssertExpectations(suite.T())
 }
 
+func (suite *controllerSuite) TestGetTaskByID_ETag() {
+	task := domain.Task{ID: "1", Title: "title", Version: 3}
+	suite.taskUsecase.On("GetTaskByID", mock.Anything, controllerSubject, task.ID).Return(task, nil)
+	client := http.Client{}
+
+	request, _ := http.NewRequest(http.MethodGet, suite.testingServer.URL+"/tasks/"+task.ID, nil)
+	response, err := client.Do(request)
+	suite.NoError(err, "no errors in request")
+	response.Body.Close()
+	suite.Equal(http.StatusOK, response.StatusCode)
+	suite.Equal(`"3"`, response.Header.Get("ETag"), "version is sent as the ETag")
+
+	request, _ = http.NewRequest(http.MethodGet, suite.testingServer.URL+"/tasks/"+task.ID, nil)
+	request.Header.Add("If-None-Match", `"2", "3"`)
+	response, err = client.Do(request)
+	suite.NoError(err, "no errors in request")
+	response.Body.Close()
+	suite.Equal(http.StatusNotModified, response.StatusCode, "not modified when the ETag matches")
+
+	request, _ = http.NewRequest(http.MethodGet, suite.testingServer.URL+"/tasks/"+task.ID, nil)
+	request.Header.Add("If-None-Match", `"2"`)
+	response, err = client.Do(request)
+	suite.NoError(err, "no errors in request")
+	response.Body.Close()
+	suite.Equal(http.StatusOK, response.StatusCode, "task is sent when the ETag is stale")
+}
+
 func (suite *controllerSuite) TestAdd_Positive() {
 	newTask := domain.Task{}
+	createdTask := domain.Task{ID: "generated_id"}
 	client := http.Client{}
-	suite.taskUsecase.On("AddTask", mock.Anything, newTask).Return(nil)
+	suite.taskUsecase.On("AddTask", mock.Anything, controllerSubject, newTask).Return(createdTask, nil)
 
 	requestBody, err := json.Marshal(&newTask)
 	suite.NoError(err, "can not marshal struct to json")
@@ -185,14 +406,15 @@ func (suite *controllerSuite) TestAdd_Positive() {
 
 	suite.NoError(err, "no errors in request")
 	suite.Equal(http.StatusCreated, response.StatusCode)
+	suite.Equal("/tasks/"+createdTask.ID, response.Header.Get("Location"), "location of the created task is sent")
 	suite.taskUsecase.AssertExpectations(suite.T())
 }
 
 func (suite *controllerSuite) TestAdd_Negative() {
 	newTask := domain.Task{}
 	client := http.Client{}
-	sampleErr := domain.TaskError{Message: "msg123", Code: domain.ERR_BAD_REQUEST}
-	suite.taskUsecase.On("AddTask", mock.Anything, newTask).Return(sampleErr)
+	sampleErr := domain.TaskError{Message: "msg123", Code: domain.ERR_CONFLICT}
+	suite.taskUsecase.On("AddTask", mock.Anything, controllerSubject, newTask).Return(domain.Task{}, sampleErr)
 	requestBody, err := json.Marshal(&newTask)
 	suite.NoError(err, "can not marshal struct to json")
 
@@ -217,7 +439,7 @@ func (suite *controllerSuite) TestUpdate_Positive() {
 	}
 
 	client := http.Client{}
-	suite.taskUsecase.On("UpdateTask", mock.Anything, taskID, taskUpdates).Return(taskUpdates, nil)
+	suite.taskUsecase.On("UpdateTask", mock.Anything, controllerSubject, taskID, taskUpdates, int64(0)).Return(taskUpdates, nil)
 
 	requestBody, err := json.Marshal(&taskUpdates)
 	suite.NoError(err, "can not marshal struct to json")
@@ -246,7 +468,7 @@ func (suite *controllerSuite) TestUpdate_Negative() {
 	taskUpdates := domain.Task{}
 	client := http.Client{}
 	sampleErr := domain.TaskError{Message: "msg123", Code: domain.ERR_BAD_REQUEST}
-	suite.taskUsecase.On("UpdateTask", mock.Anything, taskID, mock.AnythingOfType("Task")).Return(taskUpdates, sampleErr)
+	suite.taskUsecase.On("UpdateTask", mock.Anything, controllerSubject, taskID, mock.AnythingOfType("Task"), int64(0)).Return(taskUpdates, sampleErr)
 
 	requestBody, err := json.Marshal(&taskUpdates)
 	suite.NoError(err, "can not marshal struct to json")
@@ -263,10 +485,73 @@ func (suite *controllerSuite) TestUpdate_Negative() {
 	suite.taskUsecase.AssertExpectations(suite.T())
 }
 
+func (suite *controllerSuite) TestUpdate_IfMatch() {
+	taskID := "1"
+	taskUpdates := domain.Task{Title: "title"}
+	sampleErr := domain.TaskError{Message: "msg123", Code: domain.ERR_PRECONDITION_FAILED}
+	suite.taskUsecase.On("UpdateTask", mock.Anything, controllerSubject, taskID, taskUpdates, int64(2)).Return(domain.Task{}, sampleErr)
+	suite.taskUsecase.On("UpdateTask", mock.Anything, controllerSubject, taskID, taskUpdates, int64(3)).Return(domain.Task{ID: taskID, Version: 4}, nil)
+	client := http.Client{}
+
+	requestBody, err := json.Marshal(&taskUpdates)
+	suite.NoError(err, "can not marshal struct to json")
+
+	for ifMatch, statusCode := range map[string]int{`"2"`: http.StatusPreconditionFailed, `"3"`: http.StatusOK, `W/"3"`: http.StatusBadRequest} {
+		request, _ := http.NewRequest(http.MethodPut, suite.testingServer.URL+"/tasks/"+taskID, bytes.NewBuffer(requestBody))
+		request.Header.Add("Content-Type", "application/json")
+		request.Header.Add("If-Match", ifMatch)
+		response, err := client.Do(request)
+		suite.NoError(err, "no errors in request")
+		response.Body.Close()
+		suite.Equal(statusCode, response.StatusCode)
+		if statusCode == http.StatusOK {
+			suite.Equal(`"4"`, response.Header.Get("ETag"), "ETag of the updated task is sent")
+		}
+	}
+}
+
+func (suite *controllerSuite) TestPatch_Positive() {
+	taskID := "1"
+	patch := domain.TaskPatch{"title": "title", "description": nil}
+	suite.taskUsecase.On("PatchTask", mock.Anything, controllerSubject, taskID, patch, int64(0)).Return(domain.Task{ID: taskID, Title: "title", Version: 2}, nil)
+	client := http.Client{}
+
+	request, _ := http.NewRequest(http.MethodPatch, suite.testingServer.URL+"/tasks/"+taskID, bytes.NewBufferString(`{"title": "title", "description": null}`))
+	request.Header.Add("Content-Type", "application/merge-patch+json")
+	response, err := client.Do(request)
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusOK, response.StatusCode)
+	suite.Equal(`"2"`, response.Header.Get("ETag"), "ETag of the patched task is sent")
+	suite.taskUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestPatch_Negative() {
+	client := http.Client{}
+	testParams := map[string]int{
+		"text/plain":                   http.StatusUnsupportedMediaType,
+		"application/merge-patch+json": http.StatusBadRequest,
+	}
+
+	for contentType, statusCode := range testParams {
+		request, _ := http.NewRequest(http.MethodPatch, suite.testingServer.URL+"/tasks/1", bytes.NewBufferString(`["not", "an", "object"]`))
+		request.Header.Add("Content-Type", contentType)
+		response, err := client.Do(request)
+		suite.NoError(err, "no errors in request")
+		response.Body.Close()
+		suite.Equal(statusCode, response.StatusCode)
+	}
+
+	suite.taskUsecase.AssertNotCalled(suite.T(), "PatchTask", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
 func (suite *controllerSuite) TestDelete_Positive() {
 	taskID := "1"
 	client := http.Client{}
-	suite.taskUsecase.On("DeleteTask", mock.Anything, taskID).Return(nil)
+	suite.taskUsecase.On("DeleteTask", mock.Anything, controllerSubject, taskID, int64(0)).Return(nil)
 	request, _ := http.NewRequest(http.MethodDelete, suite.testingServer.URL+"/tasks/"+taskID, nil)
 	response, err := client.Do(request)
 	if response != nil {
@@ -282,7 +567,7 @@ func (suite *controllerSuite) TestDelete_Negative() {
 	wrongID := "1"
 	sampleErr := domain.TaskError{Message: "msg123", Code: domain.ERR_BAD_REQUEST}
 	client := http.Client{}
-	suite.taskUsecase.On("DeleteTask", mock.Anything, wrongID).Return(sampleErr)
+	suite.taskUsecase.On("DeleteTask", mock.Anything, controllerSubject, wrongID, int64(0)).Return(sampleErr)
 	request, _ := http.NewRequest(http.MethodDelete, suite.testingServer.URL+"/tasks/"+wrongID, nil)
 	response, err := client.Do(request)
 	if response != nil {
@@ -295,17 +580,17 @@ func (suite *controllerSuite) TestDelete_Negative() {
 }
 
 func (suite *controllerSuite) TestSignup_Positive() {
+	// the role can not be chosen on signup
 	user := domain.User{
 		Username: "lksdajf",
 		Email:    "valid@mail.com",
 		Password: "dorwssap",
-		Role:     "admin",
 	}
 
 	client := http.Client{}
-	suite.userUsecase.On("CreateUser", mock.Anything, user).Return(nil)
+	suite.userUsecase.On("CreateUser", mock.Anything, user).Return(true, nil)
 
-	requestBody, err := json.Marshal(&user)
+	requestBody, err := json.Marshal(domain.Response{"username": user.Username, "email": user.Email, "password": user.Password, "role": "admin"})
 	suite.NoError(err, "can not marshal struct to json")
 
 	request, _ := http.NewRequest(http.MethodPost, suite.testingServer.URL+"/signup", bytes.NewBuffer(requestBody))
@@ -317,14 +602,51 @@ func (suite *controllerSuite) TestSignup_Positive() {
 
 	suite.NoError(err, "no errors in request")
 	suite.Equal(http.StatusCreated, response.StatusCode)
+
+	var body domain.Response
+	suite.NoError(json.NewDecoder(response.Body).Decode(&body), "no error during body decoding")
+	suite.Equal("Signup successful: a verification token has been sent to the provided email", body["message"])
 	suite.taskUsecase.AssertExpectations(suite.T())
 }
 
+func (suite *controllerSuite) TestSignup_VerificationOff() {
+	// no verification token is sent when the email verification is off
+	user := domain.User{
+		Username: "lksdajf",
+		Email:    "valid@mail.com",
+		Password: "dorwssap",
+		Role:     "user",
+	}
+
+	client := http.Client{}
+	suite.userUsecase.On("CreateUser", mock.Anything, domain.User{Username: user.Username, Email: user.Email, Password: user.Password}).Return(false, nil)
+	suite.userUsecase.On("CreateAccount", mock.Anything, mock.Anything, user).Return(false, nil)
+
+	requestBody, err := json.Marshal(domain.Response{"username": user.Username, "email": user.Email, "password": user.Password, "role": user.Role})
+	suite.NoError(err, "can not marshal struct to json")
+
+	for path, message := range map[string]string{"/signup": "Signup successful", "/users": "Account created"} {
+		request, _ := http.NewRequest(http.MethodPost, suite.testingServer.URL+path, bytes.NewBuffer(requestBody))
+		request.Header.Add("Content-Type", "application/json")
+		response, err := client.Do(request)
+		suite.NoError(err, "no errors in request")
+
+		var body domain.Response
+		suite.NoError(json.NewDecoder(response.Body).Decode(&body), "no error during body decoding")
+		response.Body.Close()
+
+		suite.Equal(http.StatusCreated, response.StatusCode)
+		suite.Equal(message, body["message"], "the response does not mention a verification token")
+	}
+
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
 func (suite *controllerSuite) TestSignup_Negative() {
 	user := domain.User{}
 	client := http.Client{}
 	sampleErr := domain.TaskError{Message: "msg123", Code: domain.ERR_INTERNAL_SERVER}
-	suite.userUsecase.On("CreateUser", mock.Anything, user).Return(sampleErr)
+	suite.userUsecase.On("CreateUser", mock.Anything, user).Return(false, sampleErr)
 
 	requestBody, err := json.Marshal(&user)
 	suite.NoError(err, "can not marshal struct to json")
@@ -341,11 +663,37 @@ func (suite *controllerSuite) TestSignup_Negative() {
 	suite.taskUsecase.AssertExpectations(suite.T())
 }
 
+func (suite *controllerSuite) TestCreateAccount() {
+	user := domain.User{
+		Username: "lksdajf",
+		Email:    "valid@mail.com",
+		Password: "dorwssap",
+		Role:     "admin",
+	}
+
+	client := http.Client{}
+	suite.userUsecase.On("CreateAccount", mock.Anything, mock.Anything, user).Return(true, nil)
+
+	requestBody, err := json.Marshal(domain.Response{"username": user.Username, "email": user.Email, "password": user.Password, "role": user.Role})
+	suite.NoError(err, "can not marshal struct to json")
+
+	request, _ := http.NewRequest(http.MethodPost, suite.testingServer.URL+"/users", bytes.NewBuffer(requestBody))
+	request.Header.Add("Content-Type", "application/json")
+	response, err := client.Do(request)
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusCreated, response.StatusCode)
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
 func (suite *controllerSuite) TestLogin_Positive() {
 	user := domain.User{}
-	sToken := "lskad123i12.3123123sadf"
+	tokens := domain.TokenPair{AccessToken: "lskad123i12.3123123sadf", RefreshToken: "refresh_token"}
 	client := http.Client{}
-	suite.userUsecase.On("ValidateAndGetToken", mock.Anything, user).Return(sToken, nil)
+	suite.userUsecase.On("ValidateAndGetToken", mock.Anything, user, "127.0.0.1").Return(tokens, nil)
 
 	requestBody, err := json.Marshal(&user)
 	suite.NoError(err, "can not marshal struct to json")
@@ -361,7 +709,8 @@ func (suite *controllerSuite) TestLogin_Positive() {
 	responseBody := TokenResponse{}
 	err = json.NewDecoder(response.Body).Decode(&responseBody)
 	suite.NoError(err, "no error during body decoding")
-	suite.Equal(sToken, responseBody.Token)
+	suite.Equal(tokens.AccessToken, responseBody.Token)
+	suite.Equal(tokens.RefreshToken, responseBody.RefreshToken)
 	suite.Equal(http.StatusOK, response.StatusCode)
 	suite.taskUsecase.AssertExpectations(suite.T())
 }
@@ -370,7 +719,7 @@ func (suite *controllerSuite) TestLogin_Negative() {
 	user := domain.User{}
 	client := http.Client{}
 	sampleErr := domain.TaskError{Message: "msg123", Code: domain.ERR_INTERNAL_SERVER}
-	suite.userUsecase.On("ValidateAndGetToken", mock.Anything, user).Return("", sampleErr)
+	suite.userUsecase.On("ValidateAndGetToken", mock.Anything, user, mock.Anything).Return(domain.TokenPair{}, sampleErr)
 
 	requestBody, err := json.Marshal(&user)
 	suite.NoError(err, "can not marshal struct to json")
@@ -387,9 +736,71 @@ func (suite *controllerSuite) TestLogin_Negative() {
 	suite.taskUsecase.AssertExpectations(suite.T())
 }
 
+func (suite *controllerSuite) TestLogin_MFARequired() {
+	suite.userUsecase.On("ValidateAndGetToken", mock.Anything, mock.Anything, mock.Anything).Return(domain.TokenPair{ChallengeToken: "challenge", MFAEnrollmentRequired: true}, nil)
+
+	response, err := http.Post(suite.testingServer.URL+"/login", "application/json", bytes.NewBufferString(`{"username": "admin", "password": "password"}`))
+	suite.NoError(err, "no errors in request")
+	defer response.Body.Close()
+
+	var responseBody map[string]any
+	suite.NoError(json.NewDecoder(response.Body).Decode(&responseBody), "no error during body decoding")
+	suite.Equal(http.StatusOK, response.StatusCode)
+	suite.Equal(true, responseBody["mfa_required"])
+	suite.Equal(true, responseBody["mfa_enrollment_required"])
+	suite.Equal("challenge", responseBody["challenge_token"])
+	suite.NotContains(responseBody, "token")
+}
+
+func (suite *controllerSuite) TestVerifyMFALogin() {
+	tokens := domain.TokenPair{AccessToken: "access_token", RefreshToken: "refresh_token", RecoveryCodes: []string{"abcde-fghjk"}}
+	suite.userUsecase.On("VerifyMFALogin", mock.Anything, "challenge", "123456", "", "127.0.0.1").Return(tokens, nil)
+	suite.userUsecase.On("VerifyMFALogin", mock.Anything, "challenge", "", "abcde-fghjk", "127.0.0.1").Return(domain.TokenPair{}, domain.UserError{Message: "Invalid authentication code", Code: domain.ERR_UNAUTHORIZED})
+
+	requestBodies := map[string]int{
+		`{"challenge_token": "challenge", "code": "123456"}`:               http.StatusOK,
+		`{"challenge_token": "challenge", "recovery_code": "abcde-fghjk"}`: http.StatusUnauthorized,
+		`{"challenge_token": "challenge"}`:                                 http.StatusBadRequest,
+		`{"code": "123456"}`:                                               http.StatusBadRequest,
+	}
+
+	for requestBody, statusCode := range requestBodies {
+		response, err := http.Post(suite.testingServer.URL+"/login/mfa", "application/json", bytes.NewBufferString(requestBody))
+		suite.NoError(err, "no errors in request")
+		suite.Equal(statusCode, response.StatusCode)
+
+		if statusCode == http.StatusOK {
+			var responseBody struct {
+				TokenResponse
+				RecoveryCodes []string `json:"recovery_codes"`
+			}
+			suite.NoError(json.NewDecoder(response.Body).Decode(&responseBody), "no error during body decoding")
+			suite.Equal("access_token", responseBody.Token)
+			suite.Equal(tokens.RecoveryCodes, responseBody.RecoveryCodes)
+		}
+		response.Body.Close()
+	}
+
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestEnrollMFALogin() {
+	setup := domain.TOTPSetup{Secret: "SECRET", URI: "otpauth://totp/task_manager_api:admin?secret=SECRET"}
+	suite.userUsecase.On("EnrollMFALogin", mock.Anything, "challenge").Return(setup, nil)
+
+	response, err := http.Post(suite.testingServer.URL+"/login/mfa/enroll", "application/json", bytes.NewBufferString(`{"challenge_token": "challenge"}`))
+	suite.NoError(err, "no errors in request")
+	defer response.Body.Close()
+
+	var responseBody domain.TOTPSetup
+	suite.NoError(json.NewDecoder(response.Body).Decode(&responseBody), "no error during body decoding")
+	suite.Equal(http.StatusOK, response.StatusCode)
+	suite.Equal(setup, responseBody)
+}
+
 func (suite *controllerSuite) TestPromote_Positive() {
 	username := "solitary_confinement"
-	suite.userUsecase.On("Promote", mock.Anything, username).Return(nil)
+	suite.userUsecase.On("Promote", mock.Anything, mock.Anything, username).Return(nil)
 	client := http.Client{}
 
 	request, _ := http.NewRequest(http.MethodPatch, suite.testingServer.URL+"/promote/"+username, nil)
@@ -404,6 +815,721 @@ func (suite *controllerSuite) TestPromote_Positive() {
 	suite.taskUsecase.AssertExpectations(suite.T())
 }
 
+func (suite *controllerSuite) TestRefresh_Positive() {
+	tokens := domain.TokenPair{AccessToken: "new_access_token", RefreshToken: "new_refresh_token"}
+	client := http.Client{}
+	suite.userUsecase.On("RefreshTokens", mock.Anything, "old_refresh_token").Return(tokens, nil)
+
+	requestBody := []byte(`{"refresh_token": "old_refresh_token"}`)
+	request, _ := http.NewRequest(http.MethodPost, suite.testingServer.URL+"/token/refresh", bytes.NewBuffer(requestBody))
+	request.Header.Add("Content-Type", "application/json")
+	response, err := client.Do(request)
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	responseBody := TokenResponse{}
+	err = json.NewDecoder(response.Body).Decode(&responseBody)
+	suite.NoError(err, "no error during body decoding")
+	suite.Equal(tokens.AccessToken, responseBody.Token)
+	suite.Equal(tokens.RefreshToken, responseBody.RefreshToken)
+	suite.Equal(http.StatusOK, response.StatusCode)
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestRefresh_Negative() {
+	client := http.Client{}
+	sampleErr := domain.UserError{Message: "Invalid refresh token", Code: domain.ERR_UNAUTHORIZED}
+	suite.userUsecase.On("RefreshTokens", mock.Anything, "old_refresh_token").Return(domain.TokenPair{}, sampleErr)
+
+	request, _ := http.NewRequest(http.MethodPost, suite.testingServer.URL+"/token/refresh", bytes.NewBuffer([]byte(`{"refresh_token": "old_refresh_token"}`)))
+	request.Header.Add("Content-Type", "application/json")
+	response, err := client.Do(request)
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusUnauthorized, response.StatusCode)
+
+	// the refresh token is required
+	request, _ = http.NewRequest(http.MethodPost, suite.testingServer.URL+"/token/refresh", bytes.NewBuffer([]byte(`{}`)))
+	request.Header.Add("Content-Type", "application/json")
+	response, err = client.Do(request)
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusBadRequest, response.StatusCode)
+	suite.userUsecase.AssertNumberOfCalls(suite.T(), "RefreshTokens", 1)
+}
+
+func (suite *controllerSuite) TestLogout_Positive() {
+	client := http.Client{}
+	suite.userUsecase.On("Logout", mock.Anything, "refresh_token").Return(nil)
+
+	request, _ := http.NewRequest(http.MethodPost, suite.testingServer.URL+"/logout", bytes.NewBuffer([]byte(`{"refresh_token": "refresh_token"}`)))
+	request.Header.Add("Content-Type", "application/json")
+	response, err := client.Do(request)
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusOK, response.StatusCode)
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestGetSessions() {
+	sessions := []domain.Session{{ID: "session_1", Username: controllerSubject.Username, Current: true}}
+	suite.userUsecase.On("GetSessions", mock.Anything, controllerSubject).Return(sessions, nil)
+
+	response, err := http.Get(suite.testingServer.URL + "/sessions")
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	responseBody := struct {
+		Sessions []domain.Session `json:"sessions"`
+	}{}
+	err = json.NewDecoder(response.Body).Decode(&responseBody)
+	suite.NoError(err, "no error during body decoding")
+	suite.Equal(http.StatusOK, response.StatusCode)
+	suite.Len(responseBody.Sessions, 1)
+	suite.Equal("session_1", responseBody.Sessions[0].ID)
+	suite.True(responseBody.Sessions[0].Current)
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestRevokeSession() {
+	client := http.Client{}
+	suite.userUsecase.On("RevokeSession", mock.Anything, controllerSubject, "session_1").Return(nil)
+	suite.userUsecase.On("RevokeSession", mock.Anything, controllerSubject, "unknown").Return(domain.UserError{Message: "Session not found", Code: domain.ERR_NOT_FOUND})
+
+	request, _ := http.NewRequest(http.MethodDelete, suite.testingServer.URL+"/sessions/session_1", nil)
+	response, err := client.Do(request)
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusOK, response.StatusCode)
+
+	request, _ = http.NewRequest(http.MethodDelete, suite.testingServer.URL+"/sessions/unknown", nil)
+	response, err = client.Do(request)
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusNotFound, response.StatusCode)
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestCreateAPIKey() {
+	expiresAt := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	key := domain.APIKey{ID: "key_1", Name: "ci", Prefix: "tmk_abcdefgh", KeyHash: "stored_hash", Scopes: []string{domain.ScopeTasksRead}, ExpiresAt: expiresAt}
+	suite.userUsecase.On("CreateAPIKey", mock.Anything, controllerSubject, "ci", []string{domain.ScopeTasksRead}, expiresAt).Return(key, "tmk_abcdefghijk", nil)
+	suite.userUsecase.On("CreateAPIKey", mock.Anything, controllerSubject, "ci", []string{"users:write"}, time.Time{}).Return(domain.APIKey{}, "", domain.UserError{Message: "Invalid scope", Code: domain.ERR_BAD_REQUEST})
+
+	response, err := http.Post(suite.testingServer.URL+"/me/api-keys", "application/json", bytes.NewBufferString(`{"name": "ci", "scopes": ["tasks:read"], "expires_at": "2030-01-01T00:00:00Z"}`))
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusCreated, response.StatusCode)
+
+	var responseBody map[string]interface{}
+	suite.NoError(json.NewDecoder(response.Body).Decode(&responseBody), "no error during body decoding")
+	suite.Equal("tmk_abcdefghijk", responseBody["key"], "the key is sent once")
+	suite.Equal("tmk_abcdefgh", responseBody["prefix"])
+	suite.Nil(responseBody["last_used_at"], "the key has never been used")
+	suite.NotContains(responseBody, "key_hash", "the hash of the key is not sent")
+	response.Body.Close()
+
+	for requestBody, statusCode := range map[string]int{
+		`{"name": "ci", "scopes": ["users:write"]}`: http.StatusBadRequest,
+		`{"scopes": ["tasks:read"]}`:                http.StatusBadRequest,
+	} {
+		response, err := http.Post(suite.testingServer.URL+"/me/api-keys", "application/json", bytes.NewBufferString(requestBody))
+		suite.NoError(err, "no errors in request")
+		suite.Equal(statusCode, response.StatusCode)
+		response.Body.Close()
+	}
+
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestGetAPIKeys() {
+	keys := []domain.APIKey{{ID: "key_1", Name: "ci", Prefix: "tmk_abcdefgh", KeyHash: "stored_hash", Scopes: []string{domain.ScopeTasksRead}}}
+	suite.userUsecase.On("GetAPIKeys", mock.Anything, controllerSubject).Return(keys, nil)
+
+	response, err := http.Get(suite.testingServer.URL + "/me/api-keys")
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	responseBody := struct {
+		APIKeys []map[string]interface{} `json:"api_keys"`
+	}{}
+	suite.NoError(json.NewDecoder(response.Body).Decode(&responseBody), "no error during body decoding")
+	suite.Equal(http.StatusOK, response.StatusCode)
+	suite.Len(responseBody.APIKeys, 1)
+	suite.Equal("key_1", responseBody.APIKeys[0]["id"])
+	suite.NotContains(responseBody.APIKeys[0], "key", "the key is only sent when it is created")
+	suite.Nil(responseBody.APIKeys[0]["expires_at"], "the key never expires")
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestRevokeAPIKey() {
+	client := http.Client{}
+	suite.userUsecase.On("RevokeAPIKey", mock.Anything, controllerSubject, "key_1").Return(nil)
+	suite.userUsecase.On("RevokeAPIKey", mock.Anything, controllerSubject, "unknown").Return(domain.UserError{Message: "API key not found", Code: domain.ERR_NOT_FOUND})
+
+	for keyID, statusCode := range map[string]int{"key_1": http.StatusOK, "unknown": http.StatusNotFound} {
+		request, _ := http.NewRequest(http.MethodDelete, suite.testingServer.URL+"/me/api-keys/"+keyID, nil)
+		response, err := client.Do(request)
+		suite.NoError(err, "no errors in request")
+		suite.Equal(statusCode, response.StatusCode)
+		response.Body.Close()
+	}
+
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestRevokeUserSessions() {
+	client := http.Client{}
+	suite.userUsecase.On("RevokeUserSessions", mock.Anything, "solitary_confinement").Return(nil)
+
+	request, _ := http.NewRequest(http.MethodDelete, suite.testingServer.URL+"/users/solitary_confinement/sessions", nil)
+	response, err := client.Do(request)
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusOK, response.StatusCode)
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestDemote() {
+	client := http.Client{}
+	suite.userUsecase.On("Demote", mock.Anything, controllerSubject, "solitary_confinement").Return(nil)
+	suite.userUsecase.On("Demote", mock.Anything, controllerSubject, controllerSubject.Username).Return(domain.UserError{Message: "Admins can not demote their own account", Code: domain.ERR_FORBIDDEN})
+
+	for username, statusCode := range map[string]int{"solitary_confinement": http.StatusOK, controllerSubject.Username: http.StatusForbidden} {
+		request, _ := http.NewRequest(http.MethodPatch, suite.testingServer.URL+"/demote/"+username, nil)
+		response, err := client.Do(request)
+		suite.NoError(err, "no errors in request")
+		suite.Equal(statusCode, response.StatusCode)
+		response.Body.Close()
+	}
+
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestSetRole() {
+	client := http.Client{}
+	suite.userUsecase.On("SetRole", mock.Anything, controllerSubject, "solitary_confinement", "manager").Return(nil)
+	suite.userUsecase.On("SetRole", mock.Anything, controllerSubject, "solitary_confinement", "unknown").Return(domain.UserError{Message: "Invalid role: no role named 'unknown' exists", Code: domain.ERR_BAD_REQUEST})
+
+	for body, statusCode := range map[string]int{`{"role": "manager"}`: http.StatusOK, `{"role": "unknown"}`: http.StatusBadRequest, `{}`: http.StatusBadRequest} {
+		request, _ := http.NewRequest(http.MethodPut, suite.testingServer.URL+"/users/solitary_confinement/role", bytes.NewBufferString(body))
+		response, err := client.Do(request)
+		suite.NoError(err, "no errors in request")
+		suite.Equal(statusCode, response.StatusCode, "request body %v", body)
+		response.Body.Close()
+	}
+
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestGetRoles() {
+	roles := []domain.Role{{Name: domain.RoleAdmin, Permissions: domain.Permissions, BuiltIn: true}, {Name: "viewer"}}
+	suite.roleUsecase.On("GetRoles", mock.Anything).Return(roles, nil)
+
+	response, err := http.Get(suite.testingServer.URL + "/roles")
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	responseBody := struct {
+		Roles []map[string]interface{} `json:"roles"`
+	}{}
+	suite.NoError(json.NewDecoder(response.Body).Decode(&responseBody), "no error during body decoding")
+	suite.Equal(http.StatusOK, response.StatusCode)
+	suite.Len(responseBody.Roles, 2)
+	suite.Equal(true, responseBody.Roles[0]["built_in"])
+	suite.Equal([]interface{}{}, responseBody.Roles[1]["permissions"], "roles without permissions have an empty list")
+	suite.roleUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestGetRole() {
+	suite.roleUsecase.On("GetRole", mock.Anything, "viewer").Return(domain.Role{Name: "viewer", Permissions: []string{domain.PermissionTaskRead}}, nil)
+	suite.roleUsecase.On("GetRole", mock.Anything, "unknown").Return(domain.Role{}, domain.UserError{Message: "Role not found", Code: domain.ERR_NOT_FOUND})
+
+	for name, statusCode := range map[string]int{"viewer": http.StatusOK, "unknown": http.StatusNotFound} {
+		response, err := http.Get(suite.testingServer.URL + "/roles/" + name)
+		suite.NoError(err, "no errors in request")
+		suite.Equal(statusCode, response.StatusCode)
+		response.Body.Close()
+	}
+
+	suite.roleUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestSaveRole() {
+	client := http.Client{}
+	role := domain.Role{Name: "viewer", Permissions: []string{domain.PermissionTaskRead}}
+	suite.roleUsecase.On("SaveRole", mock.Anything, mock.Anything, role).Return(role, nil)
+	suite.roleUsecase.On("SaveRole", mock.Anything, mock.Anything, domain.Role{Name: "admin", Permissions: []string{}}).Return(domain.Role{}, domain.UserError{Message: "The permissions of the admin role can not be changed", Code: domain.ERR_FORBIDDEN})
+
+	tests := []struct {
+		name       string
+		body       string
+		statusCode int
+	}{
+		{"viewer", `{"permissions": ["task.read"]}`, http.StatusOK},
+		{"admin", `{"permissions": []}`, http.StatusForbidden},
+		{"viewer", `{}`, http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		request, _ := http.NewRequest(http.MethodPut, suite.testingServer.URL+"/roles/"+test.name, bytes.NewBufferString(test.body))
+		response, err := client.Do(request)
+		suite.NoError(err, "no errors in request")
+		suite.Equal(test.statusCode, response.StatusCode, "request body %v", test.body)
+		response.Body.Close()
+	}
+
+	suite.roleUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestSetRoleMFA() {
+	client := http.Client{}
+	suite.roleUsecase.On("SetRoleMFA", mock.Anything, mock.Anything, "admin", true).Return(domain.Role{Name: "admin", Permissions: domain.Permissions, MFARequired: true, BuiltIn: true}, nil)
+	suite.roleUsecase.On("SetRoleMFA", mock.Anything, mock.Anything, "viewer", false).Return(domain.Role{Name: "viewer", Permissions: []string{}}, nil)
+
+	tests := []struct {
+		name       string
+		body       string
+		statusCode int
+	}{
+		{"admin", `{"required": true}`, http.StatusOK},
+		{"viewer", `{"required": false}`, http.StatusOK},
+		{"viewer", `{}`, http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		request, _ := http.NewRequest(http.MethodPut, suite.testingServer.URL+"/roles/"+test.name+"/mfa", bytes.NewBufferString(test.body))
+		response, err := client.Do(request)
+		suite.NoError(err, "no errors in request")
+		suite.Equal(test.statusCode, response.StatusCode, "request body %v", test.body)
+		response.Body.Close()
+	}
+
+	suite.roleUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestDeleteRole() {
+	client := http.Client{}
+	suite.roleUsecase.On("DeleteRole", mock.Anything, controllerSubject, "viewer").Return(nil)
+	suite.roleUsecase.On("DeleteRole", mock.Anything, controllerSubject, "manager").Return(domain.UserError{Message: "The role is still assigned to 2 user(s)", Code: domain.ERR_CONFLICT})
+
+	for name, statusCode := range map[string]int{"viewer": http.StatusOK, "manager": http.StatusConflict} {
+		request, _ := http.NewRequest(http.MethodDelete, suite.testingServer.URL+"/roles/"+name, nil)
+		response, err := client.Do(request)
+		suite.NoError(err, "no errors in request")
+		suite.Equal(statusCode, response.StatusCode)
+		response.Body.Close()
+	}
+
+	suite.roleUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestDeactivate() {
+	client := http.Client{}
+	suite.userUsecase.On("Deactivate", mock.Anything, controllerSubject, "solitary_confinement").Return(nil)
+	suite.userUsecase.On("Deactivate", mock.Anything, controllerSubject, "unknown").Return(domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND})
+
+	for username, statusCode := range map[string]int{"solitary_confinement": http.StatusOK, "unknown": http.StatusNotFound} {
+		request, _ := http.NewRequest(http.MethodPatch, suite.testingServer.URL+"/users/"+username+"/deactivate", nil)
+		response, err := client.Do(request)
+		suite.NoError(err, "no errors in request")
+		suite.Equal(statusCode, response.StatusCode)
+		response.Body.Close()
+	}
+
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestActivate() {
+	client := http.Client{}
+	suite.userUsecase.On("Activate", mock.Anything, "solitary_confinement").Return(nil)
+
+	request, _ := http.NewRequest(http.MethodPatch, suite.testingServer.URL+"/users/solitary_confinement/activate", nil)
+	response, err := client.Do(request)
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusOK, response.StatusCode)
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestUnlock() {
+	client := http.Client{}
+	suite.userUsecase.On("UnlockAccount", mock.Anything, "locked_out").Return(nil)
+	suite.userUsecase.On("UnlockAccount", mock.Anything, "unknown").Return(domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND})
+
+	for username, statusCode := range map[string]int{"locked_out": http.StatusOK, "unknown": http.StatusNotFound} {
+		request, _ := http.NewRequest(http.MethodPatch, suite.testingServer.URL+"/users/"+username+"/unlock", nil)
+		response, err := client.Do(request)
+		suite.NoError(err, "no errors in request")
+		suite.Equal(statusCode, response.StatusCode)
+		response.Body.Close()
+	}
+
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestDeleteUser() {
+	client := http.Client{}
+	suite.userUsecase.On("DeleteUser", mock.Anything, controllerSubject, "solitary_confinement").Return(domain.UserDeletion{DeletedTasks: 3, UnassignedTasks: 1}, nil)
+
+	request, _ := http.NewRequest(http.MethodDelete, suite.testingServer.URL+"/users/solitary_confinement", nil)
+	response, err := client.Do(request)
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusOK, response.StatusCode)
+	var responseBody domain.UserDeletion
+	suite.NoError(json.NewDecoder(response.Body).Decode(&responseBody), "no error during body decoding")
+	suite.Equal(domain.UserDeletion{DeletedTasks: 3, UnassignedTasks: 1}, responseBody)
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestGetUsers() {
+	page := domain.UserPage{Users: []domain.UserProfile{{Username: "user1"}, {Username: "user2"}}, Total: 5, Limit: 2, Offset: 2}
+	suite.userUsecase.On("GetUsers", mock.Anything, domain.UserQuery{Search: "user", Limit: 2, Offset: 2}).Return(page, nil)
+
+	response, err := http.Get(suite.testingServer.URL + "/users?search=user&limit=2&offset=2")
+	suite.NoError(err, "no errors in request")
+	defer response.Body.Close()
+
+	suite.Equal(http.StatusOK, response.StatusCode)
+	var responseBody domain.UserPage
+	suite.NoError(json.NewDecoder(response.Body).Decode(&responseBody), "no error during body decoding")
+	suite.Equal(page.Users, responseBody.Users)
+	suite.Equal("/users?limit=2&offset=4&search=user", responseBody.Next)
+	suite.Equal("/users?limit=2&offset=0&search=user", responseBody.Previous)
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestGetUser() {
+	suite.userUsecase.On("GetUser", mock.Anything, "unknown").Return(domain.UserProfile{}, domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND})
+
+	response, err := http.Get(suite.testingServer.URL + "/users/unknown")
+	suite.NoError(err, "no errors in request")
+	defer response.Body.Close()
+
+	suite.Equal(http.StatusNotFound, response.StatusCode)
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestGetProfile() {
+	profile := domain.UserProfile{Username: controllerSubject.Username, Email: "mail@mail.com", DisplayName: "Controller User", Role: domain.RoleAdmin}
+	suite.userUsecase.On("GetProfile", mock.Anything, controllerSubject).Return(profile, nil)
+
+	response, err := http.Get(suite.testingServer.URL + "/me")
+	suite.NoError(err, "no errors in request")
+	defer response.Body.Close()
+
+	suite.Equal(http.StatusOK, response.StatusCode)
+	var responseBody map[string]interface{}
+	suite.NoError(json.NewDecoder(response.Body).Decode(&responseBody), "no error during body decoding")
+	suite.Equal("Controller User", responseBody["display_name"])
+	suite.NotContains(responseBody, "password", "the password hash is never returned")
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestUpdateProfile() {
+	client := http.Client{}
+	displayName := "New Name"
+	suite.userUsecase.On("UpdateProfile", mock.Anything, controllerSubject, "current_password", domain.ProfileUpdate{DisplayName: &displayName}).Return(domain.UserProfile{DisplayName: displayName}, nil)
+
+	requestBodies := map[string]int{
+		`{"current_password": "current_password", "display_name": "New Name"}`: http.StatusOK,
+		`{"display_name": "New Name"}`:                                         http.StatusBadRequest,
+	}
+
+	for requestBody, statusCode := range requestBodies {
+		request, _ := http.NewRequest(http.MethodPatch, suite.testingServer.URL+"/me", bytes.NewBufferString(requestBody))
+		request.Header.Add("Content-Type", "application/json")
+		response, err := client.Do(request)
+		suite.NoError(err, "no errors in request")
+		suite.Equal(statusCode, response.StatusCode)
+		response.Body.Close()
+	}
+
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestChangePassword() {
+	client := http.Client{}
+	suite.userUsecase.On("ChangePassword", mock.Anything, controllerSubject, "current_password", "new_password").Return(nil)
+	suite.userUsecase.On("ChangePassword", mock.Anything, controllerSubject, "incorrect_password", "new_password").Return(domain.UserError{Message: "Incorrect password", Code: domain.ERR_UNAUTHORIZED})
+
+	requestBodies := map[string]int{
+		`{"current_password": "current_password", "new_password": "new_password"}`:   http.StatusOK,
+		`{"current_password": "incorrect_password", "new_password": "new_password"}`: http.StatusUnauthorized,
+		`{"new_password": "new_password"}`:                                           http.StatusBadRequest,
+	}
+
+	for requestBody, statusCode := range requestBodies {
+		request, _ := http.NewRequest(http.MethodPut, suite.testingServer.URL+"/me/password", bytes.NewBufferString(requestBody))
+		request.Header.Add("Content-Type", "application/json")
+		response, err := client.Do(request)
+		suite.NoError(err, "no errors in request")
+		suite.Equal(statusCode, response.StatusCode)
+		response.Body.Close()
+	}
+
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestEnrollTOTP() {
+	client := http.Client{}
+	suite.userUsecase.On("EnrollTOTP", mock.Anything, controllerSubject, "current_password").Return(domain.TOTPSetup{Secret: "SECRET", URI: "otpauth://totp/x"}, nil)
+	suite.userUsecase.On("EnrollTOTP", mock.Anything, controllerSubject, "enabled").Return(domain.TOTPSetup{}, domain.UserError{Message: "Two-factor authentication is already enabled", Code: domain.ERR_CONFLICT})
+
+	requestBodies := map[string]int{
+		`{"current_password": "current_password"}`: http.StatusOK,
+		`{"current_password": "enabled"}`:          http.StatusConflict,
+		`{}`:                                       http.StatusBadRequest,
+	}
+
+	for requestBody, statusCode := range requestBodies {
+		request, _ := http.NewRequest(http.MethodPost, suite.testingServer.URL+"/me/mfa/totp", bytes.NewBufferString(requestBody))
+		request.Header.Add("Content-Type", "application/json")
+		response, err := client.Do(request)
+		suite.NoError(err, "no errors in request")
+		suite.Equal(statusCode, response.StatusCode)
+		response.Body.Close()
+	}
+
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestConfirmTOTP() {
+	suite.userUsecase.On("ConfirmTOTP", mock.Anything, controllerSubject, "123456").Return([]string{"abcde-fghjk"}, nil)
+	suite.userUsecase.On("RegenerateRecoveryCodes", mock.Anything, controllerSubject, "123456").Return([]string{"mnpqr-stuvw"}, nil)
+
+	for path, recoveryCodes := range map[string][]string{"/me/mfa/totp/confirm": {"abcde-fghjk"}, "/me/mfa/recovery-codes": {"mnpqr-stuvw"}} {
+		response, err := http.Post(suite.testingServer.URL+path, "application/json", bytes.NewBufferString(`{"code": "123456"}`))
+		suite.NoError(err, "no errors in request")
+		suite.Equal(http.StatusOK, response.StatusCode)
+
+		var responseBody struct {
+			RecoveryCodes []string `json:"recovery_codes"`
+		}
+		suite.NoError(json.NewDecoder(response.Body).Decode(&responseBody), "no error during body decoding")
+		suite.Equal(recoveryCodes, responseBody.RecoveryCodes)
+		response.Body.Close()
+
+		response, err = http.Post(suite.testingServer.URL+path, "application/json", bytes.NewBufferString(`{}`))
+		suite.NoError(err, "no errors in request")
+		suite.Equal(http.StatusBadRequest, response.StatusCode, "the code is required")
+		response.Body.Close()
+	}
+
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestDisableMFA() {
+	client := http.Client{}
+	suite.userUsecase.On("DisableMFA", mock.Anything, controllerSubject, "current_password", "123456").Return(nil)
+	suite.userUsecase.On("DisableMFA", mock.Anything, controllerSubject, "current_password", "").Return(domain.UserError{Message: "Two-factor authentication is required for the role admin", Code: domain.ERR_FORBIDDEN})
+
+	requestBodies := map[string]int{
+		`{"current_password": "current_password", "code": "123456"}`: http.StatusOK,
+		`{"current_password": "current_password"}`:                   http.StatusForbidden,
+		`{"code": "123456"}`: http.StatusBadRequest,
+	}
+
+	for requestBody, statusCode := range requestBodies {
+		request, _ := http.NewRequest(http.MethodDelete, suite.testingServer.URL+"/me/mfa", bytes.NewBufferString(requestBody))
+		request.Header.Add("Content-Type", "application/json")
+		response, err := client.Do(request)
+		suite.NoError(err, "no errors in request")
+		suite.Equal(statusCode, response.StatusCode)
+		response.Body.Close()
+	}
+
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestResetMFA() {
+	client := http.Client{}
+	suite.userUsecase.On("ResetMFA", mock.Anything, "lost_phone").Return(nil)
+	suite.userUsecase.On("ResetMFA", mock.Anything, "unknown").Return(domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND})
+
+	for username, statusCode := range map[string]int{"lost_phone": http.StatusOK, "unknown": http.StatusNotFound} {
+		request, _ := http.NewRequest(http.MethodDelete, suite.testingServer.URL+"/users/"+username+"/mfa", nil)
+		response, err := client.Do(request)
+		suite.NoError(err, "no errors in request")
+		suite.Equal(statusCode, response.StatusCode)
+		response.Body.Close()
+	}
+
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestVerifyEmail() {
+	client := http.Client{}
+	suite.userUsecase.On("VerifyEmail", mock.Anything, "verification_token").Return(nil)
+	suite.userUsecase.On("VerifyEmail", mock.Anything, "used_token").Return(domain.UserError{Message: "Invalid or expired email verification token", Code: domain.ERR_UNAUTHORIZED})
+
+	request, _ := http.NewRequest(http.MethodPost, suite.testingServer.URL+"/verify-email", bytes.NewBuffer([]byte(`{"token": "verification_token"}`)))
+	request.Header.Add("Content-Type", "application/json")
+	response, err := client.Do(request)
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusOK, response.StatusCode)
+
+	request, _ = http.NewRequest(http.MethodPost, suite.testingServer.URL+"/verify-email", bytes.NewBuffer([]byte(`{"token": "used_token"}`)))
+	request.Header.Add("Content-Type", "application/json")
+	response, err = client.Do(request)
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusUnauthorized, response.StatusCode)
+
+	// the token is required
+	request, _ = http.NewRequest(http.MethodPost, suite.testingServer.URL+"/verify-email", bytes.NewBuffer([]byte(`{}`)))
+	request.Header.Add("Content-Type", "application/json")
+	response, err = client.Do(request)
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusBadRequest, response.StatusCode)
+	suite.userUsecase.AssertNumberOfCalls(suite.T(), "VerifyEmail", 2)
+}
+
+func (suite *controllerSuite) TestResendVerification() {
+	client := http.Client{}
+	suite.userUsecase.On("ResendVerification", mock.Anything, "valid@mail.com").Return(nil)
+
+	request, _ := http.NewRequest(http.MethodPost, suite.testingServer.URL+"/verify-email/resend", bytes.NewBuffer([]byte(`{"email": "valid@mail.com"}`)))
+	request.Header.Add("Content-Type", "application/json")
+	response, err := client.Do(request)
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusOK, response.StatusCode)
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *controllerSuite) TestForgotPassword() {
+	client := http.Client{}
+	suite.userUsecase.On("ForgotPassword", mock.Anything, "valid@mail.com").Return(nil)
+
+	request, _ := http.NewRequest(http.MethodPost, suite.testingServer.URL+"/password/forgot", bytes.NewBuffer([]byte(`{"email": "valid@mail.com"}`)))
+	request.Header.Add("Content-Type", "application/json")
+	response, err := client.Do(request)
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusOK, response.StatusCode)
+
+	// the email is required
+	request, _ = http.NewRequest(http.MethodPost, suite.testingServer.URL+"/password/forgot", bytes.NewBuffer([]byte(`{}`)))
+	request.Header.Add("Content-Type", "application/json")
+	response, err = client.Do(request)
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusBadRequest, response.StatusCode)
+	suite.userUsecase.AssertNumberOfCalls(suite.T(), "ForgotPassword", 1)
+}
+
+func (suite *controllerSuite) TestResetPassword() {
+	client := http.Client{}
+	suite.userUsecase.On("ResetPassword", mock.Anything, "reset_token", "new_password").Return(nil)
+	suite.userUsecase.On("ResetPassword", mock.Anything, "used_token", "new_password").Return(domain.UserError{Message: "Invalid or expired password reset token", Code: domain.ERR_UNAUTHORIZED})
+
+	request, _ := http.NewRequest(http.MethodPost, suite.testingServer.URL+"/password/reset", bytes.NewBuffer([]byte(`{"token": "reset_token", "new_password": "new_password"}`)))
+	request.Header.Add("Content-Type", "application/json")
+	response, err := client.Do(request)
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusOK, response.StatusCode)
+
+	request, _ = http.NewRequest(http.MethodPost, suite.testingServer.URL+"/password/reset", bytes.NewBuffer([]byte(`{"token": "used_token", "new_password": "new_password"}`)))
+	request.Header.Add("Content-Type", "application/json")
+	response, err = client.Do(request)
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusUnauthorized, response.StatusCode)
+
+	// the new password is required
+	request, _ = http.NewRequest(http.MethodPost, suite.testingServer.URL+"/password/reset", bytes.NewBuffer([]byte(`{"token": "reset_token"}`)))
+	request.Header.Add("Content-Type", "application/json")
+	response, err = client.Do(request)
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	suite.Equal(http.StatusBadRequest, response.StatusCode)
+	suite.userUsecase.AssertNumberOfCalls(suite.T(), "ResetPassword", 2)
+}
+
+func (suite *controllerSuite) TestJWKSHandler() {
+	keySet := domain.JSONWebKeySet{Keys: []domain.JSONWebKey{{KeyType: "OKP", KeyID: "key", Use: "sig", Algorithm: "EdDSA", Curve: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}}}
+	router := gin.Default()
+	router.GET("/.well-known/jwks.json", controllers.JWKSHandler(keySet))
+	testingServer := httptest.NewServer(router)
+	defer testingServer.Close()
+
+	response, err := http.Get(testingServer.URL + "/.well-known/jwks.json")
+	if response != nil {
+		defer response.Body.Close()
+	}
+
+	suite.NoError(err, "no errors in request")
+	responseBody := domain.JSONWebKeySet{}
+	err = json.NewDecoder(response.Body).Decode(&responseBody)
+	suite.NoError(err, "no error during body decoding")
+	suite.Equal(http.StatusOK, response.StatusCode)
+	suite.Equal(keySet, responseBody)
+	suite.Contains(response.Header.Get("Cache-Control"), "max-age")
+}
+
 func TestControllerSuite(t *testing.T) {
 	suite.Run(t, new(controllerSuite))
 }
@@ -2,7 +2,9 @@ package tests
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	repository "task_manager_api/Repository"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -27,3 +29,56 @@ func SetupUserCollection(collection *mongo.Collection) {
 		fmt.Println("\n\n Error " + err.Error())
 	}
 }
+
+func SetupLoginAttemptCollection(collection *mongo.Collection) {
+	_, err := collection.Indexes().CreateOne(context.TODO(), mongo.IndexModel{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)})
+	if err != nil {
+		fmt.Println("\n\n Error " + err.Error())
+	}
+}
+
+func SetupMFACollection(collection *mongo.Collection) {
+	_, err := collection.Indexes().CreateOne(context.TODO(), mongo.IndexModel{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true)})
+	if err != nil {
+		fmt.Println("\n\n Error " + err.Error())
+	}
+}
+
+func SetupMFAChallengeCollection(collection *mongo.Collection) {
+	_, err := collection.Indexes().CreateOne(context.TODO(), mongo.IndexModel{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)})
+	if err != nil {
+		fmt.Println("\n\n Error " + err.Error())
+	}
+}
+
+func SetupAPIKeyCollection(collection *mongo.Collection) {
+	_, err := collection.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "key_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		fmt.Println("\n\n Error " + err.Error())
+	}
+}
+
+func SetupRoleCollection(collection *mongo.Collection) {
+	_, err := collection.Indexes().CreateOne(context.TODO(), mongo.IndexModel{Keys: bson.D{{Key: "name", Value: 1}}, Options: options.Index().SetUnique(true)})
+	if err != nil {
+		fmt.Println("\n\n Error " + err.Error())
+	}
+}
+
+/* Opens a new in-memory SQLite database and applies all the migrations */
+func SetupSQLiteDatabase() (*sql.DB, error) {
+	db, err := repository.OpenSQLDatabase(repository.DialectSQLite, ":memory:")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := repository.RunSQLMigrations(context.TODO(), db, repository.DialectSQLite); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
@@ -1,7 +1,22 @@
 package tests
 
 import (
+	"bufio"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
+	domain "task_manager_api/Domain"
 	infrastructure "task_manager_api/Infrastructure"
 	"testing"
 	"time"
@@ -18,50 +33,193 @@ type passwordServiceSuite struct {
 	suite.Suite
 }
 
-func (suite *jwtServiceSuite) TestSignWithJWTPayload_Positive() {
-	username := "suser"
-	role := "admin"
-	secret := "secret"
+type tokenServiceSuite struct {
+	suite.Suite
+}
 
-	token, err := infrastructure.SignJWTWithPayload(username, role, time.Minute, secret)
+type notifierSuite struct {
+	suite.Suite
+}
 
-	suite.NoError(err, "no error when given valid inputs")
-	suite.True(strings.HasPrefix(token, "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9."))
+type totpServiceSuite struct {
+	suite.Suite
 }
 
-func (suite *jwtServiceSuite) TestSignWithJWTPayload_Negative() {
-	username := "suser"
-	role := "admin"
-	secret := ""
+// creates a JWT service that signs the tokens with HS256
+func newHMACService(secret string) *infrastructure.JWTService {
+	service, _ := infrastructure.NewJWTService(infrastructure.JWTConfig{Issuer: "issuer", Audience: "audience", Secret: secret})
+	return service
+}
 
-	token, err := infrastructure.SignJWTWithPayload(username, role, time.Minute, secret)
+// writes the private key (or only its public key) as a PEM file named after the key ID
+func writeKey(directory string, keyID string, privateKey crypto.Signer, publicOnly bool) {
+	block := &pem.Block{Type: "PRIVATE KEY"}
+	block.Bytes, _ = x509.MarshalPKCS8PrivateKey(privateKey)
+	if publicOnly {
+		block.Type = "PUBLIC KEY"
+		block.Bytes, _ = x509.MarshalPKIXPublicKey(privateKey.Public())
+	}
 
-	suite.Error(err, "error when given valid inputs")
-	suite.Equal(token, "")
+	os.WriteFile(filepath.Join(directory, keyID+".pem"), pem.EncodeToMemory(block), 0600)
+}
+
+func (suite *jwtServiceSuite) TestNewJWTService_Negative() {
+	_, err := infrastructure.NewJWTService(infrastructure.JWTConfig{Issuer: "issuer", Audience: "audience"})
+	suite.Error(err, "error when neither a secret nor keys are provided")
+
+	_, err = infrastructure.NewJWTService(infrastructure.JWTConfig{Secret: "secret"})
+	suite.Error(err, "error when the issuer and audience are missing")
+
+	directory := suite.T().TempDir()
+	_, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	writeKey(directory, "public", privateKey, true)
+	_, err = infrastructure.NewJWTService(infrastructure.JWTConfig{Issuer: "issuer", Audience: "audience", KeysDirectory: directory, ActiveKeyID: "public"})
+	suite.Error(err, "error when the active key has no private key")
+
+	_, err = infrastructure.NewJWTService(infrastructure.JWTConfig{Issuer: "issuer", Audience: "audience", KeysDirectory: directory, ActiveKeyID: "unknown"})
+	suite.Error(err, "error when the active key does not exist")
+}
+
+func (suite *jwtServiceSuite) TestSignWithJWTPayload_Positive() {
+	token, err := newHMACService("secret").SignJWTWithPayload("suser", "admin", "session", time.Minute)
+
+	suite.NoError(err, "no error when given valid inputs")
+	parsedToken, _, _ := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+	suite.Equal("HS256", parsedToken.Header["alg"])
+	suite.Equal(infrastructure.HMACKeyID, parsedToken.Header["kid"])
 }
 
 func (suite *jwtServiceSuite) TestValidateAndParseToken_Positive() {
 	username := "suser"
 	role := "admin"
-	secret := "secret"
+	service := newHMACService("secret")
 
-	token, _ := infrastructure.SignJWTWithPayload(username, role, time.Minute, secret)
-	jwtToken, err := infrastructure.ValidateAndParseToken(token, secret)
+	token, _ := service.SignJWTWithPayload(username, role, "session", time.Minute)
+	jwtToken, err := service.ValidateAndParseToken(token)
 
 	suite.NoError(err, "no error when given the same secret key")
-	suite.Equal(username, jwtToken.Claims.(jwt.MapClaims)["username"])
-	suite.Equal(role, jwtToken.Claims.(jwt.MapClaims)["role"])
+	claims := jwtToken.Claims.(jwt.MapClaims)
+	suite.Equal(username, claims["sub"])
+	suite.Equal(role, claims["role"])
+	suite.Equal("session", claims["sid"])
+	suite.Equal("issuer", claims["iss"])
+	suite.Equal("audience", claims["aud"])
+	suite.NotEmpty(claims["jti"])
+	suite.True(claims.VerifyExpiresAt(time.Now().Unix(), true))
+	suite.True(claims.VerifyIssuedAt(time.Now().Unix(), true))
+}
+
+func (suite *jwtServiceSuite) TestSignWithJWTPayload_UniqueID() {
+	service := newHMACService("secret")
+	firstToken, _ := service.SignJWTWithPayload("suser", "admin", "session", time.Minute)
+	secondToken, _ := service.SignJWTWithPayload("suser", "admin", "session", time.Minute)
+	firstJWT, _ := service.ValidateAndParseToken(firstToken)
+	secondJWT, _ := service.ValidateAndParseToken(secondToken)
+
+	suite.NotEqual(firstJWT.Claims.(jwt.MapClaims)["jti"], secondJWT.Claims.(jwt.MapClaims)["jti"])
 }
 
 func (suite *jwtServiceSuite) TestValidateAndParseToken_Negative() {
-	username := "suser"
-	role := "admin"
-	secret := "secret"
+	token, _ := newHMACService("secret").SignJWTWithPayload("suser", "admin", "session", time.Minute)
+	_, err := newHMACService("modified secret").ValidateAndParseToken(token)
+	suite.Error(err, "error when given a different secret key")
+
+	expiredToken, _ := newHMACService("secret").SignJWTWithPayload("suser", "admin", "session", -time.Minute)
+	_, err = newHMACService("secret").ValidateAndParseToken(expiredToken)
+	suite.Error(err, "error when the token has expired")
+}
+
+func (suite *jwtServiceSuite) TestValidateAndParseToken_IssuerAndAudience() {
+	token, _ := newHMACService("secret").SignJWTWithPayload("suser", "admin", "session", time.Minute)
 
-	token, _ := infrastructure.SignJWTWithPayload(username, role, time.Minute, secret)
-	_, err := infrastructure.ValidateAndParseToken(token, "modified secret")
+	otherIssuer, _ := infrastructure.NewJWTService(infrastructure.JWTConfig{Issuer: "other_issuer", Audience: "audience", Secret: "secret"})
+	_, err := otherIssuer.ValidateAndParseToken(token)
+	suite.Error(err, "error when the token was issued by another issuer")
 
-	suite.Error(err, "error when given the same secret key")
+	otherAudience, _ := infrastructure.NewJWTService(infrastructure.JWTConfig{Issuer: "issuer", Audience: "other_audience", Secret: "secret"})
+	_, err = otherAudience.ValidateAndParseToken(token)
+	suite.Error(err, "error when the token was issued for another audience")
+}
+
+func (suite *jwtServiceSuite) TestKeyPairs() {
+	directory := suite.T().TempDir()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	writeKey(directory, "rsa_key", rsaKey, false)
+	writeKey(directory, "ed_key", edKey, false)
+
+	algorithms := map[string]string{"rsa_key": "RS256", "ed_key": "EdDSA"}
+	for keyID, algorithm := range algorithms {
+		service, err := infrastructure.NewJWTService(infrastructure.JWTConfig{Issuer: "issuer", Audience: "audience", KeysDirectory: directory, ActiveKeyID: keyID})
+		suite.NoError(err, "no error when loading the key pairs")
+
+		token, err := service.SignJWTWithPayload("suser", "admin", "session", time.Minute)
+		suite.NoError(err, "no error when signing with a key pair")
+
+		jwtToken, err := service.ValidateAndParseToken(token)
+		suite.NoError(err, "no error when verifying with a key pair")
+		suite.Equal(algorithm, jwtToken.Header["alg"])
+		suite.Equal(keyID, jwtToken.Header["kid"])
+	}
+}
+
+func (suite *jwtServiceSuite) TestKeyRotation() {
+	directory := suite.T().TempDir()
+	_, oldKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, newKey, _ := ed25519.GenerateKey(rand.Reader)
+	writeKey(directory, "old", oldKey, false)
+	oldService, _ := infrastructure.NewJWTService(infrastructure.JWTConfig{Issuer: "issuer", Audience: "audience", KeysDirectory: directory, ActiveKeyID: "old"})
+	token, _ := oldService.SignJWTWithPayload("suser", "admin", "session", time.Minute)
+
+	// the old key is only kept to verify the tokens signed before the rotation
+	writeKey(directory, "old", oldKey, true)
+	writeKey(directory, "new", newKey, false)
+	newService, err := infrastructure.NewJWTService(infrastructure.JWTConfig{Issuer: "issuer", Audience: "audience", KeysDirectory: directory, ActiveKeyID: "new"})
+	suite.NoError(err, "no error when loading the rotated keys")
+
+	_, err = newService.ValidateAndParseToken(token)
+	suite.NoError(err, "no error when verifying a token signed with the previous key")
+
+	newToken, _ := newService.SignJWTWithPayload("suser", "admin", "session", time.Minute)
+	jwtToken, _ := newService.ValidateAndParseToken(newToken)
+	suite.Equal("new", jwtToken.Header["kid"])
+
+	os.Remove(filepath.Join(directory, "old.pem"))
+	retiredService, _ := infrastructure.NewJWTService(infrastructure.JWTConfig{Issuer: "issuer", Audience: "audience", KeysDirectory: directory, ActiveKeyID: "new"})
+	_, err = retiredService.ValidateAndParseToken(token)
+	suite.Error(err, "error when the key of the token has been removed")
+}
+
+func (suite *jwtServiceSuite) TestValidateAndParseToken_AlgorithmConfusion() {
+	directory := suite.T().TempDir()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	writeKey(directory, "rsa_key", rsaKey, false)
+	service, _ := infrastructure.NewJWTService(infrastructure.JWTConfig{Issuer: "issuer", Audience: "audience", KeysDirectory: directory, ActiveKeyID: "rsa_key"})
+
+	// an HS256 token signed with the published public key must be rejected
+	publicKey, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	forgedToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iss": "issuer", "aud": "audience", "sub": "suser", "role": "admin", "exp": time.Now().Add(time.Minute).Unix()})
+	forgedToken.Header["kid"] = "rsa_key"
+	rawToken, _ := forgedToken.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}))
+
+	_, err := service.ValidateAndParseToken(rawToken)
+	suite.Error(err, "error when the algorithm of the token does not match the key")
+}
+
+func (suite *jwtServiceSuite) TestJWKS() {
+	suite.Empty(newHMACService("secret").JWKS().Keys, "HMAC keys are never published")
+
+	directory := suite.T().TempDir()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	writeKey(directory, "a_rsa_key", rsaKey, false)
+	writeKey(directory, "b_ed_key", edKey, true)
+	service, _ := infrastructure.NewJWTService(infrastructure.JWTConfig{Issuer: "issuer", Audience: "audience", KeysDirectory: directory, ActiveKeyID: "a_rsa_key"})
+
+	keys := service.JWKS().Keys
+	suite.Len(keys, 2)
+	suite.Equal(domain.JSONWebKey{KeyType: "RSA", KeyID: "a_rsa_key", Use: "sig", Algorithm: "RS256", Modulus: base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()), Exponent: "AQAB"}, keys[0])
+	suite.Equal(domain.JSONWebKey{KeyType: "OKP", KeyID: "b_ed_key", Use: "sig", Algorithm: "EdDSA", Curve: "Ed25519", X: base64.RawURLEncoding.EncodeToString(edKey.Public().(ed25519.PublicKey))}, keys[1])
 }
 
 func (suite *passwordServiceSuite) TestHashPassword() {
@@ -91,7 +249,201 @@ func (suite *passwordServiceSuite) TestValidatePassword_Negative() {
 	suite.Error(err, "error when given incorrect password")
 }
 
+func (suite *tokenServiceSuite) TestGenerateOpaqueToken() {
+	token, err := infrastructure.GenerateOpaqueToken()
+	suite.NoError(err, "no errors when generating a token")
+	suite.Len(token, 43)
+
+	otherToken, _ := infrastructure.GenerateOpaqueToken()
+	suite.NotEqual(token, otherToken)
+}
+
+func (suite *tokenServiceSuite) TestHashToken() {
+	token := "opaque_token"
+
+	suite.Equal(infrastructure.HashToken(token), infrastructure.HashToken(token))
+	suite.NotEqual(token, infrastructure.HashToken(token))
+	suite.NotEqual(infrastructure.HashToken(token), infrastructure.HashToken("other_token"))
+}
+
+// the secret of the SHA1 test vectors of RFC 6238, "12345678901234567890" encoded with base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func (suite *totpServiceSuite) TestTOTPCode() {
+	// the last six digits of the eight digit codes of RFC 6238
+	testVectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for seconds, expected := range testVectors {
+		code, err := infrastructure.TOTPCode(rfc6238Secret, infrastructure.TOTPStep(time.Unix(seconds, 0)))
+		suite.NoError(err, "no error with a valid secret")
+		suite.Equal(expected, code, "the code at %v seconds matches RFC 6238", seconds)
+	}
+
+	_, err := infrastructure.TOTPCode("not base32!", 1)
+	suite.Error(err, "error when the secret is not base32")
+}
+
+func (suite *totpServiceSuite) TestValidateTOTP() {
+	now := time.Unix(1111111111, 0)
+	step := infrastructure.TOTPStep(now)
+
+	matched, ok := infrastructure.ValidateTOTP(rfc6238Secret, "050471", now)
+	suite.True(ok, "the current code is valid")
+	suite.Equal(step, matched)
+
+	previous, _ := infrastructure.TOTPCode(rfc6238Secret, step-1)
+	matched, ok = infrastructure.ValidateTOTP(rfc6238Secret, previous[:3]+" "+previous[3:], now)
+	suite.True(ok, "the code of the previous step is valid to allow for clock drift")
+	suite.Equal(step-1, matched)
+
+	expired, _ := infrastructure.TOTPCode(rfc6238Secret, step-2)
+	_, ok = infrastructure.ValidateTOTP(rfc6238Secret, expired, now)
+	suite.False(ok, "the codes of older steps are invalid")
+
+	_, ok = infrastructure.ValidateTOTP(rfc6238Secret, "", now)
+	suite.False(ok, "an empty code is invalid")
+}
+
+func (suite *totpServiceSuite) TestGenerateTOTPSecret() {
+	secret, err := infrastructure.GenerateTOTPSecret()
+	suite.NoError(err, "no errors when generating a secret")
+	suite.Len(secret, 32, "160 bits encoded with base32")
+
+	_, codeErr := infrastructure.TOTPCode(secret, 1)
+	suite.NoError(codeErr, "the secret can be used to generate codes")
+
+	otherSecret, _ := infrastructure.GenerateTOTPSecret()
+	suite.NotEqual(secret, otherSecret)
+}
+
+func (suite *totpServiceSuite) TestTOTPURI() {
+	uri, err := url.Parse(infrastructure.TOTPURI("task manager", "user@mail.com", rfc6238Secret))
+	suite.NoError(err, "the URI can be parsed")
+	suite.Equal("otpauth", uri.Scheme)
+	suite.Equal("totp", uri.Host)
+	suite.Equal("/task manager:user@mail.com", uri.Path)
+	suite.Equal(rfc6238Secret, uri.Query().Get("secret"))
+	suite.Equal("task manager", uri.Query().Get("issuer"))
+	suite.Equal("6", uri.Query().Get("digits"))
+	suite.Equal("30", uri.Query().Get("period"))
+}
+
+func (suite *totpServiceSuite) TestGenerateRecoveryCode() {
+	code, err := infrastructure.GenerateRecoveryCode()
+	suite.NoError(err, "no errors when generating a recovery code")
+	suite.Regexp("^[a-z2-9]{5}-[a-z2-9]{5}$", code)
+
+	otherCode, _ := infrastructure.GenerateRecoveryCode()
+	suite.NotEqual(code, otherCode)
+}
+
+func (suite *notifierSuite) TestFileNotifier() {
+	path := filepath.Join(suite.T().TempDir(), "notifications.log")
+	notifier, err := infrastructure.NewFileNotifier(path)
+	suite.NoError(err, "no error when creating the notifications file")
+
+	notification := domain.Notification{Recipient: "valid@mail.com", Username: "username", Subject: "Password reset", Body: "reset_token"}
+	suite.NoError(notifier.Notify(context.TODO(), notification), "no error when writing a notification")
+	suite.NoError(notifier.Notify(context.TODO(), notification), "no error when appending a notification")
+
+	contents, _ := os.ReadFile(path)
+	suite.Equal(2, strings.Count(string(contents), `"recipient":"valid@mail.com"`), "every notification is appended to the file")
+	suite.Contains(string(contents), "reset_token")
+
+	_, err = infrastructure.NewFileNotifier(filepath.Join(path, "notifications.log"))
+	suite.Error(err, "error when the file can not be created")
+}
+
+// starts a minimal SMTP server that accepts one email and sends its data to the returned channel
+func startFakeSMTPServer(suite *notifierSuite) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err, "no error when starting the fake SMTP server")
+	suite.T().Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		fmt.Fprint(conn, "220 localhost ESMTP\r\n")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			switch strings.ToUpper(strings.Fields(line + " ")[0]) {
+			case "EHLO", "HELO":
+				fmt.Fprint(conn, "250 localhost\r\n")
+			case "MAIL", "RCPT":
+				fmt.Fprint(conn, "250 OK\r\n")
+			case "DATA":
+				fmt.Fprint(conn, "354 Start mail input\r\n")
+				var data strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil || dataLine == ".\r\n" {
+						break
+					}
+					data.WriteString(dataLine)
+				}
+				messages <- data.String()
+				fmt.Fprint(conn, "250 OK\r\n")
+			case "QUIT":
+				fmt.Fprint(conn, "221 Bye\r\n")
+				return
+			default:
+				fmt.Fprint(conn, "502 Command not implemented\r\n")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), messages
+}
+
+func (suite *notifierSuite) TestSMTPNotifier() {
+	address, messages := startFakeSMTPServer(suite)
+	notifier := infrastructure.NewSMTPNotifier(address, "noreply@mail.com", "", "")
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+
+	notification := domain.Notification{Recipient: "valid@mail.com", Username: "username", Subject: "Verify\r\nBcc: other@mail.com", Body: "first line\nverification_token"}
+	suite.NoError(notifier.Notify(ctx, notification), "no error when sending an email")
+
+	message := <-messages
+	suite.Contains(message, "From: noreply@mail.com\r\n")
+	suite.Contains(message, "To: valid@mail.com\r\n")
+	suite.Contains(message, "first line\r\nverification_token", "the line endings of the body are converted to CRLF")
+	suite.NotContains(message, "\r\nBcc:", "no headers can be injected")
+}
+
+func (suite *notifierSuite) TestSMTPNotifier_Negative() {
+	address, _ := startFakeSMTPServer(suite)
+	notifier := infrastructure.NewSMTPNotifier(address, "noreply@mail.com", "username", "password")
+	err := notifier.Notify(context.TODO(), domain.Notification{Recipient: "valid@mail.com"})
+	suite.Error(err, "error when the credentials can not be used with the server")
+	suite.Equal(domain.ERR_INTERNAL_SERVER, err.GetCode())
+
+	notifier = infrastructure.NewSMTPNotifier("127.0.0.1:1", "noreply@mail.com", "", "")
+	err = notifier.Notify(context.TODO(), domain.Notification{Recipient: "valid@mail.com"})
+	suite.Error(err, "error when the server is unreachable")
+}
+
 func TestInfrastructureSuite(t *testing.T) {
 	suite.Run(t, new(jwtServiceSuite))
 	suite.Run(t, new(passwordServiceSuite))
+	suite.Run(t, new(tokenServiceSuite))
+	suite.Run(t, new(notifierSuite))
+	suite.Run(t, new(totpServiceSuite))
 }
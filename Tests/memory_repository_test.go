@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"context"
+	domain "task_manager_api/Domain"
+	repository "task_manager_api/Repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type inMemoryTaskRepositorySuite struct {
+	suite.Suite
+	TaskRepository *repository.InMemoryTaskRepository
+}
+
+type inMemoryRateLimitRepositorySuite struct {
+	suite.Suite
+	RateLimitRepository *repository.InMemoryRateLimitRepository
+}
+
+func (suite *inMemoryTaskRepositorySuite) SetupTest() {
+	suite.TaskRepository = repository.NewInMemoryTaskRepository()
+}
+
+func (suite *inMemoryRateLimitRepositorySuite) SetupTest() {
+	suite.RateLimitRepository = repository.NewInMemoryRateLimitRepository()
+}
+
+// Tests that the stored tasks can not be modified through the returned values
+func (suite *inMemoryTaskRepositorySuite) TestIsolation() {
+	task := domain.Task{ID: "1", Title: "title", StatusHistory: []domain.StatusChange{{To: domain.StatusTodo}}}
+	suite.TaskRepository.AddTask(context.TODO(), task)
+	task.StatusHistory[0].To = domain.StatusDone
+
+	storedTask, _ := suite.TaskRepository.GetTaskByID(context.TODO(), "1")
+	suite.Equal(domain.StatusTodo, storedTask.StatusHistory[0].To)
+	storedTask.StatusHistory[0].To = domain.StatusDone
+
+	storedTask, _ = suite.TaskRepository.GetTaskByID(context.TODO(), "1")
+	suite.Equal(domain.StatusTodo, storedTask.StatusHistory[0].To)
+}
+
+// Tests that a burst of requests empties the bucket, which then refills at a steady rate
+func (suite *inMemoryRateLimitRepositorySuite) TestTakeToken() {
+	limit := domain.RateLimit{Requests: 3, Period: 3 * time.Second}
+	now := time.Now()
+	for i := int64(2); i >= 0; i-- {
+		status, err := suite.RateLimitRepository.TakeToken(context.TODO(), "key", limit, now)
+		suite.NoError(err, "no error when taking a token")
+		suite.True(status.Allowed, "the requests of a burst are allowed")
+		suite.Equal(i, status.Remaining)
+		suite.Equal(int64(3), status.Limit)
+	}
+
+	status, _ := suite.RateLimitRepository.TakeToken(context.TODO(), "key", limit, now)
+	suite.False(status.Allowed, "requests are denied once the bucket is empty")
+	suite.Equal(time.Second, status.RetryAfter, "a token is added back every second")
+	suite.Equal(3*time.Second, status.ResetAfter)
+
+	status, _ = suite.RateLimitRepository.TakeToken(context.TODO(), "other_key", limit, now)
+	suite.True(status.Allowed, "every key has its own bucket")
+
+	status, _ = suite.RateLimitRepository.TakeToken(context.TODO(), "key", limit, now.Add(1500*time.Millisecond))
+	suite.True(status.Allowed, "requests are allowed again once the bucket has refilled")
+	suite.Equal(int64(0), status.Remaining)
+
+	status, _ = suite.RateLimitRepository.TakeToken(context.TODO(), "key", limit, now.Add(1600*time.Millisecond))
+	suite.False(status.Allowed)
+	suite.Equal(400*time.Millisecond, status.RetryAfter.Round(time.Millisecond), "the fractions of tokens are kept")
+
+	status, _ = suite.RateLimitRepository.TakeToken(context.TODO(), "key", limit, now.Add(time.Hour))
+	suite.True(status.Allowed)
+	suite.Equal(int64(2), status.Remaining, "the bucket never holds more than the burst")
+}
+
+func TestInMemoryRepositorySuite(t *testing.T) {
+	suite.Run(t, new(inMemoryTaskRepositorySuite))
+	suite.Run(t, new(inMemoryRateLimitRepositorySuite))
+}
@@ -0,0 +1,1596 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	domain "task_manager_api/Domain"
+	repository "task_manager_api/Repository"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+The behaviour every implementation of the TaskRepositoryInterface must have.
+`NewRepository` creates an empty repository before every test.
+*/
+type taskRepositoryConformanceSuite struct {
+	suite.Suite
+	NewRepository  func(t *testing.T) domain.TaskRepositoryInterface
+	TaskRepository domain.TaskRepositoryInterface
+}
+
+/*
+The behaviour every implementation of the UserRepositoryInterface must have.
+`NewRepository` creates an empty repository before every test.
+*/
+type userRepositoryConformanceSuite struct {
+	suite.Suite
+	NewRepository  func(t *testing.T) domain.UserRepositoryInterface
+	UserRepository domain.UserRepositoryInterface
+}
+
+/*
+The behaviour every implementation of the LoginAttemptRepositoryInterface must
+have. `NewRepository` creates an empty repository before every test.
+*/
+type loginAttemptRepositoryConformanceSuite struct {
+	suite.Suite
+	NewRepository          func(t *testing.T) domain.LoginAttemptRepositoryInterface
+	LoginAttemptRepository domain.LoginAttemptRepositoryInterface
+}
+
+/*
+The behaviour every implementation of the MFARepositoryInterface must have.
+`NewRepository` creates an empty repository before every test.
+*/
+type mfaRepositoryConformanceSuite struct {
+	suite.Suite
+	NewRepository func(t *testing.T) domain.MFARepositoryInterface
+	MFARepository domain.MFARepositoryInterface
+}
+
+/*
+The behaviour every implementation of the MFAChallengeRepositoryInterface must
+have. `NewRepository` creates an empty repository before every test.
+*/
+type mfaChallengeRepositoryConformanceSuite struct {
+	suite.Suite
+	NewRepository          func(t *testing.T) domain.MFAChallengeRepositoryInterface
+	MFAChallengeRepository domain.MFAChallengeRepositoryInterface
+}
+
+/*
+The behaviour every implementation of the APIKeyRepositoryInterface must
+have. `NewRepository` creates an empty repository before every test.
+*/
+type apiKeyRepositoryConformanceSuite struct {
+	suite.Suite
+	NewRepository    func(t *testing.T) domain.APIKeyRepositoryInterface
+	APIKeyRepository domain.APIKeyRepositoryInterface
+}
+
+/*
+The behaviour every implementation of the RoleRepositoryInterface must have.
+`NewRepository` creates an empty repository before every test.
+*/
+type roleRepositoryConformanceSuite struct {
+	suite.Suite
+	NewRepository  func(t *testing.T) domain.RoleRepositoryInterface
+	RoleRepository domain.RoleRepositoryInterface
+}
+
+/*
+The behaviour every implementation of the RefreshTokenRepositoryInterface must
+have. `NewRepository` creates an empty repository before every test.
+*/
+type refreshTokenRepositoryConformanceSuite struct {
+	suite.Suite
+	NewRepository          func(t *testing.T) domain.RefreshTokenRepositoryInterface
+	RefreshTokenRepository domain.RefreshTokenRepositoryInterface
+}
+
+/*
+The behaviour every implementation of the SessionRepositoryInterface must have.
+`NewRepository` creates an empty repository before every test.
+*/
+type sessionRepositoryConformanceSuite struct {
+	suite.Suite
+	NewRepository     func(t *testing.T) domain.SessionRepositoryInterface
+	SessionRepository domain.SessionRepositoryInterface
+}
+
+/*
+The behaviour every implementation of the PasswordResetTokenRepositoryInterface
+must have. `NewRepository` creates an empty repository before every test.
+*/
+type passwordResetTokenRepositoryConformanceSuite struct {
+	suite.Suite
+	NewRepository        func(t *testing.T) domain.PasswordResetTokenRepositoryInterface
+	ResetTokenRepository domain.PasswordResetTokenRepositoryInterface
+}
+
+/*
+The behaviour every implementation of the
+EmailVerificationTokenRepositoryInterface must have. `NewRepository` creates an
+empty repository before every test.
+*/
+type emailVerificationTokenRepositoryConformanceSuite struct {
+	suite.Suite
+	NewRepository          func(t *testing.T) domain.EmailVerificationTokenRepositoryInterface
+	VerificationRepository domain.EmailVerificationTokenRepositoryInterface
+}
+
+func (suite *taskRepositoryConformanceSuite) SetupTest() {
+	suite.TaskRepository = suite.NewRepository(suite.T())
+}
+
+func (suite *userRepositoryConformanceSuite) SetupTest() {
+	suite.UserRepository = suite.NewRepository(suite.T())
+}
+
+func (suite *loginAttemptRepositoryConformanceSuite) SetupTest() {
+	suite.LoginAttemptRepository = suite.NewRepository(suite.T())
+}
+
+func (suite *mfaRepositoryConformanceSuite) SetupTest() {
+	suite.MFARepository = suite.NewRepository(suite.T())
+}
+
+func (suite *mfaChallengeRepositoryConformanceSuite) SetupTest() {
+	suite.MFAChallengeRepository = suite.NewRepository(suite.T())
+}
+
+func (suite *apiKeyRepositoryConformanceSuite) SetupTest() {
+	suite.APIKeyRepository = suite.NewRepository(suite.T())
+}
+
+func (suite *roleRepositoryConformanceSuite) SetupTest() {
+	suite.RoleRepository = suite.NewRepository(suite.T())
+}
+
+func (suite *refreshTokenRepositoryConformanceSuite) SetupTest() {
+	suite.RefreshTokenRepository = suite.NewRepository(suite.T())
+}
+
+func (suite *sessionRepositoryConformanceSuite) SetupTest() {
+	suite.SessionRepository = suite.NewRepository(suite.T())
+}
+
+func (suite *passwordResetTokenRepositoryConformanceSuite) SetupTest() {
+	suite.ResetTokenRepository = suite.NewRepository(suite.T())
+}
+
+func (suite *emailVerificationTokenRepositoryConformanceSuite) SetupTest() {
+	suite.VerificationRepository = suite.NewRepository(suite.T())
+}
+
+// times are stored with millisecond precision in UTC by mongoDB
+func conformanceTime() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+func (suite *taskRepositoryConformanceSuite) newTask(id string) domain.Task {
+	now := conformanceTime()
+	return domain.Task{
+		ID:            id,
+		Title:         "title " + id,
+		Description:   "description " + id,
+		DueDate:       now.Add(time.Hour),
+		Status:        domain.StatusTodo,
+		Owner:         "owner",
+		Assignee:      "assignee",
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		Version:       1,
+		StatusHistory: []domain.StatusChange{{To: domain.StatusTodo, ChangedBy: "owner", ChangedAt: now}},
+	}
+}
+
+// Tests GetAllTasks without adding any
+func (suite *taskRepositoryConformanceSuite) TestGetTasks_Empty() {
+	tasks, total, err := suite.TaskRepository.GetAllTasks(context.TODO(), defaultTaskQuery)
+	suite.NoError(err, "no error when fetching")
+	suite.Empty(tasks, "no tasks are returned when none are added")
+	suite.NotNil(tasks, "an empty slice is returned rather than nil")
+	suite.Equal(int64(0), total, "total is 0 when no tasks are added")
+}
+
+// Tests AddTask and GetTaskByID
+func (suite *taskRepositoryConformanceSuite) TestAddAndGetTask() {
+	task := suite.newTask("1")
+	err := suite.TaskRepository.AddTask(context.TODO(), task)
+	suite.NoError(err, "no error when creating")
+
+	storedTask, err := suite.TaskRepository.GetTaskByID(context.TODO(), "1")
+	suite.NoError(err, "no error when fetching an existing task")
+	suite.Equal(task, storedTask, "every field of the task is stored")
+
+	_, err = suite.TaskRepository.GetTaskByID(context.TODO(), "2")
+	suite.Error(err, "error when fetching an unknown task")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+}
+
+// Tests AddTask with a duplicate ID
+func (suite *taskRepositoryConformanceSuite) TestAddTask_DuplicateID() {
+	suite.NoError(suite.TaskRepository.AddTask(context.TODO(), suite.newTask("1")))
+
+	duplicate := suite.newTask("1")
+	duplicate.Title = "duplicate"
+	err := suite.TaskRepository.AddTask(context.TODO(), duplicate)
+	suite.Error(err, "error when creating a task with a duplicate ID")
+	suite.Equal(domain.ERR_CONFLICT, err.GetCode())
+
+	storedTask, _ := suite.TaskRepository.GetTaskByID(context.TODO(), "1")
+	suite.Equal("title 1", storedTask.Title, "the stored task is not replaced")
+}
+
+// Tests the filters of GetAllTasks
+func (suite *taskRepositoryConformanceSuite) TestGetTasks_Filters() {
+	now := conformanceTime()
+	for i, status := range []string{domain.StatusTodo, domain.StatusDone, domain.StatusTodo} {
+		task := suite.newTask(fmt.Sprint(i + 1))
+		task.Title = fmt.Sprintf("Title %v", i+1)
+		task.DueDate = now.Add(time.Duration(i) * time.Hour)
+		task.Status = status
+		task.Owner = fmt.Sprintf("user%v", i%2)
+		task.Assignee = ""
+		suite.NoError(suite.TaskRepository.AddTask(context.TODO(), task), "no error when creating")
+	}
+
+	undated := suite.newTask("4")
+	undated.Title = "100%_undated"
+	undated.DueDate = time.Time{}
+	undated.Owner = "user2"
+	undated.Assignee = "user1"
+	suite.NoError(suite.TaskRepository.AddTask(context.TODO(), undated))
+
+	query := defaultTaskQuery
+	query.Status = domain.StatusTodo
+	tasks, total, err := suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.NoError(err, "no error when filtering by status")
+	suite.Len(tasks, 3, "only tasks with the status are returned")
+	suite.Equal(int64(3), total, "only tasks with the status are counted")
+
+	query = defaultTaskQuery
+	query.Title = "title 2"
+	tasks, _, _ = suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.Len(tasks, 1, "the title filter is a case-insensitive substring match")
+
+	query.Title = "0%_"
+	tasks, _, _ = suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.Len(tasks, 1, "special characters in the title filter are matched literally")
+
+	query.Title = "1%"
+	tasks, _, _ = suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.Empty(tasks, "special characters in the title filter are not wildcards")
+
+	query = defaultTaskQuery
+	query.DueAfter = now.Add(30 * time.Minute)
+	tasks, _, _ = suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.Len(tasks, 2, "only tasks due after the provided date are returned")
+
+	query.DueBefore = now.Add(90 * time.Minute)
+	tasks, _, _ = suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.Len(tasks, 1, "only tasks due within the range are returned")
+
+	query = defaultTaskQuery
+	query.DueBefore = now.Add(30 * time.Minute)
+	tasks, _, _ = suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.Equal([]string{"1"}, taskIDs(tasks), "tasks without a due date are not due before any date")
+
+	query = defaultTaskQuery
+	query.VisibleTo = "user1"
+	tasks, _, _ = suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.Len(tasks, 2, "only owned and assigned tasks are visible")
+}
+
+// Tests the sort order and pagination of GetAllTasks
+func (suite *taskRepositoryConformanceSuite) TestGetTasks_SortAndPagination() {
+	now := conformanceTime()
+	for i := 1; i <= 3; i++ {
+		task := suite.newTask(fmt.Sprint(i))
+		task.DueDate = now.Add(time.Duration(i) * time.Hour)
+		suite.NoError(suite.TaskRepository.AddTask(context.TODO(), task))
+	}
+
+	undated := suite.newTask("4")
+	undated.DueDate = time.Time{}
+	suite.NoError(suite.TaskRepository.AddTask(context.TODO(), undated))
+
+	query := defaultTaskQuery
+	query.SortBy = "due_date"
+	tasks, _, _ := suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.Equal([]string{"4", "1", "2", "3"}, taskIDs(tasks), "tasks without a due date come first in ascending order")
+
+	query.SortOrder = domain.SortDescending
+	tasks, _, _ = suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.Equal([]string{"3", "2", "1", "4"}, taskIDs(tasks), "tasks without a due date come last in descending order")
+
+	query.SortBy = "status"
+	tasks, _, _ = suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.Equal([]string{"1", "2", "3", "4"}, taskIDs(tasks), "ties are broken by the ascending id")
+
+	query = defaultTaskQuery
+	query.SortBy = "due_date"
+	query.SortOrder = domain.SortDescending
+	query.Limit = 2
+	query.Offset = 1
+	tasks, total, err := suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.NoError(err, "no error when paginating")
+	suite.Equal(int64(4), total, "total ignores the limit and offset")
+	suite.Equal([]string{"2", "1"}, taskIDs(tasks), "tasks are sorted before they are skipped")
+
+	query.Limit = 0
+	tasks, _, err = suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.NoError(err, "no error when skipping tasks without a limit")
+	suite.Equal([]string{"2", "1", "4"}, taskIDs(tasks), "every remaining task is returned without a limit")
+
+	query.Offset = 10
+	tasks, _, err = suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.NoError(err, "no error when the offset is past the last task")
+	suite.Empty(tasks)
+}
+
+// Tests that the tasks created without a due date and those whose due date was cleared are filtered and sorted alike
+func (suite *taskRepositoryConformanceSuite) TestGetTasks_WithoutDueDate() {
+	now := conformanceTime()
+	dated := suite.newTask("1")
+	dated.DueDate = now
+	suite.NoError(suite.TaskRepository.AddTask(context.TODO(), dated))
+
+	created := suite.newTask("2")
+	created.DueDate = time.Time{}
+	suite.NoError(suite.TaskRepository.AddTask(context.TODO(), created))
+
+	updated := suite.newTask("3")
+	suite.TaskRepository.AddTask(context.TODO(), updated)
+	updated.DueDate = time.Time{}
+	_, err := suite.TaskRepository.UpdateTask(context.TODO(), "3", updated, 0)
+	suite.NoError(err, "no error when clearing the due date with an update")
+
+	suite.TaskRepository.AddTask(context.TODO(), suite.newTask("4"))
+	_, err = suite.TaskRepository.PatchTask(context.TODO(), "4", domain.TaskPatch{"due_date": nil}, 0)
+	suite.NoError(err, "no error when clearing the due date with a patch")
+
+	for _, id := range []string{"2", "3", "4"} {
+		task, _ := suite.TaskRepository.GetTaskByID(context.TODO(), id)
+		suite.True(task.DueDate.IsZero(), "the task %v has no due date", id)
+	}
+
+	query := defaultTaskQuery
+	query.DueBefore = now.Add(time.Hour)
+	tasks, total, _ := suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.Equal([]string{"1"}, taskIDs(tasks), "tasks without a due date are not due before any date")
+	suite.Equal(int64(1), total)
+
+	query = defaultTaskQuery
+	query.DueAfter = now.Add(-time.Hour)
+	tasks, _, _ = suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.Equal([]string{"1"}, taskIDs(tasks), "tasks without a due date are not due after any date")
+
+	query = defaultTaskQuery
+	query.SortBy = "due_date"
+	tasks, _, _ = suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.Equal([]string{"2", "3", "4", "1"}, taskIDs(tasks), "tasks without a due date are sorted together")
+
+	query.SortOrder = domain.SortDescending
+	tasks, _, _ = suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.Equal([]string{"1", "2", "3", "4"}, taskIDs(tasks), "tasks without a due date are sorted together")
+}
+
+// Tests UpdateTask
+func (suite *taskRepositoryConformanceSuite) TestUpdateTask() {
+	task := suite.newTask("1")
+	suite.TaskRepository.AddTask(context.TODO(), task)
+
+	updated := domain.Task{
+		Title:         "updated",
+		Status:        domain.StatusInProgress,
+		Owner:         "other",
+		CreatedAt:     task.CreatedAt.Add(time.Hour),
+		UpdatedAt:     task.UpdatedAt.Add(time.Hour),
+		StatusHistory: append(task.StatusHistory, domain.StatusChange{From: domain.StatusTodo, To: domain.StatusInProgress}),
+	}
+	updatedTask, err := suite.TaskRepository.UpdateTask(context.TODO(), "1", updated, 1)
+	suite.NoError(err, "no error when updating with the current version")
+	suite.Equal("updated", updatedTask.Title)
+	suite.Equal(domain.StatusInProgress, updatedTask.Status)
+	suite.Equal("", updatedTask.Description, "fields missing from the update are cleared")
+	suite.Equal("", updatedTask.Assignee, "fields missing from the update are cleared")
+	suite.True(updatedTask.DueDate.IsZero(), "fields missing from the update are cleared")
+	suite.Equal(updated.UpdatedAt, updatedTask.UpdatedAt)
+	suite.Len(updatedTask.StatusHistory, 2, "the status history is replaced")
+	suite.Equal("owner", updatedTask.Owner, "the owner is never modified")
+	suite.Equal(task.CreatedAt, updatedTask.CreatedAt, "the creation time is never modified")
+	suite.Equal(int64(2), updatedTask.Version, "the version is incremented")
+
+	storedTask, _ := suite.TaskRepository.GetTaskByID(context.TODO(), "1")
+	suite.Equal(updatedTask, storedTask, "the returned task is the stored task")
+
+	updatedTask, err = suite.TaskRepository.UpdateTask(context.TODO(), "1", updated, 0)
+	suite.NoError(err, "no error when updating without a version")
+	suite.Equal(int64(3), updatedTask.Version, "the version is incremented")
+}
+
+// Tests UpdateTask with a stale version and an unknown ID
+func (suite *taskRepositoryConformanceSuite) TestUpdateTask_Negative() {
+	task := suite.newTask("1")
+	suite.TaskRepository.AddTask(context.TODO(), task)
+
+	task.Title = "updated"
+	_, err := suite.TaskRepository.UpdateTask(context.TODO(), "1", task, 2)
+	suite.Error(err, "error when updating with a stale version")
+	suite.Equal(domain.ERR_PRECONDITION_FAILED, err.GetCode())
+
+	storedTask, _ := suite.TaskRepository.GetTaskByID(context.TODO(), "1")
+	suite.Equal("title 1", storedTask.Title, "the task is not modified")
+	suite.Equal(int64(1), storedTask.Version, "the version is not incremented")
+
+	_, err = suite.TaskRepository.UpdateTask(context.TODO(), "2", task, 0)
+	suite.Error(err, "error when updating an unknown task")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+
+	_, err = suite.TaskRepository.UpdateTask(context.TODO(), "2", task, 1)
+	suite.Error(err, "error when updating an unknown task with a version")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+}
+
+// Tests PatchTask
+func (suite *taskRepositoryConformanceSuite) TestPatchTask() {
+	task := suite.newTask("1")
+	suite.TaskRepository.AddTask(context.TODO(), task)
+
+	patchedTask, err := suite.TaskRepository.PatchTask(context.TODO(), "1", domain.TaskPatch{"description": nil, "due_date": nil, "status": domain.StatusDone}, 1)
+	suite.NoError(err, "no error when patching with the current version")
+	suite.Equal("", patchedTask.Description, "nil values clear the field")
+	suite.True(patchedTask.DueDate.IsZero(), "nil values clear the field")
+	suite.Equal(domain.StatusDone, patchedTask.Status, "values in the patch are set")
+	suite.Equal(task.Title, patchedTask.Title, "fields missing from the patch are kept")
+	suite.Equal(task.Assignee, patchedTask.Assignee, "fields missing from the patch are kept")
+	suite.Equal(task.StatusHistory, patchedTask.StatusHistory, "fields missing from the patch are kept")
+	suite.Equal(int64(2), patchedTask.Version, "the version is incremented")
+
+	storedTask, _ := suite.TaskRepository.GetTaskByID(context.TODO(), "1")
+	suite.Equal(patchedTask, storedTask, "the returned task is the stored task")
+
+	patchedTask, err = suite.TaskRepository.PatchTask(context.TODO(), "1", domain.TaskPatch{"title": "patched"}, 0)
+	suite.NoError(err, "no error when patching without a version")
+	suite.Equal("patched", patchedTask.Title)
+	suite.Equal(int64(3), patchedTask.Version, "the version is incremented")
+}
+
+// Tests PatchTask with a stale version and an unknown ID
+func (suite *taskRepositoryConformanceSuite) TestPatchTask_Negative() {
+	suite.TaskRepository.AddTask(context.TODO(), suite.newTask("1"))
+
+	_, err := suite.TaskRepository.PatchTask(context.TODO(), "1", domain.TaskPatch{"title": "patched"}, 2)
+	suite.Error(err, "error when patching with a stale version")
+	suite.Equal(domain.ERR_PRECONDITION_FAILED, err.GetCode())
+
+	storedTask, _ := suite.TaskRepository.GetTaskByID(context.TODO(), "1")
+	suite.Equal("title 1", storedTask.Title, "the task is not modified")
+
+	_, err = suite.TaskRepository.PatchTask(context.TODO(), "2", domain.TaskPatch{"title": "patched"}, 0)
+	suite.Error(err, "error when patching an unknown task")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+}
+
+// Tests DeleteTask
+func (suite *taskRepositoryConformanceSuite) TestDeleteTask() {
+	suite.TaskRepository.AddTask(context.TODO(), suite.newTask("1"))
+	suite.TaskRepository.AddTask(context.TODO(), suite.newTask("2"))
+
+	err := suite.TaskRepository.DeleteTask(context.TODO(), "1", 2)
+	suite.Error(err, "error when deleting with a stale version")
+	suite.Equal(domain.ERR_PRECONDITION_FAILED, err.GetCode())
+
+	suite.NoError(suite.TaskRepository.DeleteTask(context.TODO(), "1", 1), "no error when deleting with the current version")
+	suite.NoError(suite.TaskRepository.DeleteTask(context.TODO(), "2", 0), "no error when deleting without a version")
+
+	_, err = suite.TaskRepository.GetTaskByID(context.TODO(), "1")
+	suite.Error(err, "deleted tasks can not be fetched")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+
+	err = suite.TaskRepository.DeleteTask(context.TODO(), "1", 0)
+	suite.Error(err, "error when deleting an unknown task")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+}
+
+// Tests DeleteOwnerTasks and UnassignTasks
+func (suite *taskRepositoryConformanceSuite) TestUserTasks() {
+	owned := suite.newTask("1")
+	owned.Owner = "removed"
+	assigned := suite.newTask("2")
+	assigned.Assignee = "removed"
+	suite.TaskRepository.AddTask(context.TODO(), owned)
+	suite.TaskRepository.AddTask(context.TODO(), assigned)
+	suite.TaskRepository.AddTask(context.TODO(), suite.newTask("3"))
+
+	deleted, err := suite.TaskRepository.DeleteOwnerTasks(context.TODO(), "removed")
+	suite.NoError(err, "no error when deleting the tasks of a user")
+	suite.Equal(int64(1), deleted)
+	_, err = suite.TaskRepository.GetTaskByID(context.TODO(), "1")
+	suite.Error(err, "the tasks owned by the user are deleted")
+
+	updatedAt := conformanceTime().Add(time.Minute)
+	unassigned, err := suite.TaskRepository.UnassignTasks(context.TODO(), "removed", updatedAt)
+	suite.NoError(err, "no error when unassigning the tasks of a user")
+	suite.Equal(int64(1), unassigned)
+	task, _ := suite.TaskRepository.GetTaskByID(context.TODO(), "2")
+	suite.Equal("", task.Assignee)
+	suite.Equal(int64(2), task.Version, "the version of the unassigned tasks is incremented")
+	suite.True(updatedAt.Equal(task.UpdatedAt))
+
+	task, _ = suite.TaskRepository.GetTaskByID(context.TODO(), "3")
+	suite.Equal(suite.newTask("3").Assignee, task.Assignee, "the tasks of other users are kept")
+	suite.Equal(int64(1), task.Version)
+
+	deleted, err = suite.TaskRepository.DeleteOwnerTasks(context.TODO(), "removed")
+	suite.NoError(err, "no error when the user owns no tasks")
+	suite.Equal(int64(0), deleted)
+}
+
+// runs the write concurrently and returns the number of writes that succeeded
+func runConcurrently(count int, write func(i int) domain.CodedError) int {
+	var waitGroup sync.WaitGroup
+	var mutex sync.Mutex
+	succeeded := 0
+	for i := 0; i < count; i++ {
+		waitGroup.Add(1)
+		go func(i int) {
+			defer waitGroup.Done()
+			if write(i) == nil {
+				mutex.Lock()
+				succeeded++
+				mutex.Unlock()
+			}
+		}(i)
+	}
+
+	waitGroup.Wait()
+	return succeeded
+}
+
+// Tests that only one of the concurrent writes with the same version is applied
+func (suite *taskRepositoryConformanceSuite) TestConcurrentWrites_SameVersion() {
+	suite.TaskRepository.AddTask(context.TODO(), suite.newTask("1"))
+
+	succeeded := runConcurrently(10, func(i int) domain.CodedError {
+		_, err := suite.TaskRepository.PatchTask(context.TODO(), "1", domain.TaskPatch{"title": fmt.Sprint(i)}, 1)
+		return err
+	})
+
+	task, _ := suite.TaskRepository.GetTaskByID(context.TODO(), "1")
+	suite.Equal(1, succeeded, "only one write with the same version succeeds")
+	suite.Equal(int64(2), task.Version)
+}
+
+// Tests that concurrent writes without a version are all applied
+func (suite *taskRepositoryConformanceSuite) TestConcurrentWrites_WithoutVersion() {
+	suite.TaskRepository.AddTask(context.TODO(), suite.newTask("1"))
+
+	succeeded := runConcurrently(5, func(i int) domain.CodedError {
+		_, err := suite.TaskRepository.PatchTask(context.TODO(), "1", domain.TaskPatch{"title": fmt.Sprint(i)}, 0)
+		return err
+	})
+
+	task, _ := suite.TaskRepository.GetTaskByID(context.TODO(), "1")
+	suite.Equal(5, succeeded, "every write without a version succeeds")
+	suite.Equal(int64(6), task.Version, "every write increments the version")
+}
+
+// Tests that only one of the concurrent adds with the same ID is applied
+func (suite *taskRepositoryConformanceSuite) TestConcurrentAdds() {
+	succeeded := runConcurrently(10, func(i int) domain.CodedError {
+		return suite.TaskRepository.AddTask(context.TODO(), suite.newTask("1"))
+	})
+
+	_, total, _ := suite.TaskRepository.GetAllTasks(context.TODO(), defaultTaskQuery)
+	suite.Equal(1, succeeded, "only one task with the same ID is added")
+	suite.Equal(int64(1), total)
+}
+
+// returns the IDs of the tasks in order
+func taskIDs(tasks []domain.Task) []string {
+	ids := []string{}
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+
+	return ids
+}
+
+// Tests CreateUser and GetByUsername
+func (suite *userRepositoryConformanceSuite) TestCreateAndGetUser() {
+	user := domain.User{Username: "username12", Email: "mailer@mail.com", Password: "password12", Role: domain.RoleUser, CreatedAt: conformanceTime()}
+	suite.NoError(suite.UserRepository.CreateUser(context.TODO(), user), "no error when creating account")
+
+	storedUser, err := suite.UserRepository.GetByUsername(context.TODO(), user.Username)
+	suite.NoError(err, "no error when fetching an existing user")
+	suite.Equal(user, storedUser, "every field of the user is stored")
+
+	_, err = suite.UserRepository.GetByUsername(context.TODO(), "username34")
+	suite.Error(err, "error when fetching an unknown user")
+}
+
+// Tests CreateUser with a duplicate username or email
+func (suite *userRepositoryConformanceSuite) TestCreateUser_Duplicate() {
+	user := domain.User{Username: "username12", Email: "mailer@mail.com", Password: "password12", Role: domain.RoleUser}
+	suite.UserRepository.CreateUser(context.TODO(), user)
+
+	duplicate := user
+	duplicate.Email = "other@mail.com"
+	err := suite.UserRepository.CreateUser(context.TODO(), duplicate)
+	suite.Error(err, "error when creating an account with a duplicate username")
+	suite.Equal(domain.ERR_CONFLICT, err.GetCode())
+
+	duplicate = user
+	duplicate.Username = "username34"
+	err = suite.UserRepository.CreateUser(context.TODO(), duplicate)
+	suite.Error(err, "error when creating an account with a duplicate email")
+	suite.Equal(domain.ERR_CONFLICT, err.GetCode())
+
+	_, err = suite.UserRepository.GetByUsername(context.TODO(), "username34")
+	suite.Error(err, "the duplicate account is not created")
+}
+
+// Tests CheckDuplicate
+func (suite *userRepositoryConformanceSuite) TestCheckDuplicate() {
+	suite.UserRepository.CreateUser(context.TODO(), domain.User{Username: "username12", Email: "mailer@mail.com", Password: "password12", Role: domain.RoleUser})
+
+	err := suite.UserRepository.CheckDuplicate(context.TODO(), "username", "username12", "")
+	suite.Error(err, "error when the username is taken")
+	suite.Equal(domain.ERR_BAD_REQUEST, err.GetCode())
+
+	err = suite.UserRepository.CheckDuplicate(context.TODO(), "email", "mailer@mail.com", "")
+	suite.Error(err, "error when the email is taken")
+	suite.Equal(domain.ERR_BAD_REQUEST, err.GetCode())
+
+	suite.NoError(suite.UserRepository.CheckDuplicate(context.TODO(), "username", "username34", ""), "no error when the username is free")
+	suite.NoError(suite.UserRepository.CheckDuplicate(context.TODO(), "email", "other@mail.com", ""), "no error when the email is free")
+}
+
+// Tests PromoteUser
+func (suite *userRepositoryConformanceSuite) TestPromoteUser() {
+	suite.UserRepository.CreateUser(context.TODO(), domain.User{Username: "username12", Email: "mailer@mail.com", Password: "password12", Role: domain.RoleUser})
+
+	suite.NoError(suite.UserRepository.PromoteUser(context.TODO(), "username12"), "no error when promoting an existing user")
+	storedUser, _ := suite.UserRepository.GetByUsername(context.TODO(), "username12")
+	suite.Equal(domain.RoleAdmin, storedUser.Role)
+	suite.Equal("password12", storedUser.Password, "the other fields are kept")
+
+	err := suite.UserRepository.PromoteUser(context.TODO(), "username34")
+	suite.Error(err, "error when promoting an unknown user")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+}
+
+// Tests GetByEmail
+func (suite *userRepositoryConformanceSuite) TestGetByEmail() {
+	user := domain.User{Username: "username12", Email: "mailer@mail.com", Password: "password12", Role: domain.RoleUser}
+	suite.UserRepository.CreateUser(context.TODO(), user)
+
+	storedUser, err := suite.UserRepository.GetByEmail(context.TODO(), "mailer@mail.com")
+	suite.NoError(err, "no error when fetching the user of a registered email")
+	suite.Equal(user, storedUser)
+
+	_, err = suite.UserRepository.GetByEmail(context.TODO(), "other@mail.com")
+	suite.Error(err, "error when fetching the user of an unknown email")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+}
+
+// Tests UpdatePassword
+func (suite *userRepositoryConformanceSuite) TestUpdatePassword() {
+	suite.UserRepository.CreateUser(context.TODO(), domain.User{Username: "username12", Email: "mailer@mail.com", Password: "password12", Role: domain.RoleUser})
+
+	suite.NoError(suite.UserRepository.UpdatePassword(context.TODO(), "username12", "password34"), "no error when updating the password of an existing user")
+	storedUser, _ := suite.UserRepository.GetByUsername(context.TODO(), "username12")
+	suite.Equal("password34", storedUser.Password)
+	suite.Equal(domain.RoleUser, storedUser.Role, "the other fields are kept")
+
+	err := suite.UserRepository.UpdatePassword(context.TODO(), "username34", "password34")
+	suite.Error(err, "error when updating the password of an unknown user")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+}
+
+// Tests VerifyEmail
+func (suite *userRepositoryConformanceSuite) TestVerifyEmail() {
+	suite.UserRepository.CreateUser(context.TODO(), domain.User{Username: "username12", Email: "mailer@mail.com", Password: "password12", Role: domain.RoleUser})
+	storedUser, _ := suite.UserRepository.GetByUsername(context.TODO(), "username12")
+	suite.False(storedUser.Verified, "new users are stored unverified")
+
+	suite.NoError(suite.UserRepository.VerifyEmail(context.TODO(), "username12"), "no error when verifying an existing user")
+	storedUser, _ = suite.UserRepository.GetByUsername(context.TODO(), "username12")
+	suite.True(storedUser.Verified)
+	suite.Equal("password12", storedUser.Password, "the other fields are kept")
+
+	err := suite.UserRepository.VerifyEmail(context.TODO(), "username34")
+	suite.Error(err, "error when verifying an unknown user")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+}
+
+// Tests the filters, the order and the pagination of GetUsers
+func (suite *userRepositoryConformanceSuite) TestGetUsers() {
+	now := conformanceTime()
+	suite.UserRepository.CreateUser(context.TODO(), domain.User{Username: "carol", Email: "carol@mail.com", DisplayName: "Carol", Role: domain.RoleAdmin, CreatedAt: now})
+	suite.UserRepository.CreateUser(context.TODO(), domain.User{Username: "alice", Email: "alice@other.com", DisplayName: "Alice 100%", Role: domain.RoleUser, CreatedAt: now.Add(time.Minute)})
+	suite.UserRepository.CreateUser(context.TODO(), domain.User{Username: "bob", Email: "bob@mail.com", DisplayName: "Bobby", Role: domain.RoleUser, CreatedAt: now.Add(-time.Minute)})
+
+	users, total, err := suite.UserRepository.GetUsers(context.TODO(), domain.UserQuery{SortBy: "username", SortOrder: domain.SortAscending, Limit: 2})
+	suite.NoError(err, "no error when listing the users")
+	suite.Equal(int64(3), total)
+	suite.Equal([]string{"alice", "bob"}, usernames(users))
+	suite.Equal("Alice 100%", users[0].DisplayName, "every field of the users is returned")
+
+	users, _, _ = suite.UserRepository.GetUsers(context.TODO(), domain.UserQuery{SortBy: "username", SortOrder: domain.SortAscending, Limit: 2, Offset: 2})
+	suite.Equal([]string{"carol"}, usernames(users))
+
+	users, _, _ = suite.UserRepository.GetUsers(context.TODO(), domain.UserQuery{SortBy: "created_at", SortOrder: domain.SortDescending, Limit: 10})
+	suite.Equal([]string{"alice", "carol", "bob"}, usernames(users))
+
+	users, total, _ = suite.UserRepository.GetUsers(context.TODO(), domain.UserQuery{Search: "MAIL.COM", SortBy: "username", SortOrder: domain.SortAscending, Limit: 10})
+	suite.Equal(int64(2), total, "the search matches the emails regardless of their case")
+	suite.Equal([]string{"bob", "carol"}, usernames(users))
+
+	users, _, _ = suite.UserRepository.GetUsers(context.TODO(), domain.UserQuery{Search: "bobby", SortBy: "username", SortOrder: domain.SortAscending, Limit: 10})
+	suite.Equal([]string{"bob"}, usernames(users), "the search matches the display names")
+
+	users, _, _ = suite.UserRepository.GetUsers(context.TODO(), domain.UserQuery{Search: "0%", SortBy: "username", SortOrder: domain.SortAscending, Limit: 10})
+	suite.Equal([]string{"alice"}, usernames(users), "the search is not a pattern")
+
+	users, total, _ = suite.UserRepository.GetUsers(context.TODO(), domain.UserQuery{Role: domain.RoleAdmin, SortBy: "username", SortOrder: domain.SortAscending, Limit: 10})
+	suite.Equal(int64(1), total)
+	suite.Equal([]string{"carol"}, usernames(users))
+}
+
+// returns the usernames of the users in order
+func usernames(users []domain.User) []string {
+	names := []string{}
+	for _, user := range users {
+		names = append(names, user.Username)
+	}
+
+	return names
+}
+
+// Tests UpdateProfile
+func (suite *userRepositoryConformanceSuite) TestUpdateProfile() {
+	suite.UserRepository.CreateUser(context.TODO(), domain.User{Username: "username12", Email: "mailer@mail.com", Password: "password12", Role: domain.RoleUser, Verified: true})
+	suite.UserRepository.CreateUser(context.TODO(), domain.User{Username: "username34", Email: "other@mail.com", Password: "password34", Role: domain.RoleUser})
+
+	update := domain.User{Username: "username12", Email: "new@mail.com", DisplayName: "New Name", Password: "ignored", Role: domain.RoleAdmin}
+	suite.NoError(suite.UserRepository.UpdateProfile(context.TODO(), update), "no error when updating the profile of an existing user")
+	storedUser, _ := suite.UserRepository.GetByUsername(context.TODO(), "username12")
+	suite.Equal("new@mail.com", storedUser.Email)
+	suite.Equal("New Name", storedUser.DisplayName)
+	suite.False(storedUser.Verified)
+	suite.Equal("password12", storedUser.Password, "the password is kept")
+	suite.Equal(domain.RoleUser, storedUser.Role, "the role is kept")
+
+	update.Email = "other@mail.com"
+	err := suite.UserRepository.UpdateProfile(context.TODO(), update)
+	suite.Error(err, "error when the email belongs to another user")
+	suite.Equal(domain.ERR_CONFLICT, err.GetCode())
+
+	err = suite.UserRepository.UpdateProfile(context.TODO(), domain.User{Username: "unknown", Email: "unknown@mail.com"})
+	suite.Error(err, "error when updating an unknown user")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+}
+
+// Tests DemoteUser
+func (suite *userRepositoryConformanceSuite) TestDemoteUser() {
+	suite.UserRepository.CreateUser(context.TODO(), domain.User{Username: "username12", Email: "mailer@mail.com", Password: "password12", Role: domain.RoleAdmin})
+
+	suite.NoError(suite.UserRepository.DemoteUser(context.TODO(), "username12"), "no error when demoting an existing user")
+	storedUser, _ := suite.UserRepository.GetByUsername(context.TODO(), "username12")
+	suite.Equal(domain.RoleUser, storedUser.Role)
+	suite.Equal("password12", storedUser.Password, "the other fields are kept")
+
+	err := suite.UserRepository.DemoteUser(context.TODO(), "username34")
+	suite.Error(err, "error when demoting an unknown user")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+}
+
+// Tests SetRole
+func (suite *userRepositoryConformanceSuite) TestSetRole() {
+	suite.UserRepository.CreateUser(context.TODO(), domain.User{Username: "username12", Email: "mailer@mail.com", Password: "password12", Role: domain.RoleUser})
+
+	suite.NoError(suite.UserRepository.SetRole(context.TODO(), "username12", "manager"), "no error when assigning a role to an existing user")
+	storedUser, _ := suite.UserRepository.GetByUsername(context.TODO(), "username12")
+	suite.Equal("manager", storedUser.Role)
+	suite.Equal("password12", storedUser.Password, "the other fields are kept")
+
+	users, total, _ := suite.UserRepository.GetUsers(context.TODO(), domain.UserQuery{Role: "manager", SortBy: "username", SortOrder: domain.SortAscending, Limit: 1})
+	suite.Equal(int64(1), total, "the users can be filtered by the custom role")
+	suite.Equal([]string{"username12"}, usernames(users))
+
+	err := suite.UserRepository.SetRole(context.TODO(), "username34", "manager")
+	suite.Error(err, "error when assigning a role to an unknown user")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+}
+
+// Tests SetDeactivated
+func (suite *userRepositoryConformanceSuite) TestSetDeactivated() {
+	suite.UserRepository.CreateUser(context.TODO(), domain.User{Username: "username12", Email: "mailer@mail.com", Password: "password12", Role: domain.RoleUser})
+	storedUser, _ := suite.UserRepository.GetByUsername(context.TODO(), "username12")
+	suite.False(storedUser.Deactivated, "new users are stored active")
+
+	suite.NoError(suite.UserRepository.SetDeactivated(context.TODO(), "username12", true), "no error when deactivating an existing user")
+	storedUser, _ = suite.UserRepository.GetByUsername(context.TODO(), "username12")
+	suite.True(storedUser.Deactivated)
+	suite.Equal("password12", storedUser.Password, "the other fields are kept")
+
+	suite.NoError(suite.UserRepository.SetDeactivated(context.TODO(), "username12", false), "no error when activating an existing user")
+	storedUser, _ = suite.UserRepository.GetByUsername(context.TODO(), "username12")
+	suite.False(storedUser.Deactivated)
+
+	err := suite.UserRepository.SetDeactivated(context.TODO(), "username34", true)
+	suite.Error(err, "error when deactivating an unknown user")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+}
+
+// Tests DeleteUser
+func (suite *userRepositoryConformanceSuite) TestDeleteUser() {
+	suite.UserRepository.CreateUser(context.TODO(), domain.User{Username: "username12", Email: "mailer@mail.com", Password: "password12", Role: domain.RoleUser})
+
+	suite.NoError(suite.UserRepository.DeleteUser(context.TODO(), "username12"), "no error when deleting an existing user")
+	_, err := suite.UserRepository.GetByUsername(context.TODO(), "username12")
+	suite.Error(err, "deleted users can not be fetched")
+	suite.NoError(suite.UserRepository.CheckDuplicate(context.TODO(), "email", "mailer@mail.com", ""), "the email of a deleted user is free")
+
+	err = suite.UserRepository.DeleteUser(context.TODO(), "username12")
+	suite.Error(err, "error when deleting an unknown user")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+}
+
+// Tests that only one of the concurrent signups with the same username is applied
+func (suite *userRepositoryConformanceSuite) TestConcurrentCreateUser() {
+	succeeded := runConcurrently(10, func(i int) domain.CodedError {
+		return suite.UserRepository.CreateUser(context.TODO(), domain.User{Username: "username12", Email: fmt.Sprintf("mailer%v@mail.com", i), Role: domain.RoleUser})
+	})
+
+	suite.Equal(1, succeeded, "only one of the users with the same username is created")
+}
+
+// Tests RecordLoginFailure and GetLoginAttempts
+func (suite *loginAttemptRepositoryConformanceSuite) TestRecordAndGet() {
+	now := conformanceTime()
+	_, err := suite.LoginAttemptRepository.GetLoginAttempts(context.TODO(), "account:username")
+	suite.Error(err, "error when no login failed for the key")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+
+	attempts, err := suite.LoginAttemptRepository.RecordLoginFailure(context.TODO(), "account:username", now.Add(-time.Second), now.Add(time.Hour))
+	suite.NoError(err, "no error when recording the first failure")
+	suite.Equal(int64(1), attempts.Failures)
+
+	attempts, err = suite.LoginAttemptRepository.RecordLoginFailure(context.TODO(), "account:username", now, now.Add(time.Hour))
+	suite.NoError(err, "no error when recording another failure")
+	suite.Equal(domain.LoginAttempts{Key: "account:username", Failures: 2, LastFailure: now, ExpiresAt: now.Add(time.Hour)}, attempts)
+
+	storedAttempts, err := suite.LoginAttemptRepository.GetLoginAttempts(context.TODO(), "account:username")
+	suite.NoError(err, "no error when getting the failures of the key")
+	suite.Equal(attempts, storedAttempts)
+
+	attempts, _ = suite.LoginAttemptRepository.RecordLoginFailure(context.TODO(), "ip:127.0.0.1", now, now.Add(time.Hour))
+	suite.Equal(int64(1), attempts.Failures, "the failures are counted separately for every key")
+}
+
+// Tests that the failures are counted from zero again once they have expired
+func (suite *loginAttemptRepositoryConformanceSuite) TestRecordAfterExpiry() {
+	now := conformanceTime()
+	suite.LoginAttemptRepository.RecordLoginFailure(context.TODO(), "account:username", now.Add(-2*time.Hour), now.Add(-time.Hour))
+	suite.LoginAttemptRepository.RecordLoginFailure(context.TODO(), "account:username", now.Add(-2*time.Hour), now.Add(-time.Hour))
+
+	attempts, err := suite.LoginAttemptRepository.RecordLoginFailure(context.TODO(), "account:username", now, now.Add(time.Hour))
+	suite.NoError(err, "no error when recording a failure after the previous ones expired")
+	suite.Equal(int64(1), attempts.Failures)
+	suite.Equal(now.Add(time.Hour), attempts.ExpiresAt)
+}
+
+// Tests ClearLoginAttempts
+func (suite *loginAttemptRepositoryConformanceSuite) TestClear() {
+	now := conformanceTime()
+	suite.LoginAttemptRepository.RecordLoginFailure(context.TODO(), "account:username", now, now.Add(time.Hour))
+	suite.LoginAttemptRepository.RecordLoginFailure(context.TODO(), "ip:127.0.0.1", now, now.Add(time.Hour))
+
+	suite.NoError(suite.LoginAttemptRepository.ClearLoginAttempts(context.TODO(), "account:username"), "no error when clearing the failures of a key")
+	_, err := suite.LoginAttemptRepository.GetLoginAttempts(context.TODO(), "account:username")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "the failures of the key are forgotten")
+	_, err = suite.LoginAttemptRepository.GetLoginAttempts(context.TODO(), "ip:127.0.0.1")
+	suite.NoError(err, "the failures of the other keys are kept")
+
+	suite.NoError(suite.LoginAttemptRepository.ClearLoginAttempts(context.TODO(), "unknown"), "no error when clearing a key without failures")
+}
+
+// Tests that every one of the concurrent failures is counted
+func (suite *loginAttemptRepositoryConformanceSuite) TestConcurrentRecordLoginFailure() {
+	now := conformanceTime()
+	succeeded := runConcurrently(10, func(i int) domain.CodedError {
+		_, err := suite.LoginAttemptRepository.RecordLoginFailure(context.TODO(), "account:username", now, now.Add(time.Hour))
+		return err
+	})
+
+	suite.Equal(10, succeeded)
+	attempts, _ := suite.LoginAttemptRepository.GetLoginAttempts(context.TODO(), "account:username")
+	suite.Equal(int64(10), attempts.Failures, "no failure is lost")
+}
+
+func newConformanceEnrollment(username string) domain.MFAEnrollment {
+	return domain.MFAEnrollment{
+		Username:      username,
+		Secret:        "SECRET",
+		Enabled:       true,
+		LastUsedStep:  100,
+		RecoveryCodes: []string{"hash_a", "hash_b"},
+		CreatedAt:     conformanceTime(),
+	}
+}
+
+// Tests SaveMFA and GetMFA
+func (suite *mfaRepositoryConformanceSuite) TestSaveAndGet() {
+	_, err := suite.MFARepository.GetMFA(context.TODO(), "username")
+	suite.Error(err, "error when the user has no enrollment")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+
+	pending := domain.MFAEnrollment{Username: "username", Secret: "PENDING", RecoveryCodes: []string{}, CreatedAt: conformanceTime()}
+	suite.NoError(suite.MFARepository.SaveMFA(context.TODO(), pending), "no error when saving a pending enrollment")
+	stored, err := suite.MFARepository.GetMFA(context.TODO(), "username")
+	suite.NoError(err, "no error when getting the enrollment")
+	suite.Equal(pending, stored)
+
+	enrollment := newConformanceEnrollment("username")
+	suite.NoError(suite.MFARepository.SaveMFA(context.TODO(), enrollment), "no error when replacing the enrollment")
+	stored, _ = suite.MFARepository.GetMFA(context.TODO(), "username")
+	suite.Equal(enrollment, stored, "the enrollment and its recovery codes are replaced")
+
+	enrollment.RecoveryCodes[0] = "changed"
+	stored, _ = suite.MFARepository.GetMFA(context.TODO(), "username")
+	suite.Equal("hash_a", stored.RecoveryCodes[0], "the stored recovery codes can not be changed by the caller")
+}
+
+// Tests that a time step can only be used once
+func (suite *mfaRepositoryConformanceSuite) TestUseTOTPStep() {
+	suite.MFARepository.SaveMFA(context.TODO(), newConformanceEnrollment("username"))
+
+	suite.NoError(suite.MFARepository.UseTOTPStep(context.TODO(), "username", 101), "no error when the step is after the last used one")
+	err := suite.MFARepository.UseTOTPStep(context.TODO(), "username", 101)
+	suite.Error(err, "error when the step has already been used")
+	suite.Equal(domain.ERR_CONFLICT, err.GetCode())
+	err = suite.MFARepository.UseTOTPStep(context.TODO(), "username", 99)
+	suite.Equal(domain.ERR_CONFLICT, err.GetCode(), "error when the step is before the last used one")
+
+	stored, _ := suite.MFARepository.GetMFA(context.TODO(), "username")
+	suite.Equal(int64(101), stored.LastUsedStep)
+
+	err = suite.MFARepository.UseTOTPStep(context.TODO(), "unknown", 101)
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when the user has no enrollment")
+}
+
+// Tests that only one of the concurrent uses of a time step succeeds
+func (suite *mfaRepositoryConformanceSuite) TestConcurrentUseTOTPStep() {
+	suite.MFARepository.SaveMFA(context.TODO(), newConformanceEnrollment("username"))
+	succeeded := runConcurrently(10, func(i int) domain.CodedError {
+		return suite.MFARepository.UseTOTPStep(context.TODO(), "username", 101)
+	})
+
+	suite.Equal(1, succeeded, "the code of a time step is only accepted once")
+}
+
+// Tests that a recovery code can only be used once
+func (suite *mfaRepositoryConformanceSuite) TestUseRecoveryCode() {
+	suite.MFARepository.SaveMFA(context.TODO(), newConformanceEnrollment("username"))
+
+	suite.NoError(suite.MFARepository.UseRecoveryCode(context.TODO(), "username", "hash_a"), "no error when the code is unused")
+	err := suite.MFARepository.UseRecoveryCode(context.TODO(), "username", "hash_a")
+	suite.Error(err, "error when the code has been used")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+	err = suite.MFARepository.UseRecoveryCode(context.TODO(), "other_username", "hash_b")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when the code belongs to another user")
+
+	stored, _ := suite.MFARepository.GetMFA(context.TODO(), "username")
+	suite.Equal([]string{"hash_b"}, stored.RecoveryCodes)
+}
+
+// Tests DeleteMFA
+func (suite *mfaRepositoryConformanceSuite) TestDelete() {
+	suite.MFARepository.SaveMFA(context.TODO(), newConformanceEnrollment("username"))
+
+	suite.NoError(suite.MFARepository.DeleteMFA(context.TODO(), "username"), "no error when deleting the enrollment")
+	_, err := suite.MFARepository.GetMFA(context.TODO(), "username")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "the enrollment is deleted")
+
+	err = suite.MFARepository.DeleteMFA(context.TODO(), "username")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when the user has no enrollment")
+
+	suite.MFARepository.SaveMFA(context.TODO(), domain.MFAEnrollment{Username: "username", Secret: "NEW", CreatedAt: conformanceTime()})
+	stored, _ := suite.MFARepository.GetMFA(context.TODO(), "username")
+	suite.Empty(stored.RecoveryCodes, "the recovery codes are deleted along with the enrollment")
+}
+
+// Tests CreateChallenge, GetChallenge and UseChallenge
+func (suite *mfaChallengeRepositoryConformanceSuite) TestUseChallenge() {
+	now := conformanceTime()
+	challenge := domain.MFAChallenge{TokenHash: "hash_challenge", Username: "username", CreatedAt: now, ExpiresAt: now.Add(time.Minute)}
+	suite.NoError(suite.MFAChallengeRepository.CreateChallenge(context.TODO(), challenge), "no error when creating a challenge")
+
+	stored, err := suite.MFAChallengeRepository.GetChallenge(context.TODO(), "hash_challenge")
+	suite.NoError(err, "no error when getting an unused challenge")
+	suite.Equal(challenge, stored)
+
+	used, err := suite.MFAChallengeRepository.UseChallenge(context.TODO(), "hash_challenge")
+	suite.NoError(err, "no error when using an unused challenge")
+	challenge.Used = true
+	suite.Equal(challenge, used)
+
+	_, err = suite.MFAChallengeRepository.UseChallenge(context.TODO(), "hash_challenge")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when the challenge has been used")
+	_, err = suite.MFAChallengeRepository.GetChallenge(context.TODO(), "hash_challenge")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when getting a used challenge")
+	_, err = suite.MFAChallengeRepository.GetChallenge(context.TODO(), "hash_unknown")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when the challenge is unknown")
+}
+
+// Tests that only one of the concurrent uses of a challenge succeeds
+func (suite *mfaChallengeRepositoryConformanceSuite) TestConcurrentUseChallenge() {
+	now := conformanceTime()
+	suite.MFAChallengeRepository.CreateChallenge(context.TODO(), domain.MFAChallenge{TokenHash: "hash_challenge", Username: "username", CreatedAt: now, ExpiresAt: now.Add(time.Minute)})
+	succeeded := runConcurrently(10, func(i int) domain.CodedError {
+		_, err := suite.MFAChallengeRepository.UseChallenge(context.TODO(), "hash_challenge")
+		return err
+	})
+
+	suite.Equal(1, succeeded, "a challenge is only used once")
+}
+
+func (suite *apiKeyRepositoryConformanceSuite) newAPIKey(id string, username string, createdAt time.Time) domain.APIKey {
+	return domain.APIKey{
+		ID:        id,
+		Username:  username,
+		Name:      "key " + id,
+		Prefix:    "tmk_" + id,
+		KeyHash:   "hash_" + id,
+		Scopes:    []string{domain.ScopeTasksRead, domain.ScopeTasksWrite},
+		CreatedAt: createdAt,
+	}
+}
+
+// Tests CreateAPIKey, GetAPIKeyByHash and GetAPIKeys
+func (suite *apiKeyRepositoryConformanceSuite) TestGetAPIKeys() {
+	now := conformanceTime()
+	older := suite.newAPIKey("key_1", "username", now.Add(-time.Hour))
+	newer := suite.newAPIKey("key_2", "username", now)
+	newer.ExpiresAt = now.Add(24 * time.Hour)
+	suite.NoError(suite.APIKeyRepository.CreateAPIKey(context.TODO(), older), "no error when creating a key")
+	suite.NoError(suite.APIKeyRepository.CreateAPIKey(context.TODO(), newer), "no error when creating a key")
+	suite.NoError(suite.APIKeyRepository.CreateAPIKey(context.TODO(), suite.newAPIKey("key_3", "other", now)), "no error when creating a key")
+
+	stored, err := suite.APIKeyRepository.GetAPIKeyByHash(context.TODO(), "hash_key_2")
+	suite.NoError(err, "no error when getting a key by its hash")
+	suite.Equal(newer, stored)
+	_, err = suite.APIKeyRepository.GetAPIKeyByHash(context.TODO(), "hash_unknown")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when the key is unknown")
+
+	keys, err := suite.APIKeyRepository.GetAPIKeys(context.TODO(), "username")
+	suite.NoError(err, "no error when listing the keys of a user")
+	suite.Equal([]domain.APIKey{newer, older}, keys, "the keys of the user are listed from the newest")
+
+	keys, err = suite.APIKeyRepository.GetAPIKeys(context.TODO(), "unknown")
+	suite.NoError(err, "no error when the user has no keys")
+	suite.Empty(keys)
+}
+
+// Tests TouchAPIKey
+func (suite *apiKeyRepositoryConformanceSuite) TestTouchAPIKey() {
+	now := conformanceTime()
+	suite.APIKeyRepository.CreateAPIKey(context.TODO(), suite.newAPIKey("key_1", "username", now))
+
+	suite.NoError(suite.APIKeyRepository.TouchAPIKey(context.TODO(), "key_1", now.Add(time.Minute)), "no error when recording the use of a key")
+	stored, _ := suite.APIKeyRepository.GetAPIKeyByHash(context.TODO(), "hash_key_1")
+	suite.True(now.Add(time.Minute).Equal(stored.LastUsedAt), "the last use of the key is recorded")
+
+	err := suite.APIKeyRepository.TouchAPIKey(context.TODO(), "unknown", now)
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when the key is unknown")
+}
+
+// Tests DeleteAPIKey and DeleteUserAPIKeys
+func (suite *apiKeyRepositoryConformanceSuite) TestDeleteAPIKeys() {
+	now := conformanceTime()
+	suite.APIKeyRepository.CreateAPIKey(context.TODO(), suite.newAPIKey("key_1", "username", now))
+	suite.APIKeyRepository.CreateAPIKey(context.TODO(), suite.newAPIKey("key_2", "username", now))
+	suite.APIKeyRepository.CreateAPIKey(context.TODO(), suite.newAPIKey("key_3", "other", now))
+
+	err := suite.APIKeyRepository.DeleteAPIKey(context.TODO(), "other", "key_1")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when the key belongs to another user")
+	suite.NoError(suite.APIKeyRepository.DeleteAPIKey(context.TODO(), "username", "key_1"), "no error when deleting an own key")
+	err = suite.APIKeyRepository.DeleteAPIKey(context.TODO(), "username", "key_1")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when the key has been deleted")
+
+	suite.NoError(suite.APIKeyRepository.DeleteUserAPIKeys(context.TODO(), "username"), "no error when deleting the keys of a user")
+	keys, _ := suite.APIKeyRepository.GetAPIKeys(context.TODO(), "username")
+	suite.Empty(keys, "every key of the user is deleted")
+	keys, _ = suite.APIKeyRepository.GetAPIKeys(context.TODO(), "other")
+	suite.Len(keys, 1, "the keys of other users are kept")
+}
+
+// Tests SaveRole, GetRole and GetRoles
+func (suite *roleRepositoryConformanceSuite) TestSaveAndGetRoles() {
+	roles, err := suite.RoleRepository.GetRoles(context.TODO())
+	suite.NoError(err, "no error when there are no roles")
+	suite.Empty(roles)
+
+	_, err = suite.RoleRepository.GetRole(context.TODO(), "viewer")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when the role does not exist")
+
+	suite.NoError(suite.RoleRepository.SaveRole(context.TODO(), domain.Role{Name: "viewer", Permissions: []string{domain.PermissionTaskRead}}), "no error when creating a role")
+	suite.NoError(suite.RoleRepository.SaveRole(context.TODO(), domain.Role{Name: "manager", Permissions: []string{}}), "no error when creating a role without permissions")
+	suite.NoError(suite.RoleRepository.SaveRole(context.TODO(), domain.Role{Name: "viewer", Permissions: []string{domain.PermissionTaskRead, domain.PermissionTaskReadAny}}), "no error when replacing the permissions of a role")
+
+	role, err := suite.RoleRepository.GetRole(context.TODO(), "viewer")
+	suite.NoError(err, "no error when the role exists")
+	suite.Equal(domain.Role{Name: "viewer", Permissions: []string{domain.PermissionTaskRead, domain.PermissionTaskReadAny}}, role, "the permissions are replaced")
+
+	roles, err = suite.RoleRepository.GetRoles(context.TODO())
+	suite.NoError(err, "no error when listing the roles")
+	suite.Len(roles, 2)
+	suite.Equal("manager", roles[0].Name, "the roles are sorted by name")
+	suite.Empty(roles[0].Permissions)
+	suite.Equal("viewer", roles[1].Name)
+}
+
+// Tests that SaveRole replaces the second factor requirement along with the permissions
+func (suite *roleRepositoryConformanceSuite) TestSaveRole_MFARequired() {
+	required := domain.Role{Name: domain.RoleAdmin, Permissions: []string{domain.PermissionTaskRead}, MFARequired: true}
+	suite.NoError(suite.RoleRepository.SaveRole(context.TODO(), required), "no error when requiring a second factor")
+
+	role, err := suite.RoleRepository.GetRole(context.TODO(), domain.RoleAdmin)
+	suite.NoError(err, "no error when the role exists")
+	suite.Equal(required, role, "the requirement is stored")
+
+	required.MFARequired = false
+	suite.NoError(suite.RoleRepository.SaveRole(context.TODO(), required), "no error when lifting the requirement")
+	roles, err := suite.RoleRepository.GetRoles(context.TODO())
+	suite.NoError(err, "no error when listing the roles")
+	suite.Equal([]domain.Role{required}, roles, "the requirement is replaced")
+}
+
+// Tests DeleteRole
+func (suite *roleRepositoryConformanceSuite) TestDeleteRole() {
+	suite.RoleRepository.SaveRole(context.TODO(), domain.Role{Name: "viewer", Permissions: []string{domain.PermissionTaskRead}})
+
+	suite.NoError(suite.RoleRepository.DeleteRole(context.TODO(), "viewer"), "no error when deleting an existing role")
+	_, err := suite.RoleRepository.GetRole(context.TODO(), "viewer")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "the role is deleted")
+
+	err = suite.RoleRepository.DeleteRole(context.TODO(), "viewer")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when the role does not exist")
+}
+
+func newConformanceRefreshToken(hash string, family string, username string) domain.RefreshToken {
+	now := conformanceTime()
+	return domain.RefreshToken{TokenHash: hash, FamilyID: family, Username: username, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+}
+
+// Tests CreateRefreshToken and GetRefreshToken
+func (suite *refreshTokenRepositoryConformanceSuite) TestCreateAndGet() {
+	token := newConformanceRefreshToken("hash", "family", "username")
+	suite.NoError(suite.RefreshTokenRepository.CreateRefreshToken(context.TODO(), token), "no error when creating a token")
+
+	stored, err := suite.RefreshTokenRepository.GetRefreshToken(context.TODO(), "hash")
+	suite.NoError(err, "no error when getting an existing token")
+	suite.Equal(token, stored, "every field of the token is stored")
+
+	_, err = suite.RefreshTokenRepository.GetRefreshToken(context.TODO(), "unknown")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when getting an unknown token")
+}
+
+// Tests that UseRefreshToken only matches a token once, concurrently or not
+func (suite *refreshTokenRepositoryConformanceSuite) TestUseRefreshToken() {
+	token := newConformanceRefreshToken("hash", "family", "username")
+	suite.RefreshTokenRepository.CreateRefreshToken(context.TODO(), token)
+
+	used, err := suite.RefreshTokenRepository.UseRefreshToken(context.TODO(), "hash")
+	suite.NoError(err, "no error when using an unused token")
+	token.Used = true
+	suite.Equal(token, used, "the used token is returned")
+
+	_, err = suite.RefreshTokenRepository.UseRefreshToken(context.TODO(), "hash")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when using a token twice")
+	_, err = suite.RefreshTokenRepository.UseRefreshToken(context.TODO(), "unknown")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when using an unknown token")
+
+	stored, _ := suite.RefreshTokenRepository.GetRefreshToken(context.TODO(), "hash")
+	suite.True(stored.Used, "used tokens can still be looked up to detect their reuse")
+
+	suite.RefreshTokenRepository.CreateRefreshToken(context.TODO(), newConformanceRefreshToken("hash_concurrent", "family", "username"))
+	succeeded := runConcurrently(10, func(i int) domain.CodedError {
+		_, err := suite.RefreshTokenRepository.UseRefreshToken(context.TODO(), "hash_concurrent")
+		return err
+	})
+	suite.Equal(1, succeeded, "a token is only used once")
+}
+
+// Tests that RevokeTokenFamily and RevokeUserTokens only revoke the matching tokens
+func (suite *refreshTokenRepositoryConformanceSuite) TestRevokeTokens() {
+	tests := []struct {
+		revoke  func() domain.CodedError
+		revoked []string
+		kept    []string
+	}{
+		{func() domain.CodedError {
+			return suite.RefreshTokenRepository.RevokeTokenFamily(context.TODO(), "family")
+		}, []string{"hash_1", "hash_2"}, []string{"hash_3", "hash_4"}},
+		{func() domain.CodedError {
+			return suite.RefreshTokenRepository.RevokeUserTokens(context.TODO(), "username")
+		}, []string{"hash_1", "hash_2", "hash_3"}, []string{"hash_4"}},
+	}
+
+	for _, test := range tests {
+		suite.SetupTest()
+		suite.RefreshTokenRepository.CreateRefreshToken(context.TODO(), newConformanceRefreshToken("hash_1", "family", "username"))
+		suite.RefreshTokenRepository.CreateRefreshToken(context.TODO(), newConformanceRefreshToken("hash_2", "family", "username"))
+		suite.RefreshTokenRepository.CreateRefreshToken(context.TODO(), newConformanceRefreshToken("hash_3", "other_family", "username"))
+		suite.RefreshTokenRepository.CreateRefreshToken(context.TODO(), newConformanceRefreshToken("hash_4", "foreign_family", "other_username"))
+		suite.NoError(test.revoke(), "no error when revoking the tokens")
+
+		for _, hash := range test.revoked {
+			stored, _ := suite.RefreshTokenRepository.GetRefreshToken(context.TODO(), hash)
+			suite.True(stored.Revoked, "the token %v is revoked", hash)
+			_, err := suite.RefreshTokenRepository.UseRefreshToken(context.TODO(), hash)
+			suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when using the revoked token %v", hash)
+		}
+
+		for _, hash := range test.kept {
+			used, err := suite.RefreshTokenRepository.UseRefreshToken(context.TODO(), hash)
+			suite.NoError(err, "no error when using the token %v", hash)
+			suite.False(used.Revoked)
+		}
+	}
+}
+
+func newConformanceSession(id string, username string, createdAt time.Time) domain.Session {
+	return domain.Session{ID: id, Username: username, CreatedAt: createdAt, LastUsedAt: createdAt, ExpiresAt: createdAt.Add(time.Hour)}
+}
+
+// Tests CreateSession and GetSession
+func (suite *sessionRepositoryConformanceSuite) TestCreateAndGet() {
+	session := newConformanceSession("session", "username", conformanceTime())
+	suite.NoError(suite.SessionRepository.CreateSession(context.TODO(), session), "no error when creating a session")
+
+	stored, err := suite.SessionRepository.GetSession(context.TODO(), "session")
+	suite.NoError(err, "no error when getting an existing session")
+	suite.Equal(session, stored, "every field of the session is stored")
+
+	_, err = suite.SessionRepository.GetSession(context.TODO(), "unknown")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when getting an unknown session")
+}
+
+// Tests that GetActiveSessions leaves out revoked, expired and foreign sessions
+func (suite *sessionRepositoryConformanceSuite) TestGetActiveSessions() {
+	now := conformanceTime()
+	suite.SessionRepository.CreateSession(context.TODO(), newConformanceSession("older", "username", now.Add(-time.Minute)))
+	suite.SessionRepository.CreateSession(context.TODO(), newConformanceSession("newer", "username", now))
+	suite.SessionRepository.CreateSession(context.TODO(), newConformanceSession("revoked", "username", now))
+	suite.SessionRepository.CreateSession(context.TODO(), newConformanceSession("expired", "username", now.Add(-2*time.Hour)))
+	suite.SessionRepository.CreateSession(context.TODO(), newConformanceSession("foreign", "other_username", now))
+	suite.SessionRepository.RevokeSession(context.TODO(), "revoked")
+
+	sessions, err := suite.SessionRepository.GetActiveSessions(context.TODO(), "username")
+	suite.NoError(err, "no error when listing the sessions")
+	suite.Len(sessions, 2)
+	suite.Equal("newer", sessions[0].ID, "the most recent session comes first")
+	suite.Equal("older", sessions[1].ID)
+
+	sessions, err = suite.SessionRepository.GetActiveSessions(context.TODO(), "unknown")
+	suite.NoError(err, "no error when the user has no sessions")
+	suite.NotNil(sessions, "an empty slice is returned rather than nil")
+}
+
+// Tests ExtendSession
+func (suite *sessionRepositoryConformanceSuite) TestExtendSession() {
+	now := conformanceTime()
+	suite.SessionRepository.CreateSession(context.TODO(), newConformanceSession("session", "username", now.Add(-2*time.Hour)))
+
+	suite.NoError(suite.SessionRepository.ExtendSession(context.TODO(), "session", now.Add(time.Minute), now.Add(2*time.Hour)), "no error when extending an existing session")
+	session, _ := suite.SessionRepository.GetSession(context.TODO(), "session")
+	suite.Equal(now.Add(time.Minute), session.LastUsedAt)
+	suite.Equal(now.Add(2*time.Hour), session.ExpiresAt)
+
+	sessions, _ := suite.SessionRepository.GetActiveSessions(context.TODO(), "username")
+	suite.Len(sessions, 1, "an extended session is active again")
+
+	err := suite.SessionRepository.ExtendSession(context.TODO(), "unknown", now, now)
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when extending an unknown session")
+}
+
+// Tests RevokeSession and RevokeUserSessions
+func (suite *sessionRepositoryConformanceSuite) TestRevokeSessions() {
+	now := conformanceTime()
+	suite.SessionRepository.CreateSession(context.TODO(), newConformanceSession("session_1", "username", now))
+	suite.SessionRepository.CreateSession(context.TODO(), newConformanceSession("session_2", "username", now))
+	suite.SessionRepository.CreateSession(context.TODO(), newConformanceSession("foreign", "other_username", now))
+
+	suite.NoError(suite.SessionRepository.RevokeSession(context.TODO(), "session_1"), "no error when revoking an existing session")
+	session, _ := suite.SessionRepository.GetSession(context.TODO(), "session_1")
+	suite.True(session.Revoked)
+
+	err := suite.SessionRepository.RevokeSession(context.TODO(), "unknown")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when revoking an unknown session")
+
+	suite.NoError(suite.SessionRepository.RevokeUserSessions(context.TODO(), "username"), "no error when revoking the sessions of a user")
+	session, _ = suite.SessionRepository.GetSession(context.TODO(), "session_2")
+	suite.True(session.Revoked)
+	session, _ = suite.SessionRepository.GetSession(context.TODO(), "foreign")
+	suite.False(session.Revoked, "the sessions of other users are kept")
+}
+
+func newConformanceResetToken(hash string, username string) domain.PasswordResetToken {
+	now := conformanceTime()
+	return domain.PasswordResetToken{TokenHash: hash, Username: username, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+}
+
+// Tests that UseResetToken only matches a token once, concurrently or not
+func (suite *passwordResetTokenRepositoryConformanceSuite) TestUseResetToken() {
+	token := newConformanceResetToken("hash", "username")
+	suite.NoError(suite.ResetTokenRepository.CreateResetToken(context.TODO(), token), "no error when creating a token")
+
+	used, err := suite.ResetTokenRepository.UseResetToken(context.TODO(), "hash")
+	suite.NoError(err, "no error when using an unused token")
+	token.Used = true
+	suite.Equal(token, used, "the used token is returned")
+
+	_, err = suite.ResetTokenRepository.UseResetToken(context.TODO(), "hash")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when using a token twice")
+	_, err = suite.ResetTokenRepository.UseResetToken(context.TODO(), "unknown")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when using an unknown token")
+
+	suite.ResetTokenRepository.CreateResetToken(context.TODO(), newConformanceResetToken("hash_concurrent", "username"))
+	succeeded := runConcurrently(10, func(i int) domain.CodedError {
+		_, err := suite.ResetTokenRepository.UseResetToken(context.TODO(), "hash_concurrent")
+		return err
+	})
+	suite.Equal(1, succeeded, "a token is only used once")
+}
+
+// Tests that InvalidateUserResetTokens only invalidates the tokens of the user
+func (suite *passwordResetTokenRepositoryConformanceSuite) TestInvalidateUserResetTokens() {
+	suite.ResetTokenRepository.CreateResetToken(context.TODO(), newConformanceResetToken("hash_1", "username"))
+	suite.ResetTokenRepository.CreateResetToken(context.TODO(), newConformanceResetToken("hash_2", "username"))
+	suite.ResetTokenRepository.CreateResetToken(context.TODO(), newConformanceResetToken("hash_3", "other_username"))
+
+	suite.NoError(suite.ResetTokenRepository.InvalidateUserResetTokens(context.TODO(), "username"), "no error when invalidating the tokens of a user")
+	for _, hash := range []string{"hash_1", "hash_2"} {
+		_, err := suite.ResetTokenRepository.UseResetToken(context.TODO(), hash)
+		suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when using the invalidated token %v", hash)
+	}
+
+	_, err := suite.ResetTokenRepository.UseResetToken(context.TODO(), "hash_3")
+	suite.NoError(err, "no error when using a token of another user")
+}
+
+func newConformanceVerificationToken(hash string, username string) domain.EmailVerificationToken {
+	now := conformanceTime()
+	return domain.EmailVerificationToken{TokenHash: hash, Username: username, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+}
+
+// Tests that UseVerificationToken only matches a token once, concurrently or not
+func (suite *emailVerificationTokenRepositoryConformanceSuite) TestUseVerificationToken() {
+	token := newConformanceVerificationToken("hash", "username")
+	suite.NoError(suite.VerificationRepository.CreateVerificationToken(context.TODO(), token), "no error when creating a token")
+
+	used, err := suite.VerificationRepository.UseVerificationToken(context.TODO(), "hash")
+	suite.NoError(err, "no error when using an unused token")
+	token.Used = true
+	suite.Equal(token, used, "the used token is returned")
+
+	_, err = suite.VerificationRepository.UseVerificationToken(context.TODO(), "hash")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when using a token twice")
+	_, err = suite.VerificationRepository.UseVerificationToken(context.TODO(), "unknown")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when using an unknown token")
+
+	suite.VerificationRepository.CreateVerificationToken(context.TODO(), newConformanceVerificationToken("hash_concurrent", "username"))
+	succeeded := runConcurrently(10, func(i int) domain.CodedError {
+		_, err := suite.VerificationRepository.UseVerificationToken(context.TODO(), "hash_concurrent")
+		return err
+	})
+	suite.Equal(1, succeeded, "a token is only used once")
+}
+
+// Tests that InvalidateUserVerificationTokens only invalidates the tokens of the user
+func (suite *emailVerificationTokenRepositoryConformanceSuite) TestInvalidateUserVerificationTokens() {
+	suite.VerificationRepository.CreateVerificationToken(context.TODO(), newConformanceVerificationToken("hash_1", "username"))
+	suite.VerificationRepository.CreateVerificationToken(context.TODO(), newConformanceVerificationToken("hash_2", "username"))
+	suite.VerificationRepository.CreateVerificationToken(context.TODO(), newConformanceVerificationToken("hash_3", "other_username"))
+
+	suite.NoError(suite.VerificationRepository.InvalidateUserVerificationTokens(context.TODO(), "username"), "no error when invalidating the tokens of a user")
+	for _, hash := range []string{"hash_1", "hash_2"} {
+		_, err := suite.VerificationRepository.UseVerificationToken(context.TODO(), hash)
+		suite.Equal(domain.ERR_NOT_FOUND, err.GetCode(), "error when using the invalidated token %v", hash)
+	}
+
+	_, err := suite.VerificationRepository.UseVerificationToken(context.TODO(), "hash_3")
+	suite.NoError(err, "no error when using a token of another user")
+}
+
+/*
+connects to the test mongoDB database configured in `.env`. The tests are
+skipped when no database is configured or reachable.
+*/
+func connectConformanceMongo(t *testing.T) *mongo.Database {
+	viper.SetConfigFile("../.env")
+	viper.ReadInConfig()
+	if viper.GetString("DB_ADDRESS") == "" || viper.GetString("TEST_DB_NAME") == "" {
+		t.Skip("DB_ADDRESS and TEST_DB_NAME are not set")
+	}
+
+	c, cancel := context.WithTimeout(context.TODO(), 2*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(c, options.Client().ApplyURI(viper.GetString("DB_ADDRESS")))
+	if err == nil {
+		err = client.Ping(c, nil)
+	}
+	if err != nil {
+		t.Skipf("mongoDB is not reachable: %v", err.Error())
+	}
+
+	t.Cleanup(func() { client.Disconnect(context.TODO()) })
+	return client.Database(viper.GetString("TEST_DB_NAME"))
+}
+
+/* creates an empty SQLite database that is closed when the test ends */
+func newConformanceSQLiteDatabase(t *testing.T) *sql.DB {
+	db, err := SetupSQLiteDatabase()
+	if err != nil {
+		t.Fatalf("Error: %v", err.Error())
+	}
+
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestRepositoryConformance(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		suite.Run(t, &taskRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.TaskRepositoryInterface {
+			return repository.NewInMemoryTaskRepository()
+		}})
+		suite.Run(t, &userRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.UserRepositoryInterface {
+			return repository.NewInMemoryUserRepository()
+		}})
+		suite.Run(t, &loginAttemptRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.LoginAttemptRepositoryInterface {
+			return repository.NewInMemoryLoginAttemptRepository()
+		}})
+		suite.Run(t, &mfaRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.MFARepositoryInterface {
+			return repository.NewInMemoryMFARepository()
+		}})
+		suite.Run(t, &mfaChallengeRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.MFAChallengeRepositoryInterface {
+			return repository.NewInMemoryMFAChallengeRepository()
+		}})
+		suite.Run(t, &apiKeyRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.APIKeyRepositoryInterface {
+			return repository.NewInMemoryAPIKeyRepository()
+		}})
+		suite.Run(t, &roleRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.RoleRepositoryInterface {
+			return repository.NewInMemoryRoleRepository()
+		}})
+		suite.Run(t, &refreshTokenRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.RefreshTokenRepositoryInterface {
+			return repository.NewInMemoryRefreshTokenRepository()
+		}})
+		suite.Run(t, &sessionRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.SessionRepositoryInterface {
+			return repository.NewInMemorySessionRepository()
+		}})
+		suite.Run(t, &passwordResetTokenRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.PasswordResetTokenRepositoryInterface {
+			return repository.NewInMemoryPasswordResetTokenRepository()
+		}})
+		suite.Run(t, &emailVerificationTokenRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.EmailVerificationTokenRepositoryInterface {
+			return repository.NewInMemoryEmailVerificationTokenRepository()
+		}})
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		suite.Run(t, &taskRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.TaskRepositoryInterface {
+			return &repository.SQLTaskRepository{DB: newConformanceSQLiteDatabase(t), Dialect: repository.DialectSQLite}
+		}})
+		suite.Run(t, &userRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.UserRepositoryInterface {
+			return &repository.SQLUserRepository{DB: newConformanceSQLiteDatabase(t), Dialect: repository.DialectSQLite}
+		}})
+		suite.Run(t, &loginAttemptRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.LoginAttemptRepositoryInterface {
+			return &repository.SQLLoginAttemptRepository{DB: newConformanceSQLiteDatabase(t), Dialect: repository.DialectSQLite}
+		}})
+		suite.Run(t, &mfaRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.MFARepositoryInterface {
+			return &repository.SQLMFARepository{DB: newConformanceSQLiteDatabase(t), Dialect: repository.DialectSQLite}
+		}})
+		suite.Run(t, &mfaChallengeRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.MFAChallengeRepositoryInterface {
+			return &repository.SQLMFAChallengeRepository{DB: newConformanceSQLiteDatabase(t), Dialect: repository.DialectSQLite}
+		}})
+		suite.Run(t, &apiKeyRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.APIKeyRepositoryInterface {
+			return &repository.SQLAPIKeyRepository{DB: newConformanceSQLiteDatabase(t), Dialect: repository.DialectSQLite}
+		}})
+		suite.Run(t, &roleRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.RoleRepositoryInterface {
+			return &repository.SQLRoleRepository{DB: newConformanceSQLiteDatabase(t), Dialect: repository.DialectSQLite}
+		}})
+		suite.Run(t, &refreshTokenRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.RefreshTokenRepositoryInterface {
+			return &repository.SQLRefreshTokenRepository{DB: newConformanceSQLiteDatabase(t), Dialect: repository.DialectSQLite}
+		}})
+		suite.Run(t, &sessionRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.SessionRepositoryInterface {
+			return &repository.SQLSessionRepository{DB: newConformanceSQLiteDatabase(t), Dialect: repository.DialectSQLite}
+		}})
+		suite.Run(t, &passwordResetTokenRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.PasswordResetTokenRepositoryInterface {
+			return &repository.SQLPasswordResetTokenRepository{DB: newConformanceSQLiteDatabase(t), Dialect: repository.DialectSQLite}
+		}})
+		suite.Run(t, &emailVerificationTokenRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.EmailVerificationTokenRepositoryInterface {
+			return &repository.SQLEmailVerificationTokenRepository{DB: newConformanceSQLiteDatabase(t), Dialect: repository.DialectSQLite}
+		}})
+	})
+
+	t.Run("mongo", func(t *testing.T) {
+		db := connectConformanceMongo(t)
+		suite.Run(t, &taskRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.TaskRepositoryInterface {
+			collection := db.Collection("conformance_tasks")
+			collection.DeleteMany(context.TODO(), bson.D{{}})
+			SetupTaskCollection(collection)
+			return &repository.TaskRepository{Collection: collection}
+		}})
+		suite.Run(t, &userRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.UserRepositoryInterface {
+			collection := db.Collection("conformance_users")
+			collection.DeleteMany(context.TODO(), bson.D{{}})
+			SetupUserCollection(collection)
+			return &repository.UserRepository{Collection: collection}
+		}})
+		suite.Run(t, &loginAttemptRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.LoginAttemptRepositoryInterface {
+			collection := db.Collection("conformance_login_attempts")
+			collection.DeleteMany(context.TODO(), bson.D{{}})
+			SetupLoginAttemptCollection(collection)
+			return &repository.LoginAttemptRepository{Collection: collection}
+		}})
+		suite.Run(t, &mfaRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.MFARepositoryInterface {
+			collection := db.Collection("conformance_mfa_enrollments")
+			collection.DeleteMany(context.TODO(), bson.D{{}})
+			SetupMFACollection(collection)
+			return &repository.MFARepository{Collection: collection}
+		}})
+		suite.Run(t, &mfaChallengeRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.MFAChallengeRepositoryInterface {
+			collection := db.Collection("conformance_mfa_challenges")
+			collection.DeleteMany(context.TODO(), bson.D{{}})
+			SetupMFAChallengeCollection(collection)
+			return &repository.MFAChallengeRepository{Collection: collection}
+		}})
+		suite.Run(t, &apiKeyRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.APIKeyRepositoryInterface {
+			collection := db.Collection("conformance_api_keys")
+			collection.DeleteMany(context.TODO(), bson.D{{}})
+			SetupAPIKeyCollection(collection)
+			return &repository.APIKeyRepository{Collection: collection}
+		}})
+		suite.Run(t, &roleRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.RoleRepositoryInterface {
+			collection := db.Collection("conformance_roles")
+			collection.DeleteMany(context.TODO(), bson.D{{}})
+			SetupRoleCollection(collection)
+			return &repository.RoleRepository{Collection: collection}
+		}})
+		suite.Run(t, &refreshTokenRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.RefreshTokenRepositoryInterface {
+			collection := db.Collection("conformance_refresh_tokens")
+			collection.DeleteMany(context.TODO(), bson.D{{}})
+			return &repository.RefreshTokenRepository{Collection: collection}
+		}})
+		suite.Run(t, &sessionRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.SessionRepositoryInterface {
+			collection := db.Collection("conformance_sessions")
+			collection.DeleteMany(context.TODO(), bson.D{{}})
+			return &repository.SessionRepository{Collection: collection}
+		}})
+		suite.Run(t, &passwordResetTokenRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.PasswordResetTokenRepositoryInterface {
+			collection := db.Collection("conformance_password_reset_tokens")
+			collection.DeleteMany(context.TODO(), bson.D{{}})
+			return &repository.PasswordResetTokenRepository{Collection: collection}
+		}})
+		suite.Run(t, &emailVerificationTokenRepositoryConformanceSuite{NewRepository: func(t *testing.T) domain.EmailVerificationTokenRepositoryInterface {
+			collection := db.Collection("conformance_email_verification_tokens")
+			collection.DeleteMany(context.TODO(), bson.D{{}})
+			return &repository.EmailVerificationTokenRepository{Collection: collection}
+		}})
+	})
+}
@@ -0,0 +1,215 @@
+package tests
+
+import (
+	"context"
+	domain "task_manager_api/Domain"
+	mocks "task_manager_api/Mocks"
+	usecase "task_manager_api/Usecase"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type roleUsecaseSuite struct {
+	suite.Suite
+	repository     *mocks.RoleRepositoryInterface
+	userRepository *mocks.UserRepositoryInterface
+	usecase        usecase.RoleUsecase
+}
+
+func (suite *roleUsecaseSuite) SetupTest() {
+	suite.repository = new(mocks.RoleRepositoryInterface)
+	suite.userRepository = new(mocks.UserRepositoryInterface)
+	suite.usecase = usecase.RoleUsecase{RoleRepository: suite.repository, UserRepository: suite.userRepository, Timeout: 2}
+}
+
+// expects the roles that have not been registered before, including the built-in roles, not to have been saved
+func (suite *roleUsecaseSuite) expectUnsavedRoles() {
+	suite.repository.On("GetRole", mock.Anything, mock.AnythingOfType("string")).Return(domain.Role{}, domain.UserError{Message: "Role not found", Code: domain.ERR_NOT_FOUND})
+}
+
+func (suite *roleUsecaseSuite) TestGetRoles() {
+	suite.repository.On("GetRole", mock.Anything, domain.RoleAdmin).Return(domain.Role{Name: domain.RoleAdmin, Permissions: []string{}, MFARequired: true}, nil)
+	suite.expectUnsavedRoles()
+	suite.repository.On("GetRoles", mock.Anything).Return([]domain.Role{{Name: "viewer", Permissions: []string{domain.PermissionTaskRead}}, {Name: "manager", Permissions: []string{domain.PermissionTaskReadAny}}}, nil)
+	roles, err := suite.usecase.GetRoles(context.TODO())
+
+	suite.NoError(err, "no error when the roles are listed")
+	suite.Equal([]domain.Role{
+		{Name: domain.RoleAdmin, Permissions: domain.Permissions, MFARequired: true, BuiltIn: true},
+		{Name: "manager", Permissions: []string{domain.PermissionTaskReadAny}},
+		{Name: domain.RoleUser, Permissions: domain.DefaultUserPermissions, BuiltIn: true},
+		{Name: "viewer", Permissions: []string{domain.PermissionTaskRead}},
+	}, roles, "the built-in roles are listed along with the custom roles, admins keeping every permission")
+}
+
+func (suite *roleUsecaseSuite) TestRolePermissions() {
+	suite.repository.On("GetRole", mock.Anything, "viewer").Return(domain.Role{Name: "viewer", Permissions: []string{domain.PermissionTaskRead}}, nil)
+	suite.expectUnsavedRoles()
+
+	permissions, err := suite.usecase.RolePermissions(context.TODO(), domain.RoleAdmin)
+	suite.NoError(err, "no error for the admin role")
+	suite.Equal(domain.Permissions, permissions, "admins are granted every permission")
+
+	permissions, err = suite.usecase.RolePermissions(context.TODO(), domain.RoleUser)
+	suite.NoError(err, "no error for the unchanged user role")
+	suite.Equal(domain.DefaultUserPermissions, permissions)
+
+	permissions, err = suite.usecase.RolePermissions(context.TODO(), "viewer")
+	suite.NoError(err, "no error for a custom role")
+	suite.Equal([]string{domain.PermissionTaskRead}, permissions)
+
+	_, err = suite.usecase.RolePermissions(context.TODO(), "unknown")
+	suite.Error(err, "error when the role does not exist")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+}
+
+func (suite *roleUsecaseSuite) TestSaveRole_Positive() {
+	suite.repository.On("GetRole", mock.Anything, domain.RoleUser).Return(domain.Role{Name: domain.RoleUser, Permissions: domain.DefaultUserPermissions, MFARequired: true}, nil)
+	suite.expectUnsavedRoles()
+	expected := domain.Role{Name: "manager", Permissions: []string{domain.PermissionTaskReadAny, domain.PermissionUserRead}}
+	suite.repository.On("SaveRole", mock.Anything, expected).Return(nil)
+	role, err := suite.usecase.SaveRole(context.TODO(), adminSubject, domain.Role{Name: " Manager ", Permissions: []string{"user.read", " TASK.READ.ANY ", "user.read"}})
+
+	suite.NoError(err, "no error when the role is valid")
+	suite.Equal(expected, role, "the name and permissions are normalized")
+
+	suite.repository.On("SaveRole", mock.Anything, domain.Role{Name: domain.RoleUser, Permissions: []string{domain.PermissionTaskRead}, MFARequired: true}).Return(nil)
+	role, err = suite.usecase.SaveRole(context.TODO(), adminSubject, domain.Role{Name: domain.RoleUser, Permissions: []string{domain.PermissionTaskRead}})
+	suite.NoError(err, "no error when the permissions of the user role are changed")
+	suite.True(role.BuiltIn)
+	suite.True(role.MFARequired, "the second factor requirement is kept")
+}
+
+func (suite *roleUsecaseSuite) TestSaveRole_Negative() {
+	tests := []struct {
+		role domain.Role
+		code string
+	}{
+		{domain.Role{Name: "", Permissions: []string{}}, domain.ERR_BAD_REQUEST},
+		{domain.Role{Name: "team lead", Permissions: []string{}}, domain.ERR_BAD_REQUEST},
+		{domain.Role{Name: "viewer", Permissions: []string{"task.read", "task.explode"}}, domain.ERR_BAD_REQUEST},
+		{domain.Role{Name: "Admin", Permissions: []string{"task.read"}}, domain.ERR_FORBIDDEN},
+	}
+
+	for _, test := range tests {
+		_, err := suite.usecase.SaveRole(context.TODO(), adminSubject, test.role)
+		suite.Error(err, "error when saving the role '%v'", test.role.Name)
+		suite.Equal(test.code, err.GetCode())
+	}
+
+	suite.repository.AssertNotCalled(suite.T(), "SaveRole", mock.Anything, mock.Anything)
+}
+
+func (suite *roleUsecaseSuite) TestSaveRole_Escalation() {
+	roleManager := domain.Subject{Username: "role_manager", Role: "role_manager", Permissions: []string{domain.PermissionRoleManage, domain.PermissionTaskRead, domain.PermissionTaskUpdateAny}}
+	tests := []domain.Role{
+		{Name: "role_manager", Permissions: []string{domain.PermissionRoleManage, domain.PermissionUserPromote}},
+		{Name: "viewer", Permissions: []string{domain.PermissionTaskReadAny}},
+		{Name: domain.RoleUser, Permissions: append([]string{domain.PermissionUserDelete}, domain.DefaultUserPermissions...)},
+	}
+
+	for _, role := range tests {
+		_, err := suite.usecase.SaveRole(context.TODO(), roleManager, role)
+		suite.Error(err, "error when granting permissions the subject does not hold to '%v'", role.Name)
+		suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+	}
+
+	suite.repository.AssertNotCalled(suite.T(), "SaveRole", mock.Anything, mock.Anything)
+
+	suite.repository.On("GetRole", mock.Anything, "manager").Return(domain.Role{Name: "manager", Permissions: []string{domain.PermissionTaskRead, domain.PermissionUserDelete}}, nil)
+	suite.expectUnsavedRoles()
+	_, err := suite.usecase.SaveRole(context.TODO(), roleManager, domain.Role{Name: "manager", Permissions: []string{domain.PermissionTaskRead}})
+	suite.Error(err, "error when stripping permissions the subject does not hold from an existing role")
+	suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+
+	_, err = suite.usecase.SaveRole(context.TODO(), roleManager, domain.Role{Name: domain.RoleUser, Permissions: []string{domain.PermissionTaskRead}})
+	suite.Error(err, "error when changing the user role whose permissions the subject does not all hold")
+	suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+	suite.repository.AssertNotCalled(suite.T(), "SaveRole", mock.Anything, mock.Anything)
+
+	expected := domain.Role{Name: "editor", Permissions: []string{domain.PermissionTaskRead, domain.PermissionTaskUpdate}}
+	suite.repository.On("SaveRole", mock.Anything, expected).Return(nil)
+	_, err = suite.usecase.SaveRole(context.TODO(), roleManager, expected)
+	suite.NoError(err, "no error when granting permissions held by the subject, or narrower ones")
+	suite.repository.AssertCalled(suite.T(), "SaveRole", mock.Anything, expected)
+}
+
+func (suite *roleUsecaseSuite) TestSetRoleMFA() {
+	suite.repository.On("GetRole", mock.Anything, "viewer").Return(domain.Role{Name: "viewer", Permissions: []string{domain.PermissionTaskRead}}, nil)
+	suite.expectUnsavedRoles()
+	suite.repository.On("SaveRole", mock.Anything, mock.AnythingOfType("Role")).Return(nil)
+
+	role, err := suite.usecase.SetRoleMFA(context.TODO(), adminSubject, " Admin ", true)
+	suite.NoError(err, "no error when requiring a second factor for the admins")
+	suite.Equal(domain.Role{Name: domain.RoleAdmin, Permissions: domain.Permissions, MFARequired: true, BuiltIn: true}, role)
+	suite.repository.AssertCalled(suite.T(), "SaveRole", mock.Anything, domain.Role{Name: domain.RoleAdmin, Permissions: domain.Permissions, MFARequired: true})
+
+	_, err = suite.usecase.SetRoleMFA(context.TODO(), adminSubject, "viewer", true)
+	suite.NoError(err, "no error when requiring a second factor for a custom role")
+	suite.repository.AssertCalled(suite.T(), "SaveRole", mock.Anything, domain.Role{Name: "viewer", Permissions: []string{domain.PermissionTaskRead}, MFARequired: true})
+
+	_, err = suite.usecase.SetRoleMFA(context.TODO(), adminSubject, "unknown", true)
+	suite.Error(err, "error when the role does not exist")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+
+	roleManager := domain.Subject{Username: "role_manager", Role: "role_manager", Permissions: []string{domain.PermissionRoleManage}}
+	_, err = suite.usecase.SetRoleMFA(context.TODO(), roleManager, domain.RoleAdmin, false)
+	suite.Error(err, "error when the subject does not hold the permissions of the role")
+	suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+	suite.repository.AssertNumberOfCalls(suite.T(), "SaveRole", 2)
+}
+
+func (suite *roleUsecaseSuite) TestDeleteRole() {
+	query := func(role string) domain.UserQuery {
+		return domain.UserQuery{Role: role, SortBy: "username", SortOrder: domain.SortAscending, Limit: 1}
+	}
+	suite.userRepository.On("GetUsers", mock.Anything, query("viewer")).Return([]domain.User{}, int64(0), nil)
+	suite.userRepository.On("GetUsers", mock.Anything, query("manager")).Return([]domain.User{{Username: "manager_user"}}, int64(2), nil)
+	suite.repository.On("GetRole", mock.Anything, "viewer").Return(domain.Role{Name: "viewer", Permissions: []string{domain.PermissionTaskRead}}, nil)
+	suite.repository.On("GetRole", mock.Anything, "manager").Return(domain.Role{Name: "manager", Permissions: []string{domain.PermissionTaskReadAny}}, nil)
+	suite.expectUnsavedRoles()
+	suite.repository.On("DeleteRole", mock.Anything, "viewer").Return(nil)
+
+	err := suite.usecase.DeleteRole(context.TODO(), adminSubject, " Viewer ")
+	suite.NoError(err, "no error when the role is not assigned to any user")
+	suite.repository.AssertCalled(suite.T(), "DeleteRole", mock.Anything, "viewer")
+
+	err = suite.usecase.DeleteRole(context.TODO(), adminSubject, "manager")
+	suite.Error(err, "error when the role is still assigned to users")
+	suite.Equal(domain.ERR_CONFLICT, err.GetCode())
+
+	for _, role := range []string{domain.RoleAdmin, domain.RoleUser} {
+		err = suite.usecase.DeleteRole(context.TODO(), adminSubject, role)
+		suite.Error(err, "error when deleting the built-in role '%v'", role)
+		suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+	}
+
+	err = suite.usecase.DeleteRole(context.TODO(), adminSubject, "unknown")
+	suite.Error(err, "error when the role does not exist")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+
+	suite.repository.AssertNumberOfCalls(suite.T(), "DeleteRole", 1)
+}
+
+// Tests that roles granting permissions the subject does not hold can not be deleted
+func (suite *roleUsecaseSuite) TestDeleteRole_Escalation() {
+	suite.repository.On("GetRole", mock.Anything, "manager").Return(domain.Role{Name: "manager", Permissions: []string{domain.PermissionTaskReadAny, domain.PermissionUserDelete}}, nil)
+	suite.repository.On("GetRole", mock.Anything, "viewer").Return(domain.Role{Name: "viewer", Permissions: []string{domain.PermissionTaskRead}}, nil)
+	suite.userRepository.On("GetUsers", mock.Anything, mock.AnythingOfType("UserQuery")).Return([]domain.User{}, int64(0), nil)
+	suite.repository.On("DeleteRole", mock.Anything, "viewer").Return(nil)
+	roleManager := domain.Subject{Username: "role_manager", Role: "role_manager", Permissions: []string{domain.PermissionRoleManage, domain.PermissionTaskRead, domain.PermissionTaskReadAny}}
+
+	err := suite.usecase.DeleteRole(context.TODO(), roleManager, "manager")
+	suite.Error(err, "error when the role grants permissions the subject does not hold")
+	suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+	suite.repository.AssertNotCalled(suite.T(), "DeleteRole", mock.Anything, "manager")
+
+	err = suite.usecase.DeleteRole(context.TODO(), roleManager, "viewer")
+	suite.NoError(err, "no error when the subject holds every permission of the role")
+}
+
+func TestRoleUsecase(t *testing.T) {
+	suite.Run(t, new(roleUsecaseSuite))
+}
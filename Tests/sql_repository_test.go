@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	domain "task_manager_api/Domain"
+	repository "task_manager_api/Repository"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type sqlRepositorySuite struct {
+	suite.Suite
+	db *sql.DB
+}
+
+func (suite *sqlRepositorySuite) SetupTest() {
+	db, err := SetupSQLiteDatabase()
+	if err != nil {
+		log.Fatalf("Error: %v", err.Error())
+	}
+
+	suite.db = db
+}
+
+func (suite *sqlRepositorySuite) TearDownTest() {
+	suite.db.Close()
+}
+
+// Tests that the migrations are only applied once
+func (suite *sqlRepositorySuite) TestMigrations() {
+	applied, err := repository.RunSQLMigrations(context.TODO(), suite.db, repository.DialectSQLite)
+	suite.NoError(err, "no error when the schema is up to date")
+	suite.Equal(0, applied, "no migrations are applied twice")
+
+	var version int64
+	suite.db.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version)
+	suite.Equal(int64(11), version, "the version of every migration is recorded")
+}
+
+// Tests that the users created before the email verification count as verified
+func (suite *sqlRepositorySuite) TestMigrations_LegacyUsersVerified() {
+	_, err := suite.db.Exec("INSERT INTO users (username, email, password, role) VALUES ('username12', 'mailer@mail.com', 'password12', 'user')")
+	suite.NoError(err, "no error when inserting a user without the new columns")
+
+	userRepository := &repository.SQLUserRepository{DB: suite.db, Dialect: repository.DialectSQLite}
+	storedUser, err := userRepository.GetByUsername(context.TODO(), "username12")
+	suite.NoError(err, "no error when fetching a legacy user")
+	suite.True(storedUser.Verified)
+	suite.True(storedUser.CreatedAt.IsZero())
+	suite.False(storedUser.Deactivated, "legacy users stay active")
+}
+
+// Tests that CheckDuplicate only accepts the columns of the users table
+func (suite *sqlRepositorySuite) TestCheckDuplicate_UnknownField() {
+	userRepository := &repository.SQLUserRepository{DB: suite.db, Dialect: repository.DialectSQLite}
+	userRepository.CreateUser(context.TODO(), domain.User{Username: "username12", Email: "mailer@mail.com", Password: "password12", Role: domain.RoleUser})
+
+	err := userRepository.CheckDuplicate(context.TODO(), "1=1 OR email", "mailer@mail.com", "error")
+	suite.Error(err, "error when checking an unknown field")
+	suite.Equal(domain.ERR_INTERNAL_SERVER, err.GetCode())
+}
+
+func TestSQLRepositorySuite(t *testing.T) {
+	suite.Run(t, new(sqlRepositorySuite))
+}
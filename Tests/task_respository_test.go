@@ -2,6 +2,7 @@ package tests
 
 import (
 	"context"
+	"fmt"
 	"log"
 	domain "task_manager_api/Domain"
 	repository "task_manager_api/Repository"
@@ -15,6 +16,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// the normalized query that the usecase sends when no parameters are provided
+var defaultTaskQuery = domain.TaskQuery{SortBy: "id", SortOrder: domain.SortAscending, Limit: domain.DefaultPageLimit}
+
 type taskRespositorySuite struct {
 	suite.Suite
 	TaskRepository *repository.TaskRepository
@@ -42,9 +46,10 @@ func (suite *taskRespositorySuite) SetupTest() {
 
 // Tests GetAllTasks without adding any
 func (suite *taskRespositorySuite) TestGetTasks_Empty() {
-	tasks, err := suite.TaskRepository.GetAllTasks(context.TODO())
+	tasks, total, err := suite.TaskRepository.GetAllTasks(context.TODO(), defaultTaskQuery)
 	suite.NoError(err, "no error when fetching")
 	suite.Equal(0, len(tasks), "lenght of slice returned is 0 when no objects are added")
+	suite.Equal(int64(0), total, "total is 0 when no objects are added")
 }
 
 // Tests GetAllTasks after adding two tasks
@@ -63,9 +68,80 @@ func (suite *taskRespositorySuite) TestGetTasks_WithAdds() {
 	err = suite.TaskRepository.AddTask(context.TODO(), task)
 	suite.NoError(err, "no error when creating")
 
-	tasks, err := suite.TaskRepository.GetAllTasks(context.TODO())
+	tasks, total, err := suite.TaskRepository.GetAllTasks(context.TODO(), defaultTaskQuery)
 	suite.NoError(err, "no error when creating")
 	suite.Equal(2, len(tasks), "lenght of slice returned is 0 when no objects are added")
+	suite.Equal(int64(2), total, "total matches the number of added tasks")
+}
+
+// Tests the filters, sort order and pagination of GetAllTasks
+func (suite *taskRespositorySuite) TestGetTasks_Query() {
+	now := time.Now()
+	for i, status := range []string{"pending", "completed", "pending"} {
+		task := domain.Task{
+			ID:          fmt.Sprint(i + 1),
+			Title:       fmt.Sprintf("Title %v", i+1),
+			Description: "description",
+			DueDate:     now.Add(time.Duration(i) * time.Hour),
+			Status:      status,
+		}
+
+		err := suite.TaskRepository.AddTask(context.TODO(), task)
+		suite.NoError(err, "no error when creating")
+	}
+
+	query := defaultTaskQuery
+	query.Status = "pending"
+	tasks, total, err := suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.NoError(err, "no error when filtering by status")
+	suite.Equal(int64(2), total, "only tasks with the status are counted")
+	suite.Equal(2, len(tasks), "only tasks with the status are returned")
+
+	query = defaultTaskQuery
+	query.Title = "title 2"
+	tasks, _, err = suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.NoError(err, "no error when filtering by title")
+	suite.Equal(1, len(tasks), "title filter is a case-insensitive substring match")
+
+	query = defaultTaskQuery
+	query.DueAfter = now.Add(30 * time.Minute)
+	tasks, _, err = suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.NoError(err, "no error when filtering by due date")
+	suite.Equal(2, len(tasks), "only tasks due after the provided date are returned")
+
+	query = defaultTaskQuery
+	query.SortBy = "due_date"
+	query.SortOrder = domain.SortDescending
+	query.Limit = 2
+	query.Offset = 1
+	tasks, total, err = suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.NoError(err, "no error when paginating")
+	suite.Equal(int64(3), total, "total ignores the limit and offset")
+	suite.Equal(2, len(tasks), "page size is limited")
+	suite.Equal("2", tasks[0].ID, "tasks are sorted and skipped correctly")
+	suite.Equal("1", tasks[1].ID, "tasks are sorted and skipped correctly")
+}
+
+// Tests that VisibleTo restricts the results to owned and assigned tasks
+func (suite *taskRespositorySuite) TestGetTasks_Visibility() {
+	tasks := []domain.Task{
+		{ID: "1", Title: "owned", Owner: "user1"},
+		{ID: "2", Title: "assigned", Owner: "user2", Assignee: "user1"},
+		{ID: "3", Title: "other", Owner: "user2"},
+	}
+
+	for _, task := range tasks {
+		err := suite.TaskRepository.AddTask(context.TODO(), task)
+		suite.NoError(err, "no error when creating")
+	}
+
+	query := defaultTaskQuery
+	query.VisibleTo = "user1"
+	visibleTasks, total, err := suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.NoError(err, "no error when fetching")
+	suite.Equal(int64(2), total, "only owned and assigned tasks are counted")
+	suite.Equal("1", visibleTasks[0].ID, "owned task is returned")
+	suite.Equal("2", visibleTasks[1].ID, "assigned task is returned")
 }
 
 // Test GetTaskById after adding a task
@@ -113,6 +189,7 @@ func (suite *taskRespositorySuite) TestAddTask_Negative() {
 	suite.NoError(err, "no error when creating")
 	err = suite.TaskRepository.AddTask(context.TODO(), task)
 	suite.Error(err, "error when creating an object with the same id")
+	suite.Equal(domain.ERR_CONFLICT, err.GetCode(), "duplicate ids are reported as conflicts")
 }
 
 // Tests UpdateTask
@@ -134,7 +211,7 @@ func (suite *taskRespositorySuite) TestUpdateTask() {
 	err := suite.TaskRepository.AddTask(context.TODO(), task)
 	suite.NoError(err, "no error when creating")
 
-	updatedTask, err := suite.TaskRepository.UpdateTask(context.TODO(), task.ID, taskUpdates)
+	updatedTask, err := suite.TaskRepository.UpdateTask(context.TODO(), task.ID, taskUpdates, 0)
 	suite.NoError(err, "no error when updating")
 
 	suite.Equal(taskUpdates.Title, updatedTask.Title, "title updated successfully")
@@ -142,6 +219,60 @@ func (suite *taskRespositorySuite) TestUpdateTask() {
 	suite.Equal(taskUpdates.Status, updatedTask.Status, "status updated successfully")
 }
 
+// Tests that PatchTask only modifies the provided fields and removes the nil ones
+func (suite *taskRespositorySuite) TestPatchTask() {
+	task := domain.Task{
+		ID:          "1",
+		Title:       "title",
+		Description: "description 1",
+		DueDate:     time.Now(),
+		Status:      "pending",
+	}
+
+	err := suite.TaskRepository.AddTask(context.TODO(), task)
+	suite.NoError(err, "no error when creating")
+
+	patchedTask, err := suite.TaskRepository.PatchTask(context.TODO(), task.ID, domain.TaskPatch{"title": "changed title", "description": nil, "due_date": nil}, 0)
+	suite.NoError(err, "no error when patching")
+	suite.Equal("changed title", patchedTask.Title, "title patched successfully")
+	suite.Equal("", patchedTask.Description, "description removed successfully")
+	suite.True(patchedTask.DueDate.IsZero(), "due date removed successfully")
+	suite.Equal(task.Status, patchedTask.Status, "status left untouched")
+}
+
+// Tests that UpdateTask and DeleteTask honour the provided version
+func (suite *taskRespositorySuite) TestVersionedWrites() {
+	task := domain.Task{
+		ID:      "1",
+		Title:   "title",
+		Status:  "pending",
+		Version: 1,
+	}
+
+	err := suite.TaskRepository.AddTask(context.TODO(), task)
+	suite.NoError(err, "no error when creating")
+
+	updatedTask, err := suite.TaskRepository.UpdateTask(context.TODO(), task.ID, domain.Task{Title: "changed title"}, 1)
+	suite.NoError(err, "no error when the version matches")
+	suite.Equal(int64(2), updatedTask.Version, "version is incremented")
+	suite.Equal("changed title", updatedTask.Title, "updated task is returned")
+
+	_, err = suite.TaskRepository.UpdateTask(context.TODO(), task.ID, domain.Task{Title: "stale title"}, 1)
+	suite.Error(err, "error when the version is stale")
+	suite.Equal(domain.ERR_PRECONDITION_FAILED, err.GetCode())
+
+	err = suite.TaskRepository.DeleteTask(context.TODO(), task.ID, 1)
+	suite.Error(err, "error when deleting with a stale version")
+	suite.Equal(domain.ERR_PRECONDITION_FAILED, err.GetCode())
+
+	_, err = suite.TaskRepository.UpdateTask(context.TODO(), "missing", domain.Task{Title: "title"}, 1)
+	suite.Error(err, "error when the task does not exist")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+
+	err = suite.TaskRepository.DeleteTask(context.TODO(), task.ID, 2)
+	suite.NoError(err, "no error when deleting with the current version")
+}
+
 // test DeleteTask
 func (suite *taskRespositorySuite) TestDeleteTask() {
 	task := domain.Task{
@@ -155,12 +286,103 @@ func (suite *taskRespositorySuite) TestDeleteTask() {
 	err := suite.TaskRepository.AddTask(context.TODO(), task)
 	suite.NoError(err, "no error when creating")
 
-	err = suite.TaskRepository.DeleteTask(context.TODO(), task.ID)
+	err = suite.TaskRepository.DeleteTask(context.TODO(), task.ID, 0)
 	suite.NoError(err, "no error when deleting")
 	_, err = suite.TaskRepository.GetTaskByID(context.TODO(), task.ID)
 	suite.Error(err, "deleted task not found")
 }
 
+// Tests that a task created without a due date is stored without the field, like one whose due date was cleared
+func (suite *taskRespositorySuite) TestAddTask_WithoutDueDate() {
+	suite.NoError(suite.TaskRepository.AddTask(context.TODO(), domain.Task{ID: "undated", Title: "title", Status: "pending", Version: 1}))
+
+	count, err := suite.collection.CountDocuments(context.TODO(), bson.D{{Key: "id", Value: "undated"}, {Key: "due_date", Value: bson.D{{Key: "$exists", Value: false}}}})
+	suite.NoError(err, "no error when counting the tasks")
+	suite.Equal(int64(1), count, "the zero due date is not stored")
+
+	task, _ := suite.TaskRepository.GetTaskByID(context.TODO(), "undated")
+	suite.True(task.DueDate.IsZero(), "the missing field is read as the zero time")
+}
+
+// Tests that the due dates stored as `duedate` are renamed to `due_date`
+func (suite *taskRespositorySuite) TestRenameLegacyDueDates() {
+	dueDate := time.Now().UTC().Truncate(time.Millisecond)
+	_, insertErr := suite.collection.InsertOne(context.TODO(), bson.D{{Key: "id", Value: "legacy"}, {Key: "title", Value: "title"}, {Key: "duedate", Value: dueDate}})
+	suite.NoError(insertErr, "no error when inserting a task with the legacy field")
+	suite.TaskRepository.AddTask(context.TODO(), domain.Task{ID: "current", Title: "title", DueDate: dueDate.Add(time.Hour)})
+
+	renamed, err := suite.TaskRepository.RenameLegacyDueDates(context.TODO())
+	suite.NoError(err, "no error when renaming the due dates")
+	suite.Equal(int64(1), renamed, "only the task with the legacy field is updated")
+
+	legacyTask, _ := suite.TaskRepository.GetTaskByID(context.TODO(), "legacy")
+	suite.True(dueDate.Equal(legacyTask.DueDate), "the due date is kept")
+	legacyCount, _ := suite.collection.CountDocuments(context.TODO(), bson.D{{Key: "duedate", Value: bson.D{{Key: "$exists", Value: true}}}})
+	suite.Equal(int64(0), legacyCount, "the legacy field is removed")
+
+	currentTask, _ := suite.TaskRepository.GetTaskByID(context.TODO(), "current")
+	suite.True(dueDate.Add(time.Hour).Equal(currentTask.DueDate), "current due dates are kept")
+
+	query := defaultTaskQuery
+	query.DueBefore = dueDate
+	tasks, _, _ := suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.Len(tasks, 1, "the renamed due dates are filtered on")
+
+	renamed, _ = suite.TaskRepository.RenameLegacyDueDates(context.TODO())
+	suite.Equal(int64(0), renamed, "running the rename again changes nothing")
+}
+
+// Tests that the tasks stored without a version get the version 1
+func (suite *taskRespositorySuite) TestBackfillVersions() {
+	_, insertErr := suite.collection.InsertOne(context.TODO(), bson.D{{Key: "id", Value: "legacy"}, {Key: "title", Value: "title"}, {Key: "status", Value: "pending"}})
+	suite.NoError(insertErr, "no error when inserting a task without a version")
+	suite.TaskRepository.AddTask(context.TODO(), domain.Task{ID: "versioned", Title: "title", Status: "pending", Version: 3})
+
+	backfilled, err := suite.TaskRepository.BackfillVersions(context.TODO())
+	suite.NoError(err, "no error when backfilling the versions")
+	suite.Equal(int64(1), backfilled, "only the task without a version is updated")
+
+	legacyTask, _ := suite.TaskRepository.GetTaskByID(context.TODO(), "legacy")
+	suite.Equal(int64(1), legacyTask.Version)
+	versionedTask, _ := suite.TaskRepository.GetTaskByID(context.TODO(), "versioned")
+	suite.Equal(int64(3), versionedTask.Version, "existing versions are kept")
+
+	_, err = suite.TaskRepository.UpdateTask(context.TODO(), "legacy", domain.Task{Title: "new title", Status: "pending"}, 1)
+	suite.NoError(err, "no error when updating a backfilled task with its version")
+
+	backfilled, _ = suite.TaskRepository.BackfillVersions(context.TODO())
+	suite.Equal(int64(0), backfilled, "running the backfill again changes nothing")
+}
+
+// Tests that the tasks stored without an owner are given to the provided user
+func (suite *taskRespositorySuite) TestBackfillOwners() {
+	_, insertErr := suite.collection.InsertOne(context.TODO(), bson.D{{Key: "id", Value: "legacy"}, {Key: "title", Value: "title"}, {Key: "version", Value: 1}})
+	suite.NoError(insertErr, "no error when inserting a task without an owner")
+	suite.TaskRepository.AddTask(context.TODO(), domain.Task{ID: "unowned", Title: "title", Version: 1})
+	suite.TaskRepository.AddTask(context.TODO(), domain.Task{ID: "owned", Title: "title", Owner: "owner", Version: 1})
+
+	backfilled, err := suite.TaskRepository.BackfillOwners(context.TODO(), "legacy_owner")
+	suite.NoError(err, "no error when backfilling the owners")
+	suite.Equal(int64(2), backfilled, "only the tasks without an owner are updated")
+
+	for _, id := range []string{"legacy", "unowned"} {
+		task, _ := suite.TaskRepository.GetTaskByID(context.TODO(), id)
+		suite.Equal("legacy_owner", task.Owner)
+		suite.Equal(int64(2), task.Version, "the version changes along with the owner")
+	}
+
+	ownedTask, _ := suite.TaskRepository.GetTaskByID(context.TODO(), "owned")
+	suite.Equal("owner", ownedTask.Owner, "existing owners are kept")
+
+	query := defaultTaskQuery
+	query.VisibleTo = "legacy_owner"
+	tasks, _, _ := suite.TaskRepository.GetAllTasks(context.TODO(), query)
+	suite.Len(tasks, 2, "the backfilled tasks are visible to their owner")
+
+	backfilled, _ = suite.TaskRepository.BackfillOwners(context.TODO(), "other_owner")
+	suite.Equal(int64(0), backfilled, "running the backfill again changes nothing")
+}
+
 func TestTaskRepositorySuite(t *testing.T) {
 	viper.SetConfigFile("../.env")
 	viper.ReadInConfig()
@@ -12,6 +12,12 @@ import (
 	"golang.org/x/net/context"
 )
 
+var (
+	adminSubject  = domain.Subject{Username: "admin_user", Role: domain.RoleAdmin, Permissions: domain.Permissions}
+	userSubject   = domain.Subject{Username: "regular_user", Role: domain.RoleUser, Permissions: domain.DefaultUserPermissions}
+	viewerSubject = domain.Subject{Username: "viewer_user", Role: "viewer", Permissions: []string{domain.PermissionTaskRead, domain.PermissionTaskReadAny}}
+)
+
 type taskUsecaseSuite struct {
 	suite.Suite
 	repository *mocks.TaskRepositoryInterface
@@ -23,6 +29,9 @@ func (suite *taskUsecaseSuite) SetupSuite() {
 	suite.usecase = usecase.TaskUsecase{
 		TaskRepository: suite.repository,
 		Timeout:        2,
+		GenerateID: func() string {
+			return "generated_id"
+		},
 	}
 }
 
@@ -32,81 +41,352 @@ func (suite *taskUsecaseSuite) SetupTest() {
 }
 
 func (suite *taskUsecaseSuite) TestGetAllTasks() {
-	suite.repository.On("GetAllTasks", mock.Anything).Return([]domain.Task{}, nil).Twice()
-	_, err := suite.usecase.GetAllTasks(context.TODO())
+	expectedQuery := domain.TaskQuery{SortBy: "id", SortOrder: domain.SortAscending, Limit: domain.DefaultPageLimit}
+	suite.repository.On("GetAllTasks", mock.Anything, expectedQuery).Return([]domain.Task{{ID: "1"}}, int64(1), nil).Twice()
+	page, err := suite.usecase.GetAllTasks(context.TODO(), adminSubject, domain.TaskQuery{VisibleTo: "someone"})
+
+	suite.NoError(err, "no error when function is called")
+	suite.Equal(int64(1), page.Total, "total is passed through from the repository")
+	suite.Equal(int64(domain.DefaultPageLimit), page.Limit, "default limit is used when none is provided")
+	suite.repository.AssertCalled(suite.T(), "GetAllTasks", mock.Anything, expectedQuery)
+}
+
+func (suite *taskUsecaseSuite) TestGetAllTasks_Visibility() {
+	expectedQuery := domain.TaskQuery{SortBy: "id", SortOrder: domain.SortAscending, Limit: domain.DefaultPageLimit, VisibleTo: userSubject.Username}
+	suite.repository.On("GetAllTasks", mock.Anything, expectedQuery).Return([]domain.Task{}, int64(0), nil).Once()
+	_, err := suite.usecase.GetAllTasks(context.TODO(), userSubject, domain.TaskQuery{})
 
 	suite.NoError(err, "no error when function is called")
-	suite.repository.AssertCalled(suite.T(), "GetAllTasks", mock.Anything)
+	suite.repository.AssertCalled(suite.T(), "GetAllTasks", mock.Anything, expectedQuery)
+}
+
+func (suite *taskUsecaseSuite) TestGetAllTasks_InvalidQuery() {
+	invalidQueries := []domain.TaskQuery{
+		{Limit: domain.MaxPageLimit + 1},
+		{Limit: -1},
+		{Offset: -1},
+		{SortBy: "password"},
+		{SortOrder: "sideways"},
+		{DueAfter: time.Now(), DueBefore: time.Now().Add(-time.Hour)},
+	}
+
+	for _, query := range invalidQueries {
+		_, err := suite.usecase.GetAllTasks(context.TODO(), adminSubject, query)
+		suite.Error(err, "error when given an invalid query")
+		suite.Equal(domain.ERR_BAD_REQUEST, err.GetCode())
+	}
+
+	suite.repository.AssertNotCalled(suite.T(), "GetAllTasks", mock.Anything, mock.Anything)
 }
 
 func (suite *taskUsecaseSuite) TestGetTaskByID() {
 	taskID := "sample_id"
 	suite.repository.On("GetTaskByID", mock.Anything, taskID).Return(domain.Task{}, nil).Twice()
-	_, err := suite.usecase.GetTaskByID(context.TODO(), taskID)
+	_, err := suite.usecase.GetTaskByID(context.TODO(), adminSubject, taskID)
 
 	suite.NoError(err, "no error when function is called")
 	suite.repository.AssertCalled(suite.T(), "GetTaskByID", mock.Anything, taskID)
 }
 
+func (suite *taskUsecaseSuite) TestGetTaskByID_Visibility() {
+	ownedTask := domain.Task{ID: "1", Owner: userSubject.Username}
+	assignedTask := domain.Task{ID: "2", Owner: "someone_else", Assignee: userSubject.Username}
+	otherTask := domain.Task{ID: "3", Owner: "someone_else"}
+	suite.repository.On("GetTaskByID", mock.Anything, ownedTask.ID).Return(ownedTask, nil)
+	suite.repository.On("GetTaskByID", mock.Anything, assignedTask.ID).Return(assignedTask, nil)
+	suite.repository.On("GetTaskByID", mock.Anything, otherTask.ID).Return(otherTask, nil)
+
+	_, err := suite.usecase.GetTaskByID(context.TODO(), userSubject, ownedTask.ID)
+	suite.NoError(err, "no error when the user owns the task")
+
+	_, err = suite.usecase.GetTaskByID(context.TODO(), userSubject, assignedTask.ID)
+	suite.NoError(err, "no error when the user has been assigned the task")
+
+	_, err = suite.usecase.GetTaskByID(context.TODO(), userSubject, otherTask.ID)
+	suite.Error(err, "error when the user neither owns nor has been assigned the task")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+
+	_, err = suite.usecase.GetTaskByID(context.TODO(), adminSubject, otherTask.ID)
+	suite.NoError(err, "no error when an admin fetches any task")
+}
+
 // A
 func (suite *taskUsecaseSuite) TestAddTask_Negative() {
 	newTask := domain.Task{
-		ID:          "4",
 		Title:       "updated title",
 		Description: "updated description",
-		Status:      "completed",
+		Status:      domain.StatusDone,
 		DueDate:     time.Now(),
 	}
 
-	suite.repository.On("AddTask", mock.Anything, newTask).Return(nil).Twice()
-	suite.repository.On("GetTaskByID", mock.Anything, newTask.ID).Return(domain.Task{}, nil).Twice()
-	err := suite.usecase.AddTask(context.TODO(), newTask)
+	sampleErr := domain.TaskError{Message: "Task with the provided ID already exists", Code: domain.ERR_CONFLICT}
+	suite.repository.On("AddTask", mock.Anything, mock.AnythingOfType("Task")).Return(sampleErr).Once()
+	_, err := suite.usecase.AddTask(context.TODO(), adminSubject, newTask)
 
-	suite.Error(err, "error when GetTaskByID return no errors")
-	suite.repository.AssertNotCalled(suite.T(), "AddTask", mock.Anything, newTask)
-	suite.repository.AssertCalled(suite.T(), "GetTaskByID", mock.Anything, newTask.ID)
+	suite.Error(err, "error when the repository rejects the task")
+	suite.Equal(domain.ERR_CONFLICT, err.GetCode())
 }
 
 func (suite *taskUsecaseSuite) TestAddTask_Postive() {
 	newTask := domain.Task{
-		ID:          "4",
+		ID:          "client_id",
 		Title:       "updated title",
 		Description: "updated description",
-		Status:      "completed",
+		Status:      domain.StatusDone,
 		DueDate:     time.Now(),
+		Owner:       "someone_else",
 	}
 
-	suite.repository.On("AddTask", mock.Anything, newTask).Return(nil).Twice()
-	suite.repository.On("GetTaskByID", mock.Anything, newTask.ID).Return(domain.Task{}, domain.UserError{Code: domain.ERR_NOT_FOUND}).Twice()
-	err := suite.usecase.AddTask(context.TODO(), newTask)
+	suite.repository.On("AddTask", mock.Anything, mock.AnythingOfType("Task")).Return(nil).Once()
+	createdTask, err := suite.usecase.AddTask(context.TODO(), userSubject, newTask)
 
-	suite.NoError(err, "no error when GetTaskByID returns ERR_NOT_FOUND")
-	suite.repository.AssertCalled(suite.T(), "GetTaskByID", mock.Anything, newTask.ID)
-	suite.repository.AssertCalled(suite.T(), "AddTask", mock.Anything, newTask)
+	suite.NoError(err, "no error when the repository adds the task")
+	suite.Equal("generated_id", createdTask.ID, "client provided ID is replaced by a generated one")
+	suite.Equal(userSubject.Username, createdTask.Owner, "subject is recorded as the owner")
+	suite.False(createdTask.CreatedAt.IsZero(), "creation time is set")
+	suite.Equal(createdTask.CreatedAt, createdTask.UpdatedAt, "update time matches the creation time")
+	suite.repository.AssertCalled(suite.T(), "AddTask", mock.Anything, createdTask)
 }
 
 func (suite *taskUsecaseSuite) TestUpdateTask() {
 	taskUpdates := domain.Task{
 		Title:       "updated title",
 		Description: "updated description",
-		Status:      "completed",
+		Status:      domain.StatusDone,
 	}
 
 	taskID := "sample_id"
-	suite.repository.On("UpdateTask", mock.Anything, taskID, taskUpdates).Return(domain.Task{}, nil).Twice()
-	_, err := suite.usecase.UpdateTask(context.TODO(), taskID, taskUpdates)
+	suite.repository.On("GetTaskByID", mock.Anything, taskID).Return(domain.Task{ID: taskID, Owner: userSubject.Username}, nil)
+	suite.repository.On("UpdateTask", mock.Anything, taskID, mock.AnythingOfType("Task"), int64(0)).Return(domain.Task{}, nil).Twice()
+	_, err := suite.usecase.UpdateTask(context.TODO(), userSubject, taskID, taskUpdates, 0)
 
-	suite.NoError(err, "no error when function is called")
-	suite.repository.AssertCalled(suite.T(), "UpdateTask", mock.Anything, taskID, taskUpdates)
+	suite.NoError(err, "no error when the owner updates the task")
+	suite.repository.AssertCalled(suite.T(), "UpdateTask", mock.Anything, taskID, mock.MatchedBy(func(task domain.Task) bool {
+		return task.Title == taskUpdates.Title && !task.UpdatedAt.IsZero()
+	}), int64(0))
+}
+
+func (suite *taskUsecaseSuite) TestUpdateTask_Visibility() {
+	taskID := "sample_id"
+	suite.repository.On("GetTaskByID", mock.Anything, taskID).Return(domain.Task{ID: taskID, Owner: "someone_else"}, nil)
+	_, err := suite.usecase.UpdateTask(context.TODO(), userSubject, taskID, domain.Task{Title: "updated title", Status: domain.StatusTodo}, 0)
+
+	suite.Error(err, "error when the user does not have access to the task")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+	suite.repository.AssertNotCalled(suite.T(), "UpdateTask", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *taskUsecaseSuite) TestUpdateTask_Assignee() {
+	taskID := "sample_id"
+	task := domain.Task{ID: taskID, Title: "title", Status: domain.StatusInProgress, Owner: "someone_else", Assignee: userSubject.Username}
+	statusUpdate := task
+	statusUpdate.Status = domain.StatusDone
+	suite.repository.On("GetTaskByID", mock.Anything, taskID).Return(task, nil)
+	suite.repository.On("UpdateTask", mock.Anything, taskID, mock.AnythingOfType("Task"), int64(0)).Return(domain.Task{}, nil)
+
+	_, err := suite.usecase.UpdateTask(context.TODO(), userSubject, taskID, statusUpdate, 0)
+	suite.NoError(err, "no error when the assignee only changes the status")
+
+	titleUpdate := statusUpdate
+	titleUpdate.Title = "updated title"
+	_, err = suite.usecase.UpdateTask(context.TODO(), userSubject, taskID, titleUpdate, 0)
+	suite.Error(err, "error when the assignee changes other fields")
+	suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+	suite.repository.AssertNumberOfCalls(suite.T(), "UpdateTask", 1)
+}
+
+func (suite *taskUsecaseSuite) TestUpdateTask_Validation() {
+	_, err := suite.usecase.UpdateTask(context.TODO(), adminSubject, "sample_id", domain.Task{Description: "no title"}, 0)
+
+	suite.Error(err, "error when the replacement has no title")
+	suite.Equal(domain.ERR_BAD_REQUEST, err.GetCode())
+	suite.Equal([]domain.FieldError{{Field: "title", Message: "Title can not be empty"}}, err.GetFields(), "the invalid field is named")
+	suite.repository.AssertNotCalled(suite.T(), "UpdateTask", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *taskUsecaseSuite) TestPatchTask() {
+	taskID := "sample_id"
+	task := domain.Task{ID: taskID, Title: "title", Description: "description", DueDate: time.Now(), Status: domain.StatusTodo, Owner: userSubject.Username, Version: 2}
+	patch := domain.TaskPatch{"title": " new title ", "description": nil, "due_date": "2024-08-05T14:50:56Z"}
+	suite.repository.On("GetTaskByID", mock.Anything, taskID).Return(task, nil)
+	suite.repository.On("PatchTask", mock.Anything, taskID, mock.AnythingOfType("TaskPatch"), int64(2)).Return(domain.Task{}, nil)
+
+	_, err := suite.usecase.PatchTask(context.TODO(), userSubject, taskID, patch, 2)
+	suite.NoError(err, "no error when the owner patches the task")
+	suite.repository.AssertCalled(suite.T(), "PatchTask", mock.Anything, taskID, mock.MatchedBy(func(typedPatch domain.TaskPatch) bool {
+		dueDate, _ := typedPatch["due_date"].(time.Time)
+		_, hasDescription := typedPatch["description"]
+		return typedPatch["title"] == "new title" && hasDescription && typedPatch["description"] == nil &&
+			dueDate.Equal(time.Date(2024, 8, 5, 14, 50, 56, 0, time.UTC)) && typedPatch["updated_at"] != nil
+	}), int64(2))
+}
+
+func (suite *taskUsecaseSuite) TestPatchTask_Invalid() {
+	taskID := "sample_id"
+	task := domain.Task{ID: taskID, Title: "title", Status: domain.StatusTodo, Owner: userSubject.Username}
+	suite.repository.On("GetTaskByID", mock.Anything, taskID).Return(task, nil)
+
+	invalidPatches := []domain.TaskPatch{
+		{"title": nil},
+		{"title": 12},
+		{"description": true},
+		{"due_date": "tomorrow"},
+		{"owner": "someone_else"},
+		{"id": "another_id"},
+		{"status": "pending"},
+		{"status_history": nil},
+	}
+
+	for _, patch := range invalidPatches {
+		_, err := suite.usecase.PatchTask(context.TODO(), userSubject, taskID, patch, 0)
+		suite.Error(err, "error when given an invalid patch")
+		suite.Equal(domain.ERR_BAD_REQUEST, err.GetCode())
+	}
+
+	suite.repository.AssertNotCalled(suite.T(), "PatchTask", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *taskUsecaseSuite) TestPatchTask_Assignee() {
+	taskID := "sample_id"
+	task := domain.Task{ID: taskID, Title: "title", Status: domain.StatusInProgress, Owner: "someone_else", Assignee: userSubject.Username}
+	suite.repository.On("GetTaskByID", mock.Anything, taskID).Return(task, nil)
+	suite.repository.On("PatchTask", mock.Anything, taskID, mock.AnythingOfType("TaskPatch"), int64(0)).Return(domain.Task{}, nil)
+
+	_, err := suite.usecase.PatchTask(context.TODO(), userSubject, taskID, domain.TaskPatch{"status": domain.StatusDone}, 0)
+	suite.NoError(err, "no error when the assignee patches the status")
+
+	_, err = suite.usecase.PatchTask(context.TODO(), userSubject, taskID, domain.TaskPatch{"assignee": nil}, 0)
+	suite.Error(err, "error when the assignee patches other fields")
+	suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+	suite.repository.AssertNumberOfCalls(suite.T(), "PatchTask", 1)
+}
+
+func (suite *taskUsecaseSuite) TestAddTask_Status() {
+	suite.repository.On("AddTask", mock.Anything, mock.AnythingOfType("Task")).Return(nil)
+
+	createdTask, err := suite.usecase.AddTask(context.TODO(), userSubject, domain.Task{Title: "title"})
+	suite.NoError(err, "no error when no status is provided")
+	suite.Equal(domain.StatusTodo, createdTask.Status, "new tasks default to the todo status")
+	suite.Equal([]domain.StatusChange{{To: domain.StatusTodo, ChangedBy: userSubject.Username, ChangedAt: createdTask.CreatedAt}}, createdTask.StatusHistory, "initial status is recorded")
+
+	_, err = suite.usecase.AddTask(context.TODO(), userSubject, domain.Task{Title: "title", Status: "pending"})
+	suite.Error(err, "error when an undefined status is provided")
+	suite.Equal(domain.ERR_BAD_REQUEST, err.GetCode())
+}
+
+func (suite *taskUsecaseSuite) TestUpdateTask_StatusTransitions() {
+	taskID := "sample_id"
+	history := []domain.StatusChange{{To: domain.StatusTodo, ChangedBy: "someone_else"}}
+	task := domain.Task{ID: taskID, Title: "title", Status: domain.StatusTodo, Owner: "someone_else", Assignee: userSubject.Username, StatusHistory: history}
+	suite.repository.On("GetTaskByID", mock.Anything, taskID).Return(task, nil)
+	suite.repository.On("UpdateTask", mock.Anything, taskID, mock.AnythingOfType("Task"), int64(0)).Return(domain.Task{}, nil)
+	suite.repository.On("PatchTask", mock.Anything, taskID, mock.AnythingOfType("TaskPatch"), int64(0)).Return(domain.Task{}, nil)
+
+	updatedTask := task
+	updatedTask.Status = domain.StatusInProgress
+	_, err := suite.usecase.UpdateTask(context.TODO(), userSubject, taskID, updatedTask, 0)
+	suite.NoError(err, "no error when the transition is allowed")
+	suite.repository.AssertCalled(suite.T(), "UpdateTask", mock.Anything, taskID, mock.MatchedBy(func(task domain.Task) bool {
+		change := task.StatusHistory[len(task.StatusHistory)-1]
+		return len(task.StatusHistory) == 2 && change.From == domain.StatusTodo && change.To == domain.StatusInProgress &&
+			change.ChangedBy == userSubject.Username && change.ChangedAt.Equal(task.UpdatedAt)
+	}), int64(0))
+
+	task.Status = domain.StatusArchived
+	suite.repository.On("GetTaskByID", mock.Anything, "archived_id").Return(task, nil)
+	_, err = suite.usecase.PatchTask(context.TODO(), userSubject, "archived_id", domain.TaskPatch{"status": domain.StatusDone}, 0)
+	suite.Error(err, "error when the transition is not allowed")
+	suite.Equal(domain.ERR_INVALID_TRANSITION, err.GetCode())
+	suite.repository.AssertNotCalled(suite.T(), "PatchTask", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *taskUsecaseSuite) TestDeleteTask_Permissions() {
+	taskID := "sample_id"
+	suite.repository.On("GetTaskByID", mock.Anything, taskID).Return(domain.Task{ID: taskID, Owner: "task_owner", Assignee: userSubject.Username}, nil)
+	suite.repository.On("DeleteTask", mock.Anything, taskID, int64(0)).Return(nil)
+
+	err := suite.usecase.DeleteTask(context.TODO(), userSubject, taskID, 0)
+	suite.Error(err, "error when the assignee deletes the task")
+	suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+	suite.repository.AssertNotCalled(suite.T(), "DeleteTask", mock.Anything, taskID, int64(0))
+
+	err = suite.usecase.DeleteTask(context.TODO(), domain.Subject{Username: "task_owner", Role: domain.RoleUser, Permissions: domain.DefaultUserPermissions}, taskID, 0)
+	suite.NoError(err, "no error when the owner deletes the task")
+	suite.repository.AssertCalled(suite.T(), "DeleteTask", mock.Anything, taskID, int64(0))
+}
+
+func (suite *taskUsecaseSuite) TestCustomRolePermissions() {
+	task := domain.Task{ID: "sample_id", Title: "title", Status: domain.StatusTodo, Owner: "someone_else"}
+	suite.repository.On("GetTaskByID", mock.Anything, task.ID).Return(task, nil)
+	expectedQuery := domain.TaskQuery{SortBy: "id", SortOrder: domain.SortAscending, Limit: domain.DefaultPageLimit}
+	suite.repository.On("GetAllTasks", mock.Anything, expectedQuery).Return([]domain.Task{task}, int64(1), nil)
+
+	_, err := suite.usecase.GetAllTasks(context.TODO(), viewerSubject, domain.TaskQuery{})
+	suite.NoError(err, "no error when a viewer lists the tasks")
+	suite.repository.AssertCalled(suite.T(), "GetAllTasks", mock.Anything, expectedQuery)
+
+	_, err = suite.usecase.GetTaskByID(context.TODO(), viewerSubject, task.ID)
+	suite.NoError(err, "no error when a viewer fetches a task of another user")
+
+	_, err = suite.usecase.UpdateTask(context.TODO(), viewerSubject, task.ID, domain.Task{Title: "title", Status: domain.StatusDone}, 0)
+	suite.Error(err, "error when a viewer updates a task")
+	suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+
+	err = suite.usecase.DeleteTask(context.TODO(), viewerSubject, task.ID, 0)
+	suite.Error(err, "error when a viewer deletes a task")
+	suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+
+	_, err = suite.usecase.AddTask(context.TODO(), viewerSubject, domain.Task{Title: "title"})
+	suite.Error(err, "error when a viewer creates a task")
+	suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+	suite.repository.AssertNotCalled(suite.T(), "AddTask", mock.Anything, mock.Anything)
+	suite.repository.AssertNotCalled(suite.T(), "UpdateTask", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.repository.AssertNotCalled(suite.T(), "DeleteTask", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *taskUsecaseSuite) TestUpdateTask_Version() {
+	taskID := "sample_id"
+	taskUpdates := domain.Task{Title: "updated title", Status: domain.StatusTodo}
+	suite.repository.On("GetTaskByID", mock.Anything, taskID).Return(domain.Task{ID: taskID, Owner: userSubject.Username, Status: domain.StatusTodo, Version: 3}, nil)
+	suite.repository.On("UpdateTask", mock.Anything, taskID, mock.AnythingOfType("Task"), int64(3)).Return(domain.Task{ID: taskID, Version: 4}, nil)
+
+	_, err := suite.usecase.UpdateTask(context.TODO(), userSubject, taskID, taskUpdates, 2)
+	suite.Error(err, "error when the expected version is stale")
+	suite.Equal(domain.ERR_PRECONDITION_FAILED, err.GetCode())
+	suite.repository.AssertNotCalled(suite.T(), "UpdateTask", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	updatedTask, err := suite.usecase.UpdateTask(context.TODO(), userSubject, taskID, taskUpdates, 3)
+	suite.NoError(err, "no error when the expected version is current")
+	suite.Equal(int64(4), updatedTask.Version, "updated version is returned")
+
+	_, err = suite.usecase.UpdateTask(context.TODO(), userSubject, taskID, taskUpdates, 0)
+	suite.NoError(err, "no error when no version is expected")
+	suite.repository.AssertNumberOfCalls(suite.T(), "UpdateTask", 2)
+}
+
+func (suite *taskUsecaseSuite) TestDeleteTask_Version() {
+	taskID := "sample_id"
+	suite.repository.On("GetTaskByID", mock.Anything, taskID).Return(domain.Task{ID: taskID, Owner: userSubject.Username, Version: 3}, nil)
+	suite.repository.On("DeleteTask", mock.Anything, taskID, int64(3)).Return(nil)
+
+	err := suite.usecase.DeleteTask(context.TODO(), userSubject, taskID, 2)
+	suite.Error(err, "error when the expected version is stale")
+	suite.Equal(domain.ERR_PRECONDITION_FAILED, err.GetCode())
+	suite.repository.AssertNotCalled(suite.T(), "DeleteTask", mock.Anything, mock.Anything, mock.Anything)
+
+	err = suite.usecase.DeleteTask(context.TODO(), userSubject, taskID, 3)
+	suite.NoError(err, "no error when the expected version is current")
+	suite.repository.AssertCalled(suite.T(), "DeleteTask", mock.Anything, taskID, int64(3))
 }
 
 func (suite *taskUsecaseSuite) TestDeleteTask() {
 	taskID := "sample_id"
-	suite.repository.On("DeleteTask", mock.Anything, taskID).Return(nil).Twice()
-	err := suite.usecase.DeleteTask(context.TODO(), taskID)
+	suite.repository.On("GetTaskByID", mock.Anything, taskID).Return(domain.Task{ID: taskID, Owner: "someone_else"}, nil)
+	suite.repository.On("DeleteTask", mock.Anything, taskID, int64(0)).Return(nil).Twice()
+	err := suite.usecase.DeleteTask(context.TODO(), adminSubject, taskID, 0)
 
 	suite.NoError(err, "no error when function is called")
-	suite.repository.AssertCalled(suite.T(), "DeleteTask", mock.Anything, taskID)
+	suite.repository.AssertCalled(suite.T(), "DeleteTask", mock.Anything, taskID, int64(0))
 }
 
 func TestTaskUsecase(t *testing.T) {
@@ -2,9 +2,12 @@ package tests
 
 import (
 	"context"
+	"fmt"
+	"slices"
 	"strings"
 	domain "task_manager_api/Domain"
 	mocks "task_manager_api/Mocks"
+	repository "task_manager_api/Repository"
 	usecase "task_manager_api/Usecase"
 	"testing"
 	"time"
@@ -16,8 +19,19 @@ import (
 
 type userUsecaseSuite struct {
 	suite.Suite
-	repository *mocks.UserRepositoryInterface
-	usecase    usecase.UserUsecase
+	repository        *mocks.UserRepositoryInterface
+	tokenRepository   *mocks.RefreshTokenRepositoryInterface
+	sessionRepository *mocks.SessionRepositoryInterface
+	resetRepository   *mocks.PasswordResetTokenRepositoryInterface
+	verification      *mocks.EmailVerificationTokenRepositoryInterface
+	taskRepository    *mocks.TaskRepositoryInterface
+	loginAttempts     *mocks.LoginAttemptRepositoryInterface
+	mfa               *mocks.MFARepositoryInterface
+	challenges        *mocks.MFAChallengeRepositoryInterface
+	apiKeys           *mocks.APIKeyRepositoryInterface
+	roles             *mocks.RoleRepositoryInterface
+	notifier          *mocks.NotifierInterface
+	usecase           usecase.UserUsecase
 }
 
 func SanitizeUser(user *domain.User) {
@@ -48,18 +62,112 @@ func (suite *userUsecaseSuite) SetupSuite() {
 	}
 
 	// mocks SignJWTWithPayload with return types dependent on the role
-	suite.usecase.SignJWTWithPayload = func(username, role string, tokenLifeSpan time.Duration, secret string) (string, domain.CodedError) {
+	suite.usecase.SignJWTWithPayload = func(username, role, sessionID string, tokenLifeSpan time.Duration) (string, domain.CodedError) {
 		if role == "admin" || role == "user" {
 			return "valid_token", nil
 		}
 
 		return "", domain.UserError{Message: "internal server error", Code: domain.ERR_INTERNAL_SERVER}
 	}
+
+	// mocks the refresh token generation and hashing with predictable values
+	suite.usecase.GenerateToken = func() (string, domain.CodedError) {
+		return "new_refresh_token", nil
+	}
+	suite.usecase.HashToken = func(token string) string {
+		return "hash_" + token
+	}
+	suite.usecase.GenerateID = func() string {
+		return "generated_family"
+	}
+
+	// mocks the TOTP secrets and codes: "123456" is the only valid code, in the time step 100
+	suite.usecase.GenerateTOTPSecret = func() (string, domain.CodedError) {
+		return "NEWSECRET", nil
+	}
+	suite.usecase.TOTPURI = func(issuer string, account string, secret string) string {
+		return "otpauth://totp/" + issuer + ":" + account + "?secret=" + secret
+	}
+	suite.usecase.ValidateTOTP = func(secret string, code string, now time.Time) (int64, bool) {
+		return 100, code == "123456"
+	}
+
+	// mocks the recovery codes with distinct predictable values
+	recoveryCodes := 0
+	suite.usecase.GenerateRecoveryCode = func() (string, domain.CodedError) {
+		recoveryCodes++
+		return fmt.Sprintf("CODE-%05d", recoveryCodes), nil
+	}
 }
 
 func (suite *userUsecaseSuite) SetupTest() {
 	suite.repository = new(mocks.UserRepositoryInterface)
+	suite.tokenRepository = new(mocks.RefreshTokenRepositoryInterface)
 	suite.usecase.UserRespository = suite.repository
+	suite.sessionRepository = new(mocks.SessionRepositoryInterface)
+	suite.usecase.RefreshTokenRepository = suite.tokenRepository
+	suite.usecase.SessionRepository = suite.sessionRepository
+	suite.resetRepository = new(mocks.PasswordResetTokenRepositoryInterface)
+	suite.notifier = new(mocks.NotifierInterface)
+	suite.usecase.ResetTokenRepository = suite.resetRepository
+	suite.usecase.Notifier = suite.notifier
+	suite.verification = new(mocks.EmailVerificationTokenRepositoryInterface)
+	suite.usecase.VerificationRepository = suite.verification
+	suite.taskRepository = new(mocks.TaskRepositoryInterface)
+	suite.usecase.TaskRepository = suite.taskRepository
+	suite.loginAttempts = new(mocks.LoginAttemptRepositoryInterface)
+	suite.usecase.LoginAttemptRepository = suite.loginAttempts
+	suite.mfa = new(mocks.MFARepositoryInterface)
+	suite.usecase.MFARepository = suite.mfa
+	suite.challenges = new(mocks.MFAChallengeRepositoryInterface)
+	suite.usecase.MFAChallengeRepository = suite.challenges
+	suite.apiKeys = new(mocks.APIKeyRepositoryInterface)
+	suite.usecase.APIKeyRepository = suite.apiKeys
+	suite.roles = new(mocks.RoleRepositoryInterface)
+	suite.usecase.RoleRepository = suite.roles
+}
+
+// expects the "manager" role to be the only custom role and the user role to be unchanged
+func (suite *userUsecaseSuite) expectRoles() {
+	suite.roles.On("GetRole", mock.Anything, "manager").Return(domain.Role{Name: "manager", Permissions: []string{domain.PermissionTaskReadAny}}, nil)
+	suite.roles.On("GetRole", mock.Anything, mock.AnythingOfType("string")).Return(domain.Role{}, domain.UserError{Message: "Role not found", Code: domain.ERR_NOT_FOUND})
+}
+
+// expects the provided roles to require a second factor and the other roles not to have been changed
+func (suite *userUsecaseSuite) expectMFARequired(roles ...string) {
+	for _, role := range roles {
+		suite.roles.On("GetRole", mock.Anything, role).Return(domain.Role{Name: role, MFARequired: true}, nil)
+	}
+
+	suite.roles.On("GetRole", mock.Anything, mock.AnythingOfType("string")).Return(domain.Role{}, domain.UserError{Message: "Role not found", Code: domain.ERR_NOT_FOUND})
+}
+
+// expects the users not to have set up a second factor
+func (suite *userUsecaseSuite) expectNoMFA() {
+	suite.mfa.On("GetMFA", mock.Anything, mock.AnythingOfType("string")).Return(domain.MFAEnrollment{}, domain.UserError{Message: "Two-factor authentication is not set up", Code: domain.ERR_NOT_FOUND})
+	suite.mfa.On("DeleteMFA", mock.Anything, mock.AnythingOfType("string")).Return(domain.UserError{Message: "Two-factor authentication is not set up", Code: domain.ERR_NOT_FOUND})
+}
+
+// expects the logins to be throttled by keys that have no failed logins yet
+func (suite *userUsecaseSuite) expectLoginAttempts() {
+	suite.loginAttempts.On("GetLoginAttempts", mock.Anything, mock.AnythingOfType("string")).Return(domain.LoginAttempts{}, domain.UserError{Message: "Login attempts not found", Code: domain.ERR_NOT_FOUND})
+	suite.loginAttempts.On("RecordLoginFailure", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time")).Return(domain.LoginAttempts{Failures: 1}, nil)
+	suite.loginAttempts.On("ClearLoginAttempts", mock.Anything, mock.AnythingOfType("string")).Return(nil)
+}
+
+// matches the unverified user that is created from the provided sanitized user
+func createdUser(user domain.User) interface{} {
+	return mock.MatchedBy(func(created domain.User) bool {
+		return created.Username == user.Username && created.Email == user.Email && created.Password == user.Password &&
+			created.Role == user.Role && !created.Verified && !created.CreatedAt.IsZero()
+	})
+}
+
+// expects a verification token to be created and sent
+func (suite *userUsecaseSuite) expectVerification() {
+	suite.verification.On("InvalidateUserVerificationTokens", mock.Anything, mock.AnythingOfType("string")).Return(nil)
+	suite.verification.On("CreateVerificationToken", mock.Anything, mock.AnythingOfType("EmailVerificationToken")).Return(nil)
+	suite.notifier.On("Notify", mock.Anything, mock.AnythingOfType("Notification")).Return(nil)
 }
 
 func (suite *userUsecaseSuite) TestCreateUser_Positive() {
@@ -76,10 +184,19 @@ func (suite *userUsecaseSuite) TestCreateUser_Positive() {
 	suite.repository.On("CheckDuplicate", mock.Anything, "email", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 	suite.repository.On("CheckDuplicate", mock.Anything, "username", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 	suite.repository.On("CreateUser", mock.Anything, mock.AnythingOfType("User")).Return(nil)
-	err := suite.usecase.CreateUser(context.TODO(), sanitizedUser)
+	suite.expectVerification()
+	verificationSent, err := suite.usecase.CreateUser(context.TODO(), sanitizedUser)
+	sanitizedUser.Role = domain.RoleUser
 
 	suite.NoError(err, "no error when given valid data")
-	suite.repository.AssertCalled(suite.T(), "CreateUser", mock.Anything, sanitizedUser)
+	suite.True(verificationSent, "the verification token is reported as sent")
+	suite.repository.AssertCalled(suite.T(), "CreateUser", mock.Anything, createdUser(sanitizedUser))
+	suite.verification.AssertCalled(suite.T(), "CreateVerificationToken", mock.Anything, mock.MatchedBy(func(token domain.EmailVerificationToken) bool {
+		return token.TokenHash == "hash_new_refresh_token" && token.Username == sanitizedUser.Username && token.ExpiresAt.After(token.CreatedAt)
+	}))
+	suite.notifier.AssertCalled(suite.T(), "Notify", mock.Anything, mock.MatchedBy(func(notification domain.Notification) bool {
+		return notification.Recipient == sanitizedUser.Email && strings.Contains(notification.Body, "new_refresh_token")
+	}))
 }
 
 func (suite *userUsecaseSuite) TestCreateUser_UsernameValidation() {
@@ -87,7 +204,7 @@ func (suite *userUsecaseSuite) TestCreateUser_UsernameValidation() {
 		Username: " ut     ",
 		Email:    "  valid@mail.com   ",
 		Password: "password123",
-		Role:     " aDmIn ",
+		Role:     " uSeR ",
 	}
 
 	sanitizedUser := user
@@ -96,16 +213,18 @@ func (suite *userUsecaseSuite) TestCreateUser_UsernameValidation() {
 	suite.repository.On("CheckDuplicate", mock.Anything, "email", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 	suite.repository.On("CheckDuplicate", mock.Anything, "username", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 
-	err := suite.usecase.CreateUser(context.TODO(), user)
+	_, err := suite.usecase.CreateUser(context.TODO(), user)
 	suite.Error(err, "error when given invalid username")
 	suite.Equal(err.GetCode(), domain.ERR_BAD_REQUEST)
+	suite.Equal("username", err.GetFields()[0].Field, "the invalid field is named")
 	suite.repository.AssertNotCalled(suite.T(), "CreateUser", mock.Anything, mock.AnythingOfType("User"))
 
 	user.Username = "valid_username"
 	sanitizedUser.Username = user.Username
 	SanitizeUser(&sanitizedUser)
-	suite.repository.On("CreateUser", mock.Anything, sanitizedUser).Return(nil)
-	err = suite.usecase.CreateUser(context.TODO(), user)
+	suite.repository.On("CreateUser", mock.Anything, createdUser(sanitizedUser)).Return(nil)
+	suite.expectVerification()
+	_, err = suite.usecase.CreateUser(context.TODO(), user)
 
 	suite.NoError(err, "no error when given a valid username")
 	suite.repository.AssertCalled(suite.T(), "CreateUser", mock.Anything, mock.AnythingOfType("User"))
@@ -116,7 +235,7 @@ func (suite *userUsecaseSuite) TestCreateUser_EmailValidation() {
 		Username: " valid_usernmae     ",
 		Email:    "  invalid email   ",
 		Password: "password123",
-		Role:     " aDmIn ",
+		Role:     " uSeR ",
 	}
 
 	sanitizedUser := user
@@ -125,16 +244,18 @@ func (suite *userUsecaseSuite) TestCreateUser_EmailValidation() {
 	suite.repository.On("CheckDuplicate", mock.Anything, "email", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 	suite.repository.On("CheckDuplicate", mock.Anything, "username", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 
-	err := suite.usecase.CreateUser(context.TODO(), user)
+	_, err := suite.usecase.CreateUser(context.TODO(), user)
 	suite.Error(err, "error when given invalid email")
 	suite.Equal(err.GetCode(), domain.ERR_BAD_REQUEST)
+	suite.Equal("email", err.GetFields()[0].Field, "the invalid field is named")
 	suite.repository.AssertNotCalled(suite.T(), "CreateUser", mock.Anything, mock.AnythingOfType("User"))
 
 	user.Email = "valid_email@gmail.com"
 	sanitizedUser.Email = user.Email
 	SanitizeUser(&sanitizedUser)
-	suite.repository.On("CreateUser", mock.Anything, sanitizedUser).Return(nil)
-	err = suite.usecase.CreateUser(context.TODO(), user)
+	suite.repository.On("CreateUser", mock.Anything, createdUser(sanitizedUser)).Return(nil)
+	suite.expectVerification()
+	_, err = suite.usecase.CreateUser(context.TODO(), user)
 
 	suite.NoError(err, "no error when given a valid email")
 	suite.repository.AssertCalled(suite.T(), "CreateUser", mock.Anything, mock.AnythingOfType("User"))
@@ -145,7 +266,7 @@ func (suite *userUsecaseSuite) TestCreateUser_PasswordValidation() {
 		Username: " valid_usernmae     ",
 		Email:    "  valid@email.com   ",
 		Password: "invalid",
-		Role:     " aDmIn ",
+		Role:     " uSeR ",
 	}
 
 	sanitizedUser := user
@@ -154,22 +275,63 @@ func (suite *userUsecaseSuite) TestCreateUser_PasswordValidation() {
 	suite.repository.On("CheckDuplicate", mock.Anything, "email", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 	suite.repository.On("CheckDuplicate", mock.Anything, "username", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 
-	err := suite.usecase.CreateUser(context.TODO(), user)
+	_, err := suite.usecase.CreateUser(context.TODO(), user)
 	suite.Error(err, "error when given invalid password")
 	suite.Equal(err.GetCode(), domain.ERR_BAD_REQUEST)
+	suite.Equal("password", err.GetFields()[0].Field, "the invalid field is named")
 	suite.repository.AssertNotCalled(suite.T(), "CreateUser", mock.Anything, mock.AnythingOfType("User"))
 
 	user.Password = "valid_password123"
 	sanitizedUser.Password = user.Password
 	SanitizeUser(&sanitizedUser)
-	suite.repository.On("CreateUser", mock.Anything, sanitizedUser).Return(nil)
-	err = suite.usecase.CreateUser(context.TODO(), user)
+	suite.repository.On("CreateUser", mock.Anything, createdUser(sanitizedUser)).Return(nil)
+	suite.expectVerification()
+	_, err = suite.usecase.CreateUser(context.TODO(), user)
 
 	suite.NoError(err, "no error when given a valid password")
 	suite.repository.AssertCalled(suite.T(), "CreateUser", mock.Anything, mock.AnythingOfType("User"))
 }
 
-func (suite *userUsecaseSuite) TestCreateUser_RoleValidation() {
+func (suite *userUsecaseSuite) TestCreateUser_RoleIgnored() {
+	for _, role := range []string{" aDmIn ", " invalid role ", ""} {
+		suite.SetupTest()
+		user := domain.User{
+			Username: " valid_usernmae     ",
+			Email:    "  valid@email.com   ",
+			Password: "password123",
+			Role:     role,
+		}
+
+		sanitizedUser := user
+		SanitizeUser(&sanitizedUser)
+		sanitizedUser.Role = domain.RoleUser
+
+		suite.repository.On("CheckDuplicate", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+		suite.repository.On("CreateUser", mock.Anything, createdUser(sanitizedUser)).Return(nil)
+		suite.expectVerification()
+		_, err := suite.usecase.CreateUser(context.TODO(), user)
+
+		suite.NoError(err, "no error when a role is provided to the signup")
+		suite.repository.AssertCalled(suite.T(), "CreateUser", mock.Anything, createdUser(sanitizedUser))
+	}
+}
+
+func (suite *userUsecaseSuite) TestCreateUser_VerificationOff() {
+	suite.repository.On("CheckDuplicate", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+	suite.repository.On("CreateUser", mock.Anything, mock.AnythingOfType("User")).Return(nil)
+	defer viper.Set("EMAIL_VERIFICATION", domain.VerificationRequired)
+
+	viper.Set("EMAIL_VERIFICATION", domain.VerificationOff)
+	verificationSent, err := suite.usecase.CreateUser(context.TODO(), domain.User{Username: "valid_username", Email: "valid@mail.com", Password: "password123"})
+	suite.NoError(err, "no error when the verification is off")
+	suite.False(verificationSent, "no verification token is reported as sent")
+	suite.repository.AssertCalled(suite.T(), "CreateUser", mock.Anything, mock.MatchedBy(func(user domain.User) bool { return user.Verified }))
+	suite.verification.AssertNotCalled(suite.T(), "CreateVerificationToken", mock.Anything, mock.Anything)
+	suite.notifier.AssertNotCalled(suite.T(), "Notify", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestCreateAccount_RoleValidation() {
+	suite.expectRoles()
 	user := domain.User{
 		Username: " valid_usernmae     ",
 		Email:    "  valid@email.com   ",
@@ -183,37 +345,126 @@ func (suite *userUsecaseSuite) TestCreateUser_RoleValidation() {
 	suite.repository.On("CheckDuplicate", mock.Anything, "email", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 	suite.repository.On("CheckDuplicate", mock.Anything, "username", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 
-	err := suite.usecase.CreateUser(context.TODO(), user)
+	_, err := suite.usecase.CreateAccount(context.TODO(), adminSubject, user)
 	suite.Error(err, "error when given invalid role")
 	suite.Equal(err.GetCode(), domain.ERR_BAD_REQUEST)
 	suite.repository.AssertNotCalled(suite.T(), "CreateUser", mock.Anything, mock.AnythingOfType("User"))
 
-	user.Role = "user"
+	user.Role = " aDmIn "
 	sanitizedUser.Role = user.Role
 	SanitizeUser(&sanitizedUser)
-	suite.repository.On("CreateUser", mock.Anything, sanitizedUser).Return(nil)
-	err = suite.usecase.CreateUser(context.TODO(), user)
+	suite.repository.On("CreateUser", mock.Anything, createdUser(sanitizedUser)).Return(nil)
+	suite.expectVerification()
+	_, err = suite.usecase.CreateAccount(context.TODO(), adminSubject, user)
 
-	suite.NoError(err, "no error when given a valid role")
-	suite.repository.AssertCalled(suite.T(), "CreateUser", mock.Anything, mock.AnythingOfType("User"))
+	suite.NoError(err, "no error when an admin account is created by an admin")
+	suite.repository.AssertCalled(suite.T(), "CreateUser", mock.Anything, createdUser(sanitizedUser))
+	suite.notifier.AssertNumberOfCalls(suite.T(), "Notify", 1)
+}
+
+func (suite *userUsecaseSuite) TestCreateAccount_CustomRole() {
+	suite.expectRoles()
+	suite.expectVerification()
+	suite.repository.On("CheckDuplicate", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+	suite.repository.On("CreateUser", mock.Anything, mock.AnythingOfType("User")).Return(nil)
+
+	_, err := suite.usecase.CreateAccount(context.TODO(), adminSubject, domain.User{Username: "manager_user", Email: "manager@mail.com", Password: "password123", Role: "Manager"})
+	suite.NoError(err, "no error when the custom role exists")
+	suite.repository.AssertCalled(suite.T(), "CreateUser", mock.Anything, mock.MatchedBy(func(user domain.User) bool { return user.Role == "manager" }))
+
+	_, err = suite.usecase.CreateAccount(context.TODO(), adminSubject, domain.User{Username: "viewer_user", Email: "viewer@mail.com", Password: "password123", Role: "viewer"})
+	suite.Error(err, "error when the custom role does not exist")
+	suite.Equal(domain.ERR_BAD_REQUEST, err.GetCode())
+	suite.repository.AssertNumberOfCalls(suite.T(), "CreateUser", 1)
+}
+
+func (suite *userUsecaseSuite) TestCreateAccount_Escalation() {
+	suite.expectRoles()
+	creator := domain.Subject{Username: "account_creator", Role: "creator", Permissions: []string{domain.PermissionUserCreate}}
+	for _, role := range []string{domain.RoleAdmin, "manager"} {
+		_, err := suite.usecase.CreateAccount(context.TODO(), creator, domain.User{Username: "new_user", Email: "new@mail.com", Password: "password123", Role: role})
+		suite.Error(err, "error when the role grants permissions the subject does not hold")
+		suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+	}
+
+	suite.repository.AssertNotCalled(suite.T(), "CreateUser", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestBootstrapAdmin() {
+	user := domain.User{Username: "admin", Email: "admin@mail.com", Password: "password123"}
+	suite.repository.On("CheckDuplicate", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+	suite.repository.On("CreateUser", mock.Anything, mock.AnythingOfType("User")).Return(nil)
+	err := suite.usecase.BootstrapAdmin(context.TODO(), user)
+
+	suite.NoError(err, "no error when creating the initial admin")
+	suite.repository.AssertCalled(suite.T(), "CreateUser", mock.Anything, mock.MatchedBy(func(created domain.User) bool {
+		return created.Username == "admin" && created.Role == domain.RoleAdmin && created.Verified
+	}))
+	suite.notifier.AssertNotCalled(suite.T(), "Notify", mock.Anything, mock.Anything)
+}
+
+// Tests that the initial admin is only created when the username is free
+func (suite *userUsecaseSuite) TestEnsureAdmin() {
+	user := domain.User{Username: " Admin ", Email: "admin@mail.com", Password: "password123"}
+	suite.repository.On("GetByUsername", mock.Anything, "admin").Return(domain.User{}, domain.UserError{Message: "User not found", Code: domain.ERR_BAD_REQUEST}).Once()
+	suite.repository.On("CheckDuplicate", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+	suite.repository.On("CreateUser", mock.Anything, mock.AnythingOfType("User")).Return(nil)
+	created, err := suite.usecase.EnsureAdmin(context.TODO(), user)
+
+	suite.NoError(err, "no error when creating the initial admin")
+	suite.True(created)
+	suite.repository.AssertCalled(suite.T(), "CreateUser", mock.Anything, mock.MatchedBy(func(created domain.User) bool {
+		return created.Username == "admin" && created.Role == domain.RoleAdmin && created.Verified
+	}))
+
+	suite.repository.On("GetByUsername", mock.Anything, "admin").Return(domain.User{Username: "admin", Role: domain.RoleUser}, nil).Once()
+	created, err = suite.usecase.EnsureAdmin(context.TODO(), user)
+	suite.NoError(err, "no error when the account already exists")
+	suite.False(created, "an existing account is left untouched")
+	suite.repository.AssertNumberOfCalls(suite.T(), "CreateUser", 1)
+}
+
+// Tests that EnsureAdmin does not create the admin when the lookup fails
+func (suite *userUsecaseSuite) TestEnsureAdmin_LookupError() {
+	suite.repository.On("GetByUsername", mock.Anything, "admin").Return(domain.User{}, domain.UserError{Message: "Internal server error", Code: domain.ERR_INTERNAL_SERVER})
+	created, err := suite.usecase.EnsureAdmin(context.TODO(), domain.User{Username: "admin", Email: "admin@mail.com", Password: "password123"})
+
+	suite.Error(err, "error when the lookup fails")
+	suite.Equal(domain.ERR_INTERNAL_SERVER, err.GetCode())
+	suite.False(created)
+	suite.repository.AssertNotCalled(suite.T(), "CreateUser", mock.Anything, mock.Anything)
 }
 
 func (suite *userUsecaseSuite) TestValidateAndGetToken_Positive() {
+	suite.expectMFARequired()
+	suite.expectNoMFA()
+	suite.expectLoginAttempts()
 	storedUser := domain.User{
 		Username: "valid_username",
 		Email:    "valid@email.com",
 		Password: "valid_password",
 		Role:     "user",
+		Verified: true,
 	}
 
 	suite.repository.On("GetByUsername", mock.Anything, storedUser.Username).Return(storedUser, nil)
-	str, err := suite.usecase.ValidateAndGetToken(context.TODO(), storedUser)
+	suite.tokenRepository.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("RefreshToken")).Return(nil)
+	suite.sessionRepository.On("CreateSession", mock.Anything, mock.AnythingOfType("Session")).Return(nil)
+	tokens, err := suite.usecase.ValidateAndGetToken(context.TODO(), storedUser, "")
 
 	suite.NoError(err, "no error when the correct password and a valid role is provided")
-	suite.Greater(len(str), 0)
+	suite.sessionRepository.AssertCalled(suite.T(), "CreateSession", mock.Anything, mock.MatchedBy(func(session domain.Session) bool {
+		return session.ID == "generated_family" && session.Username == storedUser.Username && session.ExpiresAt.After(session.CreatedAt)
+	}))
+	suite.Equal("valid_token", tokens.AccessToken)
+	suite.Equal("new_refresh_token", tokens.RefreshToken)
+	suite.tokenRepository.AssertCalled(suite.T(), "CreateRefreshToken", mock.Anything, mock.MatchedBy(func(token domain.RefreshToken) bool {
+		return token.TokenHash == "hash_new_refresh_token" && token.FamilyID == "generated_family" && token.Username == storedUser.Username && token.ExpiresAt.After(token.CreatedAt)
+	}))
 }
 
 func (suite *userUsecaseSuite) TestValidateAndGetToken_RoleValidation() {
+	suite.expectLoginAttempts()
 	storedUser := domain.User{
 		Username: "valid_username",
 		Email:    "valid@email.com",
@@ -222,13 +473,16 @@ func (suite *userUsecaseSuite) TestValidateAndGetToken_RoleValidation() {
 	}
 
 	suite.repository.On("GetByUsername", mock.Anything, storedUser.Username).Return(storedUser, nil)
-	str, err := suite.usecase.ValidateAndGetToken(context.TODO(), storedUser)
+	tokens, err := suite.usecase.ValidateAndGetToken(context.TODO(), storedUser, "")
 
 	suite.Error(err, "error when an invalid role is provided")
-	suite.Equal(len(str), 0)
+	suite.Equal(domain.TokenPair{}, tokens)
+	suite.tokenRepository.AssertNotCalled(suite.T(), "CreateRefreshToken", mock.Anything, mock.Anything)
+	suite.sessionRepository.AssertNotCalled(suite.T(), "CreateSession", mock.Anything, mock.Anything)
 }
 
 func (suite *userUsecaseSuite) TestValidateAndGetToken_PasswordValidation() {
+	suite.expectLoginAttempts()
 	storedUser := domain.User{
 		Username: "valid_username",
 		Email:    "valid@email.com",
@@ -238,26 +492,290 @@ func (suite *userUsecaseSuite) TestValidateAndGetToken_PasswordValidation() {
 
 	suite.repository.On("GetByUsername", mock.Anything, storedUser.Username).Return(storedUser, nil)
 	storedUser.Password = "incorrect_password"
-	str, err := suite.usecase.ValidateAndGetToken(context.TODO(), storedUser)
+	tokens, err := suite.usecase.ValidateAndGetToken(context.TODO(), storedUser, "")
 
 	suite.Error(err, "error when an invalid password is provided")
-	suite.Equal(len(str), 0)
+	suite.Equal(domain.TokenPair{}, tokens)
+}
+
+func (suite *userUsecaseSuite) TestValidateAndGetToken_WrongPasswordCounted() {
+	suite.expectLoginAttempts()
+	storedUser := domain.User{Username: "valid_username", Password: "incorrect_password", Role: "user", Verified: true}
+	suite.repository.On("GetByUsername", mock.Anything, storedUser.Username).Return(storedUser, nil)
+	_, err := suite.usecase.ValidateAndGetToken(context.TODO(), storedUser, "10.0.0.1")
+
+	suite.Error(err, "error when the password is incorrect")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
+	suite.Equal("Invalid username or password", err.Error())
+	suite.loginAttempts.AssertCalled(suite.T(), "RecordLoginFailure", mock.Anything, "account:valid_username", mock.Anything, mock.Anything)
+	suite.loginAttempts.AssertCalled(suite.T(), "RecordLoginFailure", mock.Anything, "ip:10.0.0.1", mock.Anything, mock.Anything)
+	suite.loginAttempts.AssertNotCalled(suite.T(), "ClearLoginAttempts", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestValidateAndGetToken_UnknownUser() {
+	suite.expectLoginAttempts()
+	suite.repository.On("GetByUsername", mock.Anything, "unknown").Return(domain.User{}, domain.UserError{Message: "User not found", Code: domain.ERR_BAD_REQUEST})
+	_, err := suite.usecase.ValidateAndGetToken(context.TODO(), domain.User{Username: "unknown", Password: "valid_password"}, "")
+
+	suite.Error(err, "error when the username is unknown")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
+	suite.Equal("Invalid username or password", err.Error(), "unknown usernames can not be told apart from wrong passwords")
+	suite.loginAttempts.AssertCalled(suite.T(), "RecordLoginFailure", mock.Anything, "account:unknown", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestValidateAndGetToken_AccountLocked() {
+	now := time.Now()
+	attempts := domain.LoginAttempts{Key: "account:valid_username", Failures: 4, LastFailure: now.Add(-500 * time.Millisecond), ExpiresAt: now.Add(time.Hour)}
+	suite.loginAttempts.On("GetLoginAttempts", mock.Anything, "account:valid_username").Return(attempts, nil)
+	suite.expectLoginAttempts()
+	_, err := suite.usecase.ValidateAndGetToken(context.TODO(), domain.User{Username: "Valid_Username", Password: "valid_password"}, "10.0.0.1")
+
+	suite.Error(err, "error when the account is locked")
+	suite.Equal(domain.ERR_TOO_MANY_REQUESTS, err.GetCode())
+	suite.Contains(err.Error(), "try again in 2 seconds", "the delay doubles with every failure past the free ones")
+	suite.repository.AssertNotCalled(suite.T(), "GetByUsername", mock.Anything, mock.Anything)
+	suite.loginAttempts.AssertNotCalled(suite.T(), "RecordLoginFailure", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestValidateAndGetToken_LockoutElapsed() {
+	suite.expectMFARequired()
+	suite.expectNoMFA()
+	now := time.Now()
+	storedUser := domain.User{Username: "valid_username", Password: "valid_password", Role: "user", Verified: true}
+	attempts := domain.LoginAttempts{Key: "account:valid_username", Failures: 3, LastFailure: now.Add(-2 * time.Second), ExpiresAt: now.Add(time.Hour)}
+	suite.loginAttempts.On("GetLoginAttempts", mock.Anything, "account:valid_username").Return(attempts, nil)
+	suite.expectLoginAttempts()
+	suite.repository.On("GetByUsername", mock.Anything, storedUser.Username).Return(storedUser, nil)
+	suite.tokenRepository.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("RefreshToken")).Return(nil)
+	suite.sessionRepository.On("CreateSession", mock.Anything, mock.AnythingOfType("Session")).Return(nil)
+	_, err := suite.usecase.ValidateAndGetToken(context.TODO(), storedUser, "10.0.0.1")
+
+	suite.NoError(err, "no error once the lockout has elapsed")
+	suite.loginAttempts.AssertCalled(suite.T(), "ClearLoginAttempts", mock.Anything, "account:valid_username")
+	suite.loginAttempts.AssertNotCalled(suite.T(), "ClearLoginAttempts", mock.Anything, "ip:10.0.0.1")
+}
+
+func (suite *userUsecaseSuite) TestValidateAndGetToken_ExpiredFailures() {
+	suite.expectMFARequired()
+	suite.expectNoMFA()
+	now := time.Now()
+	storedUser := domain.User{Username: "valid_username", Password: "valid_password", Role: "user", Verified: true}
+	attempts := domain.LoginAttempts{Key: "account:valid_username", Failures: 20, LastFailure: now, ExpiresAt: now.Add(-time.Second)}
+	suite.loginAttempts.On("GetLoginAttempts", mock.Anything, "account:valid_username").Return(attempts, nil)
+	suite.expectLoginAttempts()
+	suite.repository.On("GetByUsername", mock.Anything, storedUser.Username).Return(storedUser, nil)
+	suite.tokenRepository.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("RefreshToken")).Return(nil)
+	suite.sessionRepository.On("CreateSession", mock.Anything, mock.AnythingOfType("Session")).Return(nil)
+	_, err := suite.usecase.ValidateAndGetToken(context.TODO(), storedUser, "")
+
+	suite.NoError(err, "no error when the failed logins are past their window")
+}
+
+func (suite *userUsecaseSuite) TestValidateAndGetToken_IPLocked() {
+	now := time.Now()
+	attempts := domain.LoginAttempts{Key: "ip:10.0.0.1", Failures: 10, LastFailure: now, ExpiresAt: now.Add(time.Hour)}
+	suite.loginAttempts.On("GetLoginAttempts", mock.Anything, "ip:10.0.0.1").Return(attempts, nil)
+	suite.expectLoginAttempts()
+	_, err := suite.usecase.ValidateAndGetToken(context.TODO(), domain.User{Username: "other_username", Password: "valid_password"}, "10.0.0.1")
+
+	suite.Error(err, "error when the client IP is locked, whichever account it tries")
+	suite.Equal(domain.ERR_TOO_MANY_REQUESTS, err.GetCode())
+	suite.repository.AssertNotCalled(suite.T(), "GetByUsername", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestUnlockAccount_Positive() {
+	suite.repository.On("GetByUsername", mock.Anything, "valid_username").Return(domain.User{Username: "valid_username"}, nil)
+	suite.loginAttempts.On("ClearLoginAttempts", mock.Anything, "account:valid_username").Return(nil)
+	err := suite.usecase.UnlockAccount(context.TODO(), "valid_username")
+
+	suite.NoError(err, "no error when an existing account is unlocked")
+	suite.loginAttempts.AssertExpectations(suite.T())
+}
+
+func (suite *userUsecaseSuite) TestUnlockAccount_UnknownUser() {
+	suite.repository.On("GetByUsername", mock.Anything, "unknown").Return(domain.User{}, domain.UserError{Message: "User not found", Code: domain.ERR_BAD_REQUEST})
+	err := suite.usecase.UnlockAccount(context.TODO(), "unknown")
+
+	suite.Error(err, "error when the account does not exist")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+	suite.loginAttempts.AssertNotCalled(suite.T(), "ClearLoginAttempts", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestRefreshTokens_Positive() {
+	storedToken := domain.RefreshToken{TokenHash: "hash_old_refresh_token", FamilyID: "family", Username: "valid_username", ExpiresAt: time.Now().Add(time.Hour), Used: true}
+	storedUser := domain.User{Username: "valid_username", Role: "admin", Verified: true}
+
+	suite.tokenRepository.On("UseRefreshToken", mock.Anything, "hash_old_refresh_token").Return(storedToken, nil)
+	suite.sessionRepository.On("GetSession", mock.Anything, "family").Return(domain.Session{ID: "family", Username: storedUser.Username}, nil)
+	suite.repository.On("GetByUsername", mock.Anything, storedUser.Username).Return(storedUser, nil)
+	suite.tokenRepository.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("RefreshToken")).Return(nil)
+	suite.sessionRepository.On("ExtendSession", mock.Anything, "family", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time")).Return(nil)
+	tokens, err := suite.usecase.RefreshTokens(context.TODO(), "old_refresh_token")
+
+	suite.NoError(err, "no error when a valid refresh token is provided")
+	suite.sessionRepository.AssertExpectations(suite.T())
+	suite.Equal(domain.TokenPair{AccessToken: "valid_token", RefreshToken: "new_refresh_token"}, tokens)
+	suite.tokenRepository.AssertCalled(suite.T(), "CreateRefreshToken", mock.Anything, mock.MatchedBy(func(token domain.RefreshToken) bool {
+		return token.FamilyID == "family" && token.Username == storedUser.Username
+	}))
+}
+
+func (suite *userUsecaseSuite) TestRefreshTokens_Expired() {
+	storedToken := domain.RefreshToken{TokenHash: "hash_old_refresh_token", FamilyID: "family", Username: "valid_username", ExpiresAt: time.Now().Add(-time.Hour), Used: true}
+
+	suite.tokenRepository.On("UseRefreshToken", mock.Anything, "hash_old_refresh_token").Return(storedToken, nil)
+	_, err := suite.usecase.RefreshTokens(context.TODO(), "old_refresh_token")
+
+	suite.Error(err, "error when an expired refresh token is provided")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
+	suite.tokenRepository.AssertNotCalled(suite.T(), "CreateRefreshToken", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestRefreshTokens_ReuseRevokesFamily() {
+	storedToken := domain.RefreshToken{TokenHash: "hash_old_refresh_token", FamilyID: "family", Username: "valid_username", ExpiresAt: time.Now().Add(time.Hour), Used: true}
+
+	suite.tokenRepository.On("UseRefreshToken", mock.Anything, "hash_old_refresh_token").Return(domain.RefreshToken{}, domain.UserError{Message: "Refresh token not found", Code: domain.ERR_NOT_FOUND})
+	suite.tokenRepository.On("GetRefreshToken", mock.Anything, "hash_old_refresh_token").Return(storedToken, nil)
+	suite.tokenRepository.On("RevokeTokenFamily", mock.Anything, "family").Return(nil)
+	suite.sessionRepository.On("RevokeSession", mock.Anything, "family").Return(nil)
+	_, err := suite.usecase.RefreshTokens(context.TODO(), "old_refresh_token")
+
+	suite.Error(err, "error when a rotated refresh token is reused")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
+	suite.tokenRepository.AssertCalled(suite.T(), "RevokeTokenFamily", mock.Anything, "family")
+	suite.sessionRepository.AssertCalled(suite.T(), "RevokeSession", mock.Anything, "family")
+	suite.tokenRepository.AssertNotCalled(suite.T(), "CreateRefreshToken", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestRefreshTokens_Unknown() {
+	notFound := domain.UserError{Message: "Refresh token not found", Code: domain.ERR_NOT_FOUND}
+	suite.tokenRepository.On("UseRefreshToken", mock.Anything, "hash_unknown").Return(domain.RefreshToken{}, notFound)
+	suite.tokenRepository.On("GetRefreshToken", mock.Anything, "hash_unknown").Return(domain.RefreshToken{}, notFound)
+	_, err := suite.usecase.RefreshTokens(context.TODO(), "unknown")
+
+	suite.Error(err, "error when an unknown refresh token is provided")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
+	suite.tokenRepository.AssertNotCalled(suite.T(), "RevokeTokenFamily", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestLogout_Positive() {
+	storedToken := domain.RefreshToken{TokenHash: "hash_refresh_token", FamilyID: "family"}
+	suite.tokenRepository.On("GetRefreshToken", mock.Anything, "hash_refresh_token").Return(storedToken, nil)
+	suite.tokenRepository.On("RevokeTokenFamily", mock.Anything, "family").Return(nil)
+	suite.sessionRepository.On("RevokeSession", mock.Anything, "family").Return(nil)
+	err := suite.usecase.Logout(context.TODO(), "refresh_token")
+
+	suite.NoError(err, "no error when a known refresh token is provided")
+	suite.tokenRepository.AssertExpectations(suite.T())
+	suite.sessionRepository.AssertExpectations(suite.T())
+}
+
+func (suite *userUsecaseSuite) TestLogout_Unknown() {
+	suite.tokenRepository.On("GetRefreshToken", mock.Anything, "hash_unknown").Return(domain.RefreshToken{}, domain.UserError{Message: "Refresh token not found", Code: domain.ERR_NOT_FOUND})
+	err := suite.usecase.Logout(context.TODO(), "unknown")
+
+	suite.Error(err, "error when an unknown refresh token is provided")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
+}
+
+func (suite *userUsecaseSuite) TestRefreshTokens_RevokedSession() {
+	storedToken := domain.RefreshToken{TokenHash: "hash_old_refresh_token", FamilyID: "family", Username: "valid_username", ExpiresAt: time.Now().Add(time.Hour), Used: true}
+
+	suite.tokenRepository.On("UseRefreshToken", mock.Anything, "hash_old_refresh_token").Return(storedToken, nil)
+	suite.sessionRepository.On("GetSession", mock.Anything, "family").Return(domain.Session{ID: "family", Revoked: true}, nil)
+	_, err := suite.usecase.RefreshTokens(context.TODO(), "old_refresh_token")
+
+	suite.Error(err, "error when the session of the refresh token has been revoked")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
+	suite.tokenRepository.AssertNotCalled(suite.T(), "CreateRefreshToken", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestGetSessions() {
+	subject := domain.Subject{Username: "valid_username", Role: "user", SessionID: "session_2"}
+	sessions := []domain.Session{{ID: "session_1", Username: subject.Username}, {ID: "session_2", Username: subject.Username}}
+	suite.sessionRepository.On("GetActiveSessions", mock.Anything, subject.Username).Return(sessions, nil)
+	result, err := suite.usecase.GetSessions(context.TODO(), subject)
+
+	suite.NoError(err, "no error when listing the sessions")
+	suite.Len(result, 2)
+	suite.False(result[0].Current)
+	suite.True(result[1].Current, "the session of the request is marked as current")
+}
+
+func (suite *userUsecaseSuite) TestRevokeSession_Positive() {
+	subject := domain.Subject{Username: "valid_username", Role: "user", SessionID: "session_1"}
+	suite.sessionRepository.On("GetSession", mock.Anything, "session_2").Return(domain.Session{ID: "session_2", Username: subject.Username}, nil)
+	suite.sessionRepository.On("RevokeSession", mock.Anything, "session_2").Return(nil)
+	suite.tokenRepository.On("RevokeTokenFamily", mock.Anything, "session_2").Return(nil)
+	err := suite.usecase.RevokeSession(context.TODO(), subject, "session_2")
+
+	suite.NoError(err, "no error when revoking an own session")
+	suite.sessionRepository.AssertExpectations(suite.T())
+	suite.tokenRepository.AssertExpectations(suite.T())
+}
+
+func (suite *userUsecaseSuite) TestRevokeSession_OtherUser() {
+	subject := domain.Subject{Username: "valid_username", Role: "admin", SessionID: "session_1"}
+	suite.sessionRepository.On("GetSession", mock.Anything, "session_2").Return(domain.Session{ID: "session_2", Username: "other_username"}, nil)
+	err := suite.usecase.RevokeSession(context.TODO(), subject, "session_2")
+
+	suite.Error(err, "error when revoking the session of another user")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+	suite.sessionRepository.AssertNotCalled(suite.T(), "RevokeSession", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestRevokeUserSessions() {
+	suite.repository.On("GetByUsername", mock.Anything, "valid_username").Return(domain.User{Username: "valid_username"}, nil)
+	suite.sessionRepository.On("RevokeUserSessions", mock.Anything, "valid_username").Return(nil)
+	suite.tokenRepository.On("RevokeUserTokens", mock.Anything, "valid_username").Return(nil)
+	err := suite.usecase.RevokeUserSessions(context.TODO(), "valid_username")
+
+	suite.NoError(err, "no error when revoking the sessions of an existing user")
+	suite.sessionRepository.AssertExpectations(suite.T())
+	suite.tokenRepository.AssertExpectations(suite.T())
+}
+
+func (suite *userUsecaseSuite) TestValidateSession() {
+	suite.sessionRepository.On("GetSession", mock.Anything, "active").Return(domain.Session{ID: "active"}, nil)
+	suite.sessionRepository.On("GetSession", mock.Anything, "revoked").Return(domain.Session{ID: "revoked", Revoked: true}, nil)
+	suite.sessionRepository.On("GetSession", mock.Anything, "unknown").Return(domain.Session{}, domain.UserError{Message: "Session not found", Code: domain.ERR_NOT_FOUND})
+
+	suite.NoError(suite.usecase.ValidateSession(context.TODO(), "active"), "no error when the session is active")
+
+	err := suite.usecase.ValidateSession(context.TODO(), "revoked")
+	suite.Error(err, "error when the session has been revoked")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
+
+	err = suite.usecase.ValidateSession(context.TODO(), "unknown")
+	suite.Error(err, "error when the session does not exist")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
 }
 
 func (suite *userUsecaseSuite) TestPromote_Positive() {
 	username := "solitary_confinment"
 	suite.repository.On("PromoteUser", mock.Anything, username).Return(nil)
-	err := suite.usecase.Promote(context.TODO(), username)
+	err := suite.usecase.Promote(context.TODO(), adminSubject, username)
 
 	suite.NoError(err, "no error when the username is passed")
 	suite.repository.AssertExpectations(suite.T())
 }
 
+func (suite *userUsecaseSuite) TestPromote_Escalation() {
+	promoter := domain.Subject{Username: "promoter", Role: "promoter", Permissions: []string{domain.PermissionUserPromote}}
+	for _, username := range []string{"solitary_confinment", "promoter"} {
+		err := suite.usecase.Promote(context.TODO(), promoter, username)
+		suite.Error(err, "error when a subject that is not an admin promotes '%v'", username)
+		suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+	}
+
+	suite.repository.AssertNotCalled(suite.T(), "PromoteUser", mock.Anything, mock.Anything)
+}
+
 func (suite *userUsecaseSuite) TestPromote_Negative() {
 	username := "solitary_confinment"
 	sampleErr := domain.TaskError{Message: "msg123", Code: domain.ERR_INTERNAL_SERVER}
 	suite.repository.On("PromoteUser", mock.Anything, username).Return(sampleErr)
-	err := suite.usecase.Promote(context.TODO(), username)
+	err := suite.usecase.Promote(context.TODO(), adminSubject, username)
 
 	suite.Error(err, "error when the username is passed")
 	suite.Equal(sampleErr.Error(), err.Error())
@@ -265,8 +783,982 @@ func (suite *userUsecaseSuite) TestPromote_Negative() {
 	suite.repository.AssertExpectations(suite.T())
 }
 
+func (suite *userUsecaseSuite) TestValidateAndGetToken_Unverified() {
+	suite.expectMFARequired()
+	suite.expectNoMFA()
+	suite.expectLoginAttempts()
+	storedUser := domain.User{Username: "valid_username", Password: "valid_password", Role: "user", CreatedAt: time.Now().Add(-time.Hour)}
+	suite.repository.On("GetByUsername", mock.Anything, storedUser.Username).Return(storedUser, nil)
+	suite.tokenRepository.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("RefreshToken")).Return(nil)
+	suite.sessionRepository.On("CreateSession", mock.Anything, mock.AnythingOfType("Session")).Return(nil)
+	defer viper.Set("EMAIL_VERIFICATION", domain.VerificationRequired)
+
+	viper.Set("EMAIL_VERIFICATION", domain.VerificationRequired)
+	_, err := suite.usecase.ValidateAndGetToken(context.TODO(), storedUser, "")
+	suite.Error(err, "error when an unverified user logs in and the verification is required")
+	suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+	suite.sessionRepository.AssertNotCalled(suite.T(), "CreateSession", mock.Anything, mock.Anything)
+
+	viper.Set("EMAIL_VERIFICATION", domain.VerificationGrace)
+	viper.Set("EMAIL_VERIFICATION_GRACE_HOURS", 2)
+	_, err = suite.usecase.ValidateAndGetToken(context.TODO(), storedUser, "")
+	suite.NoError(err, "no error when an unverified user logs in within the grace period")
+
+	viper.Set("EMAIL_VERIFICATION_GRACE_HOURS", 1)
+	_, err = suite.usecase.ValidateAndGetToken(context.TODO(), storedUser, "")
+	suite.Error(err, "error when an unverified user logs in after the grace period")
+	suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+
+	viper.Set("EMAIL_VERIFICATION", domain.VerificationOptional)
+	_, err = suite.usecase.ValidateAndGetToken(context.TODO(), storedUser, "")
+	suite.NoError(err, "no error when an unverified user logs in and the verification is optional")
+
+	viper.Set("EMAIL_VERIFICATION", domain.VerificationOff)
+	_, err = suite.usecase.ValidateAndGetToken(context.TODO(), storedUser, "")
+	suite.NoError(err, "no error when an unverified user logs in and the verification is off")
+}
+
+func (suite *userUsecaseSuite) TestValidateAndGetToken_UnverifiedWrongPassword() {
+	suite.expectLoginAttempts()
+	storedUser := domain.User{Username: "valid_username", Password: "incorrect_password", Role: "user"}
+	suite.repository.On("GetByUsername", mock.Anything, storedUser.Username).Return(storedUser, nil)
+	_, err := suite.usecase.ValidateAndGetToken(context.TODO(), storedUser, "")
+
+	suite.Error(err, "error when the password is incorrect")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode(), "the verification status is not revealed without valid credentials")
+}
+
+func (suite *userUsecaseSuite) TestVerifyEmail_Positive() {
+	storedToken := domain.EmailVerificationToken{TokenHash: "hash_verification_token", Username: "valid_username", ExpiresAt: time.Now().Add(time.Hour), Used: true}
+	suite.verification.On("UseVerificationToken", mock.Anything, "hash_verification_token").Return(storedToken, nil)
+	suite.repository.On("VerifyEmail", mock.Anything, "valid_username").Return(nil)
+	suite.verification.On("InvalidateUserVerificationTokens", mock.Anything, "valid_username").Return(nil)
+	err := suite.usecase.VerifyEmail(context.TODO(), "verification_token")
+
+	suite.NoError(err, "no error when a valid verification token is provided")
+	suite.repository.AssertExpectations(suite.T())
+	suite.verification.AssertExpectations(suite.T())
+}
+
+func (suite *userUsecaseSuite) TestVerifyEmail_Negative() {
+	expiredToken := domain.EmailVerificationToken{TokenHash: "hash_expired", Username: "valid_username", ExpiresAt: time.Now().Add(-time.Minute), Used: true}
+	suite.verification.On("UseVerificationToken", mock.Anything, "hash_expired").Return(expiredToken, nil)
+	suite.verification.On("UseVerificationToken", mock.Anything, "hash_unknown").Return(domain.EmailVerificationToken{}, domain.UserError{Message: "Email verification token not found", Code: domain.ERR_NOT_FOUND})
+
+	err := suite.usecase.VerifyEmail(context.TODO(), "expired")
+	suite.Error(err, "error when an expired verification token is provided")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
+
+	err = suite.usecase.VerifyEmail(context.TODO(), "unknown")
+	suite.Error(err, "error when an unknown or used verification token is provided")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
+	suite.repository.AssertNotCalled(suite.T(), "VerifyEmail", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestResendVerification() {
+	suite.repository.On("GetByEmail", mock.Anything, "unverified@mail.com").Return(domain.User{Username: "unverified", Email: "unverified@mail.com"}, nil)
+	suite.repository.On("GetByEmail", mock.Anything, "verified@mail.com").Return(domain.User{Username: "verified", Email: "verified@mail.com", Verified: true}, nil)
+	suite.repository.On("GetByEmail", mock.Anything, "unknown@mail.com").Return(domain.User{}, domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND})
+	suite.expectVerification()
+
+	suite.NoError(suite.usecase.ResendVerification(context.TODO(), "verified@mail.com"), "no error when the account is already verified")
+	suite.NoError(suite.usecase.ResendVerification(context.TODO(), "unknown@mail.com"), "no error when the email is not registered")
+	suite.notifier.AssertNotCalled(suite.T(), "Notify", mock.Anything, mock.Anything)
+
+	suite.NoError(suite.usecase.ResendVerification(context.TODO(), " Unverified@mail.com"), "no error when the account is unverified")
+	suite.verification.AssertCalled(suite.T(), "InvalidateUserVerificationTokens", mock.Anything, "unverified")
+	suite.notifier.AssertNumberOfCalls(suite.T(), "Notify", 1)
+}
+
+func (suite *userUsecaseSuite) TestForgotPassword_Positive() {
+	storedUser := domain.User{Username: "valid_username", Email: "valid@mail.com"}
+	suite.repository.On("GetByEmail", mock.Anything, "valid@mail.com").Return(storedUser, nil)
+	suite.resetRepository.On("InvalidateUserResetTokens", mock.Anything, "valid_username").Return(nil)
+	suite.resetRepository.On("CreateResetToken", mock.Anything, mock.AnythingOfType("PasswordResetToken")).Return(nil)
+	suite.notifier.On("Notify", mock.Anything, mock.AnythingOfType("Notification")).Return(nil)
+	err := suite.usecase.ForgotPassword(context.TODO(), "  VALID@mail.com ")
+
+	suite.NoError(err, "no error when the email is registered")
+	suite.resetRepository.AssertExpectations(suite.T())
+	suite.resetRepository.AssertCalled(suite.T(), "CreateResetToken", mock.Anything, mock.MatchedBy(func(token domain.PasswordResetToken) bool {
+		return token.TokenHash == "hash_new_refresh_token" && token.Username == "valid_username" && token.ExpiresAt.After(time.Now())
+	}))
+	suite.notifier.AssertCalled(suite.T(), "Notify", mock.Anything, mock.MatchedBy(func(notification domain.Notification) bool {
+		return notification.Recipient == "valid@mail.com" && strings.Contains(notification.Body, "new_refresh_token")
+	}))
+}
+
+func (suite *userUsecaseSuite) TestForgotPassword_UnknownEmail() {
+	suite.repository.On("GetByEmail", mock.Anything, "unknown@mail.com").Return(domain.User{}, domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND})
+	err := suite.usecase.ForgotPassword(context.TODO(), "unknown@mail.com")
+
+	suite.NoError(err, "no error when the email is not registered")
+	suite.resetRepository.AssertNotCalled(suite.T(), "CreateResetToken", mock.Anything, mock.Anything)
+	suite.notifier.AssertNotCalled(suite.T(), "Notify", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestResetPassword_Positive() {
+	storedToken := domain.PasswordResetToken{TokenHash: "hash_reset_token", Username: "valid_username", ExpiresAt: time.Now().Add(time.Hour), Used: true}
+	suite.resetRepository.On("UseResetToken", mock.Anything, "hash_reset_token").Return(storedToken, nil)
+	suite.repository.On("UpdatePassword", mock.Anything, "valid_username", "new_password").Return(nil)
+	suite.resetRepository.On("InvalidateUserResetTokens", mock.Anything, "valid_username").Return(nil)
+	suite.tokenRepository.On("RevokeUserTokens", mock.Anything, "valid_username").Return(nil)
+	suite.sessionRepository.On("RevokeUserSessions", mock.Anything, "valid_username").Return(nil)
+	suite.loginAttempts.On("ClearLoginAttempts", mock.Anything, "account:valid_username").Return(nil)
+	err := suite.usecase.ResetPassword(context.TODO(), "reset_token", "new_password")
+
+	suite.NoError(err, "no error when a valid reset token is provided")
+	suite.loginAttempts.AssertExpectations(suite.T())
+	suite.repository.AssertExpectations(suite.T())
+	suite.resetRepository.AssertExpectations(suite.T())
+	suite.tokenRepository.AssertExpectations(suite.T())
+	suite.sessionRepository.AssertExpectations(suite.T())
+}
+
+func (suite *userUsecaseSuite) TestResetPassword_InvalidToken() {
+	suite.resetRepository.On("UseResetToken", mock.Anything, "hash_unknown").Return(domain.PasswordResetToken{}, domain.UserError{Message: "Password reset token not found", Code: domain.ERR_NOT_FOUND})
+	err := suite.usecase.ResetPassword(context.TODO(), "unknown", "new_password")
+
+	suite.Error(err, "error when an unknown or used reset token is provided")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
+	suite.repository.AssertNotCalled(suite.T(), "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestResetPassword_ExpiredToken() {
+	storedToken := domain.PasswordResetToken{TokenHash: "hash_reset_token", Username: "valid_username", ExpiresAt: time.Now().Add(-time.Minute), Used: true}
+	suite.resetRepository.On("UseResetToken", mock.Anything, "hash_reset_token").Return(storedToken, nil)
+	err := suite.usecase.ResetPassword(context.TODO(), "reset_token", "new_password")
+
+	suite.Error(err, "error when an expired reset token is provided")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
+	suite.repository.AssertNotCalled(suite.T(), "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestResetPassword_PasswordValidation() {
+	err := suite.usecase.ResetPassword(context.TODO(), "reset_token", "short")
+
+	suite.Error(err, "error when the new password is too short")
+	suite.Equal(domain.ERR_BAD_REQUEST, err.GetCode())
+	suite.resetRepository.AssertNotCalled(suite.T(), "UseResetToken", mock.Anything, mock.Anything)
+}
+
+// expects every session and refresh token of the user to be revoked
+func (suite *userUsecaseSuite) expectSessionRevocation(username string) {
+	suite.tokenRepository.On("RevokeUserTokens", mock.Anything, username).Return(nil)
+	suite.sessionRepository.On("RevokeUserSessions", mock.Anything, username).Return(nil)
+}
+
+// expects the account with the provided username to exist with the provided role
+func (suite *userUsecaseSuite) expectTarget(username string, role string) {
+	suite.repository.On("GetByUsername", mock.Anything, username).Return(domain.User{Username: username, Role: role, Verified: true}, nil)
+}
+
+// expects the account with the provided username not to exist
+func (suite *userUsecaseSuite) expectUnknownTarget(username string) {
+	suite.repository.On("GetByUsername", mock.Anything, username).Return(domain.User{}, domain.UserError{Message: "User not found", Code: domain.ERR_BAD_REQUEST})
+}
+
+func (suite *userUsecaseSuite) TestDemote_Positive() {
+	suite.expectRoles()
+	suite.expectTarget("valid_username", domain.RoleAdmin)
+	suite.repository.On("DemoteUser", mock.Anything, "valid_username").Return(nil)
+	suite.expectSessionRevocation("valid_username")
+	err := suite.usecase.Demote(context.TODO(), domain.Subject{Username: "admin_username", Role: "admin", Permissions: domain.Permissions}, "valid_username")
+
+	suite.NoError(err, "no error when demoting another user")
+	suite.repository.AssertExpectations(suite.T())
+	suite.tokenRepository.AssertExpectations(suite.T())
+	suite.sessionRepository.AssertExpectations(suite.T())
+}
+
+func (suite *userUsecaseSuite) TestDemote_Negative() {
+	err := suite.usecase.Demote(context.TODO(), domain.Subject{Username: "admin_username", Role: "admin", Permissions: domain.Permissions}, "admin_username")
+	suite.Error(err, "error when an admin demotes themselves")
+	suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+	suite.repository.AssertNotCalled(suite.T(), "DemoteUser", mock.Anything, mock.Anything)
+
+	suite.expectUnknownTarget("unknown")
+	err = suite.usecase.Demote(context.TODO(), domain.Subject{Username: "admin_username", Role: "admin", Permissions: domain.Permissions}, "unknown")
+	suite.Error(err, "error when the user does not exist")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+	suite.repository.AssertNotCalled(suite.T(), "DemoteUser", mock.Anything, mock.Anything)
+	suite.sessionRepository.AssertNotCalled(suite.T(), "RevokeUserSessions", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestSetRole_Positive() {
+	suite.expectRoles()
+	suite.expectTarget("valid_username", domain.RoleUser)
+	suite.repository.On("SetRole", mock.Anything, "valid_username", "manager").Return(nil)
+	suite.expectSessionRevocation("valid_username")
+	err := suite.usecase.SetRole(context.TODO(), domain.Subject{Username: "admin_username", Role: "admin", Permissions: domain.Permissions}, "valid_username", " Manager ")
+
+	suite.NoError(err, "no error when assigning an existing role to another user")
+	suite.repository.AssertExpectations(suite.T())
+	suite.sessionRepository.AssertExpectations(suite.T())
+}
+
+func (suite *userUsecaseSuite) TestSetRole_Negative() {
+	suite.expectRoles()
+	err := suite.usecase.SetRole(context.TODO(), domain.Subject{Username: "admin_username", Role: "admin", Permissions: domain.Permissions}, "admin_username", "user")
+	suite.Error(err, "error when an admin changes their own role")
+	suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+
+	suite.expectTarget("valid_username", domain.RoleUser)
+	err = suite.usecase.SetRole(context.TODO(), domain.Subject{Username: "admin_username", Role: "admin", Permissions: domain.Permissions}, "valid_username", "viewer")
+	suite.Error(err, "error when the role does not exist")
+	suite.Equal(domain.ERR_BAD_REQUEST, err.GetCode())
+	suite.repository.AssertNotCalled(suite.T(), "SetRole", mock.Anything, mock.Anything, mock.Anything)
+
+	suite.expectUnknownTarget("unknown")
+	err = suite.usecase.SetRole(context.TODO(), domain.Subject{Username: "admin_username", Role: "admin", Permissions: domain.Permissions}, "unknown", "user")
+	suite.Error(err, "error when the user does not exist")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+	suite.sessionRepository.AssertNotCalled(suite.T(), "RevokeUserSessions", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestSetRole_Escalation() {
+	suite.expectRoles()
+	suite.expectTarget("valid_username", "viewer")
+	suite.expectTarget("role_assigner", "assigner")
+	assigner := domain.Subject{Username: "role_assigner", Role: "assigner", Permissions: []string{domain.PermissionUserAssignRole, domain.PermissionTaskRead}}
+	for _, role := range []string{domain.RoleAdmin, "manager", domain.RoleUser} {
+		err := suite.usecase.SetRole(context.TODO(), assigner, "valid_username", role)
+		suite.Error(err, "error when the role grants permissions the subject does not hold")
+		suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+	}
+
+	err := suite.usecase.SetRole(context.TODO(), assigner, "role_assigner", domain.RoleAdmin)
+	suite.Error(err, "error when the subject makes themselves an admin")
+	suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+	suite.repository.AssertNotCalled(suite.T(), "SetRole", mock.Anything, mock.Anything, mock.Anything)
+
+	assigner.Permissions = append(assigner.Permissions, domain.PermissionTaskReadAny)
+	suite.repository.On("SetRole", mock.Anything, "valid_username", "manager").Return(nil)
+	suite.expectSessionRevocation("valid_username")
+	err = suite.usecase.SetRole(context.TODO(), assigner, "valid_username", "manager")
+	suite.NoError(err, "no error when the subject holds every permission of the role")
+}
+
+func (suite *userUsecaseSuite) TestDeactivate_Positive() {
+	suite.expectRoles()
+	suite.expectTarget("valid_username", domain.RoleUser)
+	suite.repository.On("SetDeactivated", mock.Anything, "valid_username", true).Return(nil)
+	suite.expectSessionRevocation("valid_username")
+	err := suite.usecase.Deactivate(context.TODO(), domain.Subject{Username: "admin_username", Role: "admin", Permissions: domain.Permissions}, "valid_username")
+
+	suite.NoError(err, "no error when deactivating another user")
+	suite.repository.AssertExpectations(suite.T())
+	suite.tokenRepository.AssertExpectations(suite.T())
+	suite.sessionRepository.AssertExpectations(suite.T())
+}
+
+func (suite *userUsecaseSuite) TestDeactivate_Negative() {
+	err := suite.usecase.Deactivate(context.TODO(), domain.Subject{Username: "admin_username", Role: "admin", Permissions: domain.Permissions}, "admin_username")
+	suite.Error(err, "error when an admin deactivates themselves")
+	suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+
+	suite.expectUnknownTarget("unknown")
+	err = suite.usecase.Deactivate(context.TODO(), domain.Subject{Username: "admin_username", Role: "admin", Permissions: domain.Permissions}, "unknown")
+	suite.Error(err, "error when the user does not exist")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+	suite.repository.AssertNotCalled(suite.T(), "SetDeactivated", mock.Anything, mock.Anything, mock.Anything)
+	suite.sessionRepository.AssertNotCalled(suite.T(), "RevokeUserSessions", mock.Anything, mock.Anything)
+}
+
+// Tests that an admin can not be managed by a role that lacks some of the admin permissions
+func (suite *userUsecaseSuite) TestManageAccount_Escalation() {
+	suite.expectRoles()
+	suite.expectTarget("admin_username", domain.RoleAdmin)
+	suite.expectTarget("manager_username", "manager")
+	moderator := domain.Subject{Username: "moderator", Role: "moderator", Permissions: append(slices.Clone(domain.DefaultUserPermissions), domain.PermissionUserDemote, domain.PermissionUserAssignRole, domain.PermissionUserDeactivate, domain.PermissionUserDelete)}
+	operations := map[string]func(username string) domain.CodedError{
+		"demote": func(username string) domain.CodedError {
+			return suite.usecase.Demote(context.TODO(), moderator, username)
+		},
+		"set role": func(username string) domain.CodedError {
+			return suite.usecase.SetRole(context.TODO(), moderator, username, domain.RoleUser)
+		},
+		"deactivate": func(username string) domain.CodedError {
+			return suite.usecase.Deactivate(context.TODO(), moderator, username)
+		},
+		"delete": func(username string) domain.CodedError {
+			_, err := suite.usecase.DeleteUser(context.TODO(), moderator, username)
+			return err
+		},
+	}
+
+	for operation, run := range operations {
+		for _, username := range []string{"admin_username", "manager_username"} {
+			err := run(username)
+			suite.Error(err, "error when the subject can %v %v", operation, username)
+			suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+		}
+	}
+
+	suite.repository.AssertNotCalled(suite.T(), "DemoteUser", mock.Anything, mock.Anything)
+	suite.repository.AssertNotCalled(suite.T(), "SetRole", mock.Anything, mock.Anything, mock.Anything)
+	suite.repository.AssertNotCalled(suite.T(), "SetDeactivated", mock.Anything, mock.Anything, mock.Anything)
+	suite.repository.AssertNotCalled(suite.T(), "DeleteUser", mock.Anything, mock.Anything)
+	suite.sessionRepository.AssertNotCalled(suite.T(), "RevokeUserSessions", mock.Anything, mock.Anything)
+
+	suite.expectTarget("valid_username", domain.RoleUser)
+	suite.repository.On("SetDeactivated", mock.Anything, "valid_username", true).Return(nil)
+	suite.expectSessionRevocation("valid_username")
+	err := suite.usecase.Deactivate(context.TODO(), moderator, "valid_username")
+	suite.NoError(err, "no error when the subject holds every permission of the role of the user")
+}
+
+func (suite *userUsecaseSuite) TestActivate() {
+	suite.repository.On("SetDeactivated", mock.Anything, "valid_username", false).Return(nil)
+	err := suite.usecase.Activate(context.TODO(), "valid_username")
+
+	suite.NoError(err, "no error when activating a user")
+	suite.repository.AssertExpectations(suite.T())
+}
+
+func (suite *userUsecaseSuite) TestDeleteUser_Positive() {
+	suite.expectNoMFA()
+	suite.expectRoles()
+	suite.expectTarget("valid_username", domain.RoleUser)
+	suite.repository.On("SetDeactivated", mock.Anything, "valid_username", true).Return(nil)
+	suite.expectSessionRevocation("valid_username")
+	suite.taskRepository.On("DeleteOwnerTasks", mock.Anything, "valid_username").Return(int64(3), nil)
+	suite.taskRepository.On("UnassignTasks", mock.Anything, "valid_username", mock.AnythingOfType("time.Time")).Return(int64(2), nil)
+	suite.resetRepository.On("InvalidateUserResetTokens", mock.Anything, "valid_username").Return(nil)
+	suite.verification.On("InvalidateUserVerificationTokens", mock.Anything, "valid_username").Return(nil)
+	suite.apiKeys.On("DeleteUserAPIKeys", mock.Anything, "valid_username").Return(nil)
+	suite.repository.On("DeleteUser", mock.Anything, "valid_username").Return(nil)
+	deletion, err := suite.usecase.DeleteUser(context.TODO(), domain.Subject{Username: "admin_username", Role: "admin", Permissions: domain.Permissions}, "valid_username")
+
+	suite.NoError(err, "no error when deleting another user")
+	suite.Equal(domain.UserDeletion{DeletedTasks: 3, UnassignedTasks: 2}, deletion)
+	suite.repository.AssertExpectations(suite.T())
+	suite.taskRepository.AssertExpectations(suite.T())
+	suite.sessionRepository.AssertExpectations(suite.T())
+	suite.resetRepository.AssertExpectations(suite.T())
+	suite.verification.AssertExpectations(suite.T())
+	suite.mfa.AssertCalled(suite.T(), "DeleteMFA", mock.Anything, "valid_username")
+	suite.apiKeys.AssertExpectations(suite.T())
+}
+
+func (suite *userUsecaseSuite) TestDeleteUser_Negative() {
+	_, err := suite.usecase.DeleteUser(context.TODO(), domain.Subject{Username: "admin_username", Role: "admin", Permissions: domain.Permissions}, "admin_username")
+	suite.Error(err, "error when an admin deletes themselves")
+	suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+
+	suite.expectUnknownTarget("unknown")
+	_, err = suite.usecase.DeleteUser(context.TODO(), domain.Subject{Username: "admin_username", Role: "admin", Permissions: domain.Permissions}, "unknown")
+	suite.Error(err, "error when the user does not exist")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+	suite.taskRepository.AssertNotCalled(suite.T(), "DeleteOwnerTasks", mock.Anything, mock.Anything)
+	suite.repository.AssertNotCalled(suite.T(), "DeleteUser", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestValidateAndGetToken_Deactivated() {
+	suite.expectLoginAttempts()
+	storedUser := domain.User{Username: "valid_username", Password: "valid_password", Role: "user", Verified: true, Deactivated: true}
+	suite.repository.On("GetByUsername", mock.Anything, storedUser.Username).Return(storedUser, nil)
+	_, err := suite.usecase.ValidateAndGetToken(context.TODO(), storedUser, "")
+
+	suite.Error(err, "error when a deactivated user logs in")
+	suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+	suite.sessionRepository.AssertNotCalled(suite.T(), "CreateSession", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestRefreshTokens_Deactivated() {
+	storedToken := domain.RefreshToken{TokenHash: "hash_old_refresh_token", FamilyID: "family", Username: "valid_username", ExpiresAt: time.Now().Add(time.Hour), Used: true}
+	suite.tokenRepository.On("UseRefreshToken", mock.Anything, "hash_old_refresh_token").Return(storedToken, nil)
+	suite.sessionRepository.On("GetSession", mock.Anything, "family").Return(domain.Session{ID: "family", Username: "valid_username"}, nil)
+	suite.repository.On("GetByUsername", mock.Anything, "valid_username").Return(domain.User{Username: "valid_username", Role: "user", Verified: true, Deactivated: true}, nil)
+	_, err := suite.usecase.RefreshTokens(context.TODO(), "old_refresh_token")
+
+	suite.Error(err, "error when the account has been deactivated")
+	suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+	suite.tokenRepository.AssertNotCalled(suite.T(), "CreateRefreshToken", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestForgotPassword_Deactivated() {
+	suite.repository.On("GetByEmail", mock.Anything, "valid@mail.com").Return(domain.User{Username: "valid_username", Email: "valid@mail.com", Deactivated: true}, nil)
+	err := suite.usecase.ForgotPassword(context.TODO(), "valid@mail.com")
+
+	suite.NoError(err, "no error when the account has been deactivated")
+	suite.resetRepository.AssertNotCalled(suite.T(), "CreateResetToken", mock.Anything, mock.Anything)
+	suite.notifier.AssertNotCalled(suite.T(), "Notify", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestGetUsers_Positive() {
+	users := []domain.User{{Username: "admin_username", Email: "admin@mail.com", Password: "hash", Role: "admin"}}
+	suite.repository.On("GetUsers", mock.Anything, domain.UserQuery{Search: "mail", Role: "admin", SortBy: "username", SortOrder: domain.SortAscending, Limit: domain.DefaultPageLimit}).Return(users, int64(1), nil)
+	page, err := suite.usecase.GetUsers(context.TODO(), domain.UserQuery{Search: " mail ", Role: " ADMIN "})
+
+	suite.NoError(err, "no error when the query is valid")
+	suite.Equal(domain.UserPage{Users: []domain.UserProfile{users[0].Profile()}, Total: 1, Limit: domain.DefaultPageLimit}, page)
+	suite.repository.AssertExpectations(suite.T())
+}
+
+func (suite *userUsecaseSuite) TestGetUsers_CustomRole() {
+	suite.expectRoles()
+	suite.repository.On("GetUsers", mock.Anything, domain.UserQuery{Role: "manager", SortBy: "username", SortOrder: domain.SortAscending, Limit: domain.DefaultPageLimit}).Return([]domain.User{}, int64(0), nil)
+	_, err := suite.usecase.GetUsers(context.TODO(), domain.UserQuery{Role: "manager"})
+
+	suite.NoError(err, "no error when filtering by a custom role")
+	suite.repository.AssertExpectations(suite.T())
+}
+
+func (suite *userUsecaseSuite) TestGetUsers_Negative() {
+	suite.expectRoles()
+	invalidQueries := []domain.UserQuery{
+		{Limit: domain.MaxPageLimit + 1},
+		{Offset: -1},
+		{Role: "superuser"},
+		{SortBy: "password"},
+		{SortOrder: "sideways"},
+	}
+
+	for _, query := range invalidQueries {
+		_, err := suite.usecase.GetUsers(context.TODO(), query)
+		suite.Error(err, "error when the query is invalid")
+		suite.Equal(domain.ERR_BAD_REQUEST, err.GetCode())
+	}
+
+	suite.repository.AssertNotCalled(suite.T(), "GetUsers", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestGetUser() {
+	suite.repository.On("GetByUsername", mock.Anything, "valid_username").Return(domain.User{Username: "valid_username", Password: "hash"}, nil)
+	suite.repository.On("GetByUsername", mock.Anything, "unknown").Return(domain.User{}, domain.UserError{Message: "User not found", Code: domain.ERR_BAD_REQUEST})
+
+	profile, err := suite.usecase.GetUser(context.TODO(), "valid_username")
+	suite.NoError(err, "no error when the user exists")
+	suite.Equal(domain.UserProfile{Username: "valid_username"}, profile)
+
+	_, err = suite.usecase.GetUser(context.TODO(), "unknown")
+	suite.Error(err, "error when the user does not exist")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+}
+
+func (suite *userUsecaseSuite) TestUpdateProfile_DisplayName() {
+	suite.expectLoginAttempts()
+	storedUser := domain.User{Username: "valid_username", Email: "valid@mail.com", Password: "valid_password", Role: "user", Verified: true}
+	displayName := "  Valid User "
+	suite.repository.On("GetByUsername", mock.Anything, "valid_username").Return(storedUser, nil)
+	suite.repository.On("UpdateProfile", mock.Anything, mock.AnythingOfType("User")).Return(nil)
+	profile, err := suite.usecase.UpdateProfile(context.TODO(), domain.Subject{Username: "valid_username"}, "valid_password", domain.ProfileUpdate{DisplayName: &displayName})
+
+	suite.NoError(err, "no error when changing the display name")
+	suite.Equal("Valid User", profile.DisplayName)
+	suite.True(profile.Verified, "the account stays verified when the email is unchanged")
+	suite.notifier.AssertNotCalled(suite.T(), "Notify", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestUpdateProfile_Email() {
+	suite.expectLoginAttempts()
+	storedUser := domain.User{Username: "valid_username", Email: "valid@mail.com", Password: "valid_password", Role: "user", Verified: true}
+	email := " NEW@mail.com"
+	suite.repository.On("GetByUsername", mock.Anything, "valid_username").Return(storedUser, nil)
+	suite.repository.On("UpdateProfile", mock.Anything, mock.AnythingOfType("User")).Return(nil)
+	suite.expectVerification()
+	profile, err := suite.usecase.UpdateProfile(context.TODO(), domain.Subject{Username: "valid_username"}, "valid_password", domain.ProfileUpdate{Email: &email})
+
+	suite.NoError(err, "no error when changing the email")
+	suite.Equal("new@mail.com", profile.Email)
+	suite.False(profile.Verified, "the new email has to be verified")
+	suite.repository.AssertCalled(suite.T(), "UpdateProfile", mock.Anything, mock.MatchedBy(func(user domain.User) bool {
+		return user.Email == "new@mail.com" && !user.Verified && user.Password == "valid_password"
+	}))
+	suite.notifier.AssertCalled(suite.T(), "Notify", mock.Anything, mock.MatchedBy(func(notification domain.Notification) bool {
+		return notification.Recipient == "valid@mail.com" && strings.Contains(notification.Body, "new@mail.com")
+	}))
+	suite.notifier.AssertCalled(suite.T(), "Notify", mock.Anything, mock.MatchedBy(func(notification domain.Notification) bool {
+		return notification.Recipient == "new@mail.com" && strings.Contains(notification.Body, "new_refresh_token")
+	}))
+}
+
+func (suite *userUsecaseSuite) TestUpdateProfile_Negative() {
+	suite.expectLoginAttempts()
+	storedUser := domain.User{Username: "valid_username", Email: "valid@mail.com", Password: "valid_password", Role: "user"}
+	suite.repository.On("GetByUsername", mock.Anything, "valid_username").Return(storedUser, nil)
+	suite.repository.On("GetByUsername", mock.Anything, "other_username").Return(domain.User{Username: "other_username", Password: "incorrect_password"}, nil)
+	invalidEmail := "invalid"
+	longName := strings.Repeat("n", 65)
+
+	_, err := suite.usecase.UpdateProfile(context.TODO(), domain.Subject{Username: "other_username"}, "incorrect_password", domain.ProfileUpdate{})
+	suite.Error(err, "error when the current password is incorrect")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
+
+	_, err = suite.usecase.UpdateProfile(context.TODO(), domain.Subject{Username: "valid_username"}, "valid_password", domain.ProfileUpdate{Email: &invalidEmail})
+	suite.Error(err, "error when the new email is invalid")
+	suite.Equal(domain.ERR_BAD_REQUEST, err.GetCode())
+
+	_, err = suite.usecase.UpdateProfile(context.TODO(), domain.Subject{Username: "valid_username"}, "valid_password", domain.ProfileUpdate{DisplayName: &longName})
+	suite.Error(err, "error when the display name is too long")
+	suite.Equal(domain.ERR_BAD_REQUEST, err.GetCode())
+
+	suite.repository.AssertNotCalled(suite.T(), "UpdateProfile", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestChangePassword_Positive() {
+	suite.expectLoginAttempts()
+	storedUser := domain.User{Username: "valid_username", Password: "valid_password", Role: "user"}
+	sessions := []domain.Session{{ID: "current"}, {ID: "other"}}
+	suite.repository.On("GetByUsername", mock.Anything, "valid_username").Return(storedUser, nil)
+	suite.repository.On("UpdatePassword", mock.Anything, "valid_username", "new_password").Return(nil)
+	suite.resetRepository.On("InvalidateUserResetTokens", mock.Anything, "valid_username").Return(nil)
+	suite.sessionRepository.On("GetActiveSessions", mock.Anything, "valid_username").Return(sessions, nil)
+	suite.tokenRepository.On("RevokeTokenFamily", mock.Anything, "other").Return(nil)
+	suite.sessionRepository.On("RevokeSession", mock.Anything, "other").Return(nil)
+	err := suite.usecase.ChangePassword(context.TODO(), domain.Subject{Username: "valid_username", SessionID: "current"}, "valid_password", "new_password")
+
+	suite.NoError(err, "no error when the current password is correct")
+	suite.repository.AssertExpectations(suite.T())
+	suite.resetRepository.AssertExpectations(suite.T())
+	suite.sessionRepository.AssertExpectations(suite.T())
+	suite.sessionRepository.AssertNotCalled(suite.T(), "RevokeSession", mock.Anything, "current")
+}
+
+func (suite *userUsecaseSuite) TestChangePassword_Negative() {
+	suite.expectLoginAttempts()
+	suite.repository.On("GetByUsername", mock.Anything, "valid_username").Return(domain.User{Username: "valid_username", Password: "valid_password"}, nil)
+	suite.repository.On("GetByUsername", mock.Anything, "other_username").Return(domain.User{Username: "other_username", Password: "incorrect_password"}, nil)
+
+	err := suite.usecase.ChangePassword(context.TODO(), domain.Subject{Username: "other_username"}, "incorrect_password", "new_password")
+	suite.Error(err, "error when the current password is incorrect")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
+
+	err = suite.usecase.ChangePassword(context.TODO(), domain.Subject{Username: "valid_username"}, "valid_password", "short")
+	suite.Error(err, "error when the new password is too short")
+	suite.Equal(domain.ERR_BAD_REQUEST, err.GetCode())
+
+	suite.repository.AssertNotCalled(suite.T(), "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// Tests that wrong current passwords lock the account like failed logins
+func (suite *userUsecaseSuite) TestChangePassword_Lockout() {
+	suite.usecase.LoginAttemptRepository = repository.NewInMemoryLoginAttemptRepository()
+	suite.repository.On("GetByUsername", mock.Anything, "valid_username").Return(domain.User{Username: "valid_username", Password: "incorrect_password"}, nil)
+	subject := domain.Subject{Username: "valid_username"}
+
+	for i := 0; i < 3; i++ {
+		err := suite.usecase.ChangePassword(context.TODO(), subject, "guessed_password", "new_password")
+		suite.Error(err, "error when the current password is incorrect")
+		suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
+	}
+
+	err := suite.usecase.ChangePassword(context.TODO(), subject, "guessed_password", "new_password")
+	suite.Error(err, "error when the current password has been guessed too often")
+	suite.Equal(domain.ERR_TOO_MANY_REQUESTS, err.GetCode())
+
+	_, err = suite.usecase.UpdateProfile(context.TODO(), subject, "guessed_password", domain.ProfileUpdate{})
+	suite.Error(err, "error when the account is locked")
+	suite.Equal(domain.ERR_TOO_MANY_REQUESTS, err.GetCode())
+
+	_, loginErr := suite.usecase.ValidateAndGetToken(context.TODO(), domain.User{Username: "valid_username", Password: "guessed_password"}, "")
+	suite.Equal(domain.ERR_TOO_MANY_REQUESTS, loginErr.GetCode(), "the logins of the account are locked as well")
+	suite.repository.AssertNumberOfCalls(suite.T(), "GetByUsername", 3)
+	suite.repository.AssertNotCalled(suite.T(), "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// expects a valid login challenge of the user to be used
+func (suite *userUsecaseSuite) expectChallenge(username string) {
+	challenge := domain.MFAChallenge{TokenHash: "hash_challenge", Username: username, ExpiresAt: time.Now().Add(time.Minute), Used: true}
+	suite.challenges.On("UseChallenge", mock.Anything, "hash_challenge").Return(challenge, nil)
+}
+
+// expects a new session to be started
+func (suite *userUsecaseSuite) expectSession() {
+	suite.tokenRepository.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("RefreshToken")).Return(nil)
+	suite.sessionRepository.On("CreateSession", mock.Anything, mock.AnythingOfType("Session")).Return(nil)
+}
+
+func (suite *userUsecaseSuite) TestValidateAndGetToken_MFAChallenge() {
+	suite.expectMFARequired()
+	suite.expectLoginAttempts()
+	storedUser := domain.User{Username: "valid_username", Password: "valid_password", Role: "user", Verified: true}
+	suite.repository.On("GetByUsername", mock.Anything, storedUser.Username).Return(storedUser, nil)
+	suite.mfa.On("GetMFA", mock.Anything, "valid_username").Return(domain.MFAEnrollment{Username: "valid_username", Secret: "SECRET", Enabled: true}, nil)
+	suite.challenges.On("CreateChallenge", mock.Anything, mock.AnythingOfType("MFAChallenge")).Return(nil)
+	tokens, err := suite.usecase.ValidateAndGetToken(context.TODO(), storedUser, "10.0.0.1")
+
+	suite.NoError(err, "no error when the password of a user with a second factor is valid")
+	suite.Equal(domain.TokenPair{ChallengeToken: "new_refresh_token"}, tokens)
+	suite.challenges.AssertCalled(suite.T(), "CreateChallenge", mock.Anything, mock.MatchedBy(func(challenge domain.MFAChallenge) bool {
+		return challenge.TokenHash == "hash_new_refresh_token" && challenge.Username == "valid_username" && challenge.ExpiresAt.After(challenge.CreatedAt)
+	}))
+	suite.sessionRepository.AssertNotCalled(suite.T(), "CreateSession", mock.Anything, mock.Anything)
+	suite.loginAttempts.AssertNotCalled(suite.T(), "ClearLoginAttempts", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestValidateAndGetToken_MFARequiredRole() {
+	suite.expectNoMFA()
+	suite.expectLoginAttempts()
+	storedUser := domain.User{Username: "admin_username", Password: "valid_password", Role: "admin", Verified: true}
+	suite.repository.On("GetByUsername", mock.Anything, storedUser.Username).Return(storedUser, nil)
+	suite.challenges.On("CreateChallenge", mock.Anything, mock.AnythingOfType("MFAChallenge")).Return(nil)
+	suite.expectMFARequired("moderator", "admin")
+	tokens, err := suite.usecase.ValidateAndGetToken(context.TODO(), storedUser, "")
+
+	suite.NoError(err, "no error when the role requires a second factor that is not set up")
+	suite.Equal(domain.TokenPair{ChallengeToken: "new_refresh_token", MFAEnrollmentRequired: true}, tokens)
+	suite.sessionRepository.AssertNotCalled(suite.T(), "CreateSession", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestVerifyMFALogin_Positive() {
+	suite.expectMFARequired()
+	suite.expectLoginAttempts()
+	suite.expectChallenge("valid_username")
+	suite.expectSession()
+	suite.repository.On("GetByUsername", mock.Anything, "valid_username").Return(domain.User{Username: "valid_username", Role: "user", Verified: true}, nil)
+	suite.mfa.On("GetMFA", mock.Anything, "valid_username").Return(domain.MFAEnrollment{Username: "valid_username", Secret: "SECRET", Enabled: true, LastUsedStep: 99}, nil)
+	suite.mfa.On("UseTOTPStep", mock.Anything, "valid_username", int64(100)).Return(nil)
+	tokens, err := suite.usecase.VerifyMFALogin(context.TODO(), "challenge", "123456", "", "10.0.0.1")
+
+	suite.NoError(err, "no error when a valid challenge and TOTP code are provided")
+	suite.Equal("valid_token", tokens.AccessToken)
+	suite.Equal("new_refresh_token", tokens.RefreshToken)
+	suite.Nil(tokens.RecoveryCodes)
+	suite.mfa.AssertExpectations(suite.T())
+	suite.loginAttempts.AssertCalled(suite.T(), "ClearLoginAttempts", mock.Anything, "account:valid_username")
+	suite.sessionRepository.AssertCalled(suite.T(), "CreateSession", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestVerifyMFALogin_WrongCode() {
+	suite.expectMFARequired()
+	suite.expectLoginAttempts()
+	suite.expectChallenge("valid_username")
+	suite.repository.On("GetByUsername", mock.Anything, "valid_username").Return(domain.User{Username: "valid_username", Role: "user", Verified: true}, nil)
+	suite.mfa.On("GetMFA", mock.Anything, "valid_username").Return(domain.MFAEnrollment{Username: "valid_username", Secret: "SECRET", Enabled: true}, nil)
+	_, err := suite.usecase.VerifyMFALogin(context.TODO(), "challenge", "000000", "", "10.0.0.1")
+
+	suite.Error(err, "error when the TOTP code is wrong")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
+	suite.loginAttempts.AssertCalled(suite.T(), "RecordLoginFailure", mock.Anything, "account:valid_username", mock.Anything, mock.Anything)
+	suite.loginAttempts.AssertCalled(suite.T(), "RecordLoginFailure", mock.Anything, "ip:10.0.0.1", mock.Anything, mock.Anything)
+	suite.mfa.AssertNotCalled(suite.T(), "UseTOTPStep", mock.Anything, mock.Anything, mock.Anything)
+	suite.sessionRepository.AssertNotCalled(suite.T(), "CreateSession", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestVerifyMFALogin_ReplayedCode() {
+	suite.expectMFARequired()
+	suite.expectLoginAttempts()
+	suite.expectChallenge("valid_username")
+	suite.repository.On("GetByUsername", mock.Anything, "valid_username").Return(domain.User{Username: "valid_username", Role: "user", Verified: true}, nil)
+	suite.mfa.On("GetMFA", mock.Anything, "valid_username").Return(domain.MFAEnrollment{Username: "valid_username", Secret: "SECRET", Enabled: true, LastUsedStep: 99}, nil)
+	suite.mfa.On("UseTOTPStep", mock.Anything, "valid_username", int64(100)).Return(domain.UserError{Message: "Authentication code has already been used", Code: domain.ERR_CONFLICT})
+	_, err := suite.usecase.VerifyMFALogin(context.TODO(), "challenge", "123456", "", "")
+
+	suite.Error(err, "error when the TOTP code has already been used")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
+	suite.sessionRepository.AssertNotCalled(suite.T(), "CreateSession", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestVerifyMFALogin_RecoveryCode() {
+	suite.expectMFARequired()
+	suite.expectLoginAttempts()
+	suite.expectChallenge("valid_username")
+	suite.expectSession()
+	suite.repository.On("GetByUsername", mock.Anything, "valid_username").Return(domain.User{Username: "valid_username", Role: "user", Verified: true}, nil)
+	suite.mfa.On("GetMFA", mock.Anything, "valid_username").Return(domain.MFAEnrollment{Username: "valid_username", Secret: "SECRET", Enabled: true}, nil)
+	suite.mfa.On("UseRecoveryCode", mock.Anything, "valid_username", "hash_code00001").Return(nil)
+	tokens, err := suite.usecase.VerifyMFALogin(context.TODO(), "challenge", "", " CODE 00001", "")
+
+	suite.NoError(err, "no error when an unused recovery code is provided, whatever its case and separators")
+	suite.Equal("valid_token", tokens.AccessToken)
+	suite.mfa.AssertExpectations(suite.T())
+}
+
+func (suite *userUsecaseSuite) TestVerifyMFALogin_UnknownRecoveryCode() {
+	suite.expectMFARequired()
+	suite.expectLoginAttempts()
+	suite.expectChallenge("valid_username")
+	suite.repository.On("GetByUsername", mock.Anything, "valid_username").Return(domain.User{Username: "valid_username", Role: "user", Verified: true}, nil)
+	suite.mfa.On("GetMFA", mock.Anything, "valid_username").Return(domain.MFAEnrollment{Username: "valid_username", Secret: "SECRET", Enabled: true}, nil)
+	suite.mfa.On("UseRecoveryCode", mock.Anything, "valid_username", "hash_used").Return(domain.UserError{Message: "Recovery code not found", Code: domain.ERR_NOT_FOUND})
+	_, err := suite.usecase.VerifyMFALogin(context.TODO(), "challenge", "", "used", "")
+
+	suite.Error(err, "error when the recovery code is unknown or has been used")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
+	suite.loginAttempts.AssertCalled(suite.T(), "RecordLoginFailure", mock.Anything, "account:valid_username", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestVerifyMFALogin_InvalidChallenge() {
+	suite.challenges.On("UseChallenge", mock.Anything, "hash_unknown").Return(domain.MFAChallenge{}, domain.UserError{Message: "Login challenge not found", Code: domain.ERR_NOT_FOUND})
+	_, err := suite.usecase.VerifyMFALogin(context.TODO(), "unknown", "123456", "", "")
+	suite.Error(err, "error when the challenge is unknown or has been used")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
+
+	expired := domain.MFAChallenge{TokenHash: "hash_expired", Username: "valid_username", ExpiresAt: time.Now().Add(-time.Second), Used: true}
+	suite.challenges.On("UseChallenge", mock.Anything, "hash_expired").Return(expired, nil)
+	_, err = suite.usecase.VerifyMFALogin(context.TODO(), "expired", "123456", "", "")
+	suite.Error(err, "error when the challenge has expired")
+	suite.Equal(domain.ERR_UNAUTHORIZED, err.GetCode())
+	suite.repository.AssertNotCalled(suite.T(), "GetByUsername", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestVerifyMFALogin_Enrollment() {
+	suite.expectLoginAttempts()
+	suite.expectChallenge("admin_username")
+	suite.expectSession()
+	suite.repository.On("GetByUsername", mock.Anything, "admin_username").Return(domain.User{Username: "admin_username", Role: "admin", Verified: true}, nil)
+	suite.mfa.On("GetMFA", mock.Anything, "admin_username").Return(domain.MFAEnrollment{Username: "admin_username", Secret: "SECRET"}, nil)
+	suite.mfa.On("SaveMFA", mock.Anything, mock.AnythingOfType("MFAEnrollment")).Return(nil)
+	suite.expectMFARequired("admin")
+	tokens, err := suite.usecase.VerifyMFALogin(context.TODO(), "challenge", "123456", "", "")
+
+	suite.NoError(err, "no error when the pending second factor is confirmed during the login")
+	suite.Equal("valid_token", tokens.AccessToken)
+	suite.Len(tokens.RecoveryCodes, 10)
+	suite.mfa.AssertCalled(suite.T(), "SaveMFA", mock.Anything, mock.MatchedBy(func(enrollment domain.MFAEnrollment) bool {
+		return enrollment.Username == "admin_username" && enrollment.Enabled && enrollment.LastUsedStep == 100 &&
+			len(enrollment.RecoveryCodes) == 10 && strings.HasPrefix(enrollment.RecoveryCodes[0], "hash_code")
+	}))
+}
+
+func (suite *userUsecaseSuite) TestVerifyMFALogin_EnrollmentMissing() {
+	suite.expectNoMFA()
+	suite.expectLoginAttempts()
+	suite.expectChallenge("admin_username")
+	suite.repository.On("GetByUsername", mock.Anything, "admin_username").Return(domain.User{Username: "admin_username", Role: "admin", Verified: true}, nil)
+	suite.expectMFARequired("admin")
+	_, err := suite.usecase.VerifyMFALogin(context.TODO(), "challenge", "123456", "", "")
+
+	suite.Error(err, "error when the role requires a second factor that has not been set up")
+	suite.Equal(domain.ERR_CONFLICT, err.GetCode())
+	suite.sessionRepository.AssertNotCalled(suite.T(), "CreateSession", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestEnrollMFALogin() {
+	suite.expectNoMFA()
+	challenge := domain.MFAChallenge{TokenHash: "hash_challenge", Username: "admin_username", ExpiresAt: time.Now().Add(time.Minute)}
+	suite.challenges.On("GetChallenge", mock.Anything, "hash_challenge").Return(challenge, nil)
+	suite.repository.On("GetByUsername", mock.Anything, "admin_username").Return(domain.User{Username: "admin_username", Role: "admin"}, nil)
+	suite.mfa.On("SaveMFA", mock.Anything, mock.AnythingOfType("MFAEnrollment")).Return(nil)
+	suite.roles.On("GetRole", mock.Anything, "admin").Return(domain.Role{Name: "admin"}, nil).Once()
+	suite.expectMFARequired("admin")
+
+	_, err := suite.usecase.EnrollMFALogin(context.TODO(), "challenge")
+	suite.Error(err, "error when the role does not require a second factor")
+	suite.Equal(domain.ERR_CONFLICT, err.GetCode())
+
+	setup, err := suite.usecase.EnrollMFALogin(context.TODO(), "challenge")
+	suite.NoError(err, "no error when the role requires a second factor that is not set up")
+	suite.Equal(domain.TOTPSetup{Secret: "NEWSECRET", URI: "otpauth://totp/task_manager_api:admin_username?secret=NEWSECRET"}, setup)
+	suite.mfa.AssertCalled(suite.T(), "SaveMFA", mock.Anything, mock.MatchedBy(func(enrollment domain.MFAEnrollment) bool {
+		return enrollment.Username == "admin_username" && enrollment.Secret == "NEWSECRET" && !enrollment.Enabled
+	}))
+	suite.challenges.AssertNotCalled(suite.T(), "UseChallenge", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestEnrollTOTP() {
+	suite.expectLoginAttempts()
+	suite.repository.On("GetByUsername", mock.Anything, "valid_username").Return(domain.User{Username: "valid_username", Password: "valid_password", Role: "user"}, nil)
+	suite.mfa.On("GetMFA", mock.Anything, "valid_username").Return(domain.MFAEnrollment{}, domain.UserError{Message: "Two-factor authentication is not set up", Code: domain.ERR_NOT_FOUND}).Once()
+	suite.mfa.On("GetMFA", mock.Anything, "valid_username").Return(domain.MFAEnrollment{Username: "valid_username", Enabled: true}, nil)
+	suite.mfa.On("SaveMFA", mock.Anything, mock.AnythingOfType("MFAEnrollment")).Return(nil)
+
+	setup, err := suite.usecase.EnrollTOTP(context.TODO(), domain.Subject{Username: "valid_username"}, "valid_password")
+	suite.NoError(err, "no error when a user without a second factor enrolls")
+	suite.Equal("NEWSECRET", setup.Secret)
+	suite.Contains(setup.URI, "secret=NEWSECRET")
+
+	_, err = suite.usecase.EnrollTOTP(context.TODO(), domain.Subject{Username: "valid_username"}, "valid_password")
+	suite.Error(err, "error when the second factor is already enabled")
+	suite.Equal(domain.ERR_CONFLICT, err.GetCode())
+	suite.mfa.AssertNumberOfCalls(suite.T(), "SaveMFA", 1)
+}
+
+func (suite *userUsecaseSuite) TestConfirmTOTP() {
+	suite.mfa.On("GetMFA", mock.Anything, "valid_username").Return(domain.MFAEnrollment{Username: "valid_username", Secret: "SECRET"}, nil)
+	suite.mfa.On("SaveMFA", mock.Anything, mock.AnythingOfType("MFAEnrollment")).Return(nil)
+
+	_, err := suite.usecase.ConfirmTOTP(context.TODO(), domain.Subject{Username: "valid_username"}, "000000")
+	suite.Error(err, "error when the code does not match the pending secret")
+	suite.Equal(domain.ERR_BAD_REQUEST, err.GetCode())
+	suite.mfa.AssertNotCalled(suite.T(), "SaveMFA", mock.Anything, mock.Anything)
+
+	recoveryCodes, err := suite.usecase.ConfirmTOTP(context.TODO(), domain.Subject{Username: "valid_username"}, "123456")
+	suite.NoError(err, "no error when the code matches the pending secret")
+	suite.Len(recoveryCodes, 10)
+	suite.mfa.AssertCalled(suite.T(), "SaveMFA", mock.Anything, mock.MatchedBy(func(enrollment domain.MFAEnrollment) bool {
+		return enrollment.Enabled && enrollment.Secret == "SECRET" && enrollment.LastUsedStep == 100 && len(enrollment.RecoveryCodes) == 10
+	}))
+}
+
+func (suite *userUsecaseSuite) TestRegenerateRecoveryCodes() {
+	suite.mfa.On("GetMFA", mock.Anything, "valid_username").Return(domain.MFAEnrollment{Username: "valid_username", Secret: "SECRET", Enabled: true, LastUsedStep: 99}, nil)
+	suite.mfa.On("UseTOTPStep", mock.Anything, "valid_username", int64(100)).Return(nil)
+	suite.mfa.On("SaveMFA", mock.Anything, mock.AnythingOfType("MFAEnrollment")).Return(nil)
+
+	_, err := suite.usecase.RegenerateRecoveryCodes(context.TODO(), domain.Subject{Username: "valid_username"}, "000000")
+	suite.Error(err, "error when the TOTP code is wrong")
+	suite.Equal(domain.ERR_BAD_REQUEST, err.GetCode())
+
+	recoveryCodes, err := suite.usecase.RegenerateRecoveryCodes(context.TODO(), domain.Subject{Username: "valid_username"}, "123456")
+	suite.NoError(err, "no error when the TOTP code is valid")
+	suite.Len(recoveryCodes, 10)
+	suite.mfa.AssertNumberOfCalls(suite.T(), "SaveMFA", 1)
+}
+
+func (suite *userUsecaseSuite) TestDisableMFA() {
+	suite.expectLoginAttempts()
+	suite.repository.On("GetByUsername", mock.Anything, "valid_username").Return(domain.User{Username: "valid_username", Password: "valid_password", Role: "user"}, nil)
+	suite.mfa.On("GetMFA", mock.Anything, "valid_username").Return(domain.MFAEnrollment{Username: "valid_username", Secret: "SECRET", Enabled: true, LastUsedStep: 99}, nil)
+	suite.mfa.On("UseTOTPStep", mock.Anything, "valid_username", int64(100)).Return(nil)
+	suite.mfa.On("DeleteMFA", mock.Anything, "valid_username").Return(nil)
+
+	suite.roles.On("GetRole", mock.Anything, "user").Return(domain.Role{Name: "user", MFARequired: true}, nil).Once()
+	suite.expectMFARequired()
+	err := suite.usecase.DisableMFA(context.TODO(), domain.Subject{Username: "valid_username"}, "valid_password", "123456")
+	suite.Error(err, "error when the role requires a second factor")
+	suite.Equal(domain.ERR_FORBIDDEN, err.GetCode())
+
+	err = suite.usecase.DisableMFA(context.TODO(), domain.Subject{Username: "valid_username"}, "valid_password", "000000")
+	suite.Error(err, "error when the TOTP code is wrong")
+	suite.Equal(domain.ERR_BAD_REQUEST, err.GetCode())
+	suite.mfa.AssertNotCalled(suite.T(), "DeleteMFA", mock.Anything, mock.Anything)
+
+	err = suite.usecase.DisableMFA(context.TODO(), domain.Subject{Username: "valid_username"}, "valid_password", "123456")
+	suite.NoError(err, "no error when the password and the TOTP code are valid")
+	suite.mfa.AssertCalled(suite.T(), "DeleteMFA", mock.Anything, "valid_username")
+}
+
+func (suite *userUsecaseSuite) TestResetMFA() {
+	suite.repository.On("GetByUsername", mock.Anything, "valid_username").Return(domain.User{Username: "valid_username"}, nil)
+	suite.repository.On("GetByUsername", mock.Anything, "unknown").Return(domain.User{}, domain.UserError{Message: "User not found", Code: domain.ERR_BAD_REQUEST})
+	suite.mfa.On("DeleteMFA", mock.Anything, "valid_username").Return(nil)
+
+	err := suite.usecase.ResetMFA(context.TODO(), "valid_username")
+	suite.NoError(err, "no error when the second factor of an existing user is reset")
+
+	err = suite.usecase.ResetMFA(context.TODO(), "unknown")
+	suite.Error(err, "error when the user does not exist")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+	suite.mfa.AssertNumberOfCalls(suite.T(), "DeleteMFA", 1)
+}
+
+func (suite *userUsecaseSuite) TestCreateAPIKey_Positive() {
+	subject := domain.Subject{Username: "valid_username", Role: "user", SessionID: "session_1"}
+	expiresAt := time.Now().Add(24 * time.Hour)
+	suite.apiKeys.On("GetAPIKeys", mock.Anything, subject.Username).Return([]domain.APIKey{}, nil)
+	suite.apiKeys.On("CreateAPIKey", mock.Anything, mock.MatchedBy(func(key domain.APIKey) bool {
+		return key.ID == "generated_family" && key.Username == subject.Username && key.Name == "ci" && key.Prefix == "tmk_new_refr" &&
+			key.KeyHash == "hash_tmk_new_refresh_token" && !key.CreatedAt.IsZero() && key.LastUsedAt.IsZero() &&
+			key.ExpiresAt.Equal(expiresAt.UTC().Truncate(time.Millisecond))
+	})).Return(nil)
+	key, rawKey, err := suite.usecase.CreateAPIKey(context.TODO(), subject, "  ci ", []string{domain.ScopeTasksWrite, domain.ScopeTasksRead, domain.ScopeTasksWrite}, expiresAt)
+
+	suite.NoError(err, "no error when the name, scopes and expiry date are valid")
+	suite.Equal("tmk_new_refresh_token", rawKey, "the key is returned once")
+	suite.Equal([]string{domain.ScopeTasksRead, domain.ScopeTasksWrite}, key.Scopes, "the scopes are sorted without duplicates")
+	suite.apiKeys.AssertExpectations(suite.T())
+}
+
+func (suite *userUsecaseSuite) TestCreateAPIKey_Invalid() {
+	subject := domain.Subject{Username: "valid_username", Role: "user"}
+	tests := []struct {
+		name      string
+		scopes    []string
+		expiresAt time.Time
+		field     string
+	}{
+		{"", []string{domain.ScopeTasksRead}, time.Time{}, "name"},
+		{strings.Repeat("a", 65), []string{domain.ScopeTasksRead}, time.Time{}, "name"},
+		{"ci", []string{}, time.Time{}, "scopes"},
+		{"ci", []string{"users:write"}, time.Time{}, "scopes"},
+		{"ci", []string{domain.ScopeTasksRead}, time.Now().Add(-time.Minute), "expires_at"},
+	}
+
+	for _, test := range tests {
+		_, _, err := suite.usecase.CreateAPIKey(context.TODO(), subject, test.name, test.scopes, test.expiresAt)
+		suite.Error(err, "error when the %v is invalid", test.field)
+		suite.Equal(domain.ERR_BAD_REQUEST, err.GetCode())
+		suite.Equal(test.field, err.GetFields()[0].Field)
+	}
+
+	suite.apiKeys.AssertNotCalled(suite.T(), "CreateAPIKey", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestCreateAPIKey_Limit() {
+	subject := domain.Subject{Username: "valid_username", Role: "user"}
+	suite.apiKeys.On("GetAPIKeys", mock.Anything, subject.Username).Return(make([]domain.APIKey, 20), nil)
+	_, _, err := suite.usecase.CreateAPIKey(context.TODO(), subject, "ci", []string{domain.ScopeTasksRead}, time.Time{})
+
+	suite.Error(err, "error when the user already has the maximum number of keys")
+	suite.Equal(domain.ERR_CONFLICT, err.GetCode())
+	suite.apiKeys.AssertNotCalled(suite.T(), "CreateAPIKey", mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestRevokeAPIKey() {
+	subject := domain.Subject{Username: "valid_username", Role: "user"}
+	suite.apiKeys.On("DeleteAPIKey", mock.Anything, subject.Username, "key_1").Return(nil)
+	suite.apiKeys.On("DeleteAPIKey", mock.Anything, subject.Username, "key_2").Return(domain.UserError{Message: "API key not found", Code: domain.ERR_NOT_FOUND})
+
+	suite.NoError(suite.usecase.RevokeAPIKey(context.TODO(), subject, "key_1"), "no error when revoking an own key")
+
+	err := suite.usecase.RevokeAPIKey(context.TODO(), subject, "key_2")
+	suite.Error(err, "error when the key does not belong to the subject")
+	suite.Equal(domain.ERR_NOT_FOUND, err.GetCode())
+}
+
+func (suite *userUsecaseSuite) TestValidateAPIKey_Positive() {
+	suite.expectRoles()
+	key := domain.APIKey{ID: "key_1", Username: "valid_username", KeyHash: "hash_tmk_valid", Scopes: []string{domain.ScopeTasksRead}, ExpiresAt: time.Now().Add(time.Hour)}
+	suite.apiKeys.On("GetAPIKeyByHash", mock.Anything, "hash_tmk_valid").Return(key, nil)
+	suite.apiKeys.On("TouchAPIKey", mock.Anything, "key_1", mock.AnythingOfType("time.Time")).Return(nil)
+	suite.repository.On("GetByUsername", mock.Anything, "valid_username").Return(domain.User{Username: "valid_username", Role: "admin"}, nil)
+	subject, err := suite.usecase.ValidateAPIKey(context.TODO(), "tmk_valid")
+
+	suite.NoError(err, "no error when the key is valid")
+	suite.Equal(domain.Subject{Username: "valid_username", Role: "admin", Permissions: domain.Permissions, APIKeyID: "key_1", Scopes: key.Scopes}, subject, "the subject has the current role of the owner")
+	suite.apiKeys.AssertCalled(suite.T(), "TouchAPIKey", mock.Anything, "key_1", mock.AnythingOfType("time.Time"))
+}
+
+func (suite *userUsecaseSuite) TestValidateAPIKey_RecentlyUsed() {
+	key := domain.APIKey{ID: "key_1", Username: "valid_username", KeyHash: "hash_tmk_valid", Scopes: []string{domain.ScopeTasksRead}, LastUsedAt: time.Now().UTC()}
+	suite.apiKeys.On("GetAPIKeyByHash", mock.Anything, "hash_tmk_valid").Return(key, nil)
+	suite.repository.On("GetByUsername", mock.Anything, "valid_username").Return(domain.User{Username: "valid_username", Role: "user"}, nil)
+	suite.expectRoles()
+	subject, err := suite.usecase.ValidateAPIKey(context.TODO(), "tmk_valid")
+
+	suite.NoError(err, "no error when the key has been used recently")
+	suite.Equal(domain.DefaultUserPermissions, subject.Permissions, "the subject has the permissions of the role of the owner")
+	suite.apiKeys.AssertNotCalled(suite.T(), "TouchAPIKey", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *userUsecaseSuite) TestValidateAPIKey_Negative() {
+	suite.apiKeys.On("GetAPIKeyByHash", mock.Anything, "hash_tmk_unknown").Return(domain.APIKey{}, domain.UserError{Message: "API key not found", Code: domain.ERR_NOT_FOUND})
+	suite.apiKeys.On("GetAPIKeyByHash", mock.Anything, "hash_tmk_expired").Return(domain.APIKey{ID: "key_1", Username: "valid_username", ExpiresAt: time.Now().Add(-time.Minute)}, nil)
+	suite.apiKeys.On("GetAPIKeyByHash", mock.Anything, "hash_tmk_deactivated").Return(domain.APIKey{ID: "key_2", Username: "deactivated"}, nil)
+	suite.apiKeys.On("GetAPIKeyByHash", mock.Anything, "hash_tmk_deleted").Return(domain.APIKey{ID: "key_3", Username: "deleted"}, nil)
+	suite.repository.On("GetByUsername", mock.Anything, "deactivated").Return(domain.User{Username: "deactivated", Role: "user", Deactivated: true}, nil)
+	suite.repository.On("GetByUsername", mock.Anything, "deleted").Return(domain.User{}, domain.UserError{Message: "User not found", Code: domain.ERR_BAD_REQUEST})
+
+	tests := []struct {
+		key  string
+		code string
+	}{
+		{"new_refresh_token", domain.ERR_UNAUTHORIZED},
+		{"tmk_unknown", domain.ERR_UNAUTHORIZED},
+		{"tmk_expired", domain.ERR_UNAUTHORIZED},
+		{"tmk_deactivated", domain.ERR_FORBIDDEN},
+		{"tmk_deleted", domain.ERR_UNAUTHORIZED},
+	}
+
+	for _, test := range tests {
+		_, err := suite.usecase.ValidateAPIKey(context.TODO(), test.key)
+		suite.Error(err, "error when validating '%v'", test.key)
+		suite.Equal(test.code, err.GetCode())
+	}
+
+	suite.apiKeys.AssertNotCalled(suite.T(), "GetAPIKeyByHash", mock.Anything, "hash_new_refresh_token")
+	suite.apiKeys.AssertNotCalled(suite.T(), "TouchAPIKey", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestUserUsecase(t *testing.T) {
 	viper.SetConfigFile("../.env")
+	viper.SetDefault("REFRESH_TOKEN_LIFESPAN_HOURS", 168)
+	viper.SetDefault("PASSWORD_RESET_TOKEN_LIFESPAN_MINUTES", 30)
+	viper.SetDefault("EMAIL_VERIFICATION_TOKEN_LIFESPAN_HOURS", 24)
+	viper.SetDefault("TOTP_ISSUER", "task_manager_api")
 	viper.ReadInConfig()
 
 	suite.Run(t, new(userUsecaseSuite))
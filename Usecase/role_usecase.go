@@ -0,0 +1,293 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	domain "task_manager_api/Domain"
+	"time"
+)
+
+/* The pattern that the names of the roles must match */
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,31}$`)
+
+/* Implements the RoleUsecaseInterface defined in `domain`*/
+type RoleUsecase struct {
+	RoleRepository domain.RoleRepositoryInterface
+	UserRepository domain.UserRepositoryInterface
+	Timeout        time.Duration
+}
+
+/* Checks whether the role is one of the built-in roles that can not be deleted */
+func isBuiltInRole(name string) bool {
+	return name == domain.RoleAdmin || name == domain.RoleUser
+}
+
+/*
+Retrieves the role with the provided name. The `admin` role is always
+granted every permission whatever is stored for it, and the built-in roles
+fall back to their default permissions, without requiring a second factor,
+until they are changed through the roles API.
+*/
+func getRole(c context.Context, roleRepository domain.RoleRepositoryInterface, name string) (domain.Role, domain.CodedError) {
+	role, err := roleRepository.GetRole(c, name)
+	if name == domain.RoleAdmin && (err == nil || err.GetCode() == domain.ERR_NOT_FOUND) {
+		return domain.Role{Name: domain.RoleAdmin, Permissions: slices.Clone(domain.Permissions), MFARequired: role.MFARequired, BuiltIn: true}, nil
+	}
+
+	if err != nil && err.GetCode() == domain.ERR_NOT_FOUND && name == domain.RoleUser {
+		return domain.Role{Name: domain.RoleUser, Permissions: slices.Clone(domain.DefaultUserPermissions), BuiltIn: true}, nil
+	}
+
+	if err != nil {
+		return domain.Role{}, err
+	}
+
+	role.BuiltIn = isBuiltInRole(name)
+	return role, nil
+}
+
+/*
+Checks that a role with the provided name exists so that it can be assigned
+to users or used as a filter. The field holding the role is named in the error.
+*/
+func checkRoleExists(c context.Context, roleRepository domain.RoleRepositoryInterface, field string, name string) domain.CodedError {
+	if isBuiltInRole(name) {
+		return nil
+	}
+
+	if !roleNamePattern.MatchString(name) {
+		return invalidUserField(field, fmt.Sprintf("Invalid role: no role named '%v' exists", name))
+	}
+
+	_, err := roleRepository.GetRole(c, name)
+	if err != nil && err.GetCode() == domain.ERR_NOT_FOUND {
+		return invalidUserField(field, fmt.Sprintf("Invalid role: no role named '%v' exists", name))
+	}
+
+	return err
+}
+
+/*
+Checks that the subject holds every permission granted by the role, so that
+nobody can hand out, to others or to themselves, more than they are allowed
+to do. Only admins, who hold every permission, can grant the `admin` role.
+*/
+func checkCanGrant(subject domain.Subject, role domain.Role) domain.CodedError {
+	for _, permission := range role.Permissions {
+		if domain.Can(subject, permission, domain.Resource{}) {
+			continue
+		}
+
+		if role.Name == domain.RoleAdmin {
+			return domain.UserError{Message: "Only admins can assign or change the admin role", Code: domain.ERR_FORBIDDEN}
+		}
+
+		return domain.UserError{Message: fmt.Sprintf("You can not grant the '%v' permission since you do not hold it", permission), Code: domain.ERR_FORBIDDEN}
+	}
+
+	return nil
+}
+
+/*
+Checks that the role with the provided name exists and that the subject
+holds every permission it grants, so that the subject can assign it to users.
+*/
+func checkCanAssign(c context.Context, roleRepository domain.RoleRepositoryInterface, subject domain.Subject, field string, name string) domain.CodedError {
+	if err := checkRoleExists(c, roleRepository, field, name); err != nil {
+		return err
+	}
+
+	role, err := getRole(c, roleRepository, name)
+	if err != nil {
+		return err
+	}
+
+	return checkCanGrant(subject, role)
+}
+
+/* Normalizes the name of the role and checks it along with its permissions */
+func validateRole(role domain.Role) (domain.Role, domain.CodedError) {
+	role.Name = strings.ToLower(strings.TrimSpace(role.Name))
+	if !roleNamePattern.MatchString(role.Name) {
+		return role, invalidUserField("name", "Role names must start with a letter and contain at most 32 lowercase letters, digits, '_' or '-'")
+	}
+
+	permissions := []string{}
+	for _, permission := range role.Permissions {
+		permission = strings.ToLower(strings.TrimSpace(permission))
+		if !slices.Contains(domain.Permissions, permission) {
+			return role, invalidUserField("permissions", "Invalid permission: must be one of "+strings.Join(domain.Permissions, ", "))
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	slices.Sort(permissions)
+	role.Permissions = slices.Compact(permissions)
+	return role, nil
+}
+
+/*
+Retrieves every role sorted by name, including the built-in roles that
+have not been changed.
+*/
+func (rU *RoleUsecase) GetRoles(c context.Context) ([]domain.Role, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, rU.Timeout)
+	defer cancel()
+
+	stored, err := rU.RoleRepository.GetRoles(ctx)
+	if err != nil {
+		return []domain.Role{}, err
+	}
+
+	roles := []domain.Role{}
+	for _, name := range []string{domain.RoleAdmin, domain.RoleUser} {
+		role, err := getRole(ctx, rU.RoleRepository, name)
+		if err != nil {
+			return []domain.Role{}, err
+		}
+
+		roles = append(roles, role)
+	}
+
+	for _, role := range stored {
+		if !isBuiltInRole(role.Name) {
+			roles = append(roles, role)
+		}
+	}
+
+	slices.SortFunc(roles, func(a, b domain.Role) int { return strings.Compare(a.Name, b.Name) })
+	return roles, nil
+}
+
+/* Retrieves the role with the provided name */
+func (rU *RoleUsecase) GetRole(c context.Context, name string) (domain.Role, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, rU.Timeout)
+	defer cancel()
+
+	return getRole(ctx, rU.RoleRepository, strings.ToLower(strings.TrimSpace(name)))
+}
+
+/*
+Creates the role or replaces the permissions of the existing role with the
+same name, which keeps its second factor requirement. The `admin` role is
+always granted every permission and can not be changed, and the subject can
+only grant the permissions they hold. Existing roles can only be replaced by
+subjects who hold every permission they grant, so that nobody can take away
+permissions they could not have granted.
+*/
+func (rU *RoleUsecase) SaveRole(c context.Context, subject domain.Subject, role domain.Role) (domain.Role, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, rU.Timeout)
+	defer cancel()
+
+	role, err := validateRole(role)
+	if err != nil {
+		return domain.Role{}, err
+	}
+
+	if role.Name == domain.RoleAdmin {
+		return domain.Role{}, domain.UserError{Message: "The permissions of the admin role can not be changed", Code: domain.ERR_FORBIDDEN}
+	}
+
+	if err := checkCanGrant(subject, role); err != nil {
+		return domain.Role{}, err
+	}
+
+	existing, err := getRole(ctx, rU.RoleRepository, role.Name)
+	if err != nil && err.GetCode() != domain.ERR_NOT_FOUND {
+		return domain.Role{}, err
+	}
+
+	if err := checkCanGrant(subject, existing); err != nil {
+		return domain.Role{}, domain.UserError{Message: fmt.Sprintf("You can not change the role '%v' since it grants permissions you do not hold", role.Name), Code: domain.ERR_FORBIDDEN}
+	}
+
+	role.MFARequired = existing.MFARequired
+	if err := rU.RoleRepository.SaveRole(ctx, domain.Role{Name: role.Name, Permissions: role.Permissions, MFARequired: role.MFARequired}); err != nil {
+		return domain.Role{}, err
+	}
+
+	role.BuiltIn = isBuiltInRole(role.Name)
+	return role, nil
+}
+
+/*
+Sets whether the users of the role must log in with a second factor. Any
+role can require one, including the built-in roles. Since lifting the
+requirement weakens the accounts of the role, the subject must hold every
+permission the role grants.
+*/
+func (rU *RoleUsecase) SetRoleMFA(c context.Context, subject domain.Subject, name string, required bool) (domain.Role, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, rU.Timeout)
+	defer cancel()
+
+	role, err := getRole(ctx, rU.RoleRepository, strings.ToLower(strings.TrimSpace(name)))
+	if err != nil {
+		return domain.Role{}, err
+	}
+
+	if err := checkCanGrant(subject, role); err != nil {
+		return domain.Role{}, err
+	}
+
+	role.MFARequired = required
+	if err := rU.RoleRepository.SaveRole(ctx, domain.Role{Name: role.Name, Permissions: role.Permissions, MFARequired: role.MFARequired}); err != nil {
+		return domain.Role{}, err
+	}
+
+	return role, nil
+}
+
+/*
+Deletes the custom role with the provided name. The built-in roles can not
+be deleted, and neither can the roles that are still assigned to users. The
+subject must hold every permission the role grants.
+*/
+func (rU *RoleUsecase) DeleteRole(c context.Context, subject domain.Subject, name string) domain.CodedError {
+	ctx, cancel := context.WithTimeout(c, rU.Timeout)
+	defer cancel()
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	if isBuiltInRole(name) {
+		return domain.UserError{Message: "Built-in roles can not be deleted", Code: domain.ERR_FORBIDDEN}
+	}
+
+	role, err := getRole(ctx, rU.RoleRepository, name)
+	if err != nil {
+		return err
+	}
+
+	if err := checkCanGrant(subject, role); err != nil {
+		return domain.UserError{Message: fmt.Sprintf("You can not delete the role '%v' since it grants permissions you do not hold", name), Code: domain.ERR_FORBIDDEN}
+	}
+
+	_, total, err := rU.UserRepository.GetUsers(ctx, domain.UserQuery{Role: name, SortBy: "username", SortOrder: domain.SortAscending, Limit: 1})
+	if err != nil {
+		return err
+	}
+
+	if total > 0 {
+		return domain.UserError{Message: fmt.Sprintf("The role is still assigned to %v user(s)", total), Code: domain.ERR_CONFLICT}
+	}
+
+	return rU.RoleRepository.DeleteRole(ctx, name)
+}
+
+/*
+Retrieves the permissions granted by the role with the provided name. Used
+by the auth middleware to resolve the permissions of each request.
+*/
+func (rU *RoleUsecase) RolePermissions(c context.Context, name string) ([]string, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, rU.Timeout)
+	defer cancel()
+
+	role, err := getRole(ctx, rU.RoleRepository, name)
+	if err != nil {
+		return []string{}, err
+	}
+
+	return role.Permissions, nil
+}
@@ -2,6 +2,9 @@ package usecase
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
 	domain "task_manager_api/Domain"
 	"time"
 )
@@ -10,55 +13,417 @@ import (
 type TaskUsecase struct {
 	TaskRepository domain.TaskRepositoryInterface
 	Timeout        time.Duration
+	GenerateID     func() string
 }
 
-/* Calls GetAllTasks in the repository after setting the timeout */
-func (tU *TaskUsecase) GetAllTasks(c context.Context) ([]domain.Task, domain.CodedError) {
+/*
+Returns the current time truncated to the millisecond precision with
+which the timestamps are stored in the DB.
+*/
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+/* The task statuses in the order in which they are listed in error messages */
+var taskStatuses = []string{domain.StatusTodo, domain.StatusInProgress, domain.StatusBlocked, domain.StatusDone, domain.StatusArchived}
+
+/* The task fields that can be used to sort the results of GetAllTasks */
+var taskSortFields = []string{"id", "title", "status", "due_date", "created_at", "updated_at"}
+
+/*
+Validates the provided query and fills in the default values for the
+pagination and sort parameters. Returns the normalized query.
+*/
+func validateTaskQuery(query domain.TaskQuery) (domain.TaskQuery, domain.CodedError) {
+	query.Status = strings.ToLower(strings.TrimSpace(query.Status))
+	query.Title = strings.TrimSpace(query.Title)
+	query.SortBy = strings.ToLower(strings.TrimSpace(query.SortBy))
+	query.SortOrder = strings.ToLower(strings.TrimSpace(query.SortOrder))
+
+	if query.Limit == 0 {
+		query.Limit = domain.DefaultPageLimit
+	}
+	if query.Limit < 0 || query.Limit > domain.MaxPageLimit {
+		return query, invalidTaskField("limit", fmt.Sprintf("Limit must be between 1 and %v", domain.MaxPageLimit))
+	}
+
+	if query.Offset < 0 {
+		return query, invalidTaskField("offset", "Offset can not be negative")
+	}
+
+	if query.Status != "" && !domain.IsValidTaskStatus(query.Status) {
+		return query, invalidTaskField("status", "Invalid status: must be one of "+strings.Join(taskStatuses, ", "))
+	}
+
+	if query.SortBy == "" {
+		query.SortBy = "id"
+	}
+	if !slices.Contains(taskSortFields, query.SortBy) {
+		return query, invalidTaskField("sort", "Invalid sort field: must be one of "+strings.Join(taskSortFields, ", "))
+	}
+
+	if query.SortOrder == "" {
+		query.SortOrder = domain.SortAscending
+	}
+	if query.SortOrder != domain.SortAscending && query.SortOrder != domain.SortDescending {
+		return query, invalidTaskField("order", "Invalid sort order: must be either 'asc' or 'desc'")
+	}
+
+	if !query.DueAfter.IsZero() && !query.DueBefore.IsZero() && query.DueAfter.After(query.DueBefore) {
+		return query, invalidTaskField("due_after", "due_after can not be later than due_before")
+	}
+
+	return query, nil
+}
+
+/*
+Returns the error for an invalid field of a task or of a task query. The
+field is named as it appears in the request.
+*/
+func invalidTaskField(field string, message string) domain.TaskError {
+	return domain.TaskError{Message: message, Code: domain.ERR_BAD_REQUEST, Fields: []domain.FieldError{{Field: field, Message: message}}}
+}
+
+/*
+Checks whether the subject is allowed to view the provided task. Subjects
+with `task.read.any` can view every task while the others can only view the
+tasks they own or have been assigned.
+*/
+func canViewTask(subject domain.Subject, task domain.Task) bool {
+	return domain.Can(subject, domain.PermissionTaskRead, domain.Resource{Holders: []string{task.Owner, task.Assignee}})
+}
+
+/*
+Checks whether the subject is allowed to replace the task with the updated
+one. Subjects that can update the task as its owner (or with
+`task.update.any`) can edit all of its fields, while assignees are only
+allowed to change its status.
+*/
+func authorizeTaskUpdate(subject domain.Subject, task domain.Task, updatedTask domain.Task) domain.CodedError {
+	if domain.Can(subject, domain.PermissionTaskUpdate, domain.Resource{Holders: []string{task.Owner}}) {
+		return nil
+	}
+
+	if !domain.Can(subject, domain.PermissionTaskUpdate, domain.Resource{Holders: []string{task.Assignee}}) {
+		return domain.TaskError{Message: "You are not allowed to update this task", Code: domain.ERR_FORBIDDEN}
+	}
+
+	if updatedTask.Title != task.Title || updatedTask.Description != task.Description || !updatedTask.DueDate.Equal(task.DueDate) || updatedTask.Assignee != task.Assignee {
+		return domain.TaskError{Message: "Assignees can only update the status of a task", Code: domain.ERR_FORBIDDEN}
+	}
+
+	return nil
+}
+
+/*
+Sanitizes the mutable fields of the task and validates them with the
+business rules. Returns the sanitized task.
+*/
+func validateTask(task domain.Task) (domain.Task, domain.CodedError) {
+	task.Title = strings.TrimSpace(task.Title)
+	task.Assignee = strings.TrimSpace(task.Assignee)
+	task.Status = strings.ToLower(strings.TrimSpace(task.Status))
+
+	if task.Title == "" {
+		return task, invalidTaskField("title", "Title can not be empty")
+	}
+
+	if !domain.IsValidTaskStatus(task.Status) {
+		return task, invalidTaskField("status", "Invalid status: must be one of "+strings.Join(taskStatuses, ", "))
+	}
+
+	return task, nil
+}
+
+/*
+Checks whether the task is allowed to move to the status of the updated
+task and returns the status history of the task with the change recorded
+on behalf of the subject. The history is returned unchanged if the status
+stays the same.
+*/
+func transitionTaskStatus(subject domain.Subject, task domain.Task, updatedTask domain.Task) ([]domain.StatusChange, domain.CodedError) {
+	if updatedTask.Status == task.Status {
+		return task.StatusHistory, nil
+	}
+
+	if !domain.CanTransitionTaskStatus(task.Status, updatedTask.Status) {
+		return nil, domain.TaskError{Message: fmt.Sprintf("Tasks can not move from '%v' to '%v'", task.Status, updatedTask.Status), Code: domain.ERR_INVALID_TRANSITION}
+	}
+
+	change := domain.StatusChange{From: task.Status, To: updatedTask.Status, ChangedBy: subject.Username, ChangedAt: updatedTask.UpdatedAt}
+	return append(slices.Clone(task.StatusHistory), change), nil
+}
+
+/*
+Applies the merge patch to the task and returns the resulting task along
+with a copy of the patch whose values have been sanitized and converted to
+the types of the corresponding task fields. Only the mutable fields can be
+patched and the required ones (title and status) can not be removed.
+*/
+func applyTaskPatch(task domain.Task, patch domain.TaskPatch) (domain.Task, domain.TaskPatch, domain.CodedError) {
+	for key, value := range patch {
+		text, isString := value.(string)
+		switch {
+		case key == "title" || key == "status":
+			if !isString {
+				return task, nil, invalidTaskField(key, key+" must be a string")
+			}
+		case key == "description" || key == "assignee" || key == "due_date":
+			if value != nil && !isString {
+				return task, nil, invalidTaskField(key, key+" must be a string or null")
+			}
+		default:
+			return task, nil, invalidTaskField(key, key+" can not be patched")
+		}
+
+		switch key {
+		case "title":
+			task.Title = text
+		case "status":
+			task.Status = text
+		case "description":
+			task.Description = text
+		case "assignee":
+			task.Assignee = text
+		case "due_date":
+			task.DueDate = time.Time{}
+			if value != nil {
+				dueDate, err := time.Parse(time.RFC3339Nano, text)
+				if err != nil {
+					return task, nil, invalidTaskField("due_date", "due_date must be an RFC 3339 date or null")
+				}
+
+				task.DueDate = dueDate
+			}
+		}
+	}
+
+	task, err := validateTask(task)
+	if err != nil {
+		return task, nil, err
+	}
+
+	// read the sanitized values back from the task, keeping the removals
+	typedPatch := domain.TaskPatch{}
+	fieldValues := domain.TaskPatch{"title": task.Title, "status": task.Status, "description": task.Description, "assignee": task.Assignee, "due_date": task.DueDate}
+	for key, value := range patch {
+		typedPatch[key] = nil
+		if value != nil {
+			typedPatch[key] = fieldValues[key]
+		}
+	}
+
+	return task, typedPatch, nil
+}
+
+/*
+Checks that the task has not been modified since the expected version was
+read. An expected version of zero skips the check.
+*/
+func checkTaskVersion(task domain.Task, expectedVersion int64) domain.CodedError {
+	if expectedVersion != 0 && expectedVersion != task.Version {
+		return domain.TaskError{Message: "Task has been modified since it was last fetched", Code: domain.ERR_PRECONDITION_FAILED}
+	}
+
+	return nil
+}
+
+/*
+Fetches the task with the provided ID and checks whether the subject can
+view it. Tasks that the subject can not view are reported as not found to
+avoid leaking their existence.
+*/
+func (tU *TaskUsecase) getVisibleTask(c context.Context, subject domain.Subject, taskID string) (domain.Task, domain.CodedError) {
+	task, err := tU.TaskRepository.GetTaskByID(c, taskID)
+	if err != nil {
+		return domain.Task{}, err
+	}
+
+	if !canViewTask(subject, task) {
+		return domain.Task{}, domain.TaskError{Message: "Task not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return task, nil
+}
+
+/*
+Validates the query and calls GetAllTasks in the repository after setting
+the timeout, restricting subjects without `task.read.any` to the tasks they
+own or have been assigned.
+*/
+func (tU *TaskUsecase) GetAllTasks(c context.Context, subject domain.Subject, query domain.TaskQuery) (domain.TaskPage, domain.CodedError) {
 	ctx, cancel := context.WithTimeout(c, tU.Timeout)
 	defer cancel()
-	return tU.TaskRepository.GetAllTasks(ctx)
+
+	query, err := validateTaskQuery(query)
+	if err != nil {
+		return domain.TaskPage{}, err
+	}
+
+	if !domain.Can(subject, domain.PermissionTaskRead, domain.Resource{}) {
+		return domain.TaskPage{}, domain.TaskError{Message: "You are not allowed to view tasks", Code: domain.ERR_FORBIDDEN}
+	}
+
+	query.VisibleTo = ""
+	if !domain.Can(subject, domain.PermissionTaskReadAny, domain.Resource{}) {
+		query.VisibleTo = subject.Username
+	}
+
+	tasks, total, err := tU.TaskRepository.GetAllTasks(ctx, query)
+	if err != nil {
+		return domain.TaskPage{}, err
+	}
+
+	return domain.TaskPage{Tasks: tasks, Total: total, Limit: query.Limit, Offset: query.Offset}, nil
 }
 
-/* Calls GetTaskById in the repository after setting the timeout */
-func (tU *TaskUsecase) GetTaskByID(c context.Context, taskID string) (domain.Task, domain.CodedError) {
+/*
+Calls GetTaskById in the repository after setting the timeout and checks
+whether the subject can view the task
+*/
+func (tU *TaskUsecase) GetTaskByID(c context.Context, subject domain.Subject, taskID string) (domain.Task, domain.CodedError) {
 	ctx, cancel := context.WithTimeout(c, tU.Timeout)
 	defer cancel()
-	return tU.TaskRepository.GetTaskByID(ctx, taskID)
+	return tU.getVisibleTask(ctx, subject, taskID)
 }
 
 /*
-Checks if a task with a similar ID exists before calling AddTask in the repository
-with the provided user data after setting the timeout.
+Validates the task, generates its ID and timestamps, records the subject as
+its owner and the initial status in its history, and calls AddTask in the
+repository after setting the timeout. Any ID or timestamp provided by the
+client is ignored. Returns the created task.
 */
-func (tU *TaskUsecase) AddTask(c context.Context, newTask domain.Task) domain.CodedError {
+func (tU *TaskUsecase) AddTask(c context.Context, subject domain.Subject, newTask domain.Task) (domain.Task, domain.CodedError) {
 	ctx, cancel := context.WithTimeout(c, tU.Timeout)
 	defer cancel()
 
-	_, err := tU.TaskRepository.GetTaskByID(c, newTask.ID)
-	if err == nil {
-		return domain.TaskError{Message: "Task with the provided ID already exists", Code: domain.ERR_BAD_REQUEST}
+	if !domain.Can(subject, domain.PermissionTaskCreate, domain.Resource{}) {
+		return domain.Task{}, domain.TaskError{Message: "You are not allowed to create tasks", Code: domain.ERR_FORBIDDEN}
 	}
 
-	if err.GetCode() != domain.ERR_NOT_FOUND {
-		return err
+	if strings.TrimSpace(newTask.Status) == "" {
+		newTask.Status = domain.StatusTodo
+	}
+
+	newTask, err := validateTask(newTask)
+	if err != nil {
+		return domain.Task{}, err
+	}
+
+	newTask.ID = tU.GenerateID()
+	newTask.Owner = subject.Username
+	newTask.CreatedAt = now()
+	newTask.UpdatedAt = newTask.CreatedAt
+	newTask.Version = 1
+	newTask.StatusHistory = []domain.StatusChange{{To: newTask.Status, ChangedBy: subject.Username, ChangedAt: newTask.CreatedAt}}
+
+	if err = tU.TaskRepository.AddTask(ctx, newTask); err != nil {
+		return domain.Task{}, err
+	}
+
+	return newTask, nil
+}
+
+/*
+Validates the updated task and checks whether the subject is allowed to
+replace the task with it before calling UpdateTask in the repository with the
+provided ID after setting the timeout. Every mutable field is replaced and
+status changes are validated and recorded. The update is rejected if the task
+has been modified since the expected version, or since it was read for the checks.
+*/
+func (tU *TaskUsecase) UpdateTask(c context.Context, subject domain.Subject, taskID string, updatedTask domain.Task, expectedVersion int64) (domain.Task, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, tU.Timeout)
+	defer cancel()
+
+	updatedTask, err := validateTask(updatedTask)
+	if err != nil {
+		return domain.Task{}, err
+	}
+
+	task, err := tU.getVisibleTask(ctx, subject, taskID)
+	if err != nil {
+		return domain.Task{}, err
+	}
+
+	if err = checkTaskVersion(task, expectedVersion); err != nil {
+		return domain.Task{}, err
+	}
+
+	if err = authorizeTaskUpdate(subject, task, updatedTask); err != nil {
+		return domain.Task{}, err
+	}
+
+	updatedTask.UpdatedAt = now()
+	updatedTask.StatusHistory, err = transitionTaskStatus(subject, task, updatedTask)
+	if err != nil {
+		return domain.Task{}, err
 	}
 
-	return tU.TaskRepository.AddTask(ctx, newTask)
+	return tU.TaskRepository.UpdateTask(ctx, taskID, updatedTask, task.Version)
 }
 
 /*
-Calls UpdateTask in the repository with the provided ID and updated data
-after setting the timeout
+Applies the merge patch to the task, validates the result and checks whether
+the subject is allowed to make the changes before calling PatchTask in the
+repository after setting the timeout. Only the fields present in the patch
+are modified and fields set to null are cleared. Versions are checked the
+same way as UpdateTask.
 */
-func (tU *TaskUsecase) UpdateTask(c context.Context, taskID string, updatedTask domain.Task) (domain.Task, domain.CodedError) {
+func (tU *TaskUsecase) PatchTask(c context.Context, subject domain.Subject, taskID string, patch domain.TaskPatch, expectedVersion int64) (domain.Task, domain.CodedError) {
 	ctx, cancel := context.WithTimeout(c, tU.Timeout)
 	defer cancel()
-	return tU.TaskRepository.UpdateTask(ctx, taskID, updatedTask)
+
+	task, err := tU.getVisibleTask(ctx, subject, taskID)
+	if err != nil {
+		return domain.Task{}, err
+	}
+
+	if err = checkTaskVersion(task, expectedVersion); err != nil {
+		return domain.Task{}, err
+	}
+
+	patchedTask, typedPatch, err := applyTaskPatch(task, patch)
+	if err != nil {
+		return domain.Task{}, err
+	}
+
+	if err = authorizeTaskUpdate(subject, task, patchedTask); err != nil {
+		return domain.Task{}, err
+	}
+
+	patchedTask.UpdatedAt = now()
+	typedPatch["updated_at"] = patchedTask.UpdatedAt
+	if patchedTask.Status != task.Status {
+		typedPatch["status_history"], err = transitionTaskStatus(subject, task, patchedTask)
+		if err != nil {
+			return domain.Task{}, err
+		}
+	}
+
+	return tU.TaskRepository.PatchTask(ctx, taskID, typedPatch, task.Version)
 }
 
-/* Calls DeleteTask with the provided ID in the repository after setting the timeout */
-func (tU *TaskUsecase) DeleteTask(c context.Context, taskID string) domain.CodedError {
+/*
+Checks whether the subject is allowed to manage the task before calling
+DeleteTask with the provided ID in the repository after setting the timeout.
+The deletion is rejected if the task has been modified since the expected version.
+*/
+func (tU *TaskUsecase) DeleteTask(c context.Context, subject domain.Subject, taskID string, expectedVersion int64) domain.CodedError {
 	ctx, cancel := context.WithTimeout(c, tU.Timeout)
 	defer cancel()
-	return tU.TaskRepository.DeleteTask(ctx, taskID)
+
+	task, err := tU.getVisibleTask(ctx, subject, taskID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkTaskVersion(task, expectedVersion); err != nil {
+		return err
+	}
+
+	if !domain.Can(subject, domain.PermissionTaskDelete, domain.Resource{Holders: []string{task.Owner}}) {
+		return domain.TaskError{Message: "Only the owner of a task can delete it", Code: domain.ERR_FORBIDDEN}
+	}
+
+	return tU.TaskRepository.DeleteTask(ctx, taskID, task.Version)
 }
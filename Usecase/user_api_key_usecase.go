@@ -0,0 +1,199 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	domain "task_manager_api/Domain"
+	"time"
+	"unicode/utf8"
+)
+
+/*
+The format of the API keys. Every key starts with `apiKeyPrefix` so that
+leaked keys are easy to recognize, and the first `apiKeyDisplayLength`
+characters of a key are stored to help its owner tell the keys apart.
+*/
+const (
+	apiKeyPrefix        = "tmk_"
+	apiKeyDisplayLength = 12
+)
+
+/* The limits on the API keys of a user */
+const (
+	maxAPIKeyNameLength = 64
+	maxAPIKeysPerUser   = 20
+)
+
+/*
+How often the last use of an API key is recorded. Scripts can send many
+requests in a row, so the key is only updated once per interval.
+*/
+const apiKeyTouchInterval = time.Minute
+
+/* The error returned for unknown and revoked API keys */
+var errInvalidAPIKey = domain.UserError{Message: "Invalid API key", Code: domain.ERR_UNAUTHORIZED}
+
+/*
+Validates the name, scopes and expiry date of a new API key. Returns the
+trimmed name and the sorted scopes without duplicates.
+*/
+func validateAPIKey(name string, scopes []string, expiresAt time.Time, now time.Time) (string, []string, domain.CodedError) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, invalidUserField("name", "Name is required")
+	}
+
+	if utf8.RuneCountInString(name) > maxAPIKeyNameLength {
+		return "", nil, invalidUserField("name", fmt.Sprintf("Name can not be longer than %v characters", maxAPIKeyNameLength))
+	}
+
+	if len(scopes) == 0 {
+		return "", nil, invalidUserField("scopes", "At least one scope is required")
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(domain.APIKeyScopes, scope) {
+			return "", nil, invalidUserField("scopes", fmt.Sprintf("Invalid scope '%v': must be one of %v", scope, strings.Join(domain.APIKeyScopes, ", ")))
+		}
+	}
+
+	if !expiresAt.IsZero() && !expiresAt.After(now) {
+		return "", nil, invalidUserField("expires_at", "Expiry date must be in the future")
+	}
+
+	scopes = slices.Clone(scopes)
+	slices.Sort(scopes)
+	return name, slices.Compact(scopes), nil
+}
+
+/*
+Creates a personal API key for the subject with the provided name and
+scopes. A zero expiry date creates a key that never expires. Only the hash
+of the key is stored, so the key is returned along with its details and can
+not be retrieved again.
+*/
+func (uC *UserUsecase) CreateAPIKey(c context.Context, subject domain.Subject, name string, scopes []string, expiresAt time.Time) (domain.APIKey, string, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	name, scopes, err := validateAPIKey(name, scopes, expiresAt, now)
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+
+	keys, err := uC.APIKeyRepository.GetAPIKeys(ctx, subject.Username)
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+
+	if len(keys) >= maxAPIKeysPerUser {
+		return domain.APIKey{}, "", domain.UserError{Message: fmt.Sprintf("A user can not have more than %v API keys", maxAPIKeysPerUser), Code: domain.ERR_CONFLICT}
+	}
+
+	token, err := uC.GenerateToken()
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+
+	rawKey := apiKeyPrefix + token
+	key := domain.APIKey{
+		ID:        uC.GenerateID(),
+		Username:  subject.Username,
+		Name:      name,
+		Prefix:    rawKey[:min(apiKeyDisplayLength, len(rawKey))],
+		KeyHash:   uC.HashToken(rawKey),
+		Scopes:    scopes,
+		CreatedAt: now,
+	}
+	if !expiresAt.IsZero() {
+		key.ExpiresAt = expiresAt.UTC().Truncate(time.Millisecond)
+	}
+
+	if err := uC.APIKeyRepository.CreateAPIKey(ctx, key); err != nil {
+		return domain.APIKey{}, "", err
+	}
+
+	return key, rawKey, nil
+}
+
+/* Returns the API keys of the subject, most recent first */
+func (uC *UserUsecase) GetAPIKeys(c context.Context, subject domain.Subject) ([]domain.APIKey, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	return uC.APIKeyRepository.GetAPIKeys(ctx, subject.Username)
+}
+
+/*
+Revokes one of the API keys of the subject by deleting it. The keys of
+other users are reported as not found.
+*/
+func (uC *UserUsecase) RevokeAPIKey(c context.Context, subject domain.Subject, keyID string) domain.CodedError {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	return uC.APIKeyRepository.DeleteAPIKey(ctx, subject.Username, keyID)
+}
+
+/*
+Checks the API key of a request and returns the subject it acts for. Used
+by the auth middleware on every request made with an API key. The role and
+the permissions of the subject are the current ones of the owner of the key,
+so demoted and deactivated users lose their access right away.
+*/
+func (uC *UserUsecase) ValidateAPIKey(c context.Context, rawKey string) (domain.Subject, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return domain.Subject{}, errInvalidAPIKey
+	}
+
+	key, err := uC.APIKeyRepository.GetAPIKeyByHash(ctx, uC.HashToken(rawKey))
+	if err != nil && err.GetCode() == domain.ERR_NOT_FOUND {
+		return domain.Subject{}, errInvalidAPIKey
+	}
+
+	if err != nil {
+		return domain.Subject{}, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	if !key.ExpiresAt.IsZero() && !now.Before(key.ExpiresAt) {
+		return domain.Subject{}, domain.UserError{Message: "API key has expired", Code: domain.ERR_UNAUTHORIZED}
+	}
+
+	user, err := uC.UserRespository.GetByUsername(ctx, key.Username)
+	if err != nil && (err.GetCode() == domain.ERR_NOT_FOUND || err.GetCode() == domain.ERR_BAD_REQUEST) {
+		return domain.Subject{}, errInvalidAPIKey
+	}
+
+	if err != nil {
+		return domain.Subject{}, err
+	}
+
+	if err := checkDeactivated(user); err != nil {
+		return domain.Subject{}, err
+	}
+
+	if now.Sub(key.LastUsedAt) >= apiKeyTouchInterval {
+		err := uC.APIKeyRepository.TouchAPIKey(ctx, key.ID, now)
+		if err != nil && err.GetCode() == domain.ERR_NOT_FOUND {
+			return domain.Subject{}, errInvalidAPIKey
+		}
+
+		if err != nil {
+			return domain.Subject{}, err
+		}
+	}
+
+	role, err := getRole(ctx, uC.RoleRepository, user.Role)
+	if err != nil && err.GetCode() != domain.ERR_NOT_FOUND {
+		return domain.Subject{}, err
+	}
+
+	return domain.Subject{Username: user.Username, Role: user.Role, Permissions: role.Permissions, APIKeyID: key.ID, Scopes: key.Scopes}, nil
+}
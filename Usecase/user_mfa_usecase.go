@@ -0,0 +1,504 @@
+package usecase
+
+import (
+	"context"
+	"slices"
+	"strings"
+	domain "task_manager_api/Domain"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+/* How long a login challenge can be exchanged for the tokens of a session */
+const mfaChallengeLifespan = 5 * time.Minute
+
+/* The number of recovery codes generated for a second factor */
+const recoveryCodeCount = 10
+
+/* The error returned for unknown, used and expired login challenges */
+var errInvalidChallenge = domain.UserError{Message: "Invalid or expired login challenge", Code: domain.ERR_UNAUTHORIZED}
+
+/* The error returned for wrong, replayed and used TOTP and recovery codes */
+var errInvalidMFACode = domain.UserError{Message: "Invalid authentication code", Code: domain.ERR_UNAUTHORIZED}
+
+/*
+Checks whether the admins require the users of the role to log in with a
+second factor. Roles that no longer exist require none.
+*/
+func (uC *UserUsecase) mfaRequired(c context.Context, name string) (bool, domain.CodedError) {
+	role, err := getRole(c, uC.RoleRepository, name)
+	if err != nil && err.GetCode() == domain.ERR_NOT_FOUND {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return role.MFARequired, nil
+}
+
+/* Normalizes a recovery code as typed by the user, which may have lost its dash or case */
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+/*
+Retrieves the TOTP enrollment of the user. An empty enrollment is returned
+when the user has not set up a second factor.
+*/
+func (uC *UserUsecase) getMFA(c context.Context, username string) (domain.MFAEnrollment, domain.CodedError) {
+	enrollment, err := uC.MFARepository.GetMFA(c, username)
+	if err != nil && err.GetCode() == domain.ERR_NOT_FOUND {
+		return domain.MFAEnrollment{}, nil
+	}
+
+	return enrollment, err
+}
+
+/*
+Creates the login challenge of the user, whose token is returned in place of
+the tokens of a session along with whether the user has to set up their
+second factor first. Only the hash of the token is stored.
+*/
+func (uC *UserUsecase) createMFAChallenge(c context.Context, user domain.User, enrollment domain.MFAEnrollment, now time.Time) (domain.TokenPair, domain.CodedError) {
+	challengeToken, err := uC.GenerateToken()
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	err = uC.MFAChallengeRepository.CreateChallenge(c, domain.MFAChallenge{
+		TokenHash: uC.HashToken(challengeToken),
+		Username:  user.Username,
+		CreatedAt: now,
+		ExpiresAt: now.Add(mfaChallengeLifespan),
+	})
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	return domain.TokenPair{ChallengeToken: challengeToken, MFAEnrollmentRequired: !enrollment.Enabled}, nil
+}
+
+/*
+Saves a new pending TOTP enrollment for the user, replacing any pending one,
+and returns its secret along with the otpauth URI for authenticator apps.
+*/
+func (uC *UserUsecase) createPendingMFA(c context.Context, user domain.User) (domain.TOTPSetup, domain.CodedError) {
+	secret, err := uC.GenerateTOTPSecret()
+	if err != nil {
+		return domain.TOTPSetup{}, err
+	}
+
+	err = uC.MFARepository.SaveMFA(c, domain.MFAEnrollment{
+		Username:      user.Username,
+		Secret:        secret,
+		RecoveryCodes: []string{},
+		CreatedAt:     time.Now().UTC().Truncate(time.Millisecond),
+	})
+	if err != nil {
+		return domain.TOTPSetup{}, err
+	}
+
+	return uC.totpSetup(user, secret), nil
+}
+
+/* Returns the secret of the enrollment of the user along with its otpauth URI */
+func (uC *UserUsecase) totpSetup(user domain.User, secret string) domain.TOTPSetup {
+	return domain.TOTPSetup{Secret: secret, URI: uC.TOTPURI(viper.GetString("TOTP_ISSUER"), user.Username, secret)}
+}
+
+/*
+Generates a new set of recovery codes. Returns the codes, which are only
+shown to the user once, along with the hashes that are stored.
+*/
+func (uC *UserUsecase) generateRecoveryCodes() ([]string, []string, domain.CodedError) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for len(codes) < recoveryCodeCount {
+		code, err := uC.GenerateRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		hash := uC.HashToken(normalizeRecoveryCode(code))
+		if slices.Contains(hashes, hash) {
+			continue
+		}
+
+		codes = append(codes, code)
+		hashes = append(hashes, hash)
+	}
+
+	return codes, hashes, nil
+}
+
+/*
+Enables the pending enrollment once the user has proved it generates valid
+codes, with the time step of that code and a new set of recovery codes.
+Returns the recovery codes.
+*/
+func (uC *UserUsecase) enableMFA(c context.Context, enrollment domain.MFAEnrollment, step int64) ([]string, domain.CodedError) {
+	codes, hashes, err := uC.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment.Enabled = true
+	enrollment.LastUsedStep = step
+	enrollment.RecoveryCodes = hashes
+	if err := uC.MFARepository.SaveMFA(c, enrollment); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+/*
+Checks the TOTP code against the enabled enrollment and records its time step
+so that it can not be used again.
+*/
+func (uC *UserUsecase) verifyTOTP(c context.Context, enrollment domain.MFAEnrollment, code string, now time.Time) (int64, domain.CodedError) {
+	step, ok := uC.ValidateTOTP(enrollment.Secret, code, now)
+	if !ok || step <= enrollment.LastUsedStep {
+		return 0, errInvalidMFACode
+	}
+
+	err := uC.MFARepository.UseTOTPStep(c, enrollment.Username, step)
+	if err != nil && err.GetCode() == domain.ERR_CONFLICT {
+		return 0, errInvalidMFACode
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return step, nil
+}
+
+/* Checks the second factor of the login, either a TOTP code or an unused recovery code */
+func (uC *UserUsecase) verifySecondFactor(c context.Context, enrollment domain.MFAEnrollment, code string, recoveryCode string, now time.Time) domain.CodedError {
+	if recoveryCode == "" {
+		_, err := uC.verifyTOTP(c, enrollment, code, now)
+		return err
+	}
+
+	err := uC.MFARepository.UseRecoveryCode(c, enrollment.Username, uC.HashToken(normalizeRecoveryCode(recoveryCode)))
+	if err != nil && err.GetCode() == domain.ERR_NOT_FOUND {
+		return errInvalidMFACode
+	}
+
+	return err
+}
+
+/*
+Checks the TOTP code of a user managing their own second factor, where a
+wrong code is an invalid field of the request rather than a failed login.
+*/
+func (uC *UserUsecase) confirmTOTPCode(c context.Context, enrollment domain.MFAEnrollment, code string) (int64, domain.CodedError) {
+	step, err := uC.verifyTOTP(c, enrollment, code, time.Now())
+	if err != nil && err.GetCode() == domain.ERR_UNAUTHORIZED {
+		return 0, invalidUserField("code", "Invalid authentication code")
+	}
+
+	return step, err
+}
+
+/*
+Exchanges the login challenge and the second factor of the user for the
+tokens of a new session. The second factor is either a TOTP code or one of
+the recovery codes, each of which can only be used once. The challenge is
+used up by the attempt whatever its outcome, and a wrong code counts as a
+failed login of the account and of the client IP.
+
+Users whose role requires a second factor and who have not set one up yet
+confirm the enrollment started by `EnrollMFALogin` with a TOTP code instead,
+and receive their recovery codes along with the tokens.
+*/
+func (uC *UserUsecase) VerifyMFALogin(c context.Context, challengeToken string, code string, recoveryCode string, clientIP string) (domain.TokenPair, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	challenge, err := uC.MFAChallengeRepository.UseChallenge(ctx, uC.HashToken(challengeToken))
+	if err != nil && err.GetCode() == domain.ERR_NOT_FOUND {
+		return domain.TokenPair{}, errInvalidChallenge
+	}
+
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	if !challenge.ExpiresAt.After(now) {
+		return domain.TokenPair{}, errInvalidChallenge
+	}
+
+	throttles := loginThrottles(challenge.Username, clientIP)
+	if err := uC.checkLoginLockout(ctx, throttles, now); err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	user, err := uC.UserRespository.GetByUsername(ctx, challenge.Username)
+	if err != nil && err.GetCode() == domain.ERR_BAD_REQUEST {
+		return domain.TokenPair{}, errInvalidChallenge
+	}
+
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	if err := checkDeactivated(user); err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	enrollment, err := uC.getMFA(ctx, user.Username)
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	required, err := uC.mfaRequired(ctx, user.Role)
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	var recoveryCodes []string
+	switch {
+	case enrollment.Enabled:
+		err := uC.verifySecondFactor(ctx, enrollment, code, recoveryCode, now)
+		if err != nil && err.GetCode() == domain.ERR_UNAUTHORIZED {
+			uC.recordLoginFailure(ctx, throttles, now)
+			return domain.TokenPair{}, errInvalidMFACode
+		}
+
+		if err != nil {
+			return domain.TokenPair{}, err
+		}
+	case required && enrollment.Secret != "":
+		step, ok := uC.ValidateTOTP(enrollment.Secret, code, now)
+		if !ok {
+			uC.recordLoginFailure(ctx, throttles, now)
+			return domain.TokenPair{}, errInvalidMFACode
+		}
+
+		recoveryCodes, err = uC.enableMFA(ctx, enrollment, step)
+		if err != nil {
+			return domain.TokenPair{}, err
+		}
+	case required:
+		return domain.TokenPair{}, domain.UserError{Message: "Two-factor authentication has to be set up before logging in", Code: domain.ERR_CONFLICT}
+	}
+
+	// the failures of the client IP are kept, as it may be trying other accounts
+	if err := uC.LoginAttemptRepository.ClearLoginAttempts(ctx, accountLoginKey(user.Username)); err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	if err := checkVerification(user); err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	tokens, err := uC.startSession(ctx, user, now)
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	tokens.RecoveryCodes = recoveryCodes
+	return tokens, nil
+}
+
+/*
+Starts the TOTP enrollment of a user whose role requires a second factor
+during their login, as they can not log in to set it up otherwise. The
+challenge is not used up, and it is then exchanged for the tokens with a code
+of the new secret. A pending enrollment is returned again as it is.
+*/
+func (uC *UserUsecase) EnrollMFALogin(c context.Context, challengeToken string) (domain.TOTPSetup, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	challenge, err := uC.MFAChallengeRepository.GetChallenge(ctx, uC.HashToken(challengeToken))
+	if err != nil && err.GetCode() == domain.ERR_NOT_FOUND {
+		return domain.TOTPSetup{}, errInvalidChallenge
+	}
+
+	if err != nil {
+		return domain.TOTPSetup{}, err
+	}
+
+	if !challenge.ExpiresAt.After(time.Now()) {
+		return domain.TOTPSetup{}, errInvalidChallenge
+	}
+
+	user, err := uC.UserRespository.GetByUsername(ctx, challenge.Username)
+	if err != nil && err.GetCode() == domain.ERR_BAD_REQUEST {
+		return domain.TOTPSetup{}, errInvalidChallenge
+	}
+
+	if err != nil {
+		return domain.TOTPSetup{}, err
+	}
+
+	if err := checkDeactivated(user); err != nil {
+		return domain.TOTPSetup{}, err
+	}
+
+	enrollment, err := uC.getMFA(ctx, user.Username)
+	if err != nil {
+		return domain.TOTPSetup{}, err
+	}
+
+	if enrollment.Enabled {
+		return domain.TOTPSetup{}, domain.UserError{Message: "Two-factor authentication is already enabled", Code: domain.ERR_CONFLICT}
+	}
+
+	required, err := uC.mfaRequired(ctx, user.Role)
+	if err != nil {
+		return domain.TOTPSetup{}, err
+	}
+
+	if !required {
+		return domain.TOTPSetup{}, domain.UserError{Message: "Two-factor authentication is not required: set it up from the account", Code: domain.ERR_CONFLICT}
+	}
+
+	if enrollment.Secret != "" {
+		return uC.totpSetup(user, enrollment.Secret), nil
+	}
+
+	return uC.createPendingMFA(ctx, user)
+}
+
+/*
+Starts the TOTP enrollment of the subject after checking their current
+password. The enrollment stays pending until it is confirmed with a code,
+and starting again replaces the pending secret.
+*/
+func (uC *UserUsecase) EnrollTOTP(c context.Context, subject domain.Subject, currentPassword string) (domain.TOTPSetup, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	user, err := uC.reauthenticate(ctx, subject, currentPassword)
+	if err != nil {
+		return domain.TOTPSetup{}, err
+	}
+
+	enrollment, err := uC.getMFA(ctx, user.Username)
+	if err != nil {
+		return domain.TOTPSetup{}, err
+	}
+
+	if enrollment.Enabled {
+		return domain.TOTPSetup{}, domain.UserError{Message: "Two-factor authentication is already enabled", Code: domain.ERR_CONFLICT}
+	}
+
+	return uC.createPendingMFA(ctx, user)
+}
+
+/*
+Enables the pending TOTP enrollment of the subject once they provide a valid
+code of the new secret. Returns the recovery codes, which are only shown once.
+*/
+func (uC *UserUsecase) ConfirmTOTP(c context.Context, subject domain.Subject, code string) ([]string, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	enrollment, err := uC.MFARepository.GetMFA(ctx, subject.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	if enrollment.Enabled {
+		return nil, domain.UserError{Message: "Two-factor authentication is already enabled", Code: domain.ERR_CONFLICT}
+	}
+
+	step, ok := uC.ValidateTOTP(enrollment.Secret, code, time.Now())
+	if !ok {
+		return nil, invalidUserField("code", "Invalid authentication code")
+	}
+
+	return uC.enableMFA(ctx, enrollment, step)
+}
+
+/*
+Replaces the recovery codes of the subject with a new set once they provide
+a valid TOTP code. Returns the new codes, which are only shown once.
+*/
+func (uC *UserUsecase) RegenerateRecoveryCodes(c context.Context, subject domain.Subject, code string) ([]string, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	enrollment, err := uC.MFARepository.GetMFA(ctx, subject.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	if !enrollment.Enabled {
+		return nil, domain.UserError{Message: "Two-factor authentication is not set up", Code: domain.ERR_NOT_FOUND}
+	}
+
+	step, err := uC.confirmTOTPCode(ctx, enrollment, code)
+	if err != nil {
+		return nil, err
+	}
+
+	return uC.enableMFA(ctx, enrollment, step)
+}
+
+/*
+Removes the second factor of the subject after checking their current
+password and, if the second factor is enabled, a valid TOTP code. It can not
+be removed when the role of the subject requires it.
+*/
+func (uC *UserUsecase) DisableMFA(c context.Context, subject domain.Subject, currentPassword string, code string) domain.CodedError {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	user, err := uC.reauthenticate(ctx, subject, currentPassword)
+	if err != nil {
+		return err
+	}
+
+	required, err := uC.mfaRequired(ctx, user.Role)
+	if err != nil {
+		return err
+	}
+
+	if required {
+		return domain.UserError{Message: "Two-factor authentication is required for the role " + user.Role, Code: domain.ERR_FORBIDDEN}
+	}
+
+	enrollment, err := uC.MFARepository.GetMFA(ctx, user.Username)
+	if err != nil {
+		return err
+	}
+
+	if enrollment.Enabled {
+		if _, err := uC.confirmTOTPCode(ctx, enrollment, code); err != nil {
+			return err
+		}
+	}
+
+	return uC.MFARepository.DeleteMFA(ctx, user.Username)
+}
+
+/*
+Removes the second factor of the user with the provided username, for users
+who have lost both their authenticator and their recovery codes. Users whose
+role requires a second factor set up a new one during their next login.
+*/
+func (uC *UserUsecase) ResetMFA(c context.Context, username string) domain.CodedError {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	user, err := uC.UserRespository.GetByUsername(ctx, username)
+	if err != nil && err.GetCode() == domain.ERR_BAD_REQUEST {
+		return domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return uC.MFARepository.DeleteMFA(ctx, user.Username)
+}
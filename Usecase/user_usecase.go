@@ -2,102 +2,1331 @@ package usecase
 
 import (
 	"context"
+	"fmt"
 	"net/mail"
+	"net/url"
+	"slices"
 	"strings"
 	domain "task_manager_api/Domain"
 	"time"
+	"unicode/utf8"
 
 	"github.com/spf13/viper"
 )
 
+/* The maximum number of characters of a display name */
+const maxDisplayNameLength = 64
+
+/*
+The login throttling policy. Once an account or a client IP has reached its
+free failures, every further failed login locks it for a delay that doubles
+with each failure, up to the maximum lockout. The failures are forgotten once
+no login has failed for the failure window.
+*/
+const (
+	accountFreeLoginFailures = 3
+	ipFreeLoginFailures      = 10
+	loginBackoffBase         = time.Second
+	maxLoginLockout          = 15 * time.Minute
+	loginFailureWindow       = time.Hour
+)
+
+/* The error returned for every failed login, whatever the reason */
+var errInvalidCredentials = domain.UserError{Message: "Invalid username or password", Code: domain.ERR_UNAUTHORIZED}
+
+/* The user fields that can be used to sort the results of GetUsers */
+var userSortFields = []string{"username", "email", "created_at"}
+
 /* Implements the UserUsecaseInterface defined in `domain`*/
 type UserUsecase struct {
-	UserRespository    domain.UserRepositoryInterface
-	Timeout            time.Duration
-	HashUserPassword   func(password string) (string, domain.CodedError)
-	SignJWTWithPayload func(username string, role string, tokenLifeSpan time.Duration, secret string) (string, domain.CodedError)
-	ValidatePassword   func(storedPassword string, currPassword string) domain.CodedError
+	UserRespository        domain.UserRepositoryInterface
+	TaskRepository         domain.TaskRepositoryInterface
+	RefreshTokenRepository domain.RefreshTokenRepositoryInterface
+	SessionRepository      domain.SessionRepositoryInterface
+	ResetTokenRepository   domain.PasswordResetTokenRepositoryInterface
+	VerificationRepository domain.EmailVerificationTokenRepositoryInterface
+	LoginAttemptRepository domain.LoginAttemptRepositoryInterface
+	MFARepository          domain.MFARepositoryInterface
+	MFAChallengeRepository domain.MFAChallengeRepositoryInterface
+	APIKeyRepository       domain.APIKeyRepositoryInterface
+	RoleRepository         domain.RoleRepositoryInterface
+	Notifier               domain.NotifierInterface
+	Timeout                time.Duration
+	HashUserPassword       func(password string) (string, domain.CodedError)
+	SignJWTWithPayload     func(username string, role string, sessionID string, tokenLifeSpan time.Duration) (string, domain.CodedError)
+	ValidatePassword       func(storedPassword string, currPassword string) domain.CodedError
+	GenerateToken          func() (string, domain.CodedError)
+	HashToken              func(token string) string
+	GenerateID             func() string
+	GenerateTOTPSecret     func() (string, domain.CodedError)
+	TOTPURI                func(issuer string, account string, secret string) string
+	ValidateTOTP           func(secret string, code string, now time.Time) (int64, bool)
+	GenerateRecoveryCode   func() (string, domain.CodedError)
+}
+
+/*
+Creates an account through the public signup. The role in the request is
+ignored and every account is created with the `user` role, since only admins
+can create privileged accounts. The account starts unverified and a
+verification token is sent to the email of the user, unless the email
+verification is `off`. Reports whether the token was sent.
+*/
+func (uC *UserUsecase) CreateUser(c context.Context, user domain.User) (bool, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	user.Role = domain.RoleUser
+	return uC.createUser(ctx, user, false)
+}
+
+/*
+Creates an account with the provided role on behalf of an admin, who must
+hold every permission the role grants. Just like with the signup, the
+account starts unverified until the user verifies their email and the
+result reports whether a verification token was sent.
+*/
+func (uC *UserUsecase) CreateAccount(c context.Context, subject domain.Subject, user domain.User) (bool, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	user.Role = strings.ToLower(strings.TrimSpace(user.Role))
+	if err := checkCanAssign(ctx, uC.RoleRepository, subject, "role", user.Role); err != nil {
+		return false, err
+	}
+
+	return uC.createUser(ctx, user, false)
 }
 
-/* Validates the user data with business rules and calls the create function in the repository */
-func (uC *UserUsecase) CreateUser(c context.Context, user domain.User) domain.CodedError {
+/*
+Creates the initial admin account when the API is set up. The account is
+created verified since the notifications may not be configured yet. Used by
+the `create-admin` command and not reachable through the API.
+*/
+func (uC *UserUsecase) BootstrapAdmin(c context.Context, user domain.User) domain.CodedError {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	user.Role = domain.RoleAdmin
+	_, err := uC.createUser(ctx, user, true)
+	return err
+}
+
+/*
+Creates the initial admin account like BootstrapAdmin unless an account with
+the same username already exists, in which case it is left untouched. Used
+when the API starts so that it can run on every start. Reports whether the
+account was created.
+*/
+func (uC *UserUsecase) EnsureAdmin(c context.Context, user domain.User) (bool, domain.CodedError) {
 	ctx, cancel := context.WithTimeout(c, uC.Timeout)
 	defer cancel()
 
+	username := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(user.Username)), " ", "")
+	_, err := uC.UserRespository.GetByUsername(ctx, username)
+	if err == nil {
+		return false, nil
+	}
+
+	if err.GetCode() != domain.ERR_BAD_REQUEST {
+		return false, err
+	}
+
+	user.Role = domain.RoleAdmin
+	if _, err := uC.createUser(ctx, user, true); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+/*
+Validates the user data with business rules and calls the create function in
+the repository. A verification token is sent to the email of the user unless
+the account is created verified, which is always the case when the email
+verification is `off`. Reports whether the token was sent.
+*/
+func (uC *UserUsecase) createUser(c context.Context, user domain.User, verified bool) (bool, domain.CodedError) {
 	user.Username = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(user.Username)), " ", "")
 	user.Email = strings.ToLower(strings.TrimSpace(user.Email))
 	user.Role = strings.ToLower(strings.TrimSpace(user.Role))
+	user.DisplayName = strings.TrimSpace(user.DisplayName)
 
 	// validate username
 	if len(user.Username) < 3 {
-		return domain.UserError{Message: "Username must be atleast 3 characters long", Code: domain.ERR_BAD_REQUEST}
+		return false, invalidUserField("username", "Username must be atleast 3 characters long")
 	}
 
 	// validate email
 	if _, err := mail.ParseAddress(user.Email); err != nil {
-		return domain.UserError{Message: "Invalid email", Code: domain.ERR_BAD_REQUEST}
+		return false, invalidUserField("email", "Invalid email")
+	}
+
+	// validate display name
+	if err := validateDisplayName(user.DisplayName); err != nil {
+		return false, err
 	}
 
 	// validate passowrd
-	if len(user.Password) < 8 {
-		return domain.UserError{Message: "Password must be atleast 8 characters long", Code: domain.ERR_BAD_REQUEST}
+	if err := validatePasswordStrength("password", user.Password); err != nil {
+		return false, err
 	}
 
 	// validate role
-	if user.Role != "admin" && user.Role != "user" {
-		return domain.UserError{Message: "Invalid role: must be either 'user' or 'admin'", Code: domain.ERR_BAD_REQUEST}
+	if err := checkRoleExists(c, uC.RoleRepository, "role", user.Role); err != nil {
+		return false, err
 	}
 
 	// check for duplicate username
 	usernameError := uC.UserRespository.CheckDuplicate(c, "username", user.Username, "An account with the provided username already exists")
 	if usernameError != nil {
-		return usernameError
+		return false, usernameError
 	}
 
 	// check for duplicate email
 	emailError := uC.UserRespository.CheckDuplicate(c, "email", user.Email, "An account with the provided email already exists")
 	if emailError != nil {
-		return emailError
+		return false, emailError
 	}
 
 	// hash the password before storing
 	hashedPwd, hashErr := uC.HashUserPassword(user.Password)
 	if hashErr != nil {
-		return hashErr
+		return false, hashErr
 	}
 
 	user.Password = hashedPwd
-	return uC.UserRespository.CreateUser(ctx, user)
+	user.Verified = verified || viper.GetString("EMAIL_VERIFICATION") == domain.VerificationOff
+	user.Deactivated = false
+	user.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	err := uC.UserRespository.CreateUser(c, user)
+	if err != nil || user.Verified {
+		return false, err
+	}
+
+	if err := uC.sendVerification(c, user); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+/*
+Returns the error for an invalid field of an account or of a user query. The
+field is named as it appears in the request.
+*/
+func invalidUserField(field string, message string) domain.UserError {
+	return domain.UserError{Message: message, Code: domain.ERR_BAD_REQUEST, Fields: []domain.FieldError{{Field: field, Message: message}}}
+}
+
+/*
+Checks that the password satisfies the password rules. The field holding
+the password is named in the error.
+*/
+func validatePasswordStrength(field string, password string) domain.CodedError {
+	if len(password) < 8 {
+		return invalidUserField(field, "Password must be atleast 8 characters long")
+	}
+
+	return nil
+}
+
+/* Checks that the display name is not too long */
+func validateDisplayName(displayName string) domain.CodedError {
+	if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+		return invalidUserField("display_name", fmt.Sprintf("Display name can not be longer than %v characters", maxDisplayNameLength))
+	}
+
+	return nil
+}
+
+/* Checks that the account of the user has not been deactivated */
+func checkDeactivated(user domain.User) domain.CodedError {
+	if user.Deactivated {
+		return domain.UserError{Message: "Account has been deactivated", Code: domain.ERR_FORBIDDEN}
+	}
+
+	return nil
+}
+
+/*
+Checks that the user is allowed to log in with the configured email
+verification policy. Unknown policies are treated as `required`, while
+accounts left unverified before the verification was turned `off` can log in.
+*/
+func checkVerification(user domain.User) domain.CodedError {
+	if user.Verified {
+		return nil
+	}
+
+	switch viper.GetString("EMAIL_VERIFICATION") {
+	case domain.VerificationOptional, domain.VerificationOff:
+		return nil
+	case domain.VerificationGrace:
+		gracePeriod := time.Hour * time.Duration(viper.GetInt("EMAIL_VERIFICATION_GRACE_HOURS"))
+		if time.Since(user.CreatedAt) < gracePeriod {
+			return nil
+		}
+
+		return domain.UserError{Message: "Email address has not been verified and the grace period is over", Code: domain.ERR_FORBIDDEN}
+	}
+
+	return domain.UserError{Message: "Email address has not been verified", Code: domain.ERR_FORBIDDEN}
+}
+
+/* Returns the lifespan of the refresh tokens and thus of the sessions */
+func refreshTokenLifespan() time.Duration {
+	return time.Hour * time.Duration(viper.GetInt("REFRESH_TOKEN_LIFESPAN_HOURS"))
+}
+
+/*
+Signs an access token for the user that is bound to the provided session and
+issues a new refresh token that belongs to the token family of the session.
+Only the hash of the refresh token is stored in the DB.
+*/
+func (uC *UserUsecase) issueTokens(c context.Context, user domain.User, session domain.Session) (domain.TokenPair, domain.CodedError) {
+	tkLifespan := time.Minute * time.Duration(viper.GetInt("TOKEN_LIFESPAN_MINUTES"))
+	accessToken, err := uC.SignJWTWithPayload(user.Username, user.Role, session.ID, tkLifespan)
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	refreshToken, err := uC.GenerateToken()
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	err = uC.RefreshTokenRepository.CreateRefreshToken(c, domain.RefreshToken{
+		TokenHash: uC.HashToken(refreshToken),
+		FamilyID:  session.ID,
+		Username:  user.Username,
+		CreatedAt: session.LastUsedAt,
+		ExpiresAt: session.ExpiresAt,
+	})
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	return domain.TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+/* Revokes the session along with every refresh token issued for it */
+func (uC *UserUsecase) revokeSession(c context.Context, sessionID string) domain.CodedError {
+	err := uC.RefreshTokenRepository.RevokeTokenFamily(c, sessionID)
+	if err != nil {
+		return err
+	}
+
+	err = uC.SessionRepository.RevokeSession(c, sessionID)
+	if err != nil && err.GetCode() != domain.ERR_NOT_FOUND {
+		return err
+	}
+
+	return nil
+}
+
+/* A key under which the failed logins are counted and its free failures */
+type loginThrottle struct {
+	key          string
+	freeFailures int64
+}
+
+/* Returns the key under which the failed logins of the account are counted */
+func accountLoginKey(username string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+/*
+Returns the keys under which the failed logins of the request are counted:
+the submitted username, whether or not the account exists, and the IP of the
+client if it is known.
+*/
+func loginThrottles(username string, clientIP string) []loginThrottle {
+	throttles := []loginThrottle{{key: accountLoginKey(username), freeFailures: accountFreeLoginFailures}}
+	if clientIP != "" {
+		throttles = append(throttles, loginThrottle{key: "ip:" + clientIP, freeFailures: ipFreeLoginFailures})
+	}
+
+	return throttles
+}
+
+/* Returns how long the logins are locked after the provided number of failures */
+func loginLockout(failures int64, freeFailures int64) time.Duration {
+	if failures < freeFailures {
+		return 0
+	}
+
+	lockout := maxLoginLockout
+	if exponent := failures - freeFailures; exponent < 32 {
+		lockout = min(loginBackoffBase<<exponent, maxLoginLockout)
+	}
+
+	return lockout
+}
+
+/* Checks that none of the keys of the login is locked after its failed logins */
+func (uC *UserUsecase) checkLoginLockout(c context.Context, throttles []loginThrottle, now time.Time) domain.CodedError {
+	for _, throttle := range throttles {
+		attempts, err := uC.LoginAttemptRepository.GetLoginAttempts(c, throttle.key)
+		if err != nil && err.GetCode() == domain.ERR_NOT_FOUND {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		lockedUntil := attempts.LastFailure.Add(loginLockout(attempts.Failures, throttle.freeFailures))
+		if attempts.ExpiresAt.After(now) && lockedUntil.After(now) {
+			retryAfter := int64(lockedUntil.Sub(now).Seconds()) + 1
+			return domain.UserError{Message: fmt.Sprintf("Too many failed login attempts: try again in %v seconds", retryAfter), Code: domain.ERR_TOO_MANY_REQUESTS}
+		}
+	}
+
+	return nil
+}
+
+/* Counts the failed login under each of its keys and returns the credential error */
+func (uC *UserUsecase) recordLoginFailure(c context.Context, throttles []loginThrottle, now time.Time) domain.CodedError {
+	for _, throttle := range throttles {
+		_, err := uC.LoginAttemptRepository.RecordLoginFailure(c, throttle.key, now, now.Add(loginFailureWindow))
+		if err != nil {
+			return err
+		}
+	}
+
+	return errInvalidCredentials
 }
 
 /*
-Validates the user credentials with the ones in the DB and returns
-a signed JWT if the credentials match
+Validates the user credentials with the ones in the DB and, if the
+credentials match, starts a new session for which a signed JWT and a
+refresh token are returned. The failed logins are counted for the account
+and for the IP of the client, which are locked for a growing delay once they
+fail too often. The same error is returned for unknown usernames and wrong
+passwords so that the logins can not be used to find the existing accounts.
+
+When the user has enabled a second factor, or their role requires one, only
+a short-lived challenge token is returned, which is exchanged for the tokens
+of the session by `VerifyMFALogin`. The failed logins of the account are then
+kept until the second factor has been verified.
 */
-func (uC *UserUsecase) ValidateAndGetToken(c context.Context, user domain.User) (string, domain.CodedError) {
+func (uC *UserUsecase) ValidateAndGetToken(c context.Context, user domain.User, clientIP string) (domain.TokenPair, domain.CodedError) {
 	ctx, cancel := context.WithTimeout(c, uC.Timeout)
 	defer cancel()
 
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	throttles := loginThrottles(user.Username, clientIP)
+	if err := uC.checkLoginLockout(ctx, throttles, now); err != nil {
+		return domain.TokenPair{}, err
+	}
+
 	storedUser, queryErr := uC.UserRespository.GetByUsername(ctx, user.Username)
+	if queryErr != nil && queryErr.GetCode() != domain.ERR_BAD_REQUEST {
+		return domain.TokenPair{}, queryErr
+	}
+
 	if queryErr != nil {
-		return "", queryErr
+		// hash the password anyway so that unknown usernames take as long as wrong passwords
+		uC.HashUserPassword(user.Password)
+		return domain.TokenPair{}, uC.recordLoginFailure(ctx, throttles, now)
 	}
 
 	// compare the incoming password and the stored (previously hashed) password
-	pwErr := uC.ValidatePassword(storedUser.Password, user.Password)
-	if pwErr != nil {
-		return "", pwErr
+	if pwErr := uC.ValidatePassword(storedUser.Password, user.Password); pwErr != nil {
+		return domain.TokenPair{}, uC.recordLoginFailure(ctx, throttles, now)
 	}
 
-	// signs token with the secret token in the env variables
-	tkLifespan := time.Minute * time.Duration(viper.GetInt("TOKEN_LIFESPAN_MINUTES"))
-	jwtSecret := viper.GetString("SECRET_TOKEN")
-	return uC.SignJWTWithPayload(storedUser.Username, storedUser.Role, tkLifespan, jwtSecret)
+	// the status of the account is only checked once the credentials are known to be valid
+	if err := checkDeactivated(storedUser); err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	if err := checkVerification(storedUser); err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	enrollment, err := uC.getMFA(ctx, storedUser.Username)
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	required, err := uC.mfaRequired(ctx, storedUser.Role)
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	if enrollment.Enabled || required {
+		return uC.createMFAChallenge(ctx, storedUser, enrollment, now)
+	}
+
+	// the failures of the client IP are kept, as it may be trying other accounts
+	if err := uC.LoginAttemptRepository.ClearLoginAttempts(ctx, accountLoginKey(user.Username)); err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	return uC.startSession(ctx, storedUser, now)
+}
+
+/* Starts a new session for the user and issues its first access and refresh tokens */
+func (uC *UserUsecase) startSession(c context.Context, user domain.User, now time.Time) (domain.TokenPair, domain.CodedError) {
+	session := domain.Session{
+		ID:         uC.GenerateID(),
+		Username:   user.Username,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(refreshTokenLifespan()),
+	}
+
+	tokens, err := uC.issueTokens(c, user, session)
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	err = uC.SessionRepository.CreateSession(c, session)
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	return tokens, nil
+}
+
+/*
+Rotates the provided refresh token: the token is marked as used and a new
+access token and refresh token are issued in the same session. If a token
+that has already been rotated is presented again, the whole session is
+revoked since either the legitimate user or an attacker holds a stolen copy.
+*/
+func (uC *UserUsecase) RefreshTokens(c context.Context, refreshToken string) (domain.TokenPair, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	tokenHash := uC.HashToken(refreshToken)
+	storedToken, err := uC.RefreshTokenRepository.UseRefreshToken(ctx, tokenHash)
+	if err != nil && err.GetCode() != domain.ERR_NOT_FOUND {
+		return domain.TokenPair{}, err
+	}
+
+	if err != nil {
+		// the token is either unknown, revoked or has already been rotated
+		storedToken, err = uC.RefreshTokenRepository.GetRefreshToken(ctx, tokenHash)
+		if err != nil && err.GetCode() != domain.ERR_NOT_FOUND {
+			return domain.TokenPair{}, err
+		}
+
+		if err == nil && storedToken.Used && !storedToken.Revoked {
+			if revokeErr := uC.revokeSession(ctx, storedToken.FamilyID); revokeErr != nil {
+				return domain.TokenPair{}, revokeErr
+			}
+
+			return domain.TokenPair{}, domain.UserError{Message: "Refresh token reuse detected: the session has been revoked", Code: domain.ERR_UNAUTHORIZED}
+		}
+
+		return domain.TokenPair{}, domain.UserError{Message: "Invalid refresh token", Code: domain.ERR_UNAUTHORIZED}
+	}
+
+	if storedToken.ExpiresAt.Before(time.Now()) {
+		return domain.TokenPair{}, domain.UserError{Message: "Refresh token expired", Code: domain.ERR_UNAUTHORIZED}
+	}
+
+	session, err := uC.SessionRepository.GetSession(ctx, storedToken.FamilyID)
+	if err != nil && err.GetCode() != domain.ERR_NOT_FOUND {
+		return domain.TokenPair{}, err
+	}
+
+	if err != nil || session.Revoked {
+		return domain.TokenPair{}, domain.UserError{Message: "Session revoked", Code: domain.ERR_UNAUTHORIZED}
+	}
+
+	// fetch the user to sign the access token with their current role
+	storedUser, err := uC.UserRespository.GetByUsername(ctx, storedToken.Username)
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	if err := checkDeactivated(storedUser); err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	if err := checkVerification(storedUser); err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	session.LastUsedAt = time.Now().UTC().Truncate(time.Millisecond)
+	session.ExpiresAt = session.LastUsedAt.Add(refreshTokenLifespan())
+	tokens, err := uC.issueTokens(ctx, storedUser, session)
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	err = uC.SessionRepository.ExtendSession(ctx, session.ID, session.LastUsedAt, session.ExpiresAt)
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	return tokens, nil
+}
+
+/* Revokes the session of the provided refresh token */
+func (uC *UserUsecase) Logout(c context.Context, refreshToken string) domain.CodedError {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	storedToken, err := uC.RefreshTokenRepository.GetRefreshToken(ctx, uC.HashToken(refreshToken))
+	if err != nil && err.GetCode() == domain.ERR_NOT_FOUND {
+		return domain.UserError{Message: "Invalid refresh token", Code: domain.ERR_UNAUTHORIZED}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return uC.revokeSession(ctx, storedToken.FamilyID)
+}
+
+/* Returns the active sessions of the subject, marking the one the request was made with */
+func (uC *UserUsecase) GetSessions(c context.Context, subject domain.Subject) ([]domain.Session, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	sessions, err := uC.SessionRepository.GetActiveSessions(ctx, subject.Username)
+	if err != nil {
+		return []domain.Session{}, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == subject.SessionID
+	}
+
+	return sessions, nil
+}
+
+/*
+Revokes one of the sessions of the subject. The sessions of other users are
+reported as not found.
+*/
+func (uC *UserUsecase) RevokeSession(c context.Context, subject domain.Subject, sessionID string) domain.CodedError {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	session, err := uC.SessionRepository.GetSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if session.Username != subject.Username {
+		return domain.UserError{Message: "Session not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	return uC.revokeSession(ctx, sessionID)
+}
+
+/* Revokes every session of the user with the provided username */
+func (uC *UserUsecase) RevokeUserSessions(c context.Context, username string) domain.CodedError {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	_, err := uC.UserRespository.GetByUsername(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	return uC.revokeUserSessions(ctx, username)
+}
+
+/* Revokes every session of the user along with all of their refresh tokens */
+func (uC *UserUsecase) revokeUserSessions(c context.Context, username string) domain.CodedError {
+	err := uC.RefreshTokenRepository.RevokeUserTokens(c, username)
+	if err != nil {
+		return err
+	}
+
+	return uC.SessionRepository.RevokeUserSessions(c, username)
+}
+
+/*
+Checks that the session an access token was issued for has not been
+revoked. Used by the auth middleware on every authenticated request.
+*/
+func (uC *UserUsecase) ValidateSession(c context.Context, sessionID string) domain.CodedError {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	session, err := uC.SessionRepository.GetSession(ctx, sessionID)
+	if err != nil && err.GetCode() == domain.ERR_NOT_FOUND {
+		return domain.UserError{Message: "Session not found", Code: domain.ERR_UNAUTHORIZED}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if session.Revoked {
+		return domain.UserError{Message: "Session revoked", Code: domain.ERR_UNAUTHORIZED}
+	}
+
+	return nil
 }
 
-/* Calls PromoteUser with the provided username in the repository after setting the timeout */
-func (uC *UserUsecase) Promote(c context.Context, username string) domain.CodedError {
+/*
+Calls PromoteUser with the provided username in the repository after setting
+the timeout. Only admins can promote users since the `admin` role grants
+every permission.
+*/
+func (uC *UserUsecase) Promote(c context.Context, subject domain.Subject, username string) domain.CodedError {
 	ctx, cancel := context.WithTimeout(c, uC.Timeout)
 	defer cancel()
+
+	if err := checkCanGrant(subject, domain.Role{Name: domain.RoleAdmin, Permissions: domain.Permissions}); err != nil {
+		return err
+	}
+
 	return uC.UserRespository.PromoteUser(ctx, username)
 }
+
+/* rejects the account management operations of admins on their own account */
+func checkNotSelf(subject domain.Subject, username string, operation string) domain.CodedError {
+	if subject.Username == username {
+		return domain.UserError{Message: "Admins can not " + operation + " their own account", Code: domain.ERR_FORBIDDEN}
+	}
+
+	return nil
+}
+
+/*
+Checks that the subject holds every permission granted by the current role of
+the account with the provided username, so that nobody can demote, reassign,
+deactivate or delete an account that is allowed to do more than they are.
+*/
+func (uC *UserUsecase) checkCanManage(c context.Context, subject domain.Subject, username string) domain.CodedError {
+	user, err := uC.UserRespository.GetByUsername(c, username)
+	if err != nil && err.GetCode() == domain.ERR_BAD_REQUEST {
+		return domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	role, err := getRole(c, uC.RoleRepository, user.Role)
+	if err != nil && err.GetCode() == domain.ERR_NOT_FOUND {
+		// the role has been deleted since it was assigned, so it grants nothing
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := checkCanGrant(subject, role); err != nil {
+		return domain.UserError{Message: fmt.Sprintf("You can not manage the account of '%v' since their role grants permissions you do not hold", username), Code: domain.ERR_FORBIDDEN}
+	}
+
+	return nil
+}
+
+/*
+Demotes the admin with the provided username to role `user`. The subject
+must hold every permission of the current role of the user. The sessions of
+the user are revoked since their access tokens still carry the admin role.
+*/
+func (uC *UserUsecase) Demote(c context.Context, subject domain.Subject, username string) domain.CodedError {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	if err := checkNotSelf(subject, username, "demote"); err != nil {
+		return err
+	}
+
+	if err := uC.checkCanManage(ctx, subject, username); err != nil {
+		return err
+	}
+
+	err := uC.UserRespository.DemoteUser(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	return uC.revokeUserSessions(ctx, username)
+}
+
+/*
+Assigns the role with the provided name to the account with the provided
+username. The subject must hold every permission of both the current and
+the new role of the user. The sessions of the user are revoked since their
+access tokens still carry the previous role.
+*/
+func (uC *UserUsecase) SetRole(c context.Context, subject domain.Subject, username string, role string) domain.CodedError {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	if err := checkNotSelf(subject, username, "change the role of"); err != nil {
+		return err
+	}
+
+	if err := uC.checkCanManage(ctx, subject, username); err != nil {
+		return err
+	}
+
+	role = strings.ToLower(strings.TrimSpace(role))
+	if err := checkCanAssign(ctx, uC.RoleRepository, subject, "role", role); err != nil {
+		return err
+	}
+
+	if err := uC.UserRespository.SetRole(ctx, username, role); err != nil {
+		return err
+	}
+
+	return uC.revokeUserSessions(ctx, username)
+}
+
+/*
+Deactivates the account with the provided username. Every session of the
+user is revoked, so the access tokens that have already been issued are
+rejected by the auth middleware right away, and the user can not log in
+until the account is activated again. The subject must hold every permission
+of the role of the user.
+*/
+func (uC *UserUsecase) Deactivate(c context.Context, subject domain.Subject, username string) domain.CodedError {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	if err := checkNotSelf(subject, username, "deactivate"); err != nil {
+		return err
+	}
+
+	if err := uC.checkCanManage(ctx, subject, username); err != nil {
+		return err
+	}
+
+	err := uC.UserRespository.SetDeactivated(ctx, username, true)
+	if err != nil {
+		return err
+	}
+
+	return uC.revokeUserSessions(ctx, username)
+}
+
+/* Activates the deactivated account with the provided username */
+func (uC *UserUsecase) Activate(c context.Context, username string) domain.CodedError {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	return uC.UserRespository.SetDeactivated(ctx, username, false)
+}
+
+/*
+Forgets the failed logins of the account with the provided username, which
+lifts its lockout. The failures of the client IPs are left as they are.
+*/
+func (uC *UserUsecase) UnlockAccount(c context.Context, username string) domain.CodedError {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	user, err := uC.UserRespository.GetByUsername(ctx, username)
+	if err != nil && err.GetCode() == domain.ERR_BAD_REQUEST {
+		return domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return uC.LoginAttemptRepository.ClearLoginAttempts(ctx, accountLoginKey(user.Username))
+}
+
+/*
+Deletes the account with the provided username. The tasks owned by the user
+are deleted while the tasks of other users that were assigned to them are
+left unassigned, and their second factor and API keys are removed. The
+account is deactivated and its sessions are revoked first, and it is only
+deleted once everything else has been cleaned up, so a failed deletion can
+be retried. The subject must hold every permission of the role of the user.
+*/
+func (uC *UserUsecase) DeleteUser(c context.Context, subject domain.Subject, username string) (domain.UserDeletion, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	var deletion domain.UserDeletion
+	if err := checkNotSelf(subject, username, "delete"); err != nil {
+		return deletion, err
+	}
+
+	if err := uC.checkCanManage(ctx, subject, username); err != nil {
+		return deletion, err
+	}
+
+	err := uC.UserRespository.SetDeactivated(ctx, username, true)
+	if err != nil {
+		return deletion, err
+	}
+
+	err = uC.revokeUserSessions(ctx, username)
+	if err != nil {
+		return deletion, err
+	}
+
+	deletion.DeletedTasks, err = uC.TaskRepository.DeleteOwnerTasks(ctx, username)
+	if err != nil {
+		return deletion, err
+	}
+
+	deletion.UnassignedTasks, err = uC.TaskRepository.UnassignTasks(ctx, username, time.Now().UTC().Truncate(time.Millisecond))
+	if err != nil {
+		return deletion, err
+	}
+
+	err = uC.ResetTokenRepository.InvalidateUserResetTokens(ctx, username)
+	if err != nil {
+		return deletion, err
+	}
+
+	err = uC.VerificationRepository.InvalidateUserVerificationTokens(ctx, username)
+	if err != nil {
+		return deletion, err
+	}
+
+	err = uC.MFARepository.DeleteMFA(ctx, username)
+	if err != nil && err.GetCode() != domain.ERR_NOT_FOUND {
+		return deletion, err
+	}
+
+	err = uC.APIKeyRepository.DeleteUserAPIKeys(ctx, username)
+	if err != nil {
+		return deletion, err
+	}
+
+	return deletion, uC.UserRespository.DeleteUser(ctx, username)
+}
+
+/*
+Validates the provided query and fills in the default values for the
+pagination and sort parameters. Returns the normalized query.
+*/
+func validateUserQuery(query domain.UserQuery) (domain.UserQuery, domain.CodedError) {
+	query.Search = strings.TrimSpace(query.Search)
+	query.Role = strings.ToLower(strings.TrimSpace(query.Role))
+	query.SortBy = strings.ToLower(strings.TrimSpace(query.SortBy))
+	query.SortOrder = strings.ToLower(strings.TrimSpace(query.SortOrder))
+
+	if query.Limit == 0 {
+		query.Limit = domain.DefaultPageLimit
+	}
+	if query.Limit < 0 || query.Limit > domain.MaxPageLimit {
+		return query, invalidUserField("limit", fmt.Sprintf("Limit must be between 1 and %v", domain.MaxPageLimit))
+	}
+
+	if query.Offset < 0 {
+		return query, invalidUserField("offset", "Offset can not be negative")
+	}
+
+	if query.SortBy == "" {
+		query.SortBy = "username"
+	}
+	if !slices.Contains(userSortFields, query.SortBy) {
+		return query, invalidUserField("sort", "Invalid sort field: must be one of "+strings.Join(userSortFields, ", "))
+	}
+
+	if query.SortOrder == "" {
+		query.SortOrder = domain.SortAscending
+	}
+	if query.SortOrder != domain.SortAscending && query.SortOrder != domain.SortDescending {
+		return query, invalidUserField("order", "Invalid sort order: must be either 'asc' or 'desc'")
+	}
+
+	return query, nil
+}
+
+/*
+Validates the query and returns the matching page of user profiles along
+with the total number of matching users
+*/
+func (uC *UserUsecase) GetUsers(c context.Context, query domain.UserQuery) (domain.UserPage, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	query, err := validateUserQuery(query)
+	if err != nil {
+		return domain.UserPage{}, err
+	}
+
+	if query.Role != "" {
+		if err := checkRoleExists(ctx, uC.RoleRepository, "role", query.Role); err != nil {
+			return domain.UserPage{}, err
+		}
+	}
+
+	users, total, err := uC.UserRespository.GetUsers(ctx, query)
+	if err != nil {
+		return domain.UserPage{}, err
+	}
+
+	profiles := []domain.UserProfile{}
+	for _, user := range users {
+		profiles = append(profiles, user.Profile())
+	}
+
+	return domain.UserPage{Users: profiles, Total: total, Limit: query.Limit, Offset: query.Offset}, nil
+}
+
+/* Returns the profile of the user with the provided username */
+func (uC *UserUsecase) GetUser(c context.Context, username string) (domain.UserProfile, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	user, err := uC.UserRespository.GetByUsername(ctx, username)
+	if err != nil && err.GetCode() == domain.ERR_BAD_REQUEST {
+		return domain.UserProfile{}, domain.UserError{Message: "User not found", Code: domain.ERR_NOT_FOUND}
+	}
+
+	if err != nil {
+		return domain.UserProfile{}, err
+	}
+
+	return user.Profile(), nil
+}
+
+/* Returns the profile of the subject */
+func (uC *UserUsecase) GetProfile(c context.Context, subject domain.Subject) (domain.UserProfile, domain.CodedError) {
+	return uC.GetUser(c, subject.Username)
+}
+
+/*
+Fetches the account of the subject and checks the provided password against
+it. Used to re-authenticate the users before they change their credentials.
+Wrong passwords count as failed logins of the account, so that a stolen
+access token can not be used to guess the password, and the check is
+rejected while the logins of the account are locked.
+*/
+func (uC *UserUsecase) reauthenticate(c context.Context, subject domain.Subject, currentPassword string) (domain.User, domain.CodedError) {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	throttles := loginThrottles(subject.Username, "")
+	if err := uC.checkLoginLockout(c, throttles, now); err != nil {
+		return domain.User{}, err
+	}
+
+	user, err := uC.UserRespository.GetByUsername(c, subject.Username)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	if err := uC.ValidatePassword(user.Password, currentPassword); err != nil {
+		return domain.User{}, uC.recordLoginFailure(c, throttles, now)
+	}
+
+	if err := uC.LoginAttemptRepository.ClearLoginAttempts(c, accountLoginKey(user.Username)); err != nil {
+		return domain.User{}, err
+	}
+
+	return user, nil
+}
+
+/*
+Changes the email and the display name of the subject after checking their
+current password. A new email has to be verified again: the account becomes
+unverified, a verification token is sent to the new email and the previous
+email is notified of the change.
+*/
+func (uC *UserUsecase) UpdateProfile(c context.Context, subject domain.Subject, currentPassword string, update domain.ProfileUpdate) (domain.UserProfile, domain.CodedError) {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	user, err := uC.reauthenticate(ctx, subject, currentPassword)
+	if err != nil {
+		return domain.UserProfile{}, err
+	}
+
+	previousEmail := user.Email
+	if update.DisplayName != nil {
+		user.DisplayName = strings.TrimSpace(*update.DisplayName)
+		if err := validateDisplayName(user.DisplayName); err != nil {
+			return domain.UserProfile{}, err
+		}
+	}
+
+	if update.Email != nil && strings.ToLower(strings.TrimSpace(*update.Email)) != previousEmail {
+		user.Email = strings.ToLower(strings.TrimSpace(*update.Email))
+		if _, err := mail.ParseAddress(user.Email); err != nil {
+			return domain.UserProfile{}, invalidUserField("email", "Invalid email")
+		}
+
+		user.Verified = false
+	}
+
+	err = uC.UserRespository.UpdateProfile(ctx, user)
+	if err != nil {
+		return domain.UserProfile{}, err
+	}
+
+	if user.Email == previousEmail {
+		return user.Profile(), nil
+	}
+
+	err = uC.Notifier.Notify(ctx, domain.Notification{
+		Recipient: previousEmail,
+		Username:  user.Username,
+		Subject:   "Email address changed",
+		Body:      "The email address of your account has been changed to " + user.Email + ". If you did not make this change, reset your password and contact an admin.",
+	})
+	if err != nil {
+		return domain.UserProfile{}, err
+	}
+
+	return user.Profile(), uC.sendVerification(ctx, user)
+}
+
+/*
+Changes the password of the subject after checking their current password.
+The pending password reset tokens and every other session of the subject are
+revoked, while the session the request was made with is kept.
+*/
+func (uC *UserUsecase) ChangePassword(c context.Context, subject domain.Subject, currentPassword string, newPassword string) domain.CodedError {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	if err := validatePasswordStrength("new_password", newPassword); err != nil {
+		return err
+	}
+
+	user, err := uC.reauthenticate(ctx, subject, currentPassword)
+	if err != nil {
+		return err
+	}
+
+	hashedPwd, err := uC.HashUserPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	err = uC.UserRespository.UpdatePassword(ctx, user.Username, hashedPwd)
+	if err != nil {
+		return err
+	}
+
+	err = uC.ResetTokenRepository.InvalidateUserResetTokens(ctx, user.Username)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := uC.SessionRepository.GetActiveSessions(ctx, user.Username)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.ID == subject.SessionID {
+			continue
+		}
+
+		if err := uC.revokeSession(ctx, session.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+Builds the body of a notification that carries a single-use token. A link to
+the page that handles the token is sent instead of the bare token if the URL
+of the page is configured.
+*/
+func tokenNotificationBody(purpose string, pageURL string, token string, lifespan time.Duration) string {
+	body := "Use the following token to " + purpose + ": " + token
+	if pageURL != "" {
+		body = "Follow the link to " + purpose + ": " + pageURL + "?token=" + url.QueryEscape(token)
+	}
+
+	return body + "\nThe token expires in " + lifespan.String() + "."
+}
+
+/* Returns the lifespan of the password reset tokens */
+func passwordResetTokenLifespan() time.Duration {
+	return time.Minute * time.Duration(viper.GetInt("PASSWORD_RESET_TOKEN_LIFESPAN_MINUTES"))
+}
+
+/*
+Sends a single-use password reset token to the user with the provided email.
+Only the hash of the token is stored and the previously issued tokens of the
+user are invalidated. Unknown emails and deactivated accounts are not
+reported so that the endpoint can not be used to find out which emails are
+registered.
+*/
+func (uC *UserUsecase) ForgotPassword(c context.Context, email string) domain.CodedError {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	storedUser, err := uC.UserRespository.GetByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+	if err != nil && err.GetCode() == domain.ERR_NOT_FOUND {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if storedUser.Deactivated {
+		return nil
+	}
+
+	err = uC.ResetTokenRepository.InvalidateUserResetTokens(ctx, storedUser.Username)
+	if err != nil {
+		return err
+	}
+
+	resetToken, err := uC.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	err = uC.ResetTokenRepository.CreateResetToken(ctx, domain.PasswordResetToken{
+		TokenHash: uC.HashToken(resetToken),
+		Username:  storedUser.Username,
+		CreatedAt: now,
+		ExpiresAt: now.Add(passwordResetTokenLifespan()),
+	})
+	if err != nil {
+		return err
+	}
+
+	return uC.Notifier.Notify(ctx, domain.Notification{
+		Recipient: storedUser.Email,
+		Username:  storedUser.Username,
+		Subject:   "Password reset",
+		Body:      tokenNotificationBody("reset your password", viper.GetString("PASSWORD_RESET_URL"), resetToken, passwordResetTokenLifespan()),
+	})
+}
+
+/*
+Replaces the password of the user the password reset token was issued for.
+The token is consumed even if it has expired and every session of the user is
+revoked once the password has been changed.
+*/
+func (uC *UserUsecase) ResetPassword(c context.Context, resetToken string, newPassword string) domain.CodedError {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	// validate the password before the token is consumed
+	if err := validatePasswordStrength("new_password", newPassword); err != nil {
+		return err
+	}
+
+	storedToken, err := uC.ResetTokenRepository.UseResetToken(ctx, uC.HashToken(resetToken))
+	if err != nil && err.GetCode() != domain.ERR_NOT_FOUND {
+		return err
+	}
+
+	if err != nil || storedToken.ExpiresAt.Before(time.Now()) {
+		return domain.UserError{Message: "Invalid or expired password reset token", Code: domain.ERR_UNAUTHORIZED}
+	}
+
+	hashedPwd, err := uC.HashUserPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	err = uC.UserRespository.UpdatePassword(ctx, storedToken.Username, hashedPwd)
+	if err != nil {
+		return err
+	}
+
+	err = uC.ResetTokenRepository.InvalidateUserResetTokens(ctx, storedToken.Username)
+	if err != nil {
+		return err
+	}
+
+	// the owner of the account has proven themselves, so its lockout is lifted
+	err = uC.LoginAttemptRepository.ClearLoginAttempts(ctx, accountLoginKey(storedToken.Username))
+	if err != nil {
+		return err
+	}
+
+	return uC.revokeUserSessions(ctx, storedToken.Username)
+}
+
+/* Returns the lifespan of the email verification tokens */
+func verificationTokenLifespan() time.Duration {
+	return time.Hour * time.Duration(viper.GetInt("EMAIL_VERIFICATION_TOKEN_LIFESPAN_HOURS"))
+}
+
+/*
+Sends a single-use email verification token to the email of the user after
+invalidating the previously issued tokens of the user. Only the hash of the
+token is stored.
+*/
+func (uC *UserUsecase) sendVerification(c context.Context, user domain.User) domain.CodedError {
+	err := uC.VerificationRepository.InvalidateUserVerificationTokens(c, user.Username)
+	if err != nil {
+		return err
+	}
+
+	verificationToken, err := uC.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	err = uC.VerificationRepository.CreateVerificationToken(c, domain.EmailVerificationToken{
+		TokenHash: uC.HashToken(verificationToken),
+		Username:  user.Username,
+		CreatedAt: now,
+		ExpiresAt: now.Add(verificationTokenLifespan()),
+	})
+	if err != nil {
+		return err
+	}
+
+	return uC.Notifier.Notify(c, domain.Notification{
+		Recipient: user.Email,
+		Username:  user.Username,
+		Subject:   "Verify your email address",
+		Body:      tokenNotificationBody("verify your email address", viper.GetString("EMAIL_VERIFICATION_URL"), verificationToken, verificationTokenLifespan()),
+	})
+}
+
+/* Marks the email of the user the verification token was issued for as verified */
+func (uC *UserUsecase) VerifyEmail(c context.Context, verificationToken string) domain.CodedError {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	storedToken, err := uC.VerificationRepository.UseVerificationToken(ctx, uC.HashToken(verificationToken))
+	if err != nil && err.GetCode() != domain.ERR_NOT_FOUND {
+		return err
+	}
+
+	if err != nil || storedToken.ExpiresAt.Before(time.Now()) {
+		return domain.UserError{Message: "Invalid or expired email verification token", Code: domain.ERR_UNAUTHORIZED}
+	}
+
+	err = uC.UserRespository.VerifyEmail(ctx, storedToken.Username)
+	if err != nil {
+		return err
+	}
+
+	return uC.VerificationRepository.InvalidateUserVerificationTokens(ctx, storedToken.Username)
+}
+
+/*
+Sends a new email verification token to the user with the provided email.
+Just like with the password resets, unknown emails are not reported and
+nothing is sent to accounts that are already verified or deactivated.
+*/
+func (uC *UserUsecase) ResendVerification(c context.Context, email string) domain.CodedError {
+	ctx, cancel := context.WithTimeout(c, uC.Timeout)
+	defer cancel()
+
+	storedUser, err := uC.UserRespository.GetByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+	if err != nil && err.GetCode() == domain.ERR_NOT_FOUND {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if storedUser.Verified || storedUser.Deactivated {
+		return nil
+	}
+
+	return uC.sendVerification(ctx, storedUser)
+}